@@ -4,7 +4,6 @@ import (
 	"context"
 	"database/sql"
 	"fmt"
-	"github.com/vladimirimekov/url-shortener/internal/handlers"
 	"log"
 	"net"
 	"net/http"
@@ -20,10 +19,6 @@ import (
 	"github.com/vladimirimekov/url-shortener/internal/server"
 )
 
-type userIDtype string
-
-const userKey userIDtype = "userid"
-
 var (
 	buildVersion string = "N/A"
 	buildDate    string = "N/A"
@@ -35,20 +30,16 @@ func main() {
 	var dbConnection *sql.DB
 	defer dbConnection.Close()
 
-	cfg, router := server.GetServer(dbConnection)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	cfg, router, h := server.GetServer(ctx, dbConnection)
 
 	go func() {
 		http.ListenAndServe("127.0.0.1:9999", nil)
 	}()
 
 	go func() {
-
-		h := handlers.Handler{
-			LengthOfShortname: cfg.ShortnameLength,
-			Host:              cfg.BaseURL,
-			UserKey:           userKey,
-		}
-
 		listen, err := net.Listen("tcp", ":3200")
 		if err != nil {
 			log.Fatal(err.Error())
@@ -86,6 +77,8 @@ func main() {
 			if err := srv.Shutdown(context.Background()); err != nil {
 				log.Printf("HTTP server Shutdown: %v", err)
 			}
+			cancel()
+			h.Clicks.Close()
 			close(idleConnsClosed)
 		}()
 
@@ -0,0 +1,340 @@
+package analytics
+
+import (
+	"errors"
+	"net/url"
+	"sort"
+	"time"
+
+	"github.com/vladimirimekov/url-shortener/internal/storage"
+	"github.com/vladimirimekov/url-shortener/internal/useragent"
+)
+
+// Interval - размер интервала, по которым группируются переходы.
+type Interval string
+
+// Поддерживаемые интервалы.
+const (
+	Hour Interval = "hour"
+	Day  Interval = "day"
+)
+
+// Ограничения запроса статистики.
+const (
+	maxBuckets = 24 * 93
+	topLimit   = 10
+	direct     = "direct"
+)
+
+// DefaultRule - значение разбивки по правилам таргетинга для переходов на адрес по умолчанию.
+const DefaultRule = "default"
+
+// Ошибки разбора запроса статистики.
+var (
+	ErrInvalidInterval = errors.New("interval must be hour or day")
+	ErrInvalidRange    = errors.New("invalid time range")
+)
+
+// Query описывает запрашиваемый период и интервал группировки.
+// Переходы ботов учитываются в статистике, только если установлен IncludeBots.
+type Query struct {
+	From        time.Time
+	To          time.Time
+	Interval    Interval
+	IncludeBots bool
+}
+
+// NewQuery проверяет параметры запроса и подставляет значения по умолчанию:
+// интервал - день, конец периода - текущий момент, начало - неделя (для интервала в час - сутки) до конца.
+// Границы периода выравниваются по границам интервалов, чтобы каждый интервал учитывался целиком.
+func NewQuery(from, to time.Time, interval string) (Query, error) {
+	q := Query{From: from.UTC(), To: to.UTC(), Interval: Interval(interval)}
+
+	switch q.Interval {
+	case "":
+		q.Interval = Day
+	case Hour, Day:
+	default:
+		return Query{}, ErrInvalidInterval
+	}
+
+	if q.To.IsZero() {
+		q.To = time.Now().UTC()
+	}
+
+	if q.From.IsZero() {
+		if q.Interval == Hour {
+			q.From = q.To.Add(-24 * time.Hour)
+		} else {
+			q.From = q.To.AddDate(0, 0, -7)
+		}
+	}
+
+	if !q.From.Before(q.To) {
+		return Query{}, ErrInvalidRange
+	}
+
+	q.From = q.Interval.Truncate(q.From)
+	if end := q.Interval.Truncate(q.To); end.Before(q.To) {
+		q.To = q.Interval.Next(end)
+	}
+
+	if len(q.buckets()) > maxBuckets {
+		return Query{}, ErrInvalidRange
+	}
+
+	return q, nil
+}
+
+// Truncate возвращает начало интервала, в который попадает момент t.
+func (i Interval) Truncate(t time.Time) time.Time {
+	t = t.UTC()
+	if i == Hour {
+		return t.Truncate(time.Hour)
+	}
+
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+}
+
+// Next возвращает начало интервала, следующего за интервалом с началом t.
+func (i Interval) Next(t time.Time) time.Time {
+	if i == Hour {
+		return t.Add(time.Hour)
+	}
+
+	return t.AddDate(0, 0, 1)
+}
+
+// buckets возвращает начала всех интервалов запрашиваемого периода.
+func (q Query) buckets() []time.Time {
+	var result []time.Time
+
+	for t := q.Interval.Truncate(q.From); t.Before(q.To); t = q.Interval.Next(t) {
+		result = append(result, t)
+		if len(result) > maxBuckets {
+			break
+		}
+	}
+
+	return result
+}
+
+// Contains проверяет, попадает ли момент t в запрашиваемый период.
+func (q Query) Contains(t time.Time) bool {
+	return !t.Before(q.From) && t.Before(q.To)
+}
+
+// Counts проверяет, учитываются ли в статистике переходы класса class.
+func (q Query) Counts(class string) bool {
+	return q.IncludeBots || class != storage.ClassBot
+}
+
+// Summary содержит сводку переходов за период.
+type Summary struct {
+	Clicks    int64
+	Referrers map[string]int64
+	Browsers  map[string]int64
+	OS        map[string]int64
+	Rules     map[string]int64
+	Variants  map[string]int64
+	Visitors  HLL
+}
+
+// NewSummary - конструктор Summary.
+func NewSummary() *Summary {
+	return &Summary{
+		Referrers: map[string]int64{},
+		Browsers:  map[string]int64{},
+		OS:        map[string]int64{},
+		Rules:     map[string]int64{},
+		Variants:  map[string]int64{},
+		Visitors:  NewHLL(),
+	}
+}
+
+// Add учитывает переход в сводке.
+func (s *Summary) Add(c storage.Click) {
+	agent := useragent.Parse(c.UserAgent)
+
+	s.Clicks++
+	s.Referrers[ReferrerHost(c.Referrer)]++
+	s.Browsers[agent.Browser]++
+	s.OS[agent.OS]++
+	s.Rules[RuleName(c.Rule)]++
+	//переходы вне A/B теста не попадают в разбивку по вариантам
+	if c.Variant != "" {
+		s.Variants[c.Variant]++
+	}
+	s.Visitors.Add(c.IPHash + "|" + c.UserAgent)
+}
+
+// Merge объединяет сводку с другой сводкой.
+func (s *Summary) Merge(other *Summary) {
+	s.Clicks += other.Clicks
+	mergeCounts(s.Referrers, other.Referrers)
+	mergeCounts(s.Browsers, other.Browsers)
+	mergeCounts(s.OS, other.OS)
+	mergeCounts(s.Rules, other.Rules)
+	mergeCounts(s.Variants, other.Variants)
+	s.Visitors.Merge(other.Visitors)
+}
+
+// mergeCounts прибавляет к счётчикам dst значения счётчиков src.
+func mergeCounts(dst, src map[string]int64) {
+	for key, value := range src {
+		dst[key] += value
+	}
+}
+
+// ReferrerHost возвращает домен источника перехода либо direct для прямых переходов.
+func ReferrerHost(referrer string) string {
+	if referrer == "" {
+		return direct
+	}
+
+	u, err := url.Parse(referrer)
+	if err != nil || u.Hostname() == "" {
+		return direct
+	}
+
+	return u.Hostname()
+}
+
+// RuleName возвращает название сработавшего правила таргетинга либо DefaultRule для перехода на адрес по умолчанию.
+func RuleName(rule string) string {
+	if rule == "" {
+		return DefaultRule
+	}
+
+	return rule
+}
+
+// Bucket содержит количество переходов за интервал.
+type Bucket struct {
+	Start  time.Time
+	Clicks int64
+}
+
+// Count содержит количество переходов для значения разбивки.
+type Count struct {
+	Name   string
+	Clicks int64
+}
+
+// Report содержит статистику ссылки за период.
+type Report struct {
+	Query          Query
+	Total          int64
+	UniqueVisitors uint64
+	Series         []Bucket
+	Referrers      []Count
+	Browsers       []Count
+	OS             []Count
+	Rules          []Count
+	Variants       []Count
+	Classes        []Count
+
+	//Links заполняется только в отчётах по нескольким ссылкам.
+	Links []Count
+}
+
+// Builder собирает отчёт из переходов, распределяя их по интервалам запроса.
+// Разбивка по классам содержит все классы переходов, в том числе не учтённые в статистике.
+type Builder struct {
+	query   Query
+	total   *Summary
+	buckets map[time.Time]int64
+	classes map[string]int64
+}
+
+// NewBuilder - конструктор Builder.
+func NewBuilder(q Query) *Builder {
+	return &Builder{query: q, total: NewSummary(), buckets: map[time.Time]int64{}, classes: map[string]int64{}}
+}
+
+// AddClick учитывает переход, если он попадает в запрашиваемый период.
+func (b *Builder) AddClick(c storage.Click) {
+	if !b.query.Contains(c.Time) {
+		return
+	}
+
+	b.classes[c.ClickClass()]++
+	if !b.query.Counts(c.ClickClass()) {
+		return
+	}
+
+	b.total.Add(c)
+	b.buckets[b.query.Interval.Truncate(c.Time)]++
+}
+
+// AddSummary учитывает сводку переходов класса class за интервал, начинающийся в момент start.
+func (b *Builder) AddSummary(start time.Time, class string, s *Summary) {
+	b.classes[class] += s.Clicks
+	if !b.query.Counts(class) {
+		return
+	}
+
+	b.total.Merge(s)
+	b.buckets[b.query.Interval.Truncate(start)] += s.Clicks
+}
+
+// Report возвращает собранный отчёт.
+func (b *Builder) Report() Report {
+	r := Report{
+		Query:          b.query,
+		Total:          b.total.Clicks,
+		UniqueVisitors: b.total.Visitors.Count(),
+		Referrers:      top(b.total.Referrers),
+		Browsers:       top(b.total.Browsers),
+		OS:             top(b.total.OS),
+		Rules:          top(b.total.Rules),
+		Variants:       top(b.total.Variants),
+		Classes:        top(b.classes),
+	}
+
+	for _, start := range b.query.buckets() {
+		r.Series = append(r.Series, Bucket{Start: start, Clicks: b.buckets[start]})
+	}
+
+	return r
+}
+
+// Build собирает отчёт по переходам.
+func Build(q Query, clicks []storage.Click) Report {
+	b := NewBuilder(q)
+
+	for _, c := range clicks {
+		b.AddClick(c)
+	}
+
+	return b.Report()
+}
+
+// top возвращает наиболее частые значения разбивки по убыванию количества переходов.
+func top(counts map[string]int64) []Count {
+	result := sortCounts(counts)
+
+	if len(result) > topLimit {
+		result = result[:topLimit]
+	}
+
+	return result
+}
+
+// sortCounts возвращает все значения разбивки по убыванию количества переходов.
+func sortCounts(counts map[string]int64) []Count {
+	result := make([]Count, 0, len(counts))
+
+	for name, clicks := range counts {
+		result = append(result, Count{Name: name, Clicks: clicks})
+	}
+
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].Clicks != result[j].Clicks {
+			return result[i].Clicks > result[j].Clicks
+		}
+		return result[i].Name < result[j].Name
+	})
+
+	return result
+}
@@ -0,0 +1,159 @@
+package analytics
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/vladimirimekov/url-shortener/internal/storage"
+)
+
+const (
+	chromeWindows = "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/120.0.0.0 Safari/537.36"
+	safariIPhone  = "Mozilla/5.0 (iPhone; CPU iPhone OS 17_1 like Mac OS X) AppleWebKit/605.1.15 (KHTML, like Gecko) Version/17.1 Mobile/15E148 Safari/604.1"
+)
+
+// TestNewQuery проверяет разбор параметров запроса статистики.
+func TestNewQuery(t *testing.T) {
+	to := time.Date(2023, 5, 10, 12, 30, 0, 0, time.UTC)
+
+	tests := []struct {
+		name     string
+		from     time.Time
+		to       time.Time
+		interval string
+		wantErr  error
+		wantFrom time.Time
+		wantTo   time.Time
+	}{
+		{name: "default day interval", to: to, wantFrom: time.Date(2023, 5, 3, 0, 0, 0, 0, time.UTC), wantTo: time.Date(2023, 5, 11, 0, 0, 0, 0, time.UTC)},
+		{name: "default hour interval", to: to, interval: "hour", wantFrom: time.Date(2023, 5, 9, 12, 0, 0, 0, time.UTC), wantTo: time.Date(2023, 5, 10, 13, 0, 0, 0, time.UTC)},
+		{name: "aligned range", from: to.Truncate(time.Hour), to: to.Truncate(time.Hour).Add(time.Hour), interval: "hour", wantFrom: to.Truncate(time.Hour), wantTo: to.Truncate(time.Hour).Add(time.Hour)},
+		{name: "unknown interval", to: to, interval: "week", wantErr: ErrInvalidInterval},
+		{name: "reversed range", from: to, to: to.Add(-time.Hour), wantErr: ErrInvalidRange},
+		{name: "too many buckets", from: to.AddDate(-1, 0, 0), to: to, interval: "hour", wantErr: ErrInvalidRange},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			q, err := NewQuery(tt.from, tt.to, tt.interval)
+			if tt.wantErr != nil {
+				assert.ErrorIs(t, err, tt.wantErr)
+				return
+			}
+
+			require.NoError(t, err)
+			assert.Equal(t, tt.wantFrom, q.From)
+			assert.Equal(t, tt.wantTo, q.To)
+		})
+	}
+}
+
+// TestBuild проверяет группировку переходов по интервалам и разбивки.
+func TestBuild(t *testing.T) {
+	from := time.Date(2023, 5, 10, 0, 0, 0, 0, time.UTC)
+
+	q, err := NewQuery(from, from.Add(3*time.Hour), "hour")
+	require.NoError(t, err)
+
+	clicks := []storage.Click{
+		{ShortURL: "abc", Time: from.Add(10 * time.Minute), Referrer: "https://t.me/channel", UserAgent: chromeWindows, IPHash: "a", Variant: "b"},
+		{ShortURL: "abc", Time: from.Add(20 * time.Minute), Referrer: "https://t.me/other", UserAgent: chromeWindows, IPHash: "a", Variant: "b"},
+		{ShortURL: "abc", Time: from.Add(2*time.Hour + time.Minute), UserAgent: safariIPhone, IPHash: "b", Rule: "ios"},
+		{ShortURL: "abc", Time: from.Add(5 * time.Hour), UserAgent: safariIPhone, IPHash: "c"},
+	}
+
+	report := Build(q, clicks)
+
+	assert.Equal(t, int64(3), report.Total)
+	assert.Equal(t, uint64(2), report.UniqueVisitors)
+	assert.Equal(t, []Bucket{
+		{Start: from, Clicks: 2},
+		{Start: from.Add(time.Hour), Clicks: 0},
+		{Start: from.Add(2 * time.Hour), Clicks: 1},
+	}, report.Series)
+	assert.Equal(t, []Count{{Name: "t.me", Clicks: 2}, {Name: direct, Clicks: 1}}, report.Referrers)
+	assert.Equal(t, []Count{{Name: "Chrome", Clicks: 2}, {Name: "Safari", Clicks: 1}}, report.Browsers)
+	assert.Equal(t, []Count{{Name: "Windows", Clicks: 2}, {Name: "iOS", Clicks: 1}}, report.OS)
+	assert.Equal(t, []Count{{Name: DefaultRule, Clicks: 2}, {Name: "ios", Clicks: 1}}, report.Rules)
+	assert.Equal(t, []Count{{Name: "b", Clicks: 2}}, report.Variants)
+}
+
+// TestBuildBots проверяет, что переходы ботов по умолчанию не учитываются в статистике.
+func TestBuildBots(t *testing.T) {
+	from := time.Date(2023, 5, 10, 0, 0, 0, 0, time.UTC)
+
+	q, err := NewQuery(from, from.Add(time.Hour), "hour")
+	require.NoError(t, err)
+
+	clicks := []storage.Click{
+		{ShortURL: "abc", Time: from, UserAgent: chromeWindows, IPHash: "a"},
+		{ShortURL: "abc", Time: from, UserAgent: "TelegramBot (like TwitterBot)", IPHash: "b", Class: storage.ClassBot},
+		{ShortURL: "abc", Time: from, UserAgent: chromeWindows, IPHash: "c", Class: storage.ClassPrefetch},
+	}
+
+	report := Build(q, clicks)
+	assert.Equal(t, int64(2), report.Total)
+	assert.Equal(t, []Count{{Name: storage.ClassBot, Clicks: 1}, {Name: storage.ClassHuman, Clicks: 1}, {Name: storage.ClassPrefetch, Clicks: 1}}, report.Classes)
+
+	q.IncludeBots = true
+	report = Build(q, clicks)
+	assert.Equal(t, int64(3), report.Total)
+}
+
+// TestLinksReport проверяет, что отчёт по нескольким ссылкам учитывает сводки и несведённые переходы всех ссылок
+// и содержит разбивку по каждой ссылке без ограничения их количества.
+func TestLinksReport(t *testing.T) {
+	ctx := context.Background()
+	from := time.Date(2023, 5, 10, 0, 0, 0, 0, time.UTC)
+
+	s := storage.NewMemoryWork(map[string]map[string]string{})
+
+	var (
+		shortnames []string
+		clicks     []storage.Click
+		want       []Count
+	)
+	for i := 0; i < topLimit+2; i++ {
+		shortname := fmt.Sprintf("link%02d", i)
+		shortnames = append(shortnames, shortname)
+		want = append(want, Count{Name: shortname, Clicks: int64(i)})
+
+		for j := 0; j < i; j++ {
+			clicks = append(clicks, storage.Click{ShortURL: shortname, Time: from.Add(time.Duration(j) * time.Hour), UserAgent: chromeWindows, IPHash: shortname})
+		}
+	}
+	require.NoError(t, s.SaveClicks(ctx, clicks))
+
+	//часть переходов уже сведена, остальные читаются из переходов
+	_, err := s.RollupClicks(ctx, from.Add(5*time.Hour), Aggregate)
+	require.NoError(t, err)
+
+	q, err := NewQuery(from, from.AddDate(0, 0, 1), "hour")
+	require.NoError(t, err)
+
+	report, err := LinksReport(ctx, s, shortnames, q)
+	require.NoError(t, err)
+
+	assert.Equal(t, int64(len(clicks)), report.Total)
+	assert.Equal(t, uint64(len(shortnames)-1), report.UniqueVisitors)
+	assert.ElementsMatch(t, want, report.Links)
+}
+
+// TestHLL проверяет точность оценки количества уникальных значений и объединение оценщиков.
+func TestHLL(t *testing.T) {
+	first, second := NewHLL(), NewHLL()
+
+	for i := 0; i < 10000; i++ {
+		first.Add(fmt.Sprintf("visitor-%d", i))
+		second.Add(fmt.Sprintf("visitor-%d", i+5000))
+	}
+
+	assert.InEpsilon(t, 10000, float64(first.Count()), 0.05)
+
+	first.Merge(second)
+	assert.InEpsilon(t, 15000, float64(first.Count()), 0.05)
+}
@@ -0,0 +1,81 @@
+package analytics
+
+import (
+	"hash/fnv"
+	"math"
+	"math/bits"
+)
+
+// hllPrecision - количество бит хэша для выбора регистра HyperLogLog.
+// При 4096 регистрах стандартная ошибка оценки составляет около 1.6%.
+const hllPrecision = 12
+
+// HLL - оценщик количества уникальных значений HyperLogLog.
+// Оценщики можно объединять, что позволяет хранить их в сводках за периоды.
+type HLL []byte
+
+// NewHLL - конструктор HLL.
+func NewHLL() HLL {
+	return make(HLL, 1<<hllPrecision)
+}
+
+// Add учитывает значение.
+func (h HLL) Add(value string) {
+	hash := fnv.New64a()
+	hash.Write([]byte(value))
+	x := mix(hash.Sum64())
+
+	index := x >> (64 - hllPrecision)
+	rank := byte(bits.LeadingZeros64(x<<hllPrecision|1<<(hllPrecision-1))) + 1
+
+	if rank > h[index] {
+		h[index] = rank
+	}
+}
+
+// Merge объединяет оценщик с другим оценщиком.
+func (h HLL) Merge(other HLL) {
+	for i := range other {
+		if i < len(h) && other[i] > h[i] {
+			h[i] = other[i]
+		}
+	}
+}
+
+// Count возвращает оценку количества уникальных значений.
+func (h HLL) Count() uint64 {
+	m := float64(len(h))
+	if m == 0 {
+		return 0
+	}
+
+	var sum float64
+	var zeros int
+
+	for _, v := range h {
+		sum += math.Pow(2, -float64(v))
+		if v == 0 {
+			zeros++
+		}
+	}
+
+	estimate := 0.7213 / (1 + 1.079/m) * m * m / sum
+
+	//поправка для небольшого количества значений
+	if estimate <= 2.5*m && zeros > 0 {
+		estimate = m * math.Log(m/float64(zeros))
+	}
+
+	return uint64(estimate + 0.5)
+}
+
+// mix перемешивает биты хэша, чтобы старшие биты были равномерно распределены.
+func mix(x uint64) uint64 {
+	x ^= x >> 30
+	x *= 0xbf58476d1ce4e5b9
+	x ^= x >> 27
+	x *= 0x94d049bb133111eb
+	x ^= x >> 31
+
+	return x
+}
@@ -0,0 +1,211 @@
+package analytics
+
+import (
+	"context"
+	"time"
+
+	"github.com/vladimirimekov/url-shortener/internal/storage"
+)
+
+// Store - интерфейс хранилища переходов и их сводок.
+type Store interface {
+	GetClicks(ctx context.Context, shortnames []string, from, to time.Time) ([]storage.Click, error)
+	GetRollupWatermark(ctx context.Context) (time.Time, error)
+	GetRollups(ctx context.Context, shortnames []string, granularity string, from, to time.Time) ([]storage.Rollup, error)
+}
+
+// Aggregate собирает из переходов часовые и дневные сводки отдельно для каждого класса переходов.
+func Aggregate(clicks []storage.Click) []storage.Rollup {
+	type key struct {
+		shortname string
+		interval  Interval
+		start     time.Time
+		class     string
+	}
+
+	summaries := map[key]*Summary{}
+	var order []key
+
+	for _, c := range clicks {
+		for _, interval := range []Interval{Hour, Day} {
+			k := key{shortname: c.ShortURL, interval: interval, start: interval.Truncate(c.Time), class: c.ClickClass()}
+
+			s, ok := summaries[k]
+			if !ok {
+				s = NewSummary()
+				summaries[k] = s
+				order = append(order, k)
+			}
+
+			s.Add(c)
+		}
+	}
+
+	rollups := make([]storage.Rollup, 0, len(order))
+
+	for _, k := range order {
+		rollups = append(rollups, summaries[k].Rollup(k.shortname, string(k.interval), k.class, k.start))
+	}
+
+	return rollups
+}
+
+// Rollup возвращает сводку в виде, в котором она сохраняется в хранилище.
+func (s *Summary) Rollup(shortname, granularity, class string, start time.Time) storage.Rollup {
+	return storage.Rollup{
+		ShortURL:    shortname,
+		Granularity: granularity,
+		Start:       start,
+		Class:       class,
+		Clicks:      s.Clicks,
+		Referrers:   s.Referrers,
+		Browsers:    s.Browsers,
+		OS:          s.OS,
+		Rules:       s.Rules,
+		Variants:    s.Variants,
+		Visitors:    []byte(s.Visitors),
+	}
+}
+
+// summaryFromRollup возвращает сводку, сохранённую в хранилище.
+func summaryFromRollup(r storage.Rollup) *Summary {
+	s := NewSummary()
+
+	s.Clicks = r.Clicks
+	mergeCounts(s.Referrers, r.Referrers)
+	mergeCounts(s.Browsers, r.Browsers)
+	mergeCounts(s.OS, r.OS)
+	mergeCounts(s.Rules, r.Rules)
+	mergeCounts(s.Variants, r.Variants)
+	s.Visitors.Merge(HLL(r.Visitors))
+
+	return s
+}
+
+// LinkReport собирает отчёт по ссылке из сводок за уже сведённый период и переходов после него.
+// Для дневного интервала полные дни берутся из дневных сводок, а начало текущего дня - из часовых.
+func LinkReport(ctx context.Context, store Store, shortname string, q Query) (Report, error) {
+	watermark, err := store.GetRollupWatermark(ctx)
+	if err != nil {
+		return Report{}, err
+	}
+
+	b := NewBuilder(q)
+
+	if err = addLinks(ctx, store, b, []string{shortname}, watermark, nil); err != nil {
+		return Report{}, err
+	}
+
+	return b.Report(), nil
+}
+
+// LinksReport собирает общий отчёт по нескольким ссылкам. Уникальные посетители считаются по всем ссылкам вместе,
+// разбивка Links содержит количество переходов по каждой ссылке без ограничения количества ссылок.
+// Сводки и переходы всех ссылок читаются из хранилища общими запросами.
+func LinksReport(ctx context.Context, store Store, shortnames []string, q Query) (Report, error) {
+	watermark, err := store.GetRollupWatermark(ctx)
+	if err != nil {
+		return Report{}, err
+	}
+
+	b := NewBuilder(q)
+	links := make(map[string]int64, len(shortnames))
+
+	for _, shortname := range shortnames {
+		links[shortname] = 0
+	}
+
+	if err = addLinks(ctx, store, b, shortnames, watermark, links); err != nil {
+		return Report{}, err
+	}
+
+	r := b.Report()
+	r.Links = sortCounts(links)
+
+	return r, nil
+}
+
+// addLinks учитывает в отчёте переходы по ссылкам: сводки до границы сведения watermark и переходы после неё.
+// Если links не nil, в него добавляется количество учтённых переходов по каждой ссылке.
+func addLinks(ctx context.Context, store Store, b *Builder, shortnames []string, watermark time.Time, links map[string]int64) error {
+	if len(shortnames) == 0 {
+		return nil
+	}
+
+	q := b.query
+
+	hourlyFrom := q.From
+	if q.Interval == Day {
+		hourlyFrom = maxTime(q.From, Day.Truncate(watermark))
+
+		if err := addRollups(ctx, store, b, shortnames, Day, q.From, minTime(q.To, hourlyFrom), links); err != nil {
+			return err
+		}
+	}
+
+	if err := addRollups(ctx, store, b, shortnames, Hour, hourlyFrom, minTime(q.To, watermark), links); err != nil {
+		return err
+	}
+
+	clicks, err := store.GetClicks(ctx, shortnames, maxTime(q.From, watermark), q.To)
+	if err != nil {
+		return err
+	}
+
+	for _, c := range clicks {
+		before := b.total.Clicks
+		b.AddClick(c)
+
+		if links != nil {
+			links[c.ShortURL] += b.total.Clicks - before
+		}
+	}
+
+	return nil
+}
+
+// addRollups учитывает в отчёте сводки ссылок за период [from, to).
+func addRollups(ctx context.Context, store Store, b *Builder, shortnames []string, interval Interval, from, to time.Time, links map[string]int64) error {
+	if !from.Before(to) {
+		return nil
+	}
+
+	rollups, err := store.GetRollups(ctx, shortnames, string(interval), from, to)
+	if err != nil {
+		return err
+	}
+
+	for _, r := range rollups {
+		class := r.Class
+		if class == "" {
+			class = storage.ClassHuman
+		}
+
+		before := b.total.Clicks
+		b.AddSummary(r.Start, class, summaryFromRollup(r))
+
+		if links != nil {
+			links[r.ShortURL] += b.total.Clicks - before
+		}
+	}
+
+	return nil
+}
+
+// minTime возвращает более ранний из моментов.
+func minTime(a, b time.Time) time.Time {
+	if a.Before(b) {
+		return a
+	}
+
+	return b
+}
+
+// maxTime возвращает более поздний из моментов.
+func maxTime(a, b time.Time) time.Time {
+	if a.After(b) {
+		return a
+	}
+
+	return b
+}
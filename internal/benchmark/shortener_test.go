@@ -22,7 +22,7 @@ func BenchmarkShortener(b *testing.B) {
 		LengthOfShortname: 10,
 		Host:              "http://localhost:8080",
 		UserKey:           userKey,
-		Storage:           storage.MemoryWork{UserData: memoryVar},
+		Storage:           storage.NewMemoryWork(memoryVar),
 	}
 
 	ctx := context.Background()
@@ -0,0 +1,275 @@
+package botfilter
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	_ "embed"
+
+	"github.com/vladimirimekov/url-shortener/internal/storage"
+)
+
+// defaultPatterns - встроенный список шаблонов User-Agent.
+//
+//go:embed patterns.txt
+var defaultPatterns string
+
+// sweepEvery - количество классификаций, после которого из истории переходов удаляются устаревшие записи.
+const sweepEvery = 1024
+
+// Rule сопоставляет подстроку User-Agent с классом перехода.
+type Rule struct {
+	Pattern string
+	Class   string
+}
+
+// ParseRules читает шаблоны в формате "<класс>: <подстрока>". Пустые строки и строки, начинающиеся с #, пропускаются.
+func ParseRules(r io.Reader) ([]Rule, error) {
+	var rules []Rule
+
+	scanner := bufio.NewScanner(r)
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+
+		class, pattern, ok := strings.Cut(text, ":")
+		class, pattern = strings.TrimSpace(class), strings.ToLower(strings.TrimSpace(pattern))
+
+		if !ok || pattern == "" || (class != storage.ClassBot && class != storage.ClassPrefetch && class != storage.ClassHuman) {
+			return nil, fmt.Errorf("invalid pattern at line %d: %q", line, text)
+		}
+
+		rules = append(rules, Rule{Pattern: pattern, Class: class})
+	}
+
+	return rules, scanner.Err()
+}
+
+// DefaultRules возвращает встроенный список шаблонов.
+func DefaultRules() []Rule {
+	rules, err := ParseRules(strings.NewReader(defaultPatterns))
+	if err != nil {
+		panic(err)
+	}
+
+	return rules
+}
+
+// Classifier определяет класс перехода: человек, бот или предзагрузка.
+// Кроме шаблонов User-Agent учитывается поведение: слишком частые переходы с одного адреса считаются переходами бота.
+type Classifier struct {
+	mu    sync.RWMutex
+	rules []Rule
+
+	burstLimit  int
+	burstWindow time.Duration
+
+	historyMu sync.Mutex
+	history   map[string]*recent
+	calls     int
+
+	modTime time.Time
+}
+
+// New - конструктор Classifier. Если с одного адреса за burstWindow совершено больше burstLimit переходов,
+// последующие переходы с него считаются переходами бота. Нулевой burstLimit отключает эту проверку.
+func New(burstLimit int, burstWindow time.Duration) *Classifier {
+	return &Classifier{
+		rules:       DefaultRules(),
+		burstLimit:  burstLimit,
+		burstWindow: burstWindow,
+		history:     map[string]*recent{},
+	}
+}
+
+// SetRules заменяет список шаблонов.
+func (c *Classifier) SetRules(rules []Rule) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.rules = rules
+}
+
+// LoadFile добавляет к встроенным шаблонам шаблоны из файла. Шаблоны из файла проверяются первыми.
+func (c *Classifier) LoadFile(path string) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	rules, err := ParseRules(file)
+	if err != nil {
+		return err
+	}
+
+	c.SetRules(append(rules, DefaultRules()...))
+
+	return nil
+}
+
+// Run перечитывает файл шаблонов каждые interval, если он изменился, до отмены контекста.
+func (c *Classifier) Run(ctx context.Context, path string, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		c.reload(path)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// reload загружает файл шаблонов, если он изменился с последней загрузки.
+func (c *Classifier) reload(path string) {
+	info, err := os.Stat(path)
+	if err != nil {
+		log.Print(err)
+		return
+	}
+
+	if info.ModTime().Equal(c.modTime) {
+		return
+	}
+
+	if err = c.LoadFile(path); err != nil {
+		log.Print(err)
+		return
+	}
+
+	c.modTime = info.ModTime()
+}
+
+// Classify возвращает класс перехода по User-Agent, заголовкам запроса и частоте переходов с адреса ipHash.
+// Для nil Classifier все переходы считаются переходами людей.
+func (c *Classifier) Classify(userAgent string, header http.Header, ipHash string, t time.Time) string {
+	if c == nil {
+		return storage.ClassHuman
+	}
+
+	burst := c.burst(ipHash, t)
+
+	if isPrefetch(header) {
+		return storage.ClassPrefetch
+	}
+
+	if strings.TrimSpace(userAgent) == "" {
+		return storage.ClassBot
+	}
+
+	if class, ok := c.match(userAgent); ok {
+		return class
+	}
+
+	if burst {
+		return storage.ClassBot
+	}
+
+	return storage.ClassHuman
+}
+
+// match ищет первый шаблон, подходящий к User-Agent.
+func (c *Classifier) match(userAgent string) (string, bool) {
+	userAgent = strings.ToLower(userAgent)
+
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	for _, rule := range c.rules {
+		if strings.Contains(userAgent, rule.Pattern) {
+			return rule.Class, true
+		}
+	}
+
+	return "", false
+}
+
+// recent хранит моменты последних переходов с адреса в кольцевом буфере фиксированного размера,
+// поэтому память на один адрес не растёт при частых переходах.
+type recent struct {
+	times []time.Time
+	next  int
+	full  bool
+}
+
+// add запоминает переход в момент t, вытесняя самый старый из запомненных.
+func (r *recent) add(t time.Time) {
+	r.times[r.next] = t
+	r.next = (r.next + 1) % len(r.times)
+	if r.next == 0 {
+		r.full = true
+	}
+}
+
+// oldest возвращает самый старый из запомненных переходов.
+func (r *recent) oldest() time.Time {
+	if !r.full {
+		return r.times[0]
+	}
+
+	return r.times[r.next]
+}
+
+// last возвращает последний запомненный переход.
+func (r *recent) last() time.Time {
+	return r.times[(r.next+len(r.times)-1)%len(r.times)]
+}
+
+// burst запоминает переход с адреса ipHash и проверяет, превышена ли допустимая частота переходов с него.
+// Для адреса хранятся только burstLimit+1 последних переходов: частота превышена, если все они попали в окно burstWindow.
+// Адреса без переходов за окно периодически удаляются.
+func (c *Classifier) burst(ipHash string, t time.Time) bool {
+	if c.burstLimit <= 0 || ipHash == "" {
+		return false
+	}
+
+	c.historyMu.Lock()
+	defer c.historyMu.Unlock()
+
+	since := t.Add(-c.burstWindow)
+
+	c.calls++
+	if c.calls%sweepEvery == 0 {
+		for key, r := range c.history {
+			if !r.last().After(since) {
+				delete(c.history, key)
+			}
+		}
+	}
+
+	r, ok := c.history[ipHash]
+	if !ok {
+		r = &recent{times: make([]time.Time, c.burstLimit+1)}
+		c.history[ipHash] = r
+	}
+
+	r.add(t)
+
+	return r.full && r.oldest().After(since)
+}
+
+// isPrefetch проверяет, что запрос отправлен браузером для предзагрузки страницы.
+func isPrefetch(header http.Header) bool {
+	for _, name := range []string{"Purpose", "Sec-Purpose", "X-Purpose", "X-Moz"} {
+		value := strings.ToLower(header.Get(name))
+		if strings.Contains(value, "prefetch") || strings.Contains(value, "preview") {
+			return true
+		}
+	}
+
+	return false
+}
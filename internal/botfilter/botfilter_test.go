@@ -0,0 +1,103 @@
+package botfilter
+
+import (
+	"net/http"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/vladimirimekov/url-shortener/internal/storage"
+)
+
+const chromeWindows = "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/120.0.0.0 Safari/537.36"
+
+// TestClassify проверяет классификацию переходов по User-Agent и заголовкам запроса.
+func TestClassify(t *testing.T) {
+	now := time.Date(2023, 5, 10, 12, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name      string
+		userAgent string
+		header    http.Header
+		want      string
+	}{
+		{name: "browser", userAgent: chromeWindows, want: storage.ClassHuman},
+		{name: "search engine", userAgent: "Mozilla/5.0 (compatible; Googlebot/2.1; +http://www.google.com/bot.html)", want: storage.ClassBot},
+		{name: "link unfurler", userAgent: "TelegramBot (like TwitterBot)", want: storage.ClassBot},
+		{name: "facebook crawler", userAgent: "facebookexternalhit/1.1", want: storage.ClassBot},
+		{name: "http client", userAgent: "curl/8.0.1", want: storage.ClassBot},
+		{name: "empty user agent", want: storage.ClassBot},
+		{name: "prefetch header", userAgent: chromeWindows, header: http.Header{"Sec-Purpose": {"prefetch;prerender"}}, want: storage.ClassPrefetch},
+		{name: "firefox prefetch", userAgent: chromeWindows, header: http.Header{"X-Moz": {"prefetch"}}, want: storage.ClassPrefetch},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := New(0, time.Minute)
+			assert.Equal(t, tt.want, c.Classify(tt.userAgent, tt.header, "ip", now))
+		})
+	}
+}
+
+// TestClassifyBurst проверяет, что частые переходы с одного адреса считаются переходами бота.
+func TestClassifyBurst(t *testing.T) {
+	c := New(3, time.Minute)
+	now := time.Date(2023, 5, 10, 12, 0, 0, 0, time.UTC)
+
+	for i := 0; i < 3; i++ {
+		assert.Equal(t, storage.ClassHuman, c.Classify(chromeWindows, nil, "a", now.Add(time.Duration(i)*time.Second)))
+	}
+
+	assert.Equal(t, storage.ClassBot, c.Classify(chromeWindows, nil, "a", now.Add(3*time.Second)))
+	assert.Equal(t, storage.ClassHuman, c.Classify(chromeWindows, nil, "b", now.Add(3*time.Second)))
+
+	//после окончания окна переходы с адреса снова считаются переходами человека
+	assert.Equal(t, storage.ClassHuman, c.Classify(chromeWindows, nil, "a", now.Add(2*time.Minute)))
+}
+
+// TestClassifyBurstMemory проверяет, что для адреса хранится не больше burstLimit+1 переходов,
+// а адреса без переходов за окно удаляются из истории.
+func TestClassifyBurstMemory(t *testing.T) {
+	c := New(2, time.Minute)
+	now := time.Date(2023, 5, 10, 12, 0, 0, 0, time.UTC)
+
+	for i := 0; i < 100; i++ {
+		c.Classify(chromeWindows, nil, "a", now.Add(time.Duration(i)*time.Millisecond))
+	}
+	assert.Equal(t, storage.ClassBot, c.Classify(chromeWindows, nil, "a", now.Add(time.Second)))
+	assert.Len(t, c.history["a"].times, 3)
+
+	later := now.Add(2 * time.Minute)
+	for c.calls%sweepEvery != sweepEvery-1 {
+		c.Classify(chromeWindows, nil, "b", later)
+	}
+	c.Classify(chromeWindows, nil, "b", later)
+
+	assert.NotContains(t, c.history, "a")
+	assert.Contains(t, c.history, "b")
+}
+
+// TestLoadFile проверяет загрузку дополнительных шаблонов из файла.
+func TestLoadFile(t *testing.T) {
+	file, err := os.CreateTemp("", "patterns")
+	require.NoError(t, err)
+	defer os.Remove(file.Name())
+
+	_, err = file.WriteString("# внутренний мониторинг\nbot: acme-monitor\nhuman: curl/8.0.1-internal\n")
+	require.NoError(t, err)
+	require.NoError(t, file.Close())
+
+	c := New(0, time.Minute)
+	require.NoError(t, c.LoadFile(file.Name()))
+
+	now := time.Now()
+	assert.Equal(t, storage.ClassBot, c.Classify("Acme-Monitor/1.0", nil, "ip", now))
+	assert.Equal(t, storage.ClassHuman, c.Classify("curl/8.0.1-internal", nil, "ip", now))
+	assert.Equal(t, storage.ClassBot, c.Classify("Googlebot/2.1", nil, "ip", now))
+
+	_, err = ParseRules(strings.NewReader("robot googlebot"))
+	assert.Error(t, err)
+}
@@ -0,0 +1,87 @@
+package broker
+
+import (
+	"sync"
+	"sync/atomic"
+
+	"github.com/vladimirimekov/url-shortener/internal/storage"
+)
+
+// Broker рассылает переходы по ссылкам подписчикам внутри процесса.
+// Публикация никогда не блокируется: если буфер подписчика заполнен, переход для него отбрасывается.
+type Broker struct {
+	mu          sync.RWMutex
+	subscribers map[*Subscription]struct{}
+	bufferSize  int
+}
+
+// Subscription - подписка на переходы.
+type Subscription struct {
+	broker  *Broker
+	events  chan storage.Click
+	dropped uint64
+	once    sync.Once
+}
+
+// New - конструктор Broker. bufferSize задаёт размер буфера каждого подписчика.
+func New(bufferSize int) *Broker {
+	return &Broker{subscribers: map[*Subscription]struct{}{}, bufferSize: bufferSize}
+}
+
+// Publish отправляет переход всем подписчикам. Для nil Broker ничего не делает.
+func (b *Broker) Publish(c storage.Click) {
+	if b == nil {
+		return
+	}
+
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+
+	for s := range b.subscribers {
+		select {
+		case s.events <- c:
+		default:
+			atomic.AddUint64(&s.dropped, 1)
+		}
+	}
+}
+
+// Subscribe создаёт подписку на переходы. Подписку необходимо закрыть методом Close.
+func (b *Broker) Subscribe() *Subscription {
+	s := &Subscription{broker: b, events: make(chan storage.Click, b.bufferSize)}
+
+	b.mu.Lock()
+	b.subscribers[s] = struct{}{}
+	b.mu.Unlock()
+
+	return s
+}
+
+// Subscribers возвращает количество активных подписок.
+func (b *Broker) Subscribers() int {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+
+	return len(b.subscribers)
+}
+
+// Events возвращает канал переходов подписки. Канал закрывается при закрытии подписки.
+func (s *Subscription) Events() <-chan storage.Click {
+	return s.events
+}
+
+// TakeDropped возвращает количество отброшенных с прошлого вызова переходов и обнуляет его.
+func (s *Subscription) TakeDropped() uint64 {
+	return atomic.SwapUint64(&s.dropped, 0)
+}
+
+// Close отменяет подписку.
+func (s *Subscription) Close() {
+	s.once.Do(func() {
+		s.broker.mu.Lock()
+		delete(s.broker.subscribers, s)
+		s.broker.mu.Unlock()
+
+		close(s.events)
+	})
+}
@@ -0,0 +1,47 @@
+package broker
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/vladimirimekov/url-shortener/internal/storage"
+)
+
+// TestBroker проверяет рассылку переходов подписчикам и отбрасывание переходов для медленных подписчиков.
+func TestBroker(t *testing.T) {
+	b := New(2)
+
+	fast := b.Subscribe()
+	slow := b.Subscribe()
+	assert.Equal(t, 2, b.Subscribers())
+
+	for i := 0; i < 5; i++ {
+		b.Publish(storage.Click{ShortURL: "abc", Time: time.Unix(int64(i), 0)})
+
+		//быстрый подписчик читает переходы сразу
+		c := <-fast.Events()
+		assert.Equal(t, time.Unix(int64(i), 0), c.Time)
+	}
+
+	//медленный подписчик получает только то, что поместилось в буфер, и публикация не блокируется
+	assert.Equal(t, uint64(3), slow.TakeDropped())
+	assert.Equal(t, uint64(0), slow.TakeDropped())
+	assert.Len(t, slow.Events(), 2)
+	assert.Equal(t, uint64(0), fast.TakeDropped())
+
+	slow.Close()
+	slow.Close()
+	assert.Equal(t, 1, b.Subscribers())
+
+	//после закрытия подписки канал закрыт
+	<-slow.Events()
+	<-slow.Events()
+	_, ok := <-slow.Events()
+	assert.False(t, ok)
+
+	fast.Close()
+	var nilBroker *Broker
+	nilBroker.Publish(storage.Click{})
+}
@@ -0,0 +1,179 @@
+package clicks
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"log"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/vladimirimekov/url-shortener/internal/storage"
+)
+
+// Store - интерфейс хранилища переходов по ссылкам.
+type Store interface {
+	SaveClicks(context.Context, []storage.Click) error
+}
+
+// SaltStore - интерфейс хранилища соли хэширования адресов переходов.
+type SaltStore interface {
+	ClickSalt(ctx context.Context, candidate []byte) ([]byte, error)
+}
+
+// saltLength - длина соли, создаваемой при первом запуске.
+const saltLength = 32
+
+// Salt возвращает соль хэширования адресов переходов. Заданная в настройках соль используется как есть,
+// иначе берётся соль, сохранённая вместе с данными, а при первом запуске создаётся случайная и сохраняется.
+// Соль не должна меняться между перезапусками: иначе хэши одного адреса не совпадут и посетители будут посчитаны дважды.
+func Salt(ctx context.Context, store SaltStore, configured string) ([]byte, error) {
+	if configured != "" {
+		return []byte(configured), nil
+	}
+
+	candidate := make([]byte, saltLength)
+	if _, err := rand.Read(candidate); err != nil {
+		return nil, err
+	}
+
+	return store.ClickSalt(ctx, candidate)
+}
+
+// SavedFunc вызывается после сохранения пачки переходов.
+type SavedFunc func(ctx context.Context, clicks []storage.Click) error
+
+// Recorder принимает переходы по ссылкам без блокировки и сохраняет их в хранилище пачками.
+type Recorder struct {
+	store Store
+	salt  []byte
+	saved SavedFunc
+
+	events        chan storage.Click
+	batchSize     int
+	flushInterval time.Duration
+
+	dropped uint64
+
+	stop     chan struct{}
+	done     chan struct{}
+	stopOnce sync.Once
+}
+
+// New - конструктор Recorder. Если saved не nil, он вызывается для каждой успешно сохранённой пачки переходов.
+func New(store Store, salt []byte, bufferSize, batchSize int, flushInterval time.Duration, saved SavedFunc) *Recorder {
+	if batchSize <= 0 {
+		batchSize = 1
+	}
+
+	return &Recorder{
+		store:         store,
+		salt:          salt,
+		saved:         saved,
+		events:        make(chan storage.Click, bufferSize),
+		batchSize:     batchSize,
+		flushInterval: flushInterval,
+		stop:          make(chan struct{}),
+		done:          make(chan struct{}),
+	}
+}
+
+// Record ставит переход в очередь на сохранение. Если очередь заполнена, переход отбрасывается,
+// чтобы не задерживать перенаправление.
+func (r *Recorder) Record(c storage.Click) bool {
+	if r == nil {
+		return false
+	}
+
+	select {
+	case r.events <- c:
+		return true
+	default:
+		atomic.AddUint64(&r.dropped, 1)
+		return false
+	}
+}
+
+// Dropped возвращает количество отброшенных из-за переполнения очереди переходов.
+func (r *Recorder) Dropped() uint64 {
+	return atomic.LoadUint64(&r.dropped)
+}
+
+// HashIP возвращает хэш IP-адреса с солью, чтобы не хранить адреса в открытом виде.
+func (r *Recorder) HashIP(ip string) string {
+	mac := hmac.New(sha256.New, r.salt)
+	mac.Write([]byte(ip))
+
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// Run сохраняет переходы из очереди пачками по batchSize либо раз в flushInterval.
+// После вызова Close оставшиеся в очереди переходы сохраняются и Run завершается.
+func (r *Recorder) Run(ctx context.Context) {
+	defer close(r.done)
+
+	ticker := time.NewTicker(r.flushInterval)
+	defer ticker.Stop()
+
+	batch := make([]storage.Click, 0, r.batchSize)
+
+	flush := func() {
+		if len(batch) == 0 {
+			return
+		}
+		//сохраняем и после отмены ctx, чтобы не потерять накопленные переходы
+		if err := r.store.SaveClicks(context.Background(), batch); err != nil {
+			log.Print(err)
+		} else if r.saved != nil {
+			if err = r.saved(context.Background(), batch); err != nil {
+				log.Print(err)
+			}
+		}
+		batch = make([]storage.Click, 0, r.batchSize)
+	}
+
+	for {
+		select {
+		case c := <-r.events:
+			batch = append(batch, c)
+			if len(batch) >= r.batchSize {
+				flush()
+			}
+		case <-ticker.C:
+			flush()
+		case <-ctx.Done():
+			r.drain(&batch)
+			flush()
+			return
+		case <-r.stop:
+			r.drain(&batch)
+			flush()
+			return
+		}
+	}
+}
+
+// drain забирает из очереди все накопившиеся переходы.
+func (r *Recorder) drain(batch *[]storage.Click) {
+	for {
+		select {
+		case c := <-r.events:
+			*batch = append(*batch, c)
+		default:
+			return
+		}
+	}
+}
+
+// Close останавливает запущенный Run и ожидает сохранения оставшихся переходов.
+func (r *Recorder) Close() {
+	if r == nil {
+		return
+	}
+
+	r.stopOnce.Do(func() { close(r.stop) })
+	<-r.done
+}
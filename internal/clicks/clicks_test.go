@@ -0,0 +1,96 @@
+package clicks
+
+import (
+	"context"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/vladimirimekov/url-shortener/internal/storage"
+)
+
+// TestRecorder проверяет сохранение переходов пачками, подсчёт переходов по ссылкам и передачу сохранённых пачек в saved.
+func TestRecorder(t *testing.T) {
+	ctx := context.Background()
+
+	s := storage.NewMemoryWork(map[string]map[string]string{})
+	require.NoError(t, s.SaveData(ctx, map[string]map[string]string{"user": {"abc": "https://example.com", "def": "https://example.org"}}))
+
+	var saved int
+	r := New(s, []byte("salt"), 100, 3, time.Hour, func(_ context.Context, clicks []storage.Click) error {
+		saved += len(clicks)
+		return nil
+	})
+	go r.Run(ctx)
+
+	for i := 0; i < 4; i++ {
+		assert.True(t, r.Record(storage.Click{ShortURL: "abc", Time: time.Now(), IPHash: r.HashIP("127.0.0.1")}))
+	}
+	assert.True(t, r.Record(storage.Click{ShortURL: "def", Time: time.Now()}))
+
+	//оставшиеся в очереди переходы сохраняются при остановке
+	r.Close()
+
+	links, err := s.GetUserLinks(ctx, "user")
+	require.NoError(t, err)
+
+	counts := map[string]int64{}
+	for _, link := range links {
+		counts[link.ShortURL] = link.Clicks
+	}
+
+	assert.Equal(t, map[string]int64{"abc": 4, "def": 1}, counts)
+	assert.Equal(t, 5, saved)
+}
+
+// TestRecorder_Record проверяет, что переполненная очередь не блокирует запись перехода.
+func TestRecorder_Record(t *testing.T) {
+	r := New(storage.NewMemoryWork(map[string]map[string]string{}), nil, 1, 1, time.Hour, nil)
+
+	assert.True(t, r.Record(storage.Click{ShortURL: "abc"}))
+	assert.False(t, r.Record(storage.Click{ShortURL: "abc"}))
+	assert.Equal(t, uint64(1), r.Dropped())
+
+	var nilRecorder *Recorder
+	assert.False(t, nilRecorder.Record(storage.Click{ShortURL: "abc"}))
+}
+
+// TestRecorder_HashIP проверяет, что адрес не хранится в открытом виде и хэш стабилен.
+func TestRecorder_HashIP(t *testing.T) {
+	r := New(nil, []byte("salt"), 1, 1, time.Hour, nil)
+
+	assert.Equal(t, r.HashIP("10.0.0.1"), r.HashIP("10.0.0.1"))
+	assert.NotEqual(t, r.HashIP("10.0.0.1"), r.HashIP("10.0.0.2"))
+	assert.NotContains(t, r.HashIP("10.0.0.1"), "10.0.0.1")
+}
+
+// TestSalt проверяет, что соль из настроек используется как есть, а без неё создаётся один раз и сохраняется с данными.
+func TestSalt(t *testing.T) {
+	ctx := context.Background()
+
+	salt, err := Salt(ctx, storage.NewMemoryWork(map[string]map[string]string{}), "configured")
+	require.NoError(t, err)
+	assert.Equal(t, []byte("configured"), salt)
+
+	const filename = "salt_test.gob"
+	defer os.Remove(filename)
+
+	first, err := Salt(ctx, storage.FileSystemConnect{Filename: filename}, "")
+	require.NoError(t, err)
+	assert.Len(t, first, saltLength)
+
+	//после перезапуска используется сохранённая в файле соль
+	second, err := Salt(ctx, storage.FileSystemConnect{Filename: filename}, "")
+	require.NoError(t, err)
+	assert.Equal(t, first, second)
+
+	memory := storage.NewMemoryWork(map[string]map[string]string{})
+	first, err = Salt(ctx, memory, "")
+	require.NoError(t, err)
+	second, err = Salt(ctx, memory, "")
+	require.NoError(t, err)
+	assert.Equal(t, first, second)
+}
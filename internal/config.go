@@ -6,6 +6,7 @@ import (
 	"flag"
 	"log"
 	"os"
+	"time"
 
 	"github.com/caarlos0/env/v6"
 )
@@ -21,6 +22,58 @@ type Config struct {
 	EnableHTTPS     bool   `env:"ENABLE_HTTPS"`
 	TrustedSubnet   string `env:"TRUSTED_SUBNET"`
 	Secret          []byte
+
+	ShortnameMaxLength          int     `env:"SHORTNAME_MAX_LENGTH" envDefault:"32"`
+	ShortnameMaxAttempts        int     `env:"SHORTNAME_MAX_ATTEMPTS" envDefault:"20"`
+	ShortnameCollisionThreshold float64 `env:"SHORTNAME_COLLISION_THRESHOLD" envDefault:"0.3"`
+
+	KeyPoolSize           int           `env:"KEY_POOL_SIZE" envDefault:"1000"`
+	KeyPoolLowWater       int           `env:"KEY_POOL_LOW_WATER" envDefault:"200"`
+	KeyPoolClaimTTL       time.Duration `env:"KEY_POOL_CLAIM_TTL" envDefault:"1m"`
+	KeyPoolRefillInterval time.Duration `env:"KEY_POOL_REFILL_INTERVAL" envDefault:"10s"`
+
+	ClickBufferSize    int           `env:"CLICK_BUFFER_SIZE" envDefault:"10000"`
+	ClickBatchSize     int           `env:"CLICK_BATCH_SIZE" envDefault:"500"`
+	ClickFlushInterval time.Duration `env:"CLICK_FLUSH_INTERVAL" envDefault:"1s"`
+	ClickIPSalt        string        `env:"CLICK_IP_SALT"`
+
+	ClickRetention time.Duration `env:"CLICK_RETENTION" envDefault:"720h"`
+	RollupInterval time.Duration `env:"ROLLUP_INTERVAL" envDefault:"5m"`
+	RollupDelay    time.Duration `env:"ROLLUP_DELAY" envDefault:"1m"`
+
+	BotPatternsFile          string        `env:"BOT_PATTERNS_FILE"`
+	BotPatternsCheckInterval time.Duration `env:"BOT_PATTERNS_CHECK_INTERVAL" envDefault:"1m"`
+	BotBurstLimit            int           `env:"BOT_BURST_LIMIT" envDefault:"30"`
+	BotBurstWindow           time.Duration `env:"BOT_BURST_WINDOW" envDefault:"1m"`
+
+	StreamBufferSize int `env:"STREAM_BUFFER_SIZE" envDefault:"256"`
+
+	WebhookWorkers      int           `env:"WEBHOOK_WORKERS" envDefault:"8"`
+	WebhookMaxAttempts  int           `env:"WEBHOOK_MAX_ATTEMPTS" envDefault:"8"`
+	WebhookBaseDelay    time.Duration `env:"WEBHOOK_BASE_DELAY" envDefault:"10s"`
+	WebhookMaxDelay     time.Duration `env:"WEBHOOK_MAX_DELAY" envDefault:"1h"`
+	WebhookTimeout      time.Duration `env:"WEBHOOK_TIMEOUT" envDefault:"10s"`
+	WebhookPollInterval time.Duration `env:"WEBHOOK_POLL_INTERVAL" envDefault:"5s"`
+
+	QRCacheSize int `env:"QR_CACHE_SIZE" envDefault:"1024"`
+
+	PasswordMaxAttempts   int           `env:"PASSWORD_MAX_ATTEMPTS" envDefault:"5"`
+	PasswordAttemptWindow time.Duration `env:"PASSWORD_ATTEMPT_WINDOW" envDefault:"15m"`
+
+	ComingSoonURL    string `env:"COMING_SOON_URL"`
+	ComingSoonStatus int    `env:"COMING_SOON_STATUS" envDefault:"404"`
+	ComingSoonPage   string `env:"COMING_SOON_PAGE"`
+
+	GeoIPFile          string        `env:"GEOIP_FILE"`
+	GeoIPCheckInterval time.Duration `env:"GEOIP_CHECK_INTERVAL" envDefault:"1m"`
+
+	MetadataWorkers         int           `env:"METADATA_WORKERS" envDefault:"4"`
+	MetadataQueueSize       int           `env:"METADATA_QUEUE_SIZE" envDefault:"1000"`
+	MetadataTimeout         time.Duration `env:"METADATA_TIMEOUT" envDefault:"5s"`
+	MetadataMaxBytes        int64         `env:"METADATA_MAX_BYTES" envDefault:"524288"`
+	MetadataMaxAttempts     int           `env:"METADATA_MAX_ATTEMPTS" envDefault:"3"`
+	MetadataRetryDelay      time.Duration `env:"METADATA_RETRY_DELAY" envDefault:"30s"`
+	MetadataHostConcurrency int           `env:"METADATA_HOST_CONCURRENCY" envDefault:"2"`
 }
 
 // FileConfig содержит параметры для чтения из JSON.
@@ -0,0 +1,187 @@
+package geoip
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Range сопоставляет диапазон IP-адресов [Start, End] с кодом страны ISO 3166-1 alpha-2.
+type Range struct {
+	Start   net.IP
+	End     net.IP
+	Country string
+}
+
+// ParseRanges читает диапазоны в формате "<первый адрес>,<последний адрес>,<код страны>".
+// Пустые строки и строки, начинающиеся с #, пропускаются. Диапазоны возвращаются упорядоченными и не должны пересекаться.
+func ParseRanges(r io.Reader) ([]Range, error) {
+	var ranges []Range
+
+	scanner := bufio.NewScanner(r)
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+
+		fields := strings.Split(text, ",")
+		if len(fields) != 3 {
+			return nil, fmt.Errorf("invalid range at line %d: %q", line, text)
+		}
+
+		start, end := parseIP(fields[0]), parseIP(fields[1])
+		country, ok := NormalizeCountry(fields[2])
+
+		if start == nil || end == nil || !ok || isIPv4(start) != isIPv4(end) || bytes.Compare(start, end) > 0 {
+			return nil, fmt.Errorf("invalid range at line %d: %q", line, text)
+		}
+
+		ranges = append(ranges, Range{Start: start, End: end, Country: country})
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	sort.Slice(ranges, func(i, j int) bool {
+		return bytes.Compare(ranges[i].Start, ranges[j].Start) < 0
+	})
+
+	for i := 1; i < len(ranges); i++ {
+		if bytes.Compare(ranges[i].Start, ranges[i-1].End) <= 0 {
+			return nil, fmt.Errorf("range %v-%v overlaps range %v-%v", ranges[i].Start, ranges[i].End, ranges[i-1].Start, ranges[i-1].End)
+		}
+	}
+
+	return ranges, nil
+}
+
+// NormalizeCountry проверяет код страны из двух латинских букв и приводит его к верхнему регистру.
+func NormalizeCountry(code string) (string, bool) {
+	code = strings.ToUpper(strings.TrimSpace(code))
+	if len(code) != 2 || code[0] < 'A' || code[0] > 'Z' || code[1] < 'A' || code[1] > 'Z' {
+		return "", false
+	}
+
+	return code, true
+}
+
+// parseIP разбирает адрес и возвращает его в 16-байтовом представлении, в котором сравниваются адреса обоих семейств.
+func parseIP(value string) net.IP {
+	return net.ParseIP(strings.TrimSpace(value)).To16()
+}
+
+// isIPv4 проверяет, является ли адрес адресом IPv4.
+func isIPv4(ip net.IP) bool {
+	return ip.To4() != nil
+}
+
+// Database определяет страну по IP-адресу с помощью локальной базы диапазонов.
+// Базу можно заменить во время работы, не прерывая поиск.
+type Database struct {
+	mu     sync.RWMutex
+	ranges []Range
+
+	modTime time.Time
+}
+
+// New - конструктор Database с пустой базой.
+func New() *Database {
+	return &Database{}
+}
+
+// SetRanges заменяет базу диапазонов. Диапазоны должны быть упорядочены и не пересекаться, как после ParseRanges.
+func (d *Database) SetRanges(ranges []Range) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	d.ranges = ranges
+}
+
+// LoadFile заменяет базу диапазонами из файла.
+func (d *Database) LoadFile(path string) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	ranges, err := ParseRanges(file)
+	if err != nil {
+		return err
+	}
+
+	d.SetRanges(ranges)
+
+	return nil
+}
+
+// Run перечитывает файл базы каждые interval, если он изменился, до отмены контекста.
+// При ошибке чтения продолжает работать ранее загруженная база.
+func (d *Database) Run(ctx context.Context, path string, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		d.reload(path)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// reload загружает файл базы, если он изменился с последней загрузки.
+func (d *Database) reload(path string) {
+	info, err := os.Stat(path)
+	if err != nil {
+		log.Print(err)
+		return
+	}
+
+	if info.ModTime().Equal(d.modTime) {
+		return
+	}
+
+	if err = d.LoadFile(path); err != nil {
+		log.Print(err)
+		return
+	}
+
+	d.modTime = info.ModTime()
+}
+
+// Country возвращает код страны для IP-адреса либо пустую строку, если адрес не найден в базе.
+// Для nil Database страна не определяется.
+func (d *Database) Country(ip net.IP) string {
+	if d == nil || ip == nil {
+		return ""
+	}
+
+	ip = ip.To16()
+
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+
+	//первый диапазон, заканчивающийся не раньше адреса
+	i := sort.Search(len(d.ranges), func(i int) bool {
+		return bytes.Compare(d.ranges[i].End, ip) >= 0
+	})
+	if i == len(d.ranges) || bytes.Compare(d.ranges[i].Start, ip) > 0 {
+		return ""
+	}
+
+	return d.ranges[i].Country
+}
@@ -0,0 +1,103 @@
+package geoip
+
+import (
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testRanges = `
+# тестовая база
+5.0.0.0,5.0.255.255,de
+1.0.0.0,1.0.0.255,AU
+2001:db8::,2001:db8::ffff,FR
+`
+
+// TestParseRanges проверяет разбор файла базы диапазонов.
+func TestParseRanges(t *testing.T) {
+	tests := []struct {
+		name    string
+		input   string
+		wantErr bool
+	}{
+		{name: "valid", input: testRanges},
+		{name: "missing country", input: "1.0.0.0,1.0.0.255", wantErr: true},
+		{name: "invalid country", input: "1.0.0.0,1.0.0.255,AUS", wantErr: true},
+		{name: "invalid address", input: "1.0.0,1.0.0.255,AU", wantErr: true},
+		{name: "reversed range", input: "1.0.0.255,1.0.0.0,AU", wantErr: true},
+		{name: "mixed families", input: "1.0.0.0,2001:db8::,AU", wantErr: true},
+		{name: "overlapping ranges", input: "1.0.0.0,1.0.0.255,AU\n1.0.0.128,1.0.1.0,NZ", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := ParseRanges(strings.NewReader(tt.input))
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+
+			assert.NoError(t, err)
+		})
+	}
+}
+
+// TestDatabase_Country проверяет поиск страны по IP-адресу.
+func TestDatabase_Country(t *testing.T) {
+	ranges, err := ParseRanges(strings.NewReader(testRanges))
+	require.NoError(t, err)
+
+	d := New()
+	d.SetRanges(ranges)
+
+	tests := []struct {
+		ip   string
+		want string
+	}{
+		{ip: "1.0.0.0", want: "AU"},
+		{ip: "1.0.0.255", want: "AU"},
+		{ip: "1.0.1.0", want: ""},
+		{ip: "5.0.10.20", want: "DE"},
+		{ip: "0.0.0.1", want: ""},
+		{ip: "2001:db8::10", want: "FR"},
+		{ip: "2001:db8::1:0", want: ""},
+		{ip: "::ffff:1.0.0.1", want: "AU"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.ip, func(t *testing.T) {
+			assert.Equal(t, tt.want, d.Country(net.ParseIP(tt.ip)))
+		})
+	}
+
+	assert.Empty(t, d.Country(nil))
+
+	var empty *Database
+	assert.Empty(t, empty.Country(net.ParseIP("1.0.0.1")))
+}
+
+// TestDatabase_Reload проверяет, что изменённый файл базы перечитывается, а ошибочный файл не заменяет загруженную базу.
+func TestDatabase_Reload(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "ranges.csv")
+	require.NoError(t, os.WriteFile(path, []byte("1.0.0.0,1.0.0.255,AU\n"), 0o600))
+
+	d := New()
+	d.reload(path)
+	assert.Equal(t, "AU", d.Country(net.ParseIP("1.0.0.1")))
+
+	modTime := time.Now().Add(time.Hour)
+
+	require.NoError(t, os.WriteFile(path, []byte("1.0.0.0,1.0.0.255,NZ\n"), 0o600))
+	require.NoError(t, os.Chtimes(path, modTime, modTime))
+	d.reload(path)
+	assert.Equal(t, "NZ", d.Country(net.ParseIP("1.0.0.1")))
+
+	require.NoError(t, os.WriteFile(path, []byte("broken\n"), 0o600))
+	require.NoError(t, os.Chtimes(path, modTime.Add(time.Hour), modTime.Add(time.Hour)))
+	d.reload(path)
+	assert.Equal(t, "NZ", d.Country(net.ParseIP("1.0.0.1")))
+}
@@ -0,0 +1,52 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/vladimirimekov/url-shortener/internal/storage"
+)
+
+// errAutomatedClick возвращается, если ссылку с ограничением количества переходов открывает бот или предзагрузка браузера.
+var errAutomatedClick = errors.New("automated requests do not consume link clicks")
+
+// linkAvailable проверяет, может ли ссылка перенаправлять на оригинальный URL в момент now.
+func linkAvailable(link storage.Link, now time.Time) error {
+	if link.IsDeleted {
+		return storage.ErrDeleted
+	}
+
+	if err := link.CheckWindow(now); err != nil {
+		return err
+	}
+
+	if link.ClicksExhausted() {
+		return storage.ErrClicksExhausted
+	}
+
+	return nil
+}
+
+// consumeClick списывает перенаправление у ссылки с ограничением количества переходов.
+// Проверка и уменьшение остатка выполняются хранилищем атомарно, поэтому лимит не превышается при одновременных переходах.
+// Переходы ботов и предзагрузка не списываются и не перенаправляются: иначе сервисы предпросмотра ссылок в мессенджерах
+// и сканеры почты израсходовали бы одноразовую ссылку раньше получателя.
+func (h Handler) consumeClick(ctx context.Context, link storage.Link, click storage.Click) error {
+	if link.MaxClicks == 0 {
+		return nil
+	}
+
+	if click.ClickClass() != storage.ClassHuman {
+		return errAutomatedClick
+	}
+
+	return h.Storage.ConsumeClick(ctx, link.ShortURL)
+}
+
+// automatedClick отвечает боту или предзагрузке на ссылку с ограничением количества переходов без перенаправления.
+func automatedClick(w http.ResponseWriter) {
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(http.StatusNoContent)
+}
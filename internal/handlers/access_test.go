@@ -0,0 +1,75 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/vladimirimekov/url-shortener/internal/botfilter"
+	"github.com/vladimirimekov/url-shortener/internal/storage"
+	pb "github.com/vladimirimekov/url-shortener/proto"
+)
+
+// TestHandler_ClickLimit проверяет, что ссылка с ограничением количества переходов перестаёт перенаправлять после исчерпания лимита,
+// а боты и предзагрузка не расходуют переходы.
+func TestHandler_ClickLimit(t *testing.T) {
+	s := storage.NewMemoryWork(map[string]map[string]string{})
+	d := Handler{Storage: s, Host: "http://localhost:8080", UserKey: userKey, LengthOfShortname: 8, Bots: botfilter.New(0, time.Minute)}
+
+	h := chi.NewRouter()
+	h.Post("/api/shorten", d.PostShortenHandler)
+	h.Get("/{id}", d.MainHandler)
+
+	w := httptest.NewRecorder()
+	request := httptest.NewRequest(http.MethodPost, "/api/shorten", strings.NewReader(`{"url":"https://example.com","max_clicks":-1}`))
+	h.ServeHTTP(w, request.WithContext(context.WithValue(request.Context(), userKey, "owner")))
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	response, err := d.CreateShortLink(context.Background(), &pb.CreateShortLinkRequest{OriginalURL: "https://example.com/once", UserID: "owner", MaxClicks: 2})
+	require.NoError(t, err)
+	shortname := strings.TrimPrefix(response.ShortURL, d.Host+"/")
+
+	const browser = "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/120.0.0.0 Safari/537.36"
+
+	tests := []struct {
+		name       string
+		target     string
+		userAgent  string
+		purpose    string
+		wantStatus int
+	}{
+		{name: "link unfurler", target: "/" + shortname, userAgent: "Slackbot-LinkExpanding 1.0 (+https://api.slack.com/robots)", wantStatus: http.StatusNoContent},
+		{name: "empty user agent", target: "/" + shortname, wantStatus: http.StatusNoContent},
+		{name: "browser prefetch", target: "/" + shortname, userAgent: browser, purpose: "prefetch", wantStatus: http.StatusNoContent},
+		{name: "first redirect", target: "/" + shortname, userAgent: browser, wantStatus: http.StatusTemporaryRedirect},
+		{name: "preview does not consume", target: "/" + shortname + "+", userAgent: browser, wantStatus: http.StatusOK},
+		{name: "last redirect", target: "/" + shortname, userAgent: browser, wantStatus: http.StatusTemporaryRedirect},
+		{name: "limit reached", target: "/" + shortname, userAgent: browser, wantStatus: http.StatusGone},
+		{name: "preview after limit", target: "/" + shortname + "+", userAgent: browser, wantStatus: http.StatusGone},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodGet, tt.target, nil)
+			r.Header.Set("User-Agent", tt.userAgent)
+			if tt.purpose != "" {
+				r.Header.Set("Purpose", tt.purpose)
+			}
+
+			w := httptest.NewRecorder()
+			h.ServeHTTP(w, r)
+			assert.Equal(t, tt.wantStatus, w.Code)
+		})
+	}
+
+	_, err = d.GetOriginalLink(context.Background(), &pb.GetOriginalLinkRequest{ShortURL: shortname})
+	assert.Equal(t, codes.ResourceExhausted, status.Code(err))
+}
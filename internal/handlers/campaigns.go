@@ -0,0 +1,605 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/emptypb"
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	"github.com/vladimirimekov/url-shortener/internal/analytics"
+	"github.com/vladimirimekov/url-shortener/internal/storage"
+	pb "github.com/vladimirimekov/url-shortener/proto"
+)
+
+// Ограничения кампаний.
+const (
+	maxCampaignName  = 100
+	maxCampaignValue = 256
+)
+
+// Ошибки проверки кампаний.
+var (
+	errInvalidCampaignName      = errors.New("campaign name must be from 1 to 100 characters")
+	errInvalidCampaignParameter = errors.New("campaign parameters must be utm_source, utm_medium, utm_campaign, utm_term, utm_content or utm_id with values of up to 256 characters")
+	errNoCampaignParameters     = errors.New("campaign must define at least one parameter")
+	errUnknownPlaceholder       = errors.New("campaign parameters may only use {short}, {rule} and {variant} placeholders")
+	errUnknownCampaign          = errors.New("campaign not found")
+)
+
+// campaignParameters содержит названия параметров, которые может задавать кампания.
+var campaignParameters = map[string]bool{
+	"utm_source":   true,
+	"utm_medium":   true,
+	"utm_campaign": true,
+	"utm_term":     true,
+	"utm_content":  true,
+	"utm_id":       true,
+}
+
+// placeholderPattern находит подстановки в шаблонах параметров кампании.
+var placeholderPattern = regexp.MustCompile(`\{[^{}]*\}`)
+
+// Подстановки шаблонов параметров кампании.
+const (
+	placeholderShort   = "{short}"
+	placeholderRule    = "{rule}"
+	placeholderVariant = "{variant}"
+)
+
+// CampaignData содержит структуру для json данных с информацией о кампании.
+type CampaignData struct {
+	ID         string            `json:"id"`
+	Name       string            `json:"name"`
+	Parameters map[string]string `json:"parameters"`
+	CreatedAt  time.Time         `json:"created_at"`
+	UpdatedAt  time.Time         `json:"updated_at"`
+}
+
+// CreateCampaignData содержит структуру для получения json данных с названием кампании и шаблонами её параметров.
+type CreateCampaignData struct {
+	Name       string            `json:"name"`
+	Parameters map[string]string `json:"parameters"`
+}
+
+// UpdateCampaignData содержит структуру для получения json данных с изменениями кампании.
+// Незаполненные поля не изменяются, переданные параметры заменяют параметры кампании целиком.
+type UpdateCampaignData struct {
+	Name       *string            `json:"name,omitempty"`
+	Parameters *map[string]string `json:"parameters,omitempty"`
+}
+
+// CampaignStatsData содержит структуру для json данных со статистикой переходов по ссылкам кампании.
+type CampaignStatsData struct {
+	ID             string       `json:"id"`
+	Name           string       `json:"name"`
+	From           time.Time    `json:"from"`
+	To             time.Time    `json:"to"`
+	Interval       string       `json:"interval"`
+	Total          int64        `json:"total"`
+	UniqueVisitors uint64       `json:"unique_visitors"`
+	Series         []BucketData `json:"series"`
+	Links          []CountData  `json:"links"`
+	Referrers      []CountData  `json:"referrers"`
+	Browsers       []CountData  `json:"browsers"`
+	OS             []CountData  `json:"os"`
+	Rules          []CountData  `json:"rules"`
+	Variants       []CountData  `json:"variants"`
+	Classes        []CountData  `json:"classes"`
+}
+
+// checkCampaignName проверяет название кампании и возвращает его без пробелов по краям.
+func checkCampaignName(name string) (string, error) {
+	name = strings.TrimSpace(name)
+	if name == "" || utf8.RuneCountInString(name) > maxCampaignName {
+		return "", errInvalidCampaignName
+	}
+
+	return name, nil
+}
+
+// checkCampaignParameters проверяет шаблоны параметров кампании и возвращает их с названиями в нижнем регистре.
+func checkCampaignParameters(parameters map[string]string) (map[string]string, error) {
+	if len(parameters) == 0 {
+		return nil, errNoCampaignParameters
+	}
+
+	result := make(map[string]string, len(parameters))
+
+	for key, value := range parameters {
+		key = strings.ToLower(strings.TrimSpace(key))
+		value = strings.TrimSpace(value)
+
+		if !campaignParameters[key] || value == "" || utf8.RuneCountInString(value) > maxCampaignValue {
+			return nil, errInvalidCampaignParameter
+		}
+		if _, ok := result[key]; ok {
+			return nil, errInvalidCampaignParameter
+		}
+
+		for _, placeholder := range placeholderPattern.FindAllString(value, -1) {
+			if placeholder != placeholderShort && placeholder != placeholderRule && placeholder != placeholderVariant {
+				return nil, errUnknownPlaceholder
+			}
+		}
+
+		result[key] = value
+	}
+
+	return result, nil
+}
+
+// campaignURL добавляет к адресу перенаправления параметры кампании, подставляя в шаблоны короткое имя ссылки,
+// сработавшее правило таргетинга и вариант A/B теста. Параметры, уже заданные в адресе перенаправления, имеют приоритет,
+// параметры с пустым после подстановки значением не добавляются. Адреса не http(s) возвращаются без изменений.
+func campaignURL(destination string, c storage.Campaign, shortname string, target redirectTarget) string {
+	if !httpURL(destination) {
+		return destination
+	}
+
+	u, err := url.Parse(destination)
+	if err != nil {
+		return destination
+	}
+
+	replacer := strings.NewReplacer(placeholderShort, shortname, placeholderRule, analytics.RuleName(target.Rule), placeholderVariant, target.Variant)
+
+	own := u.Query()
+	extra := url.Values{}
+
+	for key, value := range c.Parameters {
+		if _, ok := own[key]; ok {
+			continue
+		}
+		if value = replacer.Replace(value); value != "" {
+			extra.Set(key, value)
+		}
+	}
+
+	if len(extra) == 0 {
+		return destination
+	}
+
+	if u.RawQuery != "" {
+		u.RawQuery += "&"
+	}
+	u.RawQuery += extra.Encode()
+
+	return u.String()
+}
+
+// redirectURL возвращает итоговый адрес перенаправления: к выбранному адресу добавляются параметры кампании ссылки,
+// затем дополнительный путь и параметры запроса. Ошибка чтения кампании не прерывает перенаправление.
+func (h Handler) redirectURL(ctx context.Context, link storage.Link, target redirectTarget, path string, query url.Values) string {
+	location := target.URL
+
+	if link.CampaignID != "" {
+		c, err := h.Storage.GetCampaign(ctx, link.CampaignID)
+		switch {
+		case err == nil && c.UserID == link.UserID:
+			location = campaignURL(location, c, link.ShortURL, target)
+		case err != nil && !errors.Is(err, storage.ErrNotFound):
+			log.Print(err)
+		}
+	}
+
+	return forwardURL(link, location, path, query)
+}
+
+// checkCampaign проверяет, что кампания, к которой привязывается ссылка, существует и принадлежит пользователю.
+// Пустой идентификатор означает, что ссылка не привязывается к кампании.
+func (h Handler) checkCampaign(ctx context.Context, userID, id string) error {
+	if id == "" {
+		return nil
+	}
+
+	c, err := h.Storage.GetCampaign(ctx, id)
+	if errors.Is(err, storage.ErrNotFound) || err == nil && c.UserID != userID {
+		return errUnknownCampaign
+	}
+
+	return err
+}
+
+// userCampaign возвращает кампанию, если она принадлежит пользователю.
+func (h Handler) userCampaign(ctx context.Context, userID, id string) (storage.Campaign, error) {
+	c, err := h.Storage.GetCampaign(ctx, id)
+	if err != nil {
+		return storage.Campaign{}, err
+	}
+
+	if c.UserID != userID {
+		return storage.Campaign{}, storage.ErrNotOwner
+	}
+
+	return c, nil
+}
+
+// createCampaign проверяет и сохраняет новую кампанию пользователя.
+func (h Handler) createCampaign(ctx context.Context, userID string, data CreateCampaignData) (storage.Campaign, error) {
+	name, err := checkCampaignName(data.Name)
+	if err != nil {
+		return storage.Campaign{}, err
+	}
+
+	parameters, err := checkCampaignParameters(data.Parameters)
+	if err != nil {
+		return storage.Campaign{}, err
+	}
+
+	now := time.Now().UTC()
+	c := storage.Campaign{ID: uuid.NewString(), UserID: userID, Name: name, Parameters: parameters, CreatedAt: now, UpdatedAt: now}
+
+	if err = h.Storage.SaveCampaign(ctx, c); err != nil {
+		return storage.Campaign{}, err
+	}
+
+	return c, nil
+}
+
+// updateCampaign проверяет и применяет изменения кампании пользователя.
+func (h Handler) updateCampaign(ctx context.Context, userID, id string, data UpdateCampaignData) (storage.Campaign, error) {
+	if data.Name == nil && data.Parameters == nil {
+		return storage.Campaign{}, errNothingToUpdate
+	}
+
+	c, err := h.userCampaign(ctx, userID, id)
+	if err != nil {
+		return storage.Campaign{}, err
+	}
+
+	if data.Name != nil {
+		if c.Name, err = checkCampaignName(*data.Name); err != nil {
+			return storage.Campaign{}, err
+		}
+	}
+	if data.Parameters != nil {
+		if c.Parameters, err = checkCampaignParameters(*data.Parameters); err != nil {
+			return storage.Campaign{}, err
+		}
+	}
+
+	c.UpdatedAt = time.Now().UTC()
+
+	if err = h.Storage.SaveCampaign(ctx, c); err != nil {
+		return storage.Campaign{}, err
+	}
+
+	return c, nil
+}
+
+// campaignStats возвращает статистику переходов по всем ссылкам, привязанным к кампании пользователя.
+func (h Handler) campaignStats(ctx context.Context, userID, id string, q analytics.Query) (storage.Campaign, analytics.Report, error) {
+	c, err := h.userCampaign(ctx, userID, id)
+	if err != nil {
+		return storage.Campaign{}, analytics.Report{}, err
+	}
+
+	page, err := h.Storage.FindUserLinks(ctx, userID, storage.LinkFilter{Campaign: c.ID})
+	if err != nil {
+		return storage.Campaign{}, analytics.Report{}, err
+	}
+
+	shortnames := make([]string, 0, len(page.Links))
+
+	for _, link := range page.Links {
+		shortnames = append(shortnames, link.ShortURL)
+	}
+
+	report, err := analytics.LinksReport(ctx, h.Storage, shortnames, q)
+	if err != nil {
+		return storage.Campaign{}, analytics.Report{}, err
+	}
+
+	for i := range report.Links {
+		report.Links[i].Name = h.Host + "/" + report.Links[i].Name
+	}
+
+	return c, report, nil
+}
+
+// campaignError возвращает код ответа для ошибки работы с кампанией.
+func campaignError(err error) int {
+	switch {
+	case errors.Is(err, errInvalidCampaignName), errors.Is(err, errInvalidCampaignParameter), errors.Is(err, errNoCampaignParameters),
+		errors.Is(err, errUnknownPlaceholder), errors.Is(err, errNothingToUpdate):
+		return http.StatusBadRequest
+	default:
+		return linkErrorStatus(err)
+	}
+}
+
+// campaignStatusError возвращает grpc ошибку для ошибки работы с кампанией.
+func campaignStatusError(err error) error {
+	if campaignError(err) == http.StatusBadRequest {
+		return status.Error(codes.InvalidArgument, err.Error())
+	}
+
+	return linkStatusError(err)
+}
+
+// campaignData возвращает json представление кампании.
+func campaignData(c storage.Campaign) CampaignData {
+	return CampaignData{ID: c.ID, Name: c.Name, Parameters: c.Parameters, CreatedAt: c.CreatedAt, UpdatedAt: c.UpdatedAt}
+}
+
+// campaignProto возвращает grpc представление кампании.
+func campaignProto(c storage.Campaign) *pb.Campaign {
+	return &pb.Campaign{Id: c.ID, Name: c.Name, Parameters: c.Parameters, CreatedAt: timestamppb.New(c.CreatedAt), UpdatedAt: timestamppb.New(c.UpdatedAt)}
+}
+
+// CreateCampaignHandler создаёт кампанию текущего пользователя.
+func (h Handler) CreateCampaignHandler(w http.ResponseWriter, r *http.Request) {
+
+	ctx, cancel := context.WithTimeout(r.Context(), 60*time.Second)
+	defer cancel()
+
+	userID, err := h.getUserID(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	b, err := io.ReadAll(r.Body)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	defer r.Body.Close()
+
+	var data CreateCampaignData
+
+	if err = json.Unmarshal(b, &data); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	c, err := h.createCampaign(ctx, userID, data)
+	if err != nil {
+		http.Error(w, err.Error(), campaignError(err))
+		return
+	}
+
+	writeJSON(w, http.StatusCreated, campaignData(c))
+}
+
+// GetCampaignsHandler отправляет список кампаний текущего пользователя.
+func (h Handler) GetCampaignsHandler(w http.ResponseWriter, r *http.Request) {
+
+	ctx, cancel := context.WithTimeout(r.Context(), 60*time.Second)
+	defer cancel()
+
+	userID, err := h.getUserID(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	list, err := h.Storage.GetCampaigns(ctx, userID)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	result := make([]CampaignData, 0, len(list))
+
+	for _, v := range list {
+		result = append(result, campaignData(v))
+	}
+
+	writeJSON(w, http.StatusOK, result)
+}
+
+// GetCampaignHandler отправляет информацию о кампании текущего пользователя.
+func (h Handler) GetCampaignHandler(w http.ResponseWriter, r *http.Request) {
+
+	ctx, cancel := context.WithTimeout(r.Context(), 60*time.Second)
+	defer cancel()
+
+	userID, err := h.getUserID(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	c, err := h.userCampaign(ctx, userID, chi.URLParam(r, "id"))
+	if err != nil {
+		http.Error(w, err.Error(), campaignError(err))
+		return
+	}
+
+	writeJSON(w, http.StatusOK, campaignData(c))
+}
+
+// UpdateCampaignHandler изменяет название или шаблоны параметров кампании текущего пользователя.
+func (h Handler) UpdateCampaignHandler(w http.ResponseWriter, r *http.Request) {
+
+	ctx, cancel := context.WithTimeout(r.Context(), 60*time.Second)
+	defer cancel()
+
+	userID, err := h.getUserID(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	b, err := io.ReadAll(r.Body)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	defer r.Body.Close()
+
+	var data UpdateCampaignData
+
+	if err = json.Unmarshal(b, &data); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	c, err := h.updateCampaign(ctx, userID, chi.URLParam(r, "id"), data)
+	if err != nil {
+		http.Error(w, err.Error(), campaignError(err))
+		return
+	}
+
+	writeJSON(w, http.StatusOK, campaignData(c))
+}
+
+// DeleteCampaignHandler удаляет кампанию текущего пользователя. Ссылки кампании продолжают работать без её параметров.
+func (h Handler) DeleteCampaignHandler(w http.ResponseWriter, r *http.Request) {
+
+	ctx, cancel := context.WithTimeout(r.Context(), 60*time.Second)
+	defer cancel()
+
+	userID, err := h.getUserID(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	if err = h.Storage.DeleteCampaign(ctx, userID, chi.URLParam(r, "id")); err != nil {
+		http.Error(w, err.Error(), campaignError(err))
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// GetCampaignStatsHandler отправляет статистику переходов по ссылкам кампании текущего пользователя.
+// Параметры запроса совпадают с параметрами статистики ссылки, разбивка links содержит переходы по каждой ссылке кампании.
+func (h Handler) GetCampaignStatsHandler(w http.ResponseWriter, r *http.Request) {
+
+	ctx, cancel := context.WithTimeout(r.Context(), 60*time.Second)
+	defer cancel()
+
+	userID, err := h.getUserID(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	q, err := parseStatsQuery(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	c, report, err := h.campaignStats(ctx, userID, chi.URLParam(r, "id"), q)
+	if err != nil {
+		http.Error(w, err.Error(), campaignError(err))
+		return
+	}
+
+	writeJSON(w, http.StatusOK, CampaignStatsData{
+		ID:             c.ID,
+		Name:           c.Name,
+		From:           report.Query.From,
+		To:             report.Query.To,
+		Interval:       string(report.Query.Interval),
+		Total:          report.Total,
+		UniqueVisitors: report.UniqueVisitors,
+		Series:         seriesData(report.Series),
+		Links:          countData(report.Links),
+		Referrers:      countData(report.Referrers),
+		Browsers:       countData(report.Browsers),
+		OS:             countData(report.OS),
+		Rules:          countData(report.Rules),
+		Variants:       countData(report.Variants),
+		Classes:        countData(report.Classes),
+	})
+}
+
+// CreateCampaign создаёт кампанию для grpc.
+func (h Handler) CreateCampaign(ctx context.Context, request *pb.CreateCampaignRequest) (*pb.Campaign, error) {
+	c, err := h.createCampaign(ctx, request.UserID, CreateCampaignData{Name: request.Name, Parameters: request.Parameters})
+	if err != nil {
+		return nil, campaignStatusError(err)
+	}
+
+	return campaignProto(c), nil
+}
+
+// GetCampaigns возвращает кампании пользователя для grpc.
+func (h Handler) GetCampaigns(ctx context.Context, request *pb.GetCampaignsRequest) (*pb.GetCampaignsResponse, error) {
+	list, err := h.Storage.GetCampaigns(ctx, request.UserID)
+	if err != nil {
+		return nil, err
+	}
+
+	response := &pb.GetCampaignsResponse{Campaigns: make([]*pb.Campaign, 0, len(list))}
+
+	for _, v := range list {
+		response.Campaigns = append(response.Campaigns, campaignProto(v))
+	}
+
+	return response, nil
+}
+
+// UpdateCampaign изменяет кампанию для grpc. Пустой список параметров не изменяет параметры кампании.
+func (h Handler) UpdateCampaign(ctx context.Context, request *pb.UpdateCampaignRequest) (*pb.Campaign, error) {
+	data := UpdateCampaignData{Name: request.Name}
+	if len(request.Parameters) > 0 {
+		data.Parameters = &request.Parameters
+	}
+
+	c, err := h.updateCampaign(ctx, request.UserID, request.Id, data)
+	if err != nil {
+		return nil, campaignStatusError(err)
+	}
+
+	return campaignProto(c), nil
+}
+
+// DeleteCampaign удаляет кампанию для grpc.
+func (h Handler) DeleteCampaign(ctx context.Context, request *pb.DeleteCampaignRequest) (*emptypb.Empty, error) {
+	if err := h.Storage.DeleteCampaign(ctx, request.UserID, request.Id); err != nil {
+		return nil, campaignStatusError(err)
+	}
+
+	return &emptypb.Empty{}, nil
+}
+
+// GetCampaignStats возвращает статистику переходов по ссылкам кампании для grpc.
+func (h Handler) GetCampaignStats(ctx context.Context, request *pb.GetCampaignStatsRequest) (*pb.GetCampaignStatsResponse, error) {
+	q, err := statsQuery(request.From, request.To, request.Interval, request.IncludeBots)
+	if err != nil {
+		return nil, err
+	}
+
+	c, report, err := h.campaignStats(ctx, request.UserID, request.Id, q)
+	if err != nil {
+		return nil, campaignStatusError(err)
+	}
+
+	return &pb.GetCampaignStatsResponse{
+		Id:             c.ID,
+		Name:           c.Name,
+		From:           timestamppb.New(report.Query.From),
+		To:             timestamppb.New(report.Query.To),
+		Interval:       string(report.Query.Interval),
+		Total:          report.Total,
+		UniqueVisitors: report.UniqueVisitors,
+		Series:         statsSeries(report.Series),
+		Links:          statsCounts(report.Links),
+		Referrers:      statsCounts(report.Referrers),
+		Browsers:       statsCounts(report.Browsers),
+		Os:             statsCounts(report.OS),
+		Rules:          statsCounts(report.Rules),
+		Variants:       statsCounts(report.Variants),
+		Classes:        statsCounts(report.Classes),
+	}, nil
+}
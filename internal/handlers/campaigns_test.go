@@ -0,0 +1,185 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+
+	"github.com/vladimirimekov/url-shortener/internal/clicks"
+	"github.com/vladimirimekov/url-shortener/internal/storage"
+	pb "github.com/vladimirimekov/url-shortener/proto"
+)
+
+// TestCheckCampaignParameters проверяет валидацию шаблонов параметров кампании.
+func TestCheckCampaignParameters(t *testing.T) {
+	tests := []struct {
+		name       string
+		parameters map[string]string
+		want       map[string]string
+		wantErr    error
+	}{
+		{name: "empty", wantErr: errNoCampaignParameters},
+		{
+			name:       "normalized",
+			parameters: map[string]string{" UTM_Source ": " newsletter ", "utm_content": "{short}-{variant}"},
+			want:       map[string]string{"utm_source": "newsletter", "utm_content": "{short}-{variant}"},
+		},
+		{name: "not utm", parameters: map[string]string{"ref": "x"}, wantErr: errInvalidCampaignParameter},
+		{name: "empty value", parameters: map[string]string{"utm_source": " "}, wantErr: errInvalidCampaignParameter},
+		{name: "duplicate key", parameters: map[string]string{"utm_source": "a", "UTM_SOURCE": "b"}, wantErr: errInvalidCampaignParameter},
+		{name: "too long", parameters: map[string]string{"utm_term": strings.Repeat("a", maxCampaignValue+1)}, wantErr: errInvalidCampaignParameter},
+		{name: "unknown placeholder", parameters: map[string]string{"utm_term": "{user}"}, wantErr: errUnknownPlaceholder},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			parameters, err := checkCampaignParameters(tt.parameters)
+			if tt.wantErr != nil {
+				assert.ErrorIs(t, err, tt.wantErr)
+				return
+			}
+
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, parameters)
+		})
+	}
+}
+
+// TestCampaignURL проверяет добавление параметров кампании к адресу перенаправления.
+func TestCampaignURL(t *testing.T) {
+	c := storage.Campaign{Parameters: map[string]string{"utm_source": "mail", "utm_medium": "{rule}", "utm_content": "{short}/{variant}", "utm_term": "{variant}"}}
+
+	tests := []struct {
+		name        string
+		destination string
+		target      redirectTarget
+		want        string
+	}{
+		{
+			name:        "default",
+			destination: "https://example.com/a",
+			want:        "https://example.com/a?utm_content=abc%2F&utm_medium=default&utm_source=mail",
+		},
+		{
+			name:        "destination parameters win",
+			destination: "https://example.com/a?utm_source=site&x=1#top",
+			target:      redirectTarget{Rule: "mobile", Variant: "b"},
+			want:        "https://example.com/a?utm_source=site&x=1&utm_content=abc%2Fb&utm_medium=mobile&utm_term=b#top",
+		},
+		{name: "not http", destination: "mailto:user@example.com", want: "mailto:user@example.com"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, campaignURL(tt.destination, c, "abc", tt.target))
+		})
+	}
+}
+
+// TestHandler_Campaigns проверяет управление кампаниями, добавление их параметров при перенаправлении и статистику кампании.
+func TestHandler_Campaigns(t *testing.T) {
+	s := storage.NewMemoryWork(map[string]map[string]string{})
+	recorder := clicks.New(s, []byte("salt"), 10, 10, time.Second, nil)
+	go recorder.Run(context.Background())
+
+	d := Handler{Storage: s, Host: "http://localhost:8080", UserKey: userKey, Clicks: recorder}
+
+	h := chi.NewRouter()
+	h.Get("/{id}", d.MainHandler)
+	h.Post("/api/shorten", d.PostShortenHandler)
+	h.Patch("/api/user/urls/{short}", d.UpdateURLHandler)
+	h.Post("/api/user/campaigns", d.CreateCampaignHandler)
+	h.Get("/api/user/campaigns", d.GetCampaignsHandler)
+	h.Patch("/api/user/campaigns/{id}", d.UpdateCampaignHandler)
+	h.Delete("/api/user/campaigns/{id}", d.DeleteCampaignHandler)
+	h.Get("/api/user/campaigns/{id}/stats", d.GetCampaignStatsHandler)
+
+	request := func(method, target, user, body string) *httptest.ResponseRecorder {
+		r := httptest.NewRequest(method, target, strings.NewReader(body))
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), userKey, user)))
+
+		return w
+	}
+
+	w := request(http.MethodPost, "/api/user/campaigns", "owner", `{"name":"Spring","parameters":{"utm_source":"newsletter","utm_campaign":"spring-{short}"}}`)
+	require.Equal(t, http.StatusCreated, w.Code)
+
+	var campaign CampaignData
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &campaign))
+
+	assert.Equal(t, http.StatusBadRequest, request(http.MethodPost, "/api/user/campaigns", "owner", `{"name":"Bad","parameters":{"ref":"x"}}`).Code)
+
+	w = request(http.MethodPost, "/api/shorten", "owner", `{"url":"https://example.com/a","campaign":"`+campaign.ID+`"}`)
+	require.Equal(t, http.StatusCreated, w.Code)
+	var result map[string]string
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &result))
+	first := strings.TrimPrefix(result["result"], d.Host+"/")
+
+	//ссылку нельзя привязать к чужой кампании
+	assert.Equal(t, http.StatusBadRequest, request(http.MethodPost, "/api/shorten", "intruder", `{"url":"https://example.com/x","campaign":"`+campaign.ID+`"}`).Code)
+
+	second := strings.TrimPrefix(mustCreate(t, d, &pb.CreateShortLinkRequest{OriginalURL: "https://example.com/b?utm_source=site", UserID: "owner"}), d.Host+"/")
+	w = request(http.MethodPatch, "/api/user/urls/"+second, "owner", `{"campaign":"`+campaign.ID+`"}`)
+	require.Equal(t, http.StatusOK, w.Code)
+
+	w = request(http.MethodGet, "/"+first, "", "")
+	require.Equal(t, http.StatusTemporaryRedirect, w.Code)
+	assert.Equal(t, "https://example.com/a?utm_campaign=spring-"+first+"&utm_source=newsletter", w.Header().Get("Location"))
+
+	w = request(http.MethodGet, "/"+second, "", "")
+	assert.Equal(t, "https://example.com/b?utm_source=site&utm_campaign=spring-"+second, w.Header().Get("Location"))
+	request(http.MethodGet, "/"+second, "", "")
+
+	w = request(http.MethodPatch, "/api/user/campaigns/"+campaign.ID, "owner", `{"parameters":{"utm_medium":"email"}}`)
+	require.Equal(t, http.StatusOK, w.Code)
+
+	w = request(http.MethodGet, "/"+first, "", "")
+	assert.Equal(t, "https://example.com/a?utm_medium=email", w.Header().Get("Location"))
+
+	recorder.Close()
+
+	w = request(http.MethodGet, "/api/user/campaigns/"+campaign.ID+"/stats?include_bots=true", "owner", "")
+	require.Equal(t, http.StatusOK, w.Code)
+	var stats CampaignStatsData
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &stats))
+	assert.Equal(t, int64(4), stats.Total)
+	assert.ElementsMatch(t, []CountData{{Name: d.Host + "/" + first, Clicks: 2}, {Name: d.Host + "/" + second, Clicks: 2}}, stats.Links)
+
+	assert.Equal(t, http.StatusForbidden, request(http.MethodGet, "/api/user/campaigns/"+campaign.ID+"/stats", "intruder", "").Code)
+
+	response, err := d.GetCampaignStats(context.Background(), &pb.GetCampaignStatsRequest{UserID: "owner", Id: campaign.ID, IncludeBots: true})
+	require.NoError(t, err)
+	assert.Equal(t, int64(4), response.Total)
+
+	_, err = d.UpdateCampaign(context.Background(), &pb.UpdateCampaignRequest{UserID: "owner", Id: campaign.ID, Name: proto.String("")})
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+
+	assert.Equal(t, http.StatusNoContent, request(http.MethodDelete, "/api/user/campaigns/"+campaign.ID, "owner", "").Code)
+
+	w = request(http.MethodGet, "/api/user/campaigns", "owner", "")
+	assert.JSONEq(t, `[]`, w.Body.String())
+
+	link, err := s.GetLink(context.Background(), first)
+	require.NoError(t, err)
+	assert.Empty(t, link.CampaignID)
+}
+
+// mustCreate создаёт ссылку через grpc и возвращает её короткий адрес.
+func mustCreate(t *testing.T, d Handler, request *pb.CreateShortLinkRequest) string {
+	t.Helper()
+
+	response, err := d.CreateShortLink(context.Background(), request)
+	require.NoError(t, err)
+
+	return response.ShortURL
+}
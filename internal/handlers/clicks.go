@@ -0,0 +1,50 @@
+package handlers
+
+import (
+	"net"
+	"net/http"
+	"time"
+
+	"github.com/vladimirimekov/url-shortener/internal/storage"
+)
+
+// newClick возвращает переход по ссылке из запроса с определённым классом: человек, бот или предзагрузка.
+// Класс определяется до списания перехода, чтобы боты и предзагрузка не расходовали лимит переходов ссылки.
+func (h Handler) newClick(r *http.Request, shortname string) storage.Click {
+	ip, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		ip = r.RemoteAddr
+	}
+
+	//без очереди переходов адрес не сохраняется, частота переходов считается по самому адресу
+	ipHash, key := "", ip
+	if h.Clicks != nil {
+		ipHash = h.Clicks.HashIP(ip)
+		key = ipHash
+	}
+
+	now := time.Now().UTC()
+
+	return storage.Click{
+		ShortURL:  shortname,
+		Time:      now,
+		Referrer:  r.Referer(),
+		UserAgent: r.UserAgent(),
+		IPHash:    ipHash,
+		Class:     h.Bots.Classify(r.UserAgent(), r.Header, key, now),
+	}
+}
+
+// recordClick ставит в очередь на сохранение переход по ссылке и публикует его в поток переходов.
+// Вместе с переходом сохраняются сработавшее правило таргетинга и вариант A/B теста из target.
+func (h Handler) recordClick(click storage.Click, target redirectTarget) {
+	if h.Clicks == nil {
+		return
+	}
+
+	click.Rule = target.Rule
+	click.Variant = target.Variant
+
+	h.Clicks.Record(click)
+	h.Events.Publish(click)
+}
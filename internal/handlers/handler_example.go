@@ -5,12 +5,26 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"github.com/vladimirimekov/url-shortener/internal/botfilter"
+	"github.com/vladimirimekov/url-shortener/internal/broker"
+	"github.com/vladimirimekov/url-shortener/internal/clicks"
+	"github.com/vladimirimekov/url-shortener/internal/geoip"
+	"github.com/vladimirimekov/url-shortener/internal/keypool"
+	"github.com/vladimirimekov/url-shortener/internal/metadata"
+	"github.com/vladimirimekov/url-shortener/internal/namegen"
+	"github.com/vladimirimekov/url-shortener/internal/qrcode"
+	"github.com/vladimirimekov/url-shortener/internal/ratelimit"
+	"github.com/vladimirimekov/url-shortener/internal/storage"
+	"github.com/vladimirimekov/url-shortener/internal/webhooks"
 	pb "github.com/vladimirimekov/url-shortener/proto"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
 	"google.golang.org/protobuf/types/known/emptypb"
 	"io"
-	"math/rand"
+	"log"
 	"net/http"
 	"net/url"
+	"strconv"
 	"time"
 
 	"golang.org/x/sync/errgroup"
@@ -28,6 +42,30 @@ type Repositories interface {
 	GetURLByShortname(context.Context, string) (string, bool)
 	PingDBConnection(ctx context.Context) error
 	GetStatistic() (int, int)
+	CountShortnames(length int) int
+	GetLink(context.Context, string) (storage.Link, error)
+	UpdateLink(context.Context, string, string, int64, storage.UpdateFunc) (storage.Link, error)
+	ConfigureLink(context.Context, string, storage.UpdateFunc) (storage.Link, error)
+	CreateLink(context.Context, string, string, string, storage.UpdateFunc) (storage.Link, error)
+	ConsumeClick(context.Context, string) error
+	GetLinkHistory(context.Context, string) ([]storage.Revision, error)
+	GetUserLinks(context.Context, string) ([]storage.Link, error)
+	GetClicks(context.Context, []string, time.Time, time.Time) ([]storage.Click, error)
+	GetRollupWatermark(context.Context) (time.Time, error)
+	GetRollups(context.Context, []string, string, time.Time, time.Time) ([]storage.Rollup, error)
+	SaveWebhook(context.Context, storage.Webhook) error
+	GetWebhooks(context.Context, string) ([]storage.Webhook, error)
+	DeleteWebhook(context.Context, string, string) error
+	GetDelivery(context.Context, string) (storage.Delivery, error)
+	GetDeliveries(context.Context, string, string) ([]storage.Delivery, error)
+	SaveCampaign(context.Context, storage.Campaign) error
+	GetCampaign(context.Context, string) (storage.Campaign, error)
+	GetCampaigns(context.Context, string) ([]storage.Campaign, error)
+	DeleteCampaign(context.Context, string, string) error
+	FindUserLinks(context.Context, string, storage.LinkFilter) (storage.LinkPage, error)
+	RenameTag(context.Context, string, string, string) (int64, error)
+	DeleteTag(context.Context, string, string) (int64, error)
+	SearchUserLinks(context.Context, string, storage.LinkSearch) ([]storage.Link, error)
 }
 
 // Handler хранит базовые настройки хэндлера и интерфейс с методами для работы с хэнделами.
@@ -36,24 +74,52 @@ type Handler struct {
 	LengthOfShortname int
 	Host              string
 	UserKey           interface{}
+	Generator         *namegen.Generator
+	Keys              *keypool.Pool
+	Clicks            *clicks.Recorder
+	Bots              *botfilter.Classifier
+	Events            *broker.Broker
+	Webhooks          *webhooks.Dispatcher
+	QRCodes           *qrcode.Cache
+	PasswordAttempts  *ratelimit.Limiter
+	ComingSoon        ComingSoon
+	Geo               *geoip.Database
+	Metadata          *metadata.Fetcher
 	pb.UnimplementedUrlShortenerServer
 }
 
 // GetData содержит структуру для получения ссылок в формате json.
 type GetData struct {
 	URL string `json:"url"`
+	LinkOptions
 }
 
 // AllUserURLs содержит структуру для json данных со всеми пользовательскими URL.
 type AllUserURLs struct {
-	ShortURL    string `json:"short_url"`
-	OriginalURL string `json:"original_url"`
+	ShortURL    string        `json:"short_url"`
+	OriginalURL string        `json:"original_url"`
+	Clicks      int64         `json:"clicks"`
+	Tags        []string      `json:"tags,omitempty"`
+	Folder      string        `json:"folder,omitempty"`
+	Title       string        `json:"title,omitempty"`
+	Metadata    *MetadataData `json:"metadata,omitempty"`
 }
 
 // Statistic содержит структуру для json данных со статистикой.
 type Statistic struct {
-	Urls  int `json:"urls"`
-	Users int `json:"users"`
+	Urls     int               `json:"urls"`
+	Users    int               `json:"users"`
+	Keyspace KeyspaceStatistic `json:"keyspace"`
+}
+
+// KeyspaceStatistic содержит структуру для json данных о заполненности пространства коротких имён.
+type KeyspaceStatistic struct {
+	Length        int     `json:"length"`
+	Capacity      float64 `json:"capacity"`
+	Utilisation   float64 `json:"utilisation"`
+	Attempts      uint64  `json:"attempts"`
+	Collisions    uint64  `json:"collisions"`
+	CollisionRate float64 `json:"collision_rate"`
 }
 
 // BatchData содержит структуру для получения json данных с пачкой ссылок для сокращения.
@@ -64,38 +130,84 @@ type BatchData struct {
 }
 
 // GetShortname возвращает неиспользуемую раннее строку для сокращения ссылок.
-func (h Handler) GetShortname(ctx context.Context) string {
-	var shortname string
-	var result bool
+// Сначала имя берётся из пула заранее сгенерированных имён, при его отсутствии генерируется на месте.
+func (h Handler) GetShortname(ctx context.Context) (string, error) {
+	if h.Keys != nil {
+		key, err := h.Keys.Take(ctx)
+		if err == nil {
+			return key, nil
+		}
+		if !errors.Is(err, storage.ErrPoolEmpty) {
+			log.Print(err)
+		}
+	}
 
-	savedData := h.Storage.ReadData(ctx)
+	var inPool func(string) bool
 
-	//проверка на существование сгенерированного имени
-	for {
-		result = true
+	if h.Keys != nil {
+		//имя из пула уже может быть выдано другому запросу, поэтому такие имена пропускаются
+		inPool = func(shortname string) bool {
+			ok, err := h.Keys.Contains(ctx, shortname)
+			if err != nil {
+				log.Print(err)
+				return true
+			}
+			return ok
+		}
+	}
 
-		letters := []rune("abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789")
+	shortnames, err := h.generateShortnames(ctx, 1, inPool)
+	if err != nil {
+		return "", err
+	}
 
-		s := make([]rune, h.LengthOfShortname)
-		for i := range s {
-			s[i] = letters[rand.Intn(len(letters))]
-		}
+	return shortnames[0], nil
+}
 
-		shortname = string(s)
+// GenerateShortnames генерирует n неиспользуемых раннее строк для сокращения ссылок.
+func (h Handler) GenerateShortnames(ctx context.Context, n int) ([]string, error) {
+	return h.generateShortnames(ctx, n, nil)
+}
 
+// generateShortnames генерирует n неиспользуемых раннее строк, пропуская также имена, для которых taken возвращает true.
+func (h Handler) generateShortnames(ctx context.Context, n int, taken func(string) bool) ([]string, error) {
+	savedData := h.Storage.ReadData(ctx)
+	result := make([]string, 0, n)
+	generated := make(map[string]struct{}, n)
+
+	//проверка на существование сгенерированного имени
+	exists := func(shortname string) bool {
+		if _, ok := generated[shortname]; ok {
+			return true
+		}
 		for _, value := range savedData {
 			if _, ok := value[shortname]; ok {
-				result = false
-				break
+				return true
 			}
 		}
+		return taken != nil && taken(shortname)
+	}
 
-		if result {
-			break
+	for len(result) < n {
+		shortname, err := h.generator().Next(exists)
+		if err != nil {
+			return nil, err
 		}
+
+		generated[shortname] = struct{}{}
+		result = append(result, shortname)
 	}
 
-	return shortname
+	return result, nil
+}
+
+// generator возвращает генератор имён хэндлера либо временный генератор с параметрами по умолчанию.
+func (h Handler) generator() *namegen.Generator {
+	if h.Generator != nil {
+		return h.Generator
+	}
+
+	return namegen.New(h.LengthOfShortname, namegen.DefaultMaxLength, namegen.DefaultMaxAttempts, namegen.DefaultCollisionThreshold)
 }
 
 // Внутренняя функция для получения айди из контекста
@@ -128,16 +240,50 @@ func (h Handler) MainHandler(w http.ResponseWriter, r *http.Request) {
 		defer cancel()
 		r = r.WithContext(ctx)
 
-		shortname := chi.URLParam(r, "id")
+		shortname, preview := previewShortname(r, chi.URLParam(r, "id"))
+		if preview {
+			h.previewLink(w, r, shortname)
+			return
+		}
+
 		w.Header().Set("content-type", "text/plain; charset=utf-8")
 
-		if originalURL, isDelete := h.Storage.GetURLByShortname(ctx, shortname); isDelete {
-			w.WriteHeader(http.StatusGone)
-		} else if originalURL == "" {
-			http.Error(w, "URL not found", http.StatusNotFound)
-		} else {
-			w.Header().Set("Location", originalURL)
-			w.WriteHeader(http.StatusTemporaryRedirect)
+		var path string
+
+		link, err := h.Storage.GetLink(ctx, shortname)
+		if err == nil {
+			err = linkAvailable(link, time.Now())
+		}
+		if err == nil {
+			path, err = checkPassthrough(link, passthroughPath(r))
+		}
+
+		switch {
+		case errors.Is(err, storage.ErrNotActive):
+			h.comingSoon(w, r, link)
+		case err != nil:
+			http.Error(w, err.Error(), linkErrorStatus(err))
+		case link.PasswordHash != "":
+			h.renderPasswordForm(w, r, http.StatusOK, shortname, nil)
+		default:
+			click := h.newClick(r, shortname)
+
+			err = h.consumeClick(ctx, link, click)
+			switch {
+			case errors.Is(err, errAutomatedClick):
+				automatedClick(w)
+				h.recordClick(click, redirectTarget{})
+				return
+			case err != nil:
+				http.Error(w, err.Error(), linkErrorStatus(err))
+				return
+			}
+
+			target := h.requestDestination(w, r, link)
+			target.URL = h.redirectURL(ctx, link, target, path, r.URL.Query())
+
+			redirect(w, link, target.URL)
+			h.recordClick(click, target)
 		}
 
 	case http.MethodPost:
@@ -146,6 +292,12 @@ func (h Handler) MainHandler(w http.ResponseWriter, r *http.Request) {
 		defer cancel()
 		r = r.WithContext(ctx)
 
+		//POST запрос на короткую ссылку отправляет форма ввода пароля
+		if shortname := chi.URLParam(r, "id"); shortname != "" {
+			h.unlockLink(w, r, shortname)
+			return
+		}
+
 		userID, err := h.getUserID(r)
 		if err != nil {
 			http.Error(w, err.Error(), http.StatusInternalServerError)
@@ -173,7 +325,11 @@ func (h Handler) MainHandler(w http.ResponseWriter, r *http.Request) {
 			return
 		}
 
-		shortname := h.GetShortname(ctx)
+		shortname, err := h.GetShortname(ctx)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
 		resultData := map[string]map[string]string{userID: {shortname: currentURL}}
 
 		if err = h.Storage.SaveData(ctx, resultData); err != nil {
@@ -206,6 +362,9 @@ func (h Handler) MainHandler(w http.ResponseWriter, r *http.Request) {
 			}
 		}
 
+		h.emitCreated(ctx, resultData)
+		h.fetchMetadata(resultData)
+
 		w.Header().Set("content-type", "application/json")
 		w.WriteHeader(http.StatusCreated)
 		_, err = w.Write([]byte(h.Host + "/" + shortname))
@@ -260,49 +419,70 @@ func (h Handler) PostShortenHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	shortname := h.GetShortname(ctx)
+	configure, err := g.LinkOptions.updateFunc()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if err = h.checkCampaign(ctx, userID, g.Campaign); err != nil {
+		http.Error(w, err.Error(), linkErrorStatus(err))
+		return
+	}
+
+	shortname, err := h.GetShortname(ctx)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
 
 	resultData := map[string]map[string]string{userID: {shortname: g.URL}}
 
-	if err = h.Storage.SaveData(ctx, resultData); err != nil {
-		switch e := err.(type) {
-		case *pq.Error:
-			if pgerrcode.IsIntegrityConstraintViolation(string(e.Code)) {
-				w.Header().Set("content-type", "application/json")
-				w.WriteHeader(http.StatusConflict)
+	if _, err = h.Storage.CreateLink(ctx, userID, shortname, g.URL, configure); err != nil {
+		if !errors.Is(err, storage.ErrURLExists) {
+			http.Error(w, err.Error(), linkErrorStatus(err))
+			return
+		}
 
-				savedData := h.Storage.ReadData(ctx)
+		//настройки нельзя применить к уже существующей ссылке: она могла быть создана без пароля или ограничений
+		if configure != nil {
+			http.Error(w, err.Error(), http.StatusConflict)
+			return
+		}
 
-				for _, value := range savedData {
-					for short, original := range value {
-						if original == g.URL {
+		w.Header().Set("content-type", "application/json")
+		w.WriteHeader(http.StatusConflict)
 
-							resultJSON, err := json.Marshal(map[string]string{"result": h.Host + "/" + short})
+		savedData := h.Storage.ReadData(ctx)
 
-							if err != nil {
-								http.Error(w, err.Error(), http.StatusInternalServerError)
-								return
-							}
+		for _, value := range savedData {
+			for short, original := range value {
+				if original == g.URL {
 
-							_, err = w.Write(resultJSON)
-							if err != nil {
-								http.Error(w, err.Error(), http.StatusInternalServerError)
-								return
-							}
+					resultJSON, err := json.Marshal(map[string]string{"result": h.Host + "/" + short})
 
-							break
-						}
+					if err != nil {
+						http.Error(w, err.Error(), http.StatusInternalServerError)
+						return
 					}
-				}
 
-				return
+					_, err = w.Write(resultJSON)
+					if err != nil {
+						http.Error(w, err.Error(), http.StatusInternalServerError)
+						return
+					}
+
+					break
+				}
 			}
-		default:
-			http.Error(w, e.Error(), http.StatusInternalServerError)
-			return
 		}
+
+		return
 	}
 
+	h.emitCreated(ctx, resultData)
+	h.fetchMetadata(resultData)
+
 	resultJSON, err := json.Marshal(map[string]string{"result": h.Host + "/" + shortname})
 
 	if err != nil {
@@ -357,7 +537,11 @@ func (h Handler) PostShortenBatchHandler(w http.ResponseWriter, r *http.Request)
 	dataToSave[userID] = map[string]string{}
 
 	for index, value := range g {
-		shortname := h.GetShortname(ctx)
+		shortname, err := h.GetShortname(ctx)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
 		dataToSave[userID][shortname] = value.OriginalURL
 		g[index].ShortURL = h.Host + "/" + shortname
 		g[index].OriginalURL = ""
@@ -368,6 +552,9 @@ func (h Handler) PostShortenBatchHandler(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
+	h.emitCreated(ctx, dataToSave)
+	h.fetchMetadata(dataToSave)
+
 	resultJSON, err := json.Marshal(g)
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
@@ -383,7 +570,9 @@ func (h Handler) PostShortenBatchHandler(w http.ResponseWriter, r *http.Request)
 	}
 }
 
-// GetAllShorterURLsHandler отправляет список всех сокращенных ссылок текущего пользователя.
+// GetAllShorterURLsHandler отправляет страницу списка сокращенных ссылок текущего пользователя.
+// Количество всех подходящих ссылок передаётся в заголовке X-Total-Count, курсор следующей страницы - в X-Next-Cursor.
+// Если ссылок нет, отправляется пустой список.
 func (h Handler) GetAllShorterURLsHandler(w http.ResponseWriter, r *http.Request) {
 
 	ctx, cancel := context.WithTimeout(r.Context(), 60*time.Second)
@@ -396,28 +585,29 @@ func (h Handler) GetAllShorterURLsHandler(w http.ResponseWriter, r *http.Request
 		return
 	}
 
-	savedData := h.Storage.ReadData(ctx)
-	userData := savedData[userID]
-
-	if len(userData) == 0 {
-		err := errors.New("there are no shortened links")
-		http.Error(w, err.Error(), http.StatusNoContent)
+	filter, err := parseLinkFilter(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
 		return
 	}
 
-	result := make([]AllUserURLs, 0, len(userData))
-
-	for key, value := range userData {
-		result = append(result, AllUserURLs{ShortURL: h.Host + "/" + key, OriginalURL: value})
+	page, next, err := h.userLinksPage(ctx, userID, filter)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
 	}
 
-	resultJSON, err := json.Marshal(result)
+	resultJSON, err := json.Marshal(h.userURLs(page.Links))
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
 
 	w.Header().Set("content-type", "application/json")
+	w.Header().Set("X-Total-Count", strconv.FormatInt(page.Total, 10))
+	if next != "" {
+		w.Header().Set("X-Next-Cursor", next)
+	}
 	w.WriteHeader(http.StatusOK)
 	_, err = w.Write(resultJSON)
 	if err != nil {
@@ -467,7 +657,7 @@ func (h Handler) DeleteBatchURLS(w http.ResponseWriter, r *http.Request) {
 				err := errors.New("context canceled")
 				return err
 			default:
-				h.Storage.DeleteData(s, userID)
+				h.deleteLinks(ctx, s, userID)
 				return nil
 			}
 		}
@@ -493,7 +683,17 @@ func (h Handler) PingConnection(w http.ResponseWriter, r *http.Request) {
 func (h Handler) GetStatistics(w http.ResponseWriter, r *http.Request) {
 
 	urls, users := h.Storage.GetStatistic()
-	result := Statistic{Urls: urls, Users: users}
+
+	g := h.generator()
+	gs := g.Stats()
+	result := Statistic{Urls: urls, Users: users, Keyspace: KeyspaceStatistic{
+		Length:        gs.Length,
+		Capacity:      namegen.Capacity(gs.Length),
+		Utilisation:   g.Utilisation(h.Storage.CountShortnames(gs.Length)),
+		Attempts:      gs.Attempts,
+		Collisions:    gs.Collisions,
+		CollisionRate: gs.CollisionRate,
+	}}
 
 	resultJSON, err := json.Marshal(result)
 	if err != nil {
@@ -514,47 +714,76 @@ func (h Handler) GetStatistics(w http.ResponseWriter, r *http.Request) {
 func (h Handler) CreateShortLink(ctx context.Context, request *pb.CreateShortLinkRequest) (*pb.CreateShortLinkResponse, error) {
 	var response pb.CreateShortLinkResponse
 
-	shortname := h.GetShortname(ctx)
+	options := LinkOptions{Password: request.Password, MaxClicks: request.MaxClicks, Rules: targetRulesFromProto(request.Rules), Variants: variantsFromProto(request.Variants)}
+	options.RedirectStatus = int(request.RedirectStatus)
+	options.CacheControl = request.CacheControl
+	options.MetaRefresh = request.MetaRefresh
+	options.Passthrough = request.Passthrough
+	options.Campaign = request.Campaign
+	options.Tags = request.Tags
+	options.Folder = request.Folder
+	options.Title = request.Title
+	if request.NotBefore != nil {
+		options.NotBefore = request.NotBefore.AsTime()
+	}
+	if request.NotAfter != nil {
+		options.NotAfter = request.NotAfter.AsTime()
+	}
+
+	configure, err := options.updateFunc()
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+
+	if err = h.checkCampaign(ctx, request.UserID, options.Campaign); err != nil {
+		return nil, linkStatusError(err)
+	}
+
+	shortname, err := h.GetShortname(ctx)
+	if err != nil {
+		return nil, err
+	}
 	resultData := map[string]map[string]string{request.UserID: {shortname: request.OriginalURL}}
 
-	if err := h.Storage.SaveData(ctx, resultData); err != nil {
-		switch e := err.(type) {
-		case *pq.Error:
-			if pgerrcode.IsIntegrityConstraintViolation(string(e.Code)) {
-				savedData := h.Storage.ReadData(ctx)
-
-				for _, value := range savedData {
-					for short, original := range value {
-						if original == request.OriginalURL {
-							response.ShortURL = short
-							break
-						}
-					}
-				}
+	if _, err = h.Storage.CreateLink(ctx, request.UserID, shortname, request.OriginalURL, configure); err != nil {
+		if !errors.Is(err, storage.ErrURLExists) || configure != nil {
+			return nil, linkStatusError(err)
+		}
+
+		savedData := h.Storage.ReadData(ctx)
 
-				return &response, nil
+		for _, value := range savedData {
+			for short, original := range value {
+				if original == request.OriginalURL {
+					response.ShortURL = short
+					break
+				}
 			}
-		default:
-			return nil, err
 		}
+
+		return &response, nil
 	}
 
+	h.emitCreated(ctx, resultData)
+	h.fetchMetadata(resultData)
+
 	response.ShortURL = h.Host + "/" + shortname
 
 	return &response, nil
 }
 
-// GetOriginalLink возвращает короткую ссылку для grpc .
+// GetOriginalLink возвращает оригинальную ссылку для grpc. Для защищённых ссылок требуется пароль.
+// Правила таргетинга ссылки проверяются по переданным заголовку User-Agent и IP-адресу клиента.
+// Назначенный клиенту вариант A/B теста возвращается в ответе, чтобы клиент передавал его при повторных запросах.
 func (h Handler) GetOriginalLink(ctx context.Context, request *pb.GetOriginalLinkRequest) (*pb.GetOriginalLinkResponse, error) {
 	var response pb.GetOriginalLinkResponse
 
-	if originalURL, isDelete := h.Storage.GetURLByShortname(ctx, request.ShortURL); isDelete {
-		return nil, errors.New("this link has been removed")
-	} else if originalURL == "" {
-		return nil, errors.New("URL not found")
-	} else {
-		response.OriginalURL = originalURL
+	target, err := h.originalURL(ctx, request)
+	if err != nil {
+		return nil, passwordStatusError(err)
 	}
+	response.OriginalURL = target.URL
+	response.Variant = target.Variant
 
 	return &response, nil
 }
@@ -568,7 +797,10 @@ func (h Handler) CreateLinksInBatches(ctx context.Context, request *pb.CreateLin
 	dataToSave[request.UserID] = map[string]string{}
 
 	for _, value := range request.OriginalURLs {
-		shortname := h.GetShortname(ctx)
+		shortname, err := h.GetShortname(ctx)
+		if err != nil {
+			return nil, err
+		}
 		dataToSave[request.UserID][shortname] = value.OriginalURL
 		response.ShortURLs = append(response.ShortURLs, &pb.BatchResponse{ShortURL: h.Host + "/" + shortname, CorrelationID: value.CorrelationID})
 	}
@@ -577,24 +809,33 @@ func (h Handler) CreateLinksInBatches(ctx context.Context, request *pb.CreateLin
 		return nil, err
 	}
 
+	h.emitCreated(ctx, dataToSave)
+	h.fetchMetadata(dataToSave)
+
 	return &response, nil
 }
 
-// GetAllShorterURLs возвращает все ссылки пользователя.
+// GetAllShorterURLs возвращает страницу ссылок пользователя с общим количеством подходящих ссылок и курсором следующей страницы.
 func (h Handler) GetAllShorterURLs(ctx context.Context, request *pb.GetAllShorterURLsRequest) (*pb.GetAllShorterURLsResponse, error) {
 	var response pb.GetAllShorterURLsResponse
 
-	savedData := h.Storage.ReadData(ctx)
-	userData := savedData[request.UserID]
-
-	if len(userData) == 0 {
-		return nil, errors.New("there are no shortened links")
+	filter, err := linkFilter(request.Tags, request.Folder)
+	if err == nil {
+		filter, err = linkPage(filter, request.Sort, request.Order, request.Cursor, int(request.Limit))
+	}
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
 	}
 
-	for key, value := range userData {
-		response.ShortURLs = append(response.ShortURLs, &pb.AllShorterURLsResponse{ShortURL: h.Host + "/" + key, OriginalURL: value})
+	page, next, err := h.userLinksPage(ctx, request.UserID, filter)
+	if err != nil {
+		return nil, err
 	}
 
+	response.ShortURLs = h.userURLsProto(page.Links)
+	response.Total = page.Total
+	response.NextCursor = next
+
 	return &response, nil
 }
 
@@ -609,7 +850,7 @@ func (h Handler) DeleteURLS(ctx context.Context, request *pb.DeleteURLSRequest)
 			case <-ctx.Done():
 				return errors.New("context canceled")
 			default:
-				h.Storage.DeleteData(request.ShortURLs, request.UserID)
+				h.deleteLinks(ctx, request.ShortURLs, request.UserID)
 				return nil
 			}
 		}
@@ -2,6 +2,7 @@ package handlers
 
 import (
 	"bytes"
+	"context"
 	"crypto/rand"
 	"database/sql"
 	"encoding/json"
@@ -13,6 +14,7 @@ import (
 	"os"
 	"strings"
 	"testing"
+	"time"
 
 	urlshortener "github.com/vladimirimekov/url-shortener/internal"
 
@@ -20,7 +22,9 @@ import (
 	_ "github.com/lib/pq"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+	"github.com/vladimirimekov/url-shortener/internal/keypool"
 	"github.com/vladimirimekov/url-shortener/internal/middlewares"
+	"github.com/vladimirimekov/url-shortener/internal/namegen"
 	"github.com/vladimirimekov/url-shortener/internal/storage"
 )
 
@@ -335,3 +339,35 @@ func TestHandler_ShortenHandler(t *testing.T) {
 	}
 
 }
+
+// TestHandler_GetShortnamePoolKeys проверяет, что имя, сгенерированное в обход опустевшего пула,
+// не совпадает с именами, уже закреплёнными в пуле за другими запросами.
+func TestHandler_GetShortnamePoolKeys(t *testing.T) {
+	const letters = "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789"
+
+	ctx := context.Background()
+	s := storage.NewMemoryWork(map[string]map[string]string{})
+
+	keys := make([]string, 0, len(letters))
+	for _, c := range letters[1:] {
+		keys = append(keys, string(c))
+	}
+	_, err := s.AddPoolKeys(ctx, keys)
+	require.NoError(t, err)
+
+	//все имена пула закреплены, поэтому имя генерируется на месте
+	for range keys {
+		_, err = s.ClaimPoolKey(ctx, "other")
+		require.NoError(t, err)
+	}
+
+	d := Handler{
+		Storage:   s,
+		Generator: namegen.New(1, 1, 10000, 1),
+		Keys:      keypool.New(s, nil, 0, 0, time.Minute, time.Minute),
+	}
+
+	shortname, err := d.GetShortname(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, "a", shortname)
+}
@@ -0,0 +1,147 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+
+	"github.com/vladimirimekov/url-shortener/internal/storage"
+	"github.com/vladimirimekov/url-shortener/internal/webhooks"
+)
+
+// RevisionData содержит структуру для json данных с записью истории изменений ссылки.
+type RevisionData struct {
+	Version     int64     `json:"version"`
+	OriginalURL string    `json:"original_url"`
+	IsDeleted   bool      `json:"is_deleted"`
+	ChangedAt   time.Time `json:"changed_at"`
+}
+
+// RollbackData содержит структуру для получения json данных с версией, к которой нужно вернуть ссылку.
+type RollbackData struct {
+	Version int64 `json:"version"`
+}
+
+// errRevisionNotFound возвращается, если у ссылки нет записи истории с запрошенной версией.
+var errRevisionNotFound = errors.New("revision not found")
+
+// userLinkHistory возвращает историю изменений ссылки, если она принадлежит пользователю.
+func (h Handler) userLinkHistory(ctx context.Context, userID, shortname string) ([]storage.Revision, error) {
+	link, err := h.Storage.GetLink(ctx, shortname)
+	if err != nil {
+		return nil, err
+	}
+
+	if link.UserID != userID {
+		return nil, storage.ErrNotOwner
+	}
+
+	return h.Storage.GetLinkHistory(ctx, shortname)
+}
+
+// GetURLHistoryHandler отправляет историю изменений ссылки текущего пользователя.
+func (h Handler) GetURLHistoryHandler(w http.ResponseWriter, r *http.Request) {
+
+	ctx, cancel := context.WithTimeout(r.Context(), 60*time.Second)
+	defer cancel()
+
+	userID, err := h.getUserID(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	revisions, err := h.userLinkHistory(ctx, userID, chi.URLParam(r, "short"))
+	if err != nil {
+		http.Error(w, err.Error(), linkErrorStatus(err))
+		return
+	}
+
+	result := make([]RevisionData, 0, len(revisions))
+
+	for _, v := range revisions {
+		result = append(result, RevisionData{Version: v.Version, OriginalURL: v.OriginalURL, IsDeleted: v.IsDeleted, ChangedAt: v.ChangedAt})
+	}
+
+	writeJSON(w, http.StatusOK, result)
+}
+
+// RollbackURLHandler возвращает адрес и состояние ссылки текущего пользователя к одной из прошлых версий.
+// Текущую версию ссылки можно передать в заголовке If-Match.
+func (h Handler) RollbackURLHandler(w http.ResponseWriter, r *http.Request) {
+
+	ctx, cancel := context.WithTimeout(r.Context(), 60*time.Second)
+	defer cancel()
+
+	userID, err := h.getUserID(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	b, err := io.ReadAll(r.Body)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	defer r.Body.Close()
+
+	var rb RollbackData
+
+	if err = json.Unmarshal(b, &rb); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	version, err := parseIfMatch(r)
+	if err != nil {
+		http.Error(w, "Invalid If-Match value", http.StatusBadRequest)
+		return
+	}
+
+	shortname := chi.URLParam(r, "short")
+
+	revisions, err := h.userLinkHistory(ctx, userID, shortname)
+	if err != nil {
+		http.Error(w, err.Error(), linkErrorStatus(err))
+		return
+	}
+
+	var target *storage.Revision
+
+	for i := range revisions {
+		if revisions[i].Version == rb.Version {
+			target = &revisions[i]
+			break
+		}
+	}
+
+	if target == nil {
+		http.Error(w, errRevisionNotFound.Error(), http.StatusNotFound)
+		return
+	}
+
+	link, err := h.Storage.UpdateLink(ctx, userID, shortname, version, func(link *storage.Link) error {
+		if link.OriginalURL != target.OriginalURL {
+			link.Metadata = storage.LinkMetadata{}
+		}
+		link.OriginalURL = target.OriginalURL
+		link.IsDeleted = target.IsDeleted
+		return nil
+	})
+	if err != nil {
+		http.Error(w, err.Error(), linkErrorStatus(err))
+		return
+	}
+
+	h.emit(ctx, userID, webhooks.EventLinkUpdated, link.ShortURL)
+	h.Metadata.Enqueue(link.ShortURL)
+
+	w.Header().Set("ETag", etag(link.Version))
+	writeJSON(w, http.StatusOK, h.linkData(link))
+}
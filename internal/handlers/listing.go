@@ -0,0 +1,145 @@
+package handlers
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/vladimirimekov/url-shortener/internal/storage"
+)
+
+// Размер страницы списка ссылок пользователя.
+const (
+	defaultPageSize = 100
+	maxPageSize     = 1000
+)
+
+// Ошибки проверки параметров списка ссылок пользователя.
+var (
+	errInvalidSort     = errors.New("sort must be created, clicks or alias")
+	errInvalidOrder    = errors.New("order must be asc or desc")
+	errInvalidCursor   = errors.New("invalid cursor")
+	errInvalidPageSize = errors.New("limit must be between 1 and 1000")
+)
+
+// pageCursor содержит данные курсора страницы. Курсор передаётся клиенту в виде base64 строки и действителен
+// только для того же порядка сортировки, в котором был получен.
+type pageCursor struct {
+	Sort      string    `json:"s"`
+	Desc      bool      `json:"d,omitempty"`
+	CreatedAt time.Time `json:"c"`
+	Clicks    int64     `json:"k,omitempty"`
+	ShortURL  string    `json:"u"`
+}
+
+// encodeCursor возвращает курсор следующей страницы, начинающейся после ссылки link.
+func encodeCursor(filter storage.LinkFilter, link storage.Link) string {
+	position := link.Cursor()
+
+	b, err := json.Marshal(pageCursor{Sort: filter.Sort, Desc: filter.Desc, CreatedAt: position.CreatedAt, Clicks: position.Clicks, ShortURL: position.ShortURL})
+	if err != nil {
+		return ""
+	}
+
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+// decodeCursor разбирает курсор страницы и проверяет, что он получен для того же порядка сортировки.
+func decodeCursor(value string, filter storage.LinkFilter) (*storage.LinkCursor, error) {
+	b, err := base64.RawURLEncoding.DecodeString(value)
+	if err != nil {
+		return nil, errInvalidCursor
+	}
+
+	var c pageCursor
+
+	if err = json.Unmarshal(b, &c); err != nil || c.ShortURL == "" || c.Sort != filter.Sort || c.Desc != filter.Desc {
+		return nil, errInvalidCursor
+	}
+
+	return &storage.LinkCursor{CreatedAt: c.CreatedAt, Clicks: c.Clicks, ShortURL: c.ShortURL}, nil
+}
+
+// linkPage дополняет условия выборки порядком сортировки и страницей. По умолчанию ссылки упорядочены от новых к старым,
+// по умолчанию сортировка по количеству переходов идёт по убыванию, по короткому имени - по возрастанию.
+// Нулевой limit при заданном курсоре заменяется размером страницы по умолчанию, а без курсора означает выдачу всех ссылок:
+// клиенты, не знающие о постраничной выдаче, по-прежнему получают полный список.
+func linkPage(filter storage.LinkFilter, sort, order, cursor string, limit int) (storage.LinkFilter, error) {
+	switch sort {
+	case "":
+		sort = storage.LinkSortCreated
+	case storage.LinkSortCreated, storage.LinkSortClicks, storage.LinkSortAlias:
+	default:
+		return storage.LinkFilter{}, errInvalidSort
+	}
+
+	switch order {
+	case "":
+		filter.Desc = sort != storage.LinkSortAlias
+	case "asc":
+	case "desc":
+		filter.Desc = true
+	default:
+		return storage.LinkFilter{}, errInvalidOrder
+	}
+
+	filter.Sort = sort
+
+	if limit == 0 && cursor != "" {
+		limit = defaultPageSize
+	}
+	if limit < 0 || limit > maxPageSize {
+		return storage.LinkFilter{}, errInvalidPageSize
+	}
+	filter.Limit = limit
+
+	if cursor != "" {
+		after, err := decodeCursor(cursor, filter)
+		if err != nil {
+			return storage.LinkFilter{}, err
+		}
+		filter.After = after
+	}
+
+	return filter, nil
+}
+
+// parseLinkFilter возвращает условия выборки ссылок из параметров запроса: tag и folder ограничивают выборку,
+// sort (created, clicks или alias) и order (asc или desc) задают порядок, cursor и limit - страницу.
+func parseLinkFilter(r *http.Request) (storage.LinkFilter, error) {
+	values := r.URL.Query()
+
+	filter, err := linkFilter(values["tag"], values.Get("folder"))
+	if err != nil {
+		return storage.LinkFilter{}, err
+	}
+
+	var limit int
+	if value := values.Get("limit"); value != "" {
+		if limit, err = strconv.Atoi(value); err != nil || limit == 0 {
+			return storage.LinkFilter{}, errInvalidPageSize
+		}
+	}
+
+	return linkPage(filter, values.Get("sort"), values.Get("order"), values.Get("cursor"), limit)
+}
+
+// userLinksPage возвращает страницу ссылок пользователя и курсор следующей страницы.
+// Если следующей страницы нет, курсор пустой.
+func (h Handler) userLinksPage(ctx context.Context, userID string, filter storage.LinkFilter) (storage.LinkPage, string, error) {
+	page, err := h.Storage.FindUserLinks(ctx, userID, filter)
+	if err != nil {
+		return storage.LinkPage{}, "", err
+	}
+
+	var next string
+	if page.HasMore && len(page.Links) > 0 {
+		next = encodeCursor(filter, page.Links[len(page.Links)-1])
+	}
+
+	return page, next, nil
+}
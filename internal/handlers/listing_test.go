@@ -0,0 +1,133 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/vladimirimekov/url-shortener/internal/storage"
+	pb "github.com/vladimirimekov/url-shortener/proto"
+)
+
+// TestLinkPage проверяет разбор порядка сортировки, курсора и размера страницы.
+func TestLinkPage(t *testing.T) {
+	cursor := encodeCursor(storage.LinkFilter{Sort: storage.LinkSortClicks, Desc: true}, storage.Link{ShortURL: "abc", Clicks: 7})
+
+	tests := []struct {
+		name    string
+		sort    string
+		order   string
+		cursor  string
+		limit   int
+		want    storage.LinkFilter
+		wantErr error
+	}{
+		{name: "defaults", want: storage.LinkFilter{Sort: storage.LinkSortCreated, Desc: true}},
+		{name: "alias ascending", sort: "alias", limit: 10, want: storage.LinkFilter{Sort: storage.LinkSortAlias, Limit: 10}},
+		{name: "created ascending", sort: "created", order: "asc", want: storage.LinkFilter{Sort: storage.LinkSortCreated}},
+		{
+			name:   "cursor",
+			sort:   "clicks",
+			cursor: cursor,
+			want:   storage.LinkFilter{Sort: storage.LinkSortClicks, Desc: true, Limit: defaultPageSize, After: &storage.LinkCursor{Clicks: 7, ShortURL: "abc"}},
+		},
+		{name: "cursor for another order", sort: "clicks", order: "asc", cursor: cursor, wantErr: errInvalidCursor},
+		{name: "broken cursor", cursor: "!!!", wantErr: errInvalidCursor},
+		{name: "unknown sort", sort: "title", wantErr: errInvalidSort},
+		{name: "unknown order", order: "up", wantErr: errInvalidOrder},
+		{name: "too large", limit: maxPageSize + 1, wantErr: errInvalidPageSize},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			filter, err := linkPage(storage.LinkFilter{}, tt.sort, tt.order, tt.cursor, tt.limit)
+			if tt.wantErr != nil {
+				assert.ErrorIs(t, err, tt.wantErr)
+				return
+			}
+
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, filter)
+		})
+	}
+}
+
+// TestHandler_GetAllShorterURLsPages проверяет постраничную выдачу ссылок пользователя и ответ для пустого списка.
+func TestHandler_GetAllShorterURLsPages(t *testing.T) {
+	d := Handler{Storage: storage.NewMemoryWork(map[string]map[string]string{}), Host: "http://localhost:8080", UserKey: userKey}
+
+	h := chi.NewRouter()
+	h.Get("/api/user/urls", d.GetAllShorterURLsHandler)
+
+	request := func(target, user string) *httptest.ResponseRecorder {
+		r := httptest.NewRequest(http.MethodGet, target, nil)
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), userKey, user)))
+
+		return w
+	}
+
+	w := request("/api/user/urls", "owner")
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.JSONEq(t, `[]`, w.Body.String())
+	assert.Equal(t, "0", w.Header().Get("X-Total-Count"))
+	assert.Empty(t, w.Header().Get("X-Next-Cursor"))
+
+	var created []string
+	for _, u := range []string{"https://example.com/a", "https://example.com/b", "https://example.com/c"} {
+		created = append(created, mustCreate(t, d, &pb.CreateShortLinkRequest{OriginalURL: u, UserID: "owner"}))
+	}
+
+	var listed []string
+	cursor := ""
+
+	for i := 0; i < len(created); i++ {
+		w = request("/api/user/urls?sort=alias&limit=2&cursor="+cursor, "owner")
+		require.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, "3", w.Header().Get("X-Total-Count"))
+
+		var result []AllUserURLs
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &result))
+		for _, v := range result {
+			listed = append(listed, v.ShortURL)
+		}
+
+		if cursor = w.Header().Get("X-Next-Cursor"); cursor == "" {
+			break
+		}
+	}
+
+	assert.Len(t, listed, 3)
+	assert.ElementsMatch(t, created, listed)
+	assert.True(t, strings.Compare(listed[0], listed[1]) < 0 && strings.Compare(listed[1], listed[2]) < 0)
+
+	assert.Equal(t, http.StatusBadRequest, request("/api/user/urls?sort=title", "owner").Code)
+	assert.Equal(t, http.StatusBadRequest, request("/api/user/urls?limit=-1", "owner").Code)
+
+	response, err := d.GetAllShorterURLs(context.Background(), &pb.GetAllShorterURLsRequest{UserID: "owner", Sort: "alias", Limit: 2})
+	require.NoError(t, err)
+	assert.Equal(t, int64(3), response.Total)
+	require.Len(t, response.ShortURLs, 2)
+	require.NotEmpty(t, response.NextCursor)
+
+	response, err = d.GetAllShorterURLs(context.Background(), &pb.GetAllShorterURLsRequest{UserID: "owner", Sort: "alias", Limit: 2, Cursor: response.NextCursor})
+	require.NoError(t, err)
+	require.Len(t, response.ShortURLs, 1)
+	assert.Equal(t, listed[2], response.ShortURLs[0].ShortURL)
+	assert.Empty(t, response.NextCursor)
+
+	response, err = d.GetAllShorterURLs(context.Background(), &pb.GetAllShorterURLsRequest{UserID: "nobody"})
+	require.NoError(t, err)
+	assert.Empty(t, response.ShortURLs)
+
+	_, err = d.GetAllShorterURLs(context.Background(), &pb.GetAllShorterURLsRequest{UserID: "owner", Cursor: "bad"})
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+}
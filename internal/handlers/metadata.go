@@ -0,0 +1,62 @@
+package handlers
+
+import (
+	"time"
+
+	"github.com/vladimirimekov/url-shortener/internal/storage"
+	pb "github.com/vladimirimekov/url-shortener/proto"
+)
+
+// MetadataData содержит структуру для json данных со сведениями о странице перенаправления ссылки.
+type MetadataData struct {
+	Title       string    `json:"title,omitempty"`
+	Description string    `json:"description,omitempty"`
+	Image       string    `json:"image,omitempty"`
+	SiteName    string    `json:"site_name,omitempty"`
+	Favicon     string    `json:"favicon,omitempty"`
+	FetchedAt   time.Time `json:"fetched_at"`
+	Error       string    `json:"error,omitempty"`
+}
+
+// metadataData возвращает json представление сведений о странице. Если сведения ещё не загружались, возвращается nil.
+func metadataData(m storage.LinkMetadata) *MetadataData {
+	if m.FetchedAt.IsZero() {
+		return nil
+	}
+
+	return &MetadataData{
+		Title:       m.Title,
+		Description: m.Description,
+		Image:       m.Image,
+		SiteName:    m.SiteName,
+		Favicon:     m.Favicon,
+		FetchedAt:   m.FetchedAt,
+		Error:       m.Error,
+	}
+}
+
+// metadataProto возвращает grpc представление сведений о странице. Если сведения ещё не загружались, возвращается nil.
+func metadataProto(m storage.LinkMetadata) *pb.LinkMetadata {
+	if m.FetchedAt.IsZero() {
+		return nil
+	}
+
+	return &pb.LinkMetadata{
+		Title:       m.Title,
+		Description: m.Description,
+		Image:       m.Image,
+		SiteName:    m.SiteName,
+		Favicon:     m.Favicon,
+		FetchedAt:   timestamp(m.FetchedAt),
+		Error:       m.Error,
+	}
+}
+
+// fetchMetadata ставит созданные ссылки в очередь на загрузку сведений о страницах перенаправления.
+func (h Handler) fetchMetadata(data map[string]map[string]string) {
+	for _, links := range data {
+		for shortname := range links {
+			h.Metadata.Enqueue(shortname)
+		}
+	}
+}
@@ -0,0 +1,100 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/vladimirimekov/url-shortener/internal/metadata"
+	"github.com/vladimirimekov/url-shortener/internal/storage"
+	pb "github.com/vladimirimekov/url-shortener/proto"
+)
+
+// TestHandler_Metadata проверяет загрузку сведений о странице после создания ссылки, их выдачу в списке ссылок
+// и повторную загрузку после изменения адреса перенаправления.
+func TestHandler_Metadata(t *testing.T) {
+	page := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		fmt.Fprintf(w, `<head><title>Page %s</title><meta property="og:image" content="/cover.png"></head>`, strings.TrimPrefix(r.URL.Path, "/"))
+	}))
+	defer page.Close()
+
+	s := storage.NewMemoryWork(map[string]map[string]string{})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	fetcher := metadata.New(s, page.Client(), 2, 10, 1024, 1, time.Millisecond, 2)
+	go fetcher.Run(ctx)
+
+	d := Handler{Storage: s, Host: "http://localhost:8080", UserKey: userKey, Metadata: fetcher}
+
+	h := chi.NewRouter()
+	h.Post("/api/shorten", d.PostShortenHandler)
+	h.Get("/api/user/urls", d.GetAllShorterURLsHandler)
+	h.Patch("/api/user/urls/{short}", d.UpdateURLHandler)
+
+	request := func(method, target, body string) *httptest.ResponseRecorder {
+		r := httptest.NewRequest(method, target, strings.NewReader(body))
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), userKey, "owner")))
+
+		return w
+	}
+
+	w := request(http.MethodPost, "/api/shorten", `{"url":"`+page.URL+`/first"}`)
+	require.Equal(t, http.StatusCreated, w.Code)
+	var result map[string]string
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &result))
+	short := strings.TrimPrefix(result["result"], d.Host+"/")
+
+	waitTitle := func(title string) {
+		t.Helper()
+
+		require.Eventually(t, func() bool {
+			link, err := s.GetLink(context.Background(), short)
+			require.NoError(t, err)
+			return link.Metadata.Title == title
+		}, 5*time.Second, 10*time.Millisecond)
+	}
+
+	waitTitle("Page first")
+
+	w = request(http.MethodGet, "/api/user/urls", "")
+	require.Equal(t, http.StatusOK, w.Code)
+	var links []AllUserURLs
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &links))
+	require.Len(t, links, 1)
+	require.NotNil(t, links[0].Metadata)
+	assert.Equal(t, "Page first", links[0].Metadata.Title)
+	assert.Equal(t, page.URL+"/cover.png", links[0].Metadata.Image)
+	assert.False(t, links[0].Metadata.FetchedAt.IsZero())
+
+	w = request(http.MethodPatch, "/api/user/urls/"+short, `{"original_url":"`+page.URL+`/second"}`)
+	require.Equal(t, http.StatusOK, w.Code)
+
+	waitTitle("Page second")
+
+	response, err := d.GetAllShorterURLs(context.Background(), &pb.GetAllShorterURLsRequest{UserID: "owner"})
+	require.NoError(t, err)
+	require.Len(t, response.ShortURLs, 1)
+	assert.Equal(t, "Page second", response.ShortURLs[0].Metadata.Title)
+
+	//без загрузчика сведения не запрашиваются и не выдаются
+	d.Metadata = nil
+	_, err = d.CreateShortLink(context.Background(), &pb.CreateShortLinkRequest{OriginalURL: page.URL + "/third", UserID: "other"})
+	require.NoError(t, err)
+	response, err = d.GetAllShorterURLs(context.Background(), &pb.GetAllShorterURLsRequest{UserID: "other"})
+	require.NoError(t, err)
+	require.Len(t, response.ShortURLs, 1)
+	assert.Nil(t, response.ShortURLs[0].Metadata)
+}
@@ -0,0 +1,157 @@
+package handlers
+
+import (
+	"errors"
+	"time"
+
+	"github.com/vladimirimekov/url-shortener/internal/storage"
+)
+
+// errInvalidMaxClicks возвращается при отрицательном ограничении количества переходов.
+var errInvalidMaxClicks = errors.New("max_clicks must not be negative")
+
+// LinkOptions содержит необязательные настройки, задаваемые при создании ссылки.
+type LinkOptions struct {
+	Password  string    `json:"password,omitempty"`
+	MaxClicks int64     `json:"max_clicks,omitempty"`
+	NotBefore time.Time `json:"not_before,omitempty"`
+	NotAfter  time.Time `json:"not_after,omitempty"`
+
+	Rules    []TargetRuleData `json:"rules,omitempty"`
+	Variants []VariantData    `json:"variants,omitempty"`
+
+	RedirectStatus int    `json:"redirect_status,omitempty"`
+	CacheControl   string `json:"cache_control,omitempty"`
+	MetaRefresh    bool   `json:"meta_refresh,omitempty"`
+
+	Passthrough bool   `json:"passthrough,omitempty"`
+	Campaign    string `json:"campaign,omitempty"`
+
+	Tags   []string `json:"tags,omitempty"`
+	Folder string   `json:"folder,omitempty"`
+	Title  string   `json:"title,omitempty"`
+}
+
+// updateFunc проверяет настройки и возвращает изменение, которое применяет их к ссылке при её создании.
+// Если настройки не заданы, возвращается nil.
+func (o LinkOptions) updateFunc() (storage.UpdateFunc, error) {
+	var updates []storage.UpdateFunc
+
+	if o.Password != "" {
+		hash, err := hashPassword(o.Password)
+		if err != nil {
+			return nil, err
+		}
+		updates = append(updates, func(link *storage.Link) error {
+			link.PasswordHash = hash
+			return nil
+		})
+	}
+
+	if o.MaxClicks < 0 {
+		return nil, errInvalidMaxClicks
+	}
+	if o.MaxClicks > 0 {
+		updates = append(updates, func(link *storage.Link) error {
+			link.MaxClicks = o.MaxClicks
+			link.RemainingClicks = o.MaxClicks
+			return nil
+		})
+	}
+
+	if err := checkWindow(o.NotBefore, o.NotAfter); err != nil {
+		return nil, err
+	}
+	if !o.NotBefore.IsZero() || !o.NotAfter.IsZero() {
+		updates = append(updates, func(link *storage.Link) error {
+			link.NotBefore = o.NotBefore
+			link.NotAfter = o.NotAfter
+			return nil
+		})
+	}
+
+	if len(o.Rules) > 0 {
+		rules, err := checkRules(o.Rules)
+		if err != nil {
+			return nil, err
+		}
+		updates = append(updates, func(link *storage.Link) error {
+			link.Rules = rules
+			return nil
+		})
+	}
+
+	variants, err := checkVariants(o.Variants)
+	if err != nil {
+		return nil, err
+	}
+	if len(variants) > 0 {
+		updates = append(updates, func(link *storage.Link) error {
+			link.Variants = variants
+			return nil
+		})
+	}
+
+	if err = checkRedirectStatus(o.RedirectStatus); err != nil {
+		return nil, err
+	}
+	cacheControl, err := normalizeCacheControl(o.CacheControl)
+	if err != nil {
+		return nil, err
+	}
+	if o.RedirectStatus != 0 || cacheControl != "" || o.MetaRefresh {
+		updates = append(updates, func(link *storage.Link) error {
+			link.RedirectStatus = o.RedirectStatus
+			link.CacheControl = cacheControl
+			link.MetaRefresh = o.MetaRefresh
+			return nil
+		})
+	}
+
+	if o.Passthrough {
+		updates = append(updates, func(link *storage.Link) error {
+			link.Passthrough = true
+			return nil
+		})
+	}
+	if o.Campaign != "" {
+		updates = append(updates, func(link *storage.Link) error {
+			link.CampaignID = o.Campaign
+			return nil
+		})
+	}
+
+	tags, err := checkTags(o.Tags)
+	if err != nil {
+		return nil, err
+	}
+	folder, err := checkFolder(o.Folder)
+	if err != nil {
+		return nil, err
+	}
+	title, err := checkTitle(o.Title)
+	if err != nil {
+		return nil, err
+	}
+	if len(tags) > 0 || folder != "" || title != "" {
+		updates = append(updates, func(link *storage.Link) error {
+			link.Tags = tags
+			link.Folder = folder
+			link.Title = title
+			return nil
+		})
+	}
+
+	if len(updates) == 0 {
+		return nil, nil
+	}
+
+	return func(link *storage.Link) error {
+		for _, update := range updates {
+			if err := update(link); err != nil {
+				return err
+			}
+		}
+		return nil
+	}, nil
+}
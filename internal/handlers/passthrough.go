@@ -0,0 +1,89 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/vladimirimekov/url-shortener/internal/storage"
+)
+
+// Ошибки проверки дополнительного пути короткой ссылки.
+var (
+	errPassthroughDisabled     = errors.New("the link does not forward additional path")
+	errInvalidPassthroughPath  = errors.New("additional path must not contain '.' or '..' segments")
+	errInvalidPassthroughQuery = errors.New("invalid query value")
+)
+
+// passthroughPath возвращает экранированный дополнительный путь запроса после короткого имени ссылки без начального '/'.
+func passthroughPath(r *http.Request) string {
+	_, path, _ := strings.Cut(strings.TrimPrefix(r.URL.EscapedPath(), "/"), "/")
+
+	return path
+}
+
+// checkPassthrough проверяет дополнительный путь запроса к ссылке и возвращает его с заново экранированными сегментами.
+// Дополнительный путь допускается только у ссылок с включённым переносом пути.
+func checkPassthrough(link storage.Link, path string) (string, error) {
+	if path == "" {
+		return "", nil
+	}
+	if !link.Passthrough {
+		return "", errPassthroughDisabled
+	}
+
+	segments := strings.Split(path, "/")
+
+	for i, segment := range segments {
+		value, err := url.PathUnescape(segment)
+		if err != nil || value == "." || value == ".." {
+			return "", errInvalidPassthroughPath
+		}
+		segments[i] = url.PathEscape(value)
+	}
+
+	return strings.Join(segments, "/"), nil
+}
+
+// forwardURL добавляет к адресу перенаправления destination дополнительный путь и параметры запроса к ссылке.
+// Путь дописывается к пути адреса перенаправления. Параметры, уже заданные в адресе перенаправления, имеют приоритет
+// и не изменяются, остальные параметры запроса добавляются в конец. Параметр preview не переносится.
+// Если перенос пути у ссылки выключен или адрес перенаправления не http(s), адрес возвращается без изменений.
+func forwardURL(link storage.Link, destination, path string, query url.Values) string {
+	if !link.Passthrough || !httpURL(destination) {
+		return destination
+	}
+
+	u, err := url.Parse(destination)
+	if err != nil {
+		return destination
+	}
+
+	if path != "" {
+		escaped := strings.TrimSuffix(u.EscapedPath(), "/") + "/" + path
+		if u.Path, err = url.PathUnescape(escaped); err != nil {
+			return destination
+		}
+		u.RawPath = escaped
+	}
+
+	own := u.Query()
+	extra := url.Values{}
+
+	for key, values := range query {
+		if _, ok := own[key]; ok || key == "preview" {
+			continue
+		}
+		extra[key] = values
+	}
+
+	if len(extra) > 0 {
+		if u.RawQuery != "" {
+			u.RawQuery += "&"
+		}
+		u.RawQuery += extra.Encode()
+	}
+
+	return u.String()
+}
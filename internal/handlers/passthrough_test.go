@@ -0,0 +1,159 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/vladimirimekov/url-shortener/internal/storage"
+	pb "github.com/vladimirimekov/url-shortener/proto"
+)
+
+// TestForwardURL проверяет добавление дополнительного пути и слияние параметров запроса с адресом перенаправления.
+func TestForwardURL(t *testing.T) {
+	link := storage.Link{Passthrough: true}
+
+	tests := []struct {
+		name        string
+		link        storage.Link
+		destination string
+		path        string
+		query       string
+		want        string
+	}{
+		{name: "disabled", link: storage.Link{}, destination: "https://example.com/a", path: "b", query: "x=1", want: "https://example.com/a"},
+		{name: "no path", link: link, destination: "https://example.com", path: "docs/page", want: "https://example.com/docs/page"},
+		{name: "trailing slash", link: link, destination: "https://example.com/base/", path: "docs/", want: "https://example.com/base/docs/"},
+		{name: "escaped segment", link: link, destination: "https://example.com/base", path: "a%2Fb/c%20d", want: "https://example.com/base/a%2Fb/c%20d"},
+		{
+			name:        "destination parameters win",
+			link:        link,
+			destination: "https://example.com/base?utm_source=short&b=2#top",
+			path:        "page",
+			query:       "utm_source=evil&a=1&a=3&preview=0",
+			want:        "https://example.com/base/page?utm_source=short&b=2&a=1&a=3#top",
+		},
+		{name: "encoded values", link: link, destination: "https://example.com", query: "q=a%26b%3Dc", want: "https://example.com?q=a%26b%3Dc"},
+		{name: "not http", link: link, destination: "mailto:user@example.com", path: "x", query: "y=1", want: "mailto:user@example.com"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			query, err := url.ParseQuery(tt.query)
+			require.NoError(t, err)
+
+			assert.Equal(t, tt.want, forwardURL(tt.link, tt.destination, tt.path, query))
+		})
+	}
+}
+
+// TestCheckPassthrough проверяет проверку дополнительного пути запроса к ссылке.
+func TestCheckPassthrough(t *testing.T) {
+	tests := []struct {
+		name    string
+		link    storage.Link
+		path    string
+		want    string
+		wantErr error
+	}{
+		{name: "empty path", link: storage.Link{}},
+		{name: "disabled", link: storage.Link{}, path: "docs", wantErr: errPassthroughDisabled},
+		{name: "reescaped", link: storage.Link{Passthrough: true}, path: "a b/%7Euser/%3F", want: "a%20b/~user/%3F"},
+		{name: "dot dot", link: storage.Link{Passthrough: true}, path: "docs/../admin", wantErr: errInvalidPassthroughPath},
+		{name: "escaped dot dot", link: storage.Link{Passthrough: true}, path: "docs/%2e%2E", wantErr: errInvalidPassthroughPath},
+		{name: "bad escape", link: storage.Link{Passthrough: true}, path: "docs/%zz", wantErr: errInvalidPassthroughPath},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path, err := checkPassthrough(tt.link, tt.path)
+			if tt.wantErr != nil {
+				assert.ErrorIs(t, err, tt.wantErr)
+				return
+			}
+
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, path)
+		})
+	}
+}
+
+// TestHandler_Passthrough проверяет перенаправление запросов с дополнительным путём для ссылок с переносом пути и без него.
+func TestHandler_Passthrough(t *testing.T) {
+	d := Handler{Storage: storage.NewMemoryWork(map[string]map[string]string{}), Host: "http://localhost:8080", UserKey: userKey}
+
+	create := func(request *pb.CreateShortLinkRequest) string {
+		request.UserID = "owner"
+		response, err := d.CreateShortLink(context.Background(), request)
+		require.NoError(t, err)
+
+		return strings.TrimPrefix(response.ShortURL, d.Host+"/")
+	}
+
+	site := create(&pb.CreateShortLinkRequest{OriginalURL: "https://example.com/site?ref=short", Passthrough: true})
+	plain := create(&pb.CreateShortLinkRequest{OriginalURL: "https://example.com/plain"})
+	locked := create(&pb.CreateShortLinkRequest{OriginalURL: "https://example.com/locked", Passthrough: true, Password: "secret"})
+
+	h := chi.NewRouter()
+	h.Route("/{id}", func(r chi.Router) {
+		r.Get("/", d.MainHandler)
+		r.Post("/", d.MainHandler)
+		r.Get("/*", d.MainHandler)
+		r.Post("/*", d.MainHandler)
+	})
+
+	tests := []struct {
+		name         string
+		target       string
+		wantStatus   int
+		wantLocation string
+	}{
+		{name: "base", target: "/" + site, wantStatus: http.StatusTemporaryRedirect, wantLocation: "https://example.com/site?ref=short"},
+		{
+			name:         "path and query",
+			target:       "/" + site + "/docs/page?x=1&ref=other",
+			wantStatus:   http.StatusTemporaryRedirect,
+			wantLocation: "https://example.com/site/docs/page?ref=short&x=1",
+		},
+		{name: "escaped path", target: "/" + site + "/a%2Fb", wantStatus: http.StatusTemporaryRedirect, wantLocation: "https://example.com/site/a%2Fb?ref=short"},
+		{name: "dot segment", target: "/" + site + "/docs/%2E%2E/admin", wantStatus: http.StatusBadRequest},
+		{name: "plain without path", target: "/" + plain + "?x=1", wantStatus: http.StatusTemporaryRedirect, wantLocation: "https://example.com/plain"},
+		{name: "plain with path", target: "/" + plain + "/docs", wantStatus: http.StatusNotFound},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			h.ServeHTTP(w, httptest.NewRequest(http.MethodGet, tt.target, nil))
+
+			assert.Equal(t, tt.wantStatus, w.Code)
+			assert.Equal(t, tt.wantLocation, w.Header().Get("Location"))
+		})
+	}
+
+	//форма ввода пароля отправляется на адрес с дополнительным путём
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/"+locked+"/docs?x=1", nil))
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), `action="`+d.Host+"/"+locked+`/docs?x=1"`)
+
+	request := httptest.NewRequest(http.MethodPost, "/"+locked+"/docs?x=1", strings.NewReader("password=secret"))
+	request.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	w = httptest.NewRecorder()
+	h.ServeHTTP(w, request)
+	assert.Equal(t, http.StatusSeeOther, w.Code)
+	assert.Equal(t, "https://example.com/locked/docs?x=1", w.Header().Get("Location"))
+
+	original, err := d.GetOriginalLink(context.Background(), &pb.GetOriginalLinkRequest{ShortURL: site, Path: "/docs", Query: "x=1"})
+	require.NoError(t, err)
+	assert.Equal(t, "https://example.com/site/docs?ref=short&x=1", original.OriginalURL)
+
+	_, err = d.GetOriginalLink(context.Background(), &pb.GetOriginalLinkRequest{ShortURL: plain, Path: "docs"})
+	assert.Equal(t, codes.NotFound, status.Code(err))
+}
@@ -0,0 +1,198 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"golang.org/x/crypto/bcrypt"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/vladimirimekov/url-shortener/internal/storage"
+	pb "github.com/vladimirimekov/url-shortener/proto"
+)
+
+// maxPasswordLength - максимальная длина пароля в байтах, которую учитывает bcrypt.
+const maxPasswordLength = 72
+
+// Ошибки проверки пароля ссылки.
+var (
+	errPasswordTooLong   = fmt.Errorf("password must not be longer than %d bytes", maxPasswordLength)
+	errPasswordRequired  = errors.New("the link is password protected")
+	errPasswordIncorrect = errors.New("incorrect password")
+	errTooManyAttempts   = errors.New("too many incorrect password attempts")
+)
+
+// PasswordData содержит данные страницы ввода пароля ссылки.
+type PasswordData struct {
+	ShortURL string
+	Action   string
+	Error    string
+}
+
+// hashPassword возвращает bcrypt хэш пароля ссылки.
+func hashPassword(password string) (string, error) {
+	if len(password) > maxPasswordLength {
+		return "", errPasswordTooLong
+	}
+
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return "", err
+	}
+
+	return string(hash), nil
+}
+
+// checkPassword проверяет пароль ссылки с учётом ограничения количества неудачных попыток.
+// При превышении лимита возвращается errTooManyAttempts и время до следующей разрешённой попытки.
+func (h Handler) checkPassword(link storage.Link, password string) (time.Duration, error) {
+	if password == "" {
+		return 0, errPasswordRequired
+	}
+
+	//попытка учитывается до медленного сравнения хэша, чтобы параллельные запросы не обходили лимит
+	attempt, allowed, wait := h.PasswordAttempts.Reserve(link.ShortURL, time.Now())
+	if !allowed {
+		return wait, errTooManyAttempts
+	}
+
+	if err := bcrypt.CompareHashAndPassword([]byte(link.PasswordHash), []byte(password)); err != nil {
+		return 0, errPasswordIncorrect
+	}
+
+	attempt.Cancel()
+
+	return 0, nil
+}
+
+// renderPasswordForm отправляет страницу ввода пароля ссылки.
+// Форма отправляется на адрес запроса, чтобы после ввода пароля сохранились дополнительный путь и параметры.
+func (h Handler) renderPasswordForm(w http.ResponseWriter, r *http.Request, statusCode int, shortname string, err error) {
+	data := PasswordData{ShortURL: h.Host + "/" + shortname, Action: h.Host + r.URL.RequestURI()}
+	if err != nil {
+		data.Error = err.Error()
+	}
+
+	w.Header().Set("Cache-Control", "no-store")
+	renderPage(w, statusCode, "password", data)
+}
+
+// unlockLink проверяет пароль, отправленный формой, и перенаправляет на оригинальный URL.
+func (h Handler) unlockLink(w http.ResponseWriter, r *http.Request, shortname string) {
+	var path string
+
+	link, err := h.Storage.GetLink(r.Context(), shortname)
+	if err == nil {
+		err = linkAvailable(link, time.Now())
+	}
+	if err == nil {
+		path, err = checkPassthrough(link, passthroughPath(r))
+	}
+	if errors.Is(err, storage.ErrNotActive) {
+		h.comingSoon(w, r, link)
+		return
+	}
+	if err != nil {
+		http.Error(w, err.Error(), linkErrorStatus(err))
+		return
+	}
+
+	if link.PasswordHash == "" {
+		http.Redirect(w, r, h.Host+r.URL.RequestURI(), http.StatusSeeOther)
+		return
+	}
+
+	wait, err := h.checkPassword(link, r.PostFormValue("password"))
+	switch {
+	case errors.Is(err, errTooManyAttempts):
+		w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(wait.Seconds()))))
+		h.renderPasswordForm(w, r, http.StatusTooManyRequests, shortname, err)
+		return
+	case errors.Is(err, errPasswordRequired):
+		h.renderPasswordForm(w, r, http.StatusBadRequest, shortname, err)
+		return
+	case err != nil:
+		h.renderPasswordForm(w, r, http.StatusForbidden, shortname, err)
+		return
+	}
+
+	click := h.newClick(r, shortname)
+
+	err = h.consumeClick(r.Context(), link, click)
+	switch {
+	case errors.Is(err, errAutomatedClick):
+		automatedClick(w)
+		h.recordClick(click, redirectTarget{})
+		return
+	case err != nil:
+		http.Error(w, err.Error(), linkErrorStatus(err))
+		return
+	}
+
+	target := h.requestDestination(w, r, link)
+	target.URL = h.redirectURL(r.Context(), link, target, path, r.URL.Query())
+
+	w.Header().Set("Cache-Control", "no-store")
+	w.Header().Set("Location", target.URL)
+	w.WriteHeader(http.StatusSeeOther)
+	h.recordClick(click, target)
+}
+
+// passwordStatusError возвращает grpc ошибку для ошибки проверки пароля или работы со ссылкой.
+func passwordStatusError(err error) error {
+	switch {
+	case errors.Is(err, errTooManyAttempts):
+		return status.Error(codes.ResourceExhausted, err.Error())
+	case errors.Is(err, errPasswordRequired), errors.Is(err, errPasswordIncorrect):
+		return status.Error(codes.PermissionDenied, err.Error())
+	default:
+		return linkStatusError(err)
+	}
+}
+
+// originalURL возвращает адрес перенаправления для grpc, проверяя пароль защищённых ссылок
+// и списывая перенаправление у ссылок с ограничением количества переходов. Адрес выбирается по правилам таргетинга
+// и вариантам A/B теста для посетителя из запроса, к нему добавляются параметры кампании, дополнительный путь и параметры из запроса.
+func (h Handler) originalURL(ctx context.Context, request *pb.GetOriginalLinkRequest) (redirectTarget, error) {
+	query, err := url.ParseQuery(request.Query)
+	if err != nil {
+		return redirectTarget{}, errInvalidPassthroughQuery
+	}
+
+	var path string
+
+	link, err := h.Storage.GetLink(ctx, request.ShortURL)
+	if err == nil {
+		err = linkAvailable(link, time.Now())
+	}
+	if err == nil {
+		path, err = checkPassthrough(link, strings.TrimPrefix(request.Path, "/"))
+	}
+	if err != nil {
+		return redirectTarget{}, err
+	}
+
+	if link.PasswordHash != "" {
+		if _, err = h.checkPassword(link, request.Password); err != nil {
+			return redirectTarget{}, err
+		}
+	}
+
+	if err = h.consumeClick(ctx, link, storage.Click{}); err != nil {
+		return redirectTarget{}, err
+	}
+
+	target := h.destination(link, request.UserAgent, net.ParseIP(request.ClientIP), request.Variant)
+	target.URL = h.redirectURL(ctx, link, target, path, query)
+
+	return target, nil
+}
@@ -0,0 +1,148 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/vladimirimekov/url-shortener/internal/ratelimit"
+	"github.com/vladimirimekov/url-shortener/internal/storage"
+	pb "github.com/vladimirimekov/url-shortener/proto"
+)
+
+// TestHandler_PasswordProtectedLink проверяет создание защищённой паролем ссылки, форму ввода пароля
+// и ограничение количества неудачных попыток.
+func TestHandler_PasswordProtectedLink(t *testing.T) {
+	s := storage.NewMemoryWork(map[string]map[string]string{})
+	d := Handler{Storage: s, Host: "http://localhost:8080", UserKey: userKey, LengthOfShortname: 8, PasswordAttempts: ratelimit.New(2, time.Minute)}
+
+	h := chi.NewRouter()
+	h.Post("/api/shorten", d.PostShortenHandler)
+	h.Get("/{id}", d.MainHandler)
+	h.Post("/{id}", d.MainHandler)
+
+	create := func(body string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		request := httptest.NewRequest(http.MethodPost, "/api/shorten", strings.NewReader(body))
+		h.ServeHTTP(w, request.WithContext(context.WithValue(request.Context(), userKey, "owner")))
+		return w
+	}
+
+	w := create(`{"url":"https://example.com/secret","password":"` + strings.Repeat("x", 73) + `"}`)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	w = create(`{"url":"https://example.com/secret","password":"s3cret"}`)
+	require.Equal(t, http.StatusCreated, w.Code)
+
+	var result map[string]string
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &result))
+	shortname := strings.TrimPrefix(result["result"], d.Host+"/")
+
+	link, err := s.GetLink(context.Background(), shortname)
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), link.Version)
+	assert.NotEqual(t, "s3cret", link.PasswordHash)
+
+	w = httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/"+shortname, nil))
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Empty(t, w.Header().Get("Location"))
+	assert.Contains(t, w.Body.String(), `<form method="post"`)
+	assert.NotContains(t, w.Body.String(), "example.com/secret")
+
+	tests := []struct {
+		name         string
+		password     string
+		wantStatus   int
+		wantLocation string
+	}{
+		{name: "empty password", password: "", wantStatus: http.StatusBadRequest},
+		{name: "wrong password", password: "guess", wantStatus: http.StatusForbidden},
+		{name: "correct password", password: "s3cret", wantStatus: http.StatusSeeOther, wantLocation: "https://example.com/secret"},
+		{name: "second wrong password", password: "guess", wantStatus: http.StatusForbidden},
+		{name: "limit reached", password: "s3cret", wantStatus: http.StatusTooManyRequests},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			request := httptest.NewRequest(http.MethodPost, "/"+shortname, strings.NewReader(url.Values{"password": {tt.password}}.Encode()))
+			request.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+			h.ServeHTTP(w, request)
+
+			assert.Equal(t, tt.wantStatus, w.Code)
+			assert.Equal(t, tt.wantLocation, w.Header().Get("Location"))
+		})
+	}
+
+	_, err = d.GetOriginalLink(context.Background(), &pb.GetOriginalLinkRequest{ShortURL: shortname, Password: "s3cret"})
+	assert.Equal(t, codes.ResourceExhausted, status.Code(err))
+
+	d.PasswordAttempts = nil
+
+	_, err = d.GetOriginalLink(context.Background(), &pb.GetOriginalLinkRequest{ShortURL: shortname})
+	assert.Equal(t, codes.PermissionDenied, status.Code(err))
+
+	response, err := d.GetOriginalLink(context.Background(), &pb.GetOriginalLinkRequest{ShortURL: shortname, Password: "s3cret"})
+	require.NoError(t, err)
+	assert.Equal(t, "https://example.com/secret", response.OriginalURL)
+
+	_, err = d.GetOriginalLink(context.Background(), &pb.GetOriginalLinkRequest{ShortURL: "nope"})
+	assert.Equal(t, codes.NotFound, status.Code(err))
+}
+
+// TestHandler_PasswordConcurrentAttempts проверяет, что одновременные попытки с неверным паролем
+// не обходят ограничение: до сравнения хэша доходит не больше разрешённого количества попыток.
+func TestHandler_PasswordConcurrentAttempts(t *testing.T) {
+	const limit = 3
+
+	hash, err := hashPassword("s3cret")
+	require.NoError(t, err)
+
+	d := Handler{PasswordAttempts: ratelimit.New(limit, time.Minute)}
+	link := storage.Link{ShortURL: "abc", PasswordHash: hash}
+
+	var (
+		wg       sync.WaitGroup
+		mu       sync.Mutex
+		compared int
+		rejected int
+	)
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			_, err := d.checkPassword(link, "wrong")
+
+			mu.Lock()
+			defer mu.Unlock()
+
+			switch {
+			case errors.Is(err, errPasswordIncorrect):
+				compared++
+			case errors.Is(err, errTooManyAttempts):
+				rejected++
+			}
+		}()
+	}
+	wg.Wait()
+
+	assert.Equal(t, limit, compared)
+	assert.Equal(t, 20-limit, rejected)
+
+	_, err = d.checkPassword(link, "s3cret")
+	assert.ErrorIs(t, err, errTooManyAttempts)
+}
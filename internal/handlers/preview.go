@@ -0,0 +1,126 @@
+package handlers
+
+import (
+	"embed"
+	"errors"
+	"html/template"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/vladimirimekov/url-shortener/internal/storage"
+)
+
+// Статусы ссылки на странице предпросмотра.
+const (
+	statusActive    = "active"
+	statusDeleted   = "deleted"
+	statusExhausted = "exhausted"
+	statusScheduled = "scheduled"
+	statusExpired   = "expired"
+)
+
+//go:embed templates/*.html
+var templateFiles embed.FS
+
+// pages содержит шаблоны html страниц.
+var pages = template.Must(template.ParseFS(templateFiles, "templates/*.html"))
+
+// PreviewData содержит данные страницы предпросмотра ссылки.
+type PreviewData struct {
+	ShortURL    string
+	OriginalURL string
+	Domain      string
+	CreatedAt   time.Time
+	NotBefore   time.Time
+	NotAfter    time.Time
+	Status      string
+	Hidden      bool
+}
+
+// previewShortname возвращает сокращённое имя ссылки и признак режима предпросмотра.
+// Предпросмотр запрашивается суффиксом + в имени или параметром preview.
+func previewShortname(r *http.Request, shortname string) (string, bool) {
+	if name := strings.TrimSuffix(shortname, "+"); name != shortname {
+		return name, true
+	}
+
+	preview, _ := strconv.ParseBool(r.URL.Query().Get("preview"))
+
+	return shortname, preview
+}
+
+// previewData возвращает данные страницы предпросмотра ссылки.
+func (h Handler) previewData(link storage.Link) PreviewData {
+	data := PreviewData{
+		ShortURL:    h.Host + "/" + link.ShortURL,
+		OriginalURL: link.OriginalURL,
+		CreatedAt:   link.CreatedAt,
+		NotBefore:   link.NotBefore,
+		NotAfter:    link.NotAfter,
+		Status:      statusActive,
+	}
+
+	switch err := linkAvailable(link, time.Now()); {
+	case errors.Is(err, storage.ErrDeleted):
+		data.Status = statusDeleted
+	case errors.Is(err, storage.ErrNotActive):
+		data.Status = statusScheduled
+	case errors.Is(err, storage.ErrExpired):
+		data.Status = statusExpired
+	case errors.Is(err, storage.ErrClicksExhausted):
+		data.Status = statusExhausted
+	}
+
+	//адрес защищённой паролем или ещё не начавшей действовать ссылки не раскрывается
+	if link.PasswordHash != "" || data.Status == statusScheduled {
+		data.OriginalURL = ""
+		data.Hidden = true
+	} else if u, err := url.Parse(link.OriginalURL); err == nil {
+		data.Domain = u.Hostname()
+	}
+
+	return data
+}
+
+// renderPage отправляет html страницу по встроенному шаблону с указанным статусом.
+func renderPage(w http.ResponseWriter, statusCode int, name string, data interface{}) {
+	renderTemplate(w, statusCode, pages.Lookup(name), data)
+}
+
+// renderTemplate отправляет html страницу по шаблону с указанным статусом.
+func renderTemplate(w http.ResponseWriter, statusCode int, t *template.Template, data interface{}) {
+	var buf strings.Builder
+	if err := t.Execute(&buf, data); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("content-type", "text/html; charset=utf-8")
+	w.WriteHeader(statusCode)
+	_, err := w.Write([]byte(buf.String()))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+}
+
+// previewLink отправляет страницу предпросмотра ссылки. Переход по ссылке происходит только после подтверждения
+// пользователем, поэтому предпросмотр не учитывается в статистике переходов.
+func (h Handler) previewLink(w http.ResponseWriter, r *http.Request, shortname string) {
+	link, err := h.Storage.GetLink(r.Context(), shortname)
+	if err != nil {
+		http.Error(w, err.Error(), linkErrorStatus(err))
+		return
+	}
+
+	statusCode := http.StatusOK
+	if err = linkAvailable(link, time.Now()); err != nil {
+		statusCode = linkErrorStatus(err)
+	}
+
+	w.Header().Set("Cache-Control", "no-store")
+	renderPage(w, statusCode, "preview", h.previewData(link))
+}
@@ -0,0 +1,57 @@
+package handlers
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/vladimirimekov/url-shortener/internal/storage"
+)
+
+// TestHandler_Preview проверяет страницу предпросмотра ссылки вместо перенаправления.
+func TestHandler_Preview(t *testing.T) {
+	tests := []struct {
+		name         string
+		target       string
+		wantStatus   int
+		wantContains []string
+	}{
+		{name: "plus suffix", target: "/abc+", wantStatus: http.StatusOK, wantContains: []string{"https://example.com/page?a=1&amp;b=2", "example.com", "active", `href="http://localhost:8080/abc"`}},
+		{name: "query parameter", target: "/abc?preview=1", wantStatus: http.StatusOK, wantContains: []string{"Continue to example.com"}},
+		{name: "deleted link", target: "/old+", wantStatus: http.StatusGone, wantContains: []string{"deleted", "no longer redirects"}},
+		{name: "unknown link", target: "/nope+", wantStatus: http.StatusNotFound},
+		{name: "redirect without preview", target: "/abc?preview=0", wantStatus: http.StatusTemporaryRedirect},
+	}
+
+	s := storage.NewMemoryWork(map[string]map[string]string{})
+	require.NoError(t, s.SaveData(context.Background(), map[string]map[string]string{"owner": {"abc": "https://example.com/page?a=1&b=2", "old": "https://example.org"}}))
+	s.DeleteData([]string{"old"}, "owner")
+
+	d := Handler{Storage: s, Host: "http://localhost:8080", UserKey: userKey}
+
+	h := chi.NewRouter()
+	h.Get("/{id}", d.MainHandler)
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			h.ServeHTTP(w, httptest.NewRequest(http.MethodGet, tt.target, nil))
+			result := w.Result()
+			defer result.Body.Close()
+
+			assert.Equal(t, tt.wantStatus, result.StatusCode)
+
+			body, err := io.ReadAll(result.Body)
+			require.NoError(t, err)
+			for _, v := range tt.wantContains {
+				assert.Contains(t, string(body), v)
+			}
+		})
+	}
+}
@@ -0,0 +1,184 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/vladimirimekov/url-shortener/internal/qrcode"
+	"github.com/vladimirimekov/url-shortener/internal/storage"
+	pb "github.com/vladimirimekov/url-shortener/proto"
+)
+
+// qrParams содержит параметры QR кода ссылки.
+type qrParams struct {
+	level qrcode.Level
+	opts  qrcode.Options
+}
+
+// newQRParams разбирает параметры QR кода. Пустые значения и нулевой размер заменяются значениями по умолчанию,
+// рамка заменяется значением по умолчанию, если margin равен nil.
+func newQRParams(format string, size int, level string, margin *int, foreground, background string) (qrParams, error) {
+	var (
+		p   qrParams
+		err error
+	)
+
+	p.opts = qrcode.DefaultOptions()
+
+	if format != "" {
+		p.opts.Format = strings.ToLower(format)
+	}
+	if size != 0 {
+		p.opts.Size = size
+	}
+	if margin != nil {
+		p.opts.Margin = *margin
+	}
+
+	if foreground != "" {
+		if p.opts.Foreground, err = qrcode.ParseColor(foreground); err != nil {
+			return p, err
+		}
+	}
+	if background != "" {
+		if p.opts.Background, err = qrcode.ParseColor(background); err != nil {
+			return p, err
+		}
+	}
+
+	if p.level, err = qrcode.ParseLevel(level); err != nil {
+		return p, err
+	}
+
+	return p, p.opts.Validate()
+}
+
+// key возвращает ключ кэша изображения ссылки.
+func (p qrParams) key(shortname string) string {
+	return fmt.Sprintf("%s|%s|%d|%s|%d|%v|%v", shortname, p.opts.Format, p.opts.Size, p.level, p.opts.Margin, p.opts.Foreground, p.opts.Background)
+}
+
+// qrImage возвращает изображение QR кода с полной короткой ссылкой. Изображения кэшируются: короткая ссылка не
+// меняется, поэтому проверяется только существование ссылки.
+func (h Handler) qrImage(ctx context.Context, shortname string, p qrParams) ([]byte, error) {
+	link, err := h.Storage.GetLink(ctx, shortname)
+	if err != nil {
+		return nil, err
+	}
+
+	if link.IsDeleted {
+		return nil, storage.ErrDeleted
+	}
+
+	key := p.key(shortname)
+	if image, ok := h.QRCodes.Get(key); ok {
+		return image, nil
+	}
+
+	code, err := qrcode.Encode(h.Host+"/"+shortname, p.level)
+	if err != nil {
+		return nil, err
+	}
+
+	image, err := code.Render(p.opts)
+	if err != nil {
+		return nil, err
+	}
+
+	h.QRCodes.Put(key, image)
+
+	return image, nil
+}
+
+// QRCodeHandler отправляет QR код короткой ссылки.
+// Параметры: format (png или svg), size (ширина в пикселях), level (L, M, Q или H), margin (рамка в модулях),
+// fg и bg (цвета в формате RRGGBB).
+func (h Handler) QRCodeHandler(w http.ResponseWriter, r *http.Request) {
+
+	ctx, cancel := context.WithTimeout(r.Context(), 60*time.Second)
+	defer cancel()
+
+	query := r.URL.Query()
+
+	var size int
+	if value := query.Get("size"); value != "" {
+		var err error
+		if size, err = strconv.Atoi(value); err != nil || size <= 0 {
+			http.Error(w, "Invalid size value", http.StatusBadRequest)
+			return
+		}
+	}
+
+	var margin *int
+	if value := query.Get("margin"); value != "" {
+		m, err := strconv.Atoi(value)
+		if err != nil {
+			http.Error(w, "Invalid margin value", http.StatusBadRequest)
+			return
+		}
+		margin = &m
+	}
+
+	p, err := newQRParams(query.Get("format"), size, query.Get("level"), margin, query.Get("fg"), query.Get("bg"))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	image, err := h.qrImage(ctx, chi.URLParam(r, "id"), p)
+	if err != nil {
+		http.Error(w, err.Error(), linkErrorStatus(err))
+		return
+	}
+
+	w.Header().Set("content-type", p.opts.ContentType())
+	w.Header().Set("Cache-Control", "public, max-age=86400")
+	w.WriteHeader(http.StatusOK)
+	_, err = w.Write(image)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+}
+
+// LinkQRCodeHandler отправляет QR код короткой ссылки по адресу /{id}/qr. У ссылок с переносом пути этот адрес
+// принадлежит ссылке: запрос перенаправляется с путём qr, как и любой другой дополнительный путь.
+// QR код таких ссылок доступен по адресу /api/qr/{id}, который обслуживает QRCodeHandler.
+func (h Handler) LinkQRCodeHandler(w http.ResponseWriter, r *http.Request) {
+	link, err := h.Storage.GetLink(r.Context(), chi.URLParam(r, "id"))
+	if err == nil && link.Passthrough {
+		h.MainHandler(w, r)
+		return
+	}
+
+	h.QRCodeHandler(w, r)
+}
+
+// GetQRCode возвращает QR код короткой ссылки для grpc.
+func (h Handler) GetQRCode(ctx context.Context, request *pb.GetQRCodeRequest) (*pb.GetQRCodeResponse, error) {
+	var margin *int
+	if request.Margin != nil {
+		m := int(*request.Margin)
+		margin = &m
+	}
+
+	p, err := newQRParams(request.Format, int(request.Size), request.Level, margin, request.Foreground, request.Background)
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+
+	image, err := h.qrImage(ctx, request.ShortURL, p)
+	if err != nil {
+		return nil, linkStatusError(err)
+	}
+
+	return &pb.GetQRCodeResponse{ContentType: p.opts.ContentType(), Image: image}, nil
+}
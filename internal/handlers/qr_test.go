@@ -0,0 +1,82 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"image/png"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/vladimirimekov/url-shortener/internal/qrcode"
+	"github.com/vladimirimekov/url-shortener/internal/storage"
+)
+
+// TestHandler_QRCodeHandler проверяет выдачу QR кода ссылки в разных форматах и кэширование изображений,
+// а также то, что у ссылки с переносом пути адрес /{id}/qr перенаправляется как дополнительный путь.
+func TestHandler_QRCodeHandler(t *testing.T) {
+	tests := []struct {
+		name            string
+		target          string
+		wantStatus      int
+		wantContentType string
+		wantLocation    string
+	}{
+		{name: "default png", target: "/abc/qr", wantStatus: http.StatusOK, wantContentType: "image/png"},
+		{name: "svg with colors", target: "/abc/qr?format=svg&level=H&margin=0&fg=%23112233&bg=ffffff", wantStatus: http.StatusOK, wantContentType: "image/svg+xml"},
+		{name: "unknown format", target: "/abc/qr?format=gif", wantStatus: http.StatusBadRequest},
+		{name: "invalid size", target: "/abc/qr?size=big", wantStatus: http.StatusBadRequest},
+		{name: "too large", target: "/abc/qr?size=100000", wantStatus: http.StatusBadRequest},
+		{name: "invalid color", target: "/abc/qr?fg=red", wantStatus: http.StatusBadRequest},
+		{name: "unknown link", target: "/nope/qr", wantStatus: http.StatusNotFound},
+		{name: "deleted link", target: "/old/qr", wantStatus: http.StatusGone},
+		{name: "passthrough link", target: "/fwd/qr", wantStatus: http.StatusTemporaryRedirect, wantLocation: "https://example.net/docs/qr"},
+		{name: "passthrough link api", target: "/api/qr/fwd", wantStatus: http.StatusOK, wantContentType: "image/png"},
+	}
+
+	s := storage.NewMemoryWork(map[string]map[string]string{})
+	require.NoError(t, s.SaveData(context.Background(), map[string]map[string]string{"owner": {"abc": "https://example.com", "old": "https://example.org"}}))
+	s.DeleteData([]string{"old"}, "owner")
+	require.NoError(t, s.SaveData(context.Background(), map[string]map[string]string{"owner": {"fwd": "https://example.net/docs"}}))
+	_, err := s.ConfigureLink(context.Background(), "fwd", func(link *storage.Link) error {
+		link.Passthrough = true
+		return nil
+	})
+	require.NoError(t, err)
+
+	d := Handler{Storage: s, Host: "http://localhost:8080", UserKey: userKey, QRCodes: qrcode.NewCache(10)}
+
+	h := chi.NewRouter()
+	h.Get("/{id}/qr", d.LinkQRCodeHandler)
+	h.Get("/api/qr/{id}", d.QRCodeHandler)
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			h.ServeHTTP(w, httptest.NewRequest(http.MethodGet, tt.target, nil))
+			result := w.Result()
+			defer result.Body.Close()
+
+			assert.Equal(t, tt.wantStatus, result.StatusCode)
+			if tt.wantContentType != "" {
+				assert.Equal(t, tt.wantContentType, result.Header.Get("content-type"))
+			}
+			if tt.wantLocation != "" {
+				assert.Equal(t, tt.wantLocation, result.Header.Get("Location"))
+			}
+		})
+	}
+
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/abc/qr?size=300", nil))
+	require.Equal(t, http.StatusOK, w.Code)
+
+	img, err := png.Decode(bytes.NewReader(w.Body.Bytes()))
+	require.NoError(t, err)
+	assert.LessOrEqual(t, img.Bounds().Dx(), 300)
+	assert.Equal(t, 4, d.QRCodes.Len())
+}
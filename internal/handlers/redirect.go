@@ -0,0 +1,99 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/vladimirimekov/url-shortener/internal/storage"
+)
+
+// Ошибки проверки настроек перенаправления.
+var (
+	errInvalidRedirectStatus = errors.New("redirect_status must be 301, 302, 303, 307 or 308")
+	errInvalidCacheControl   = errors.New("cache_control must be a list of public, private, no-cache, no-store, must-revalidate, immutable, max-age=N or s-maxage=N")
+)
+
+// RedirectData содержит данные страницы перенаправления через meta refresh.
+type RedirectData struct {
+	URL string
+}
+
+// checkRedirectStatus проверяет код ответа перенаправления. Нулевой код означает код по умолчанию.
+func checkRedirectStatus(code int) error {
+	switch code {
+	case 0, http.StatusMovedPermanently, http.StatusFound, http.StatusSeeOther, http.StatusTemporaryRedirect, http.StatusPermanentRedirect:
+		return nil
+	default:
+		return errInvalidRedirectStatus
+	}
+}
+
+// normalizeCacheControl проверяет значение заголовка Cache-Control и приводит его к каноническому виду.
+// Пустое значение означает, что заголовок не отправляется.
+func normalizeCacheControl(value string) (string, error) {
+	if strings.TrimSpace(value) == "" {
+		return "", nil
+	}
+
+	var directives []string
+
+	for _, directive := range strings.Split(value, ",") {
+		directive = strings.ToLower(strings.TrimSpace(directive))
+
+		name, seconds, hasValue := strings.Cut(directive, "=")
+		switch name {
+		case "public", "private", "no-cache", "no-store", "must-revalidate", "immutable":
+			if hasValue {
+				return "", errInvalidCacheControl
+			}
+		case "max-age", "s-maxage":
+			n, err := strconv.ParseUint(seconds, 10, 31)
+			if !hasValue || err != nil {
+				return "", errInvalidCacheControl
+			}
+			directive = name + "=" + strconv.FormatUint(n, 10)
+		default:
+			return "", errInvalidCacheControl
+		}
+
+		directives = append(directives, directive)
+	}
+
+	return strings.Join(directives, ", "), nil
+}
+
+// redirectStatus возвращает код ответа перенаправления ссылки.
+func redirectStatus(link storage.Link) int {
+	if link.RedirectStatus == 0 {
+		return http.StatusTemporaryRedirect
+	}
+
+	return link.RedirectStatus
+}
+
+// httpURL проверяет, что адрес использует схему http или https.
+func httpURL(location string) bool {
+	u, err := url.Parse(location)
+
+	return err == nil && (u.Scheme == "http" || u.Scheme == "https")
+}
+
+// redirect отправляет перенаправление на адрес location с кодом ответа и политикой кэширования ссылки.
+// Для ссылок в режиме meta refresh вместо перенаправления отправляется html страница, которая переходит на адрес сама.
+func redirect(w http.ResponseWriter, link storage.Link, location string) {
+	if link.CacheControl != "" {
+		w.Header().Set("Cache-Control", link.CacheControl)
+	}
+
+	//meta refresh поддерживается только для адресов http, чтобы страница не выполняла произвольные схемы
+	if link.MetaRefresh && httpURL(location) {
+		renderPage(w, http.StatusOK, "redirect", RedirectData{URL: location})
+		return
+	}
+
+	w.Header().Set("Location", location)
+	w.WriteHeader(redirectStatus(link))
+}
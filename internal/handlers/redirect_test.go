@@ -0,0 +1,134 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+
+	"github.com/vladimirimekov/url-shortener/internal/storage"
+	pb "github.com/vladimirimekov/url-shortener/proto"
+)
+
+// TestNormalizeCacheControl проверяет разбор политики кэширования ссылки.
+func TestNormalizeCacheControl(t *testing.T) {
+	tests := []struct {
+		value   string
+		want    string
+		wantErr bool
+	}{
+		{value: "", want: ""},
+		{value: "no-store", want: "no-store"},
+		{value: " Public , MAX-AGE=0300, immutable", want: "public, max-age=300, immutable"},
+		{value: "private, s-maxage=60", want: "private, s-maxage=60"},
+		{value: "max-age", wantErr: true},
+		{value: "max-age=-1", wantErr: true},
+		{value: "no-store=1", wantErr: true},
+		{value: "no-transform", wantErr: true},
+		{value: "no-store\r\nSet-Cookie: a=b", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			got, err := normalizeCacheControl(tt.value)
+			if tt.wantErr {
+				assert.ErrorIs(t, err, errInvalidCacheControl)
+				return
+			}
+
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+// TestHandler_RedirectSettings проверяет код ответа, заголовок Cache-Control и режим meta refresh перенаправления.
+func TestHandler_RedirectSettings(t *testing.T) {
+	d := Handler{Storage: storage.NewMemoryWork(map[string]map[string]string{}), Host: "http://localhost:8080", UserKey: userKey, LengthOfShortname: 8}
+
+	h := chi.NewRouter()
+	h.Post("/api/shorten", d.PostShortenHandler)
+	h.Get("/{id}", d.MainHandler)
+
+	shorten := func(body string) (string, int) {
+		w := httptest.NewRecorder()
+		request := httptest.NewRequest(http.MethodPost, "/api/shorten", strings.NewReader(body))
+		h.ServeHTTP(w, request.WithContext(context.WithValue(request.Context(), userKey, "owner")))
+
+		var result map[string]string
+		_ = json.Unmarshal(w.Body.Bytes(), &result)
+
+		return strings.TrimPrefix(result["result"], d.Host+"/"), w.Code
+	}
+
+	tests := []struct {
+		name             string
+		body             string
+		wantCreateStatus int
+		wantStatus       int
+		wantCacheControl string
+		wantBody         string
+	}{
+		{name: "default", body: `{"url":"https://example.com/a"}`, wantCreateStatus: http.StatusCreated, wantStatus: http.StatusTemporaryRedirect},
+		{
+			name:             "permanent with caching",
+			body:             `{"url":"https://example.com/b","redirect_status":308,"cache_control":"public, max-age=86400"}`,
+			wantCreateStatus: http.StatusCreated,
+			wantStatus:       http.StatusPermanentRedirect,
+			wantCacheControl: "public, max-age=86400",
+		},
+		{
+			name:             "meta refresh",
+			body:             `{"url":"https://example.com/c?x=1&y=2","meta_refresh":true,"cache_control":"no-store"}`,
+			wantCreateStatus: http.StatusCreated,
+			wantStatus:       http.StatusOK,
+			wantCacheControl: "no-store",
+			wantBody:         `<meta http-equiv="refresh" content="0; url=https://example.com/c?x=1&amp;y=2">`,
+		},
+		{name: "invalid status", body: `{"url":"https://example.com/d","redirect_status":200}`, wantCreateStatus: http.StatusBadRequest},
+		{name: "invalid cache control", body: `{"url":"https://example.com/e","cache_control":"max-age=soon"}`, wantCreateStatus: http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			shortname, code := shorten(tt.body)
+			require.Equal(t, tt.wantCreateStatus, code)
+			if code != http.StatusCreated {
+				return
+			}
+
+			w := httptest.NewRecorder()
+			h.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/"+shortname, nil))
+
+			assert.Equal(t, tt.wantStatus, w.Code)
+			assert.Equal(t, tt.wantCacheControl, w.Header().Get("Cache-Control"))
+			if tt.wantBody != "" {
+				assert.Contains(t, w.Body.String(), tt.wantBody)
+				assert.Empty(t, w.Header().Get("Location"))
+			}
+		})
+	}
+
+	created, err := d.CreateShortLink(context.Background(), &pb.CreateShortLinkRequest{OriginalURL: "https://example.com/f", UserID: "owner", RedirectStatus: 301})
+	require.NoError(t, err)
+	shortname := strings.TrimPrefix(created.ShortURL, d.Host+"/")
+
+	updated, err := d.UpdateLink(context.Background(), &pb.UpdateLinkRequest{ShortURL: shortname, UserID: "owner", CacheControl: proto.String("no-cache")})
+	require.NoError(t, err)
+	assert.Equal(t, int32(http.StatusMovedPermanently), updated.RedirectStatus)
+	assert.Equal(t, "no-cache", updated.CacheControl)
+
+	updated, err = d.UpdateLink(context.Background(), &pb.UpdateLinkRequest{ShortURL: shortname, UserID: "owner", RedirectStatus: proto.Int32(0)})
+	require.NoError(t, err)
+	assert.Equal(t, int32(http.StatusTemporaryRedirect), updated.RedirectStatus)
+
+	_, err = d.UpdateLink(context.Background(), &pb.UpdateLinkRequest{ShortURL: shortname, UserID: "owner", RedirectStatus: proto.Int32(304)})
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+}
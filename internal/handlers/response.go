@@ -0,0 +1,80 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/vladimirimekov/url-shortener/internal/storage"
+)
+
+// writeJSON отправляет значение v в формате json с указанным статусом.
+func writeJSON(w http.ResponseWriter, statusCode int, v interface{}) {
+	resultJSON, err := json.Marshal(v)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("content-type", "application/json")
+	w.WriteHeader(statusCode)
+	_, err = w.Write(resultJSON)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+}
+
+// linkErrorStatus возвращает HTTP статус для ошибки работы со ссылкой.
+func linkErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, storage.ErrNotFound), errors.Is(err, storage.ErrNotActive), errors.Is(err, errPassthroughDisabled),
+		errors.Is(err, errTagNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, storage.ErrNotOwner):
+		return http.StatusForbidden
+	case errors.Is(err, storage.ErrVersionConflict):
+		return http.StatusPreconditionFailed
+	case errors.Is(err, storage.ErrURLExists):
+		return http.StatusConflict
+	case errors.Is(err, storage.ErrDeleted), errors.Is(err, storage.ErrClicksExhausted), errors.Is(err, storage.ErrExpired):
+		return http.StatusGone
+	case errors.Is(err, errInvalidWindow), errors.Is(err, errInvalidPassthroughPath), errors.Is(err, errUnknownCampaign),
+		errors.Is(err, errInvalidTag), errors.Is(err, errTooManyTags), errors.Is(err, errInvalidFolder),
+		errors.Is(err, errInvalidTitle):
+		return http.StatusBadRequest
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
+// linkStatusError возвращает grpc ошибку для ошибки работы со ссылкой.
+func linkStatusError(err error) error {
+	var code codes.Code
+
+	switch {
+	case errors.Is(err, storage.ErrNotFound), errors.Is(err, errPassthroughDisabled), errors.Is(err, errTagNotFound):
+		code = codes.NotFound
+	case errors.Is(err, storage.ErrNotOwner):
+		code = codes.PermissionDenied
+	case errors.Is(err, storage.ErrVersionConflict):
+		code = codes.Aborted
+	case errors.Is(err, storage.ErrURLExists):
+		code = codes.AlreadyExists
+	case errors.Is(err, storage.ErrDeleted), errors.Is(err, storage.ErrNotActive), errors.Is(err, storage.ErrExpired):
+		code = codes.FailedPrecondition
+	case errors.Is(err, errInvalidWindow), errors.Is(err, errInvalidPassthroughPath), errors.Is(err, errInvalidPassthroughQuery),
+		errors.Is(err, errUnknownCampaign), errors.Is(err, errInvalidTag), errors.Is(err, errTooManyTags), errors.Is(err, errInvalidFolder),
+		errors.Is(err, errInvalidTitle):
+		code = codes.InvalidArgument
+	case errors.Is(err, storage.ErrClicksExhausted):
+		code = codes.ResourceExhausted
+	default:
+		code = codes.Internal
+	}
+
+	return status.Error(code, err.Error())
+}
@@ -0,0 +1,61 @@
+package handlers
+
+import (
+	"errors"
+	"html/template"
+	"net/http"
+	"time"
+
+	"github.com/vladimirimekov/url-shortener/internal/storage"
+)
+
+// errInvalidWindow возвращается, если окно действия ссылки заканчивается раньше, чем начинается.
+var errInvalidWindow = errors.New("not_after must be later than not_before")
+
+// ComingSoon содержит настройки ответа на переход по ссылке до начала её действия.
+// Если задан URL, выполняется временное перенаправление на него. Иначе отправляется страница Page
+// или встроенная страница со статусом Status, по умолчанию 404.
+type ComingSoon struct {
+	URL    string
+	Status int
+	Page   *template.Template
+}
+
+// ComingSoonData содержит данные страницы ссылки, которая ещё не начала действовать.
+type ComingSoonData struct {
+	ShortURL  string
+	NotBefore time.Time
+}
+
+// checkWindow проверяет, что окно действия ссылки не пустое.
+func checkWindow(notBefore, notAfter time.Time) error {
+	if !notBefore.IsZero() && !notAfter.IsZero() && !notAfter.After(notBefore) {
+		return errInvalidWindow
+	}
+
+	return nil
+}
+
+// comingSoon отвечает на переход по ссылке, которая ещё не начала действовать.
+func (h Handler) comingSoon(w http.ResponseWriter, r *http.Request, link storage.Link) {
+	w.Header().Set("Cache-Control", "no-store")
+
+	if h.ComingSoon.URL != "" {
+		http.Redirect(w, r, h.ComingSoon.URL, http.StatusFound)
+		return
+	}
+
+	statusCode := h.ComingSoon.Status
+	if statusCode == 0 {
+		statusCode = http.StatusNotFound
+	}
+
+	data := ComingSoonData{ShortURL: h.Host + "/" + link.ShortURL, NotBefore: link.NotBefore}
+
+	if h.ComingSoon.Page != nil {
+		renderTemplate(w, statusCode, h.ComingSoon.Page, data)
+		return
+	}
+
+	renderPage(w, statusCode, "coming_soon", data)
+}
@@ -0,0 +1,100 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	"github.com/vladimirimekov/url-shortener/internal/storage"
+	pb "github.com/vladimirimekov/url-shortener/proto"
+)
+
+// TestHandler_ActivationWindow проверяет окно действия ссылки: ответ до начала, после окончания и изменение окна владельцем.
+func TestHandler_ActivationWindow(t *testing.T) {
+	s := storage.NewMemoryWork(map[string]map[string]string{})
+	d := Handler{Storage: s, Host: "http://localhost:8080", UserKey: userKey, LengthOfShortname: 8}
+
+	h := chi.NewRouter()
+	h.Post("/api/shorten", d.PostShortenHandler)
+	h.Patch("/api/user/urls/{short}", d.UpdateURLHandler)
+	h.Get("/{id}", d.MainHandler)
+
+	do := func(method, target, body string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		request := httptest.NewRequest(method, target, strings.NewReader(body))
+		h.ServeHTTP(w, request.WithContext(context.WithValue(request.Context(), userKey, "owner")))
+		return w
+	}
+
+	now := time.Now().UTC().Truncate(time.Second)
+	launch := now.Add(24 * time.Hour).Format(time.RFC3339)
+
+	w := do(http.MethodPost, "/api/shorten", `{"url":"https://example.com/launch","not_before":"`+launch+`","not_after":"`+now.Format(time.RFC3339)+`"}`)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	w = do(http.MethodPost, "/api/shorten", `{"url":"https://example.com/launch","not_before":"`+launch+`"}`)
+	require.Equal(t, http.StatusCreated, w.Code)
+
+	var result map[string]string
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &result))
+	shortname := strings.TrimPrefix(result["result"], d.Host+"/")
+
+	w = do(http.MethodGet, "/"+shortname, "")
+	assert.Equal(t, http.StatusNotFound, w.Code)
+	assert.Contains(t, w.Body.String(), "Coming soon")
+	assert.Empty(t, w.Header().Get("Location"))
+
+	//вместо страницы можно настроить перенаправление
+	redirecting := d
+	redirecting.ComingSoon = ComingSoon{URL: "https://example.com/soon"}
+	r := chi.NewRouter()
+	r.Get("/{id}", redirecting.MainHandler)
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/"+shortname, nil))
+	assert.Equal(t, http.StatusFound, w.Code)
+	assert.Equal(t, "https://example.com/soon", w.Header().Get("Location"))
+
+	_, err := d.GetOriginalLink(context.Background(), &pb.GetOriginalLinkRequest{ShortURL: shortname})
+	assert.Equal(t, codes.FailedPrecondition, status.Code(err))
+
+	tests := []struct {
+		name       string
+		body       string
+		wantStatus int
+		wantGet    int
+	}{
+		{name: "empty window", body: `{"not_after":"` + now.Format(time.RFC3339) + `"}`, wantStatus: http.StatusBadRequest, wantGet: http.StatusNotFound},
+		{name: "invalid time", body: `{"not_before":"tomorrow"}`, wantStatus: http.StatusBadRequest, wantGet: http.StatusNotFound},
+		{name: "go live", body: `{"not_before":""}`, wantStatus: http.StatusOK, wantGet: http.StatusTemporaryRedirect},
+		{name: "expire", body: `{"not_after":"` + now.Add(-time.Hour).Format(time.RFC3339) + `"}`, wantStatus: http.StatusOK, wantGet: http.StatusGone},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := do(http.MethodPatch, "/api/user/urls/"+shortname, tt.body)
+			assert.Equal(t, tt.wantStatus, w.Code)
+
+			w = do(http.MethodGet, "/"+shortname, "")
+			assert.Equal(t, tt.wantGet, w.Code)
+		})
+	}
+
+	response, err := d.UpdateLink(context.Background(), &pb.UpdateLinkRequest{ShortURL: shortname, UserID: "owner", ClearNotAfter: true, NotBefore: timestamppb.New(now.Add(-time.Hour))})
+	require.NoError(t, err)
+	assert.Nil(t, response.NotAfter)
+	assert.Equal(t, now.Add(-time.Hour), response.NotBefore.AsTime())
+	assert.Equal(t, "https://example.com/launch", response.OriginalURL)
+
+	w = do(http.MethodGet, "/"+shortname, "")
+	assert.Equal(t, http.StatusTemporaryRedirect, w.Code)
+}
@@ -0,0 +1,190 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/vladimirimekov/url-shortener/internal/storage"
+	pb "github.com/vladimirimekov/url-shortener/proto"
+)
+
+// Ограничения названия ссылки и поиска ссылок.
+const (
+	maxTitleLength     = 200
+	maxSearchQuery     = 100
+	defaultSearchLimit = 50
+	maxSearchLimit     = 200
+)
+
+// Ошибки проверки названия ссылки и условий поиска.
+var (
+	errInvalidTitle       = errors.New("title must be up to 200 characters without control characters")
+	errInvalidSearchQuery = errors.New("search query must be up to 100 characters without control characters")
+	errInvalidSearchMatch = errors.New("match must be substring or prefix")
+	errInvalidSearchLimit = errors.New("limit must be between 1 and 200")
+	errInvalidSearchRange = errors.New("created_from must be before created_to")
+)
+
+// checkTitle проверяет название ссылки и возвращает его без пробелов по краям.
+func checkTitle(title string) (string, error) {
+	title = strings.TrimSpace(title)
+	if utf8.RuneCountInString(title) > maxTitleLength || strings.IndexFunc(title, unicode.IsControl) >= 0 {
+		return "", errInvalidTitle
+	}
+
+	return title, nil
+}
+
+// linkSearch проверяет условия поиска ссылок. Нулевой limit заменяется ограничением по умолчанию.
+func linkSearch(query string, prefix bool, from, to time.Time, limit int) (storage.LinkSearch, error) {
+	query = strings.TrimSpace(query)
+	if utf8.RuneCountInString(query) > maxSearchQuery || strings.IndexFunc(query, unicode.IsControl) >= 0 {
+		return storage.LinkSearch{}, errInvalidSearchQuery
+	}
+
+	if limit == 0 {
+		limit = defaultSearchLimit
+	}
+	if limit < 0 || limit > maxSearchLimit {
+		return storage.LinkSearch{}, errInvalidSearchLimit
+	}
+
+	if !from.IsZero() && !to.IsZero() && !from.Before(to) {
+		return storage.LinkSearch{}, errInvalidSearchRange
+	}
+
+	return storage.LinkSearch{Query: query, Prefix: prefix, CreatedFrom: from, CreatedTo: to, Limit: limit}, nil
+}
+
+// parseLinkSearch возвращает условия поиска из параметров запроса: q - искомая строка, match - способ сравнения
+// (substring или prefix), created_from и created_to - границы времени создания в формате RFC3339, limit - количество ссылок.
+func parseLinkSearch(r *http.Request) (storage.LinkSearch, error) {
+	values := r.URL.Query()
+
+	var prefix bool
+
+	switch values.Get("match") {
+	case "", "substring":
+	case "prefix":
+		prefix = true
+	default:
+		return storage.LinkSearch{}, errInvalidSearchMatch
+	}
+
+	from, err := parseTime(values.Get("created_from"))
+	if err != nil {
+		return storage.LinkSearch{}, errors.New("Invalid created_from value")
+	}
+	to, err := parseTime(values.Get("created_to"))
+	if err != nil {
+		return storage.LinkSearch{}, errors.New("Invalid created_to value")
+	}
+
+	var limit int
+	if value := values.Get("limit"); value != "" {
+		if limit, err = strconv.Atoi(value); err != nil || limit == 0 {
+			return storage.LinkSearch{}, errInvalidSearchLimit
+		}
+	}
+
+	return linkSearch(values.Get("q"), prefix, from, to, limit)
+}
+
+// userURLs возвращает json представление ссылок пользователя.
+func (h Handler) userURLs(links []storage.Link) []AllUserURLs {
+	result := make([]AllUserURLs, 0, len(links))
+
+	for _, link := range links {
+		result = append(result, AllUserURLs{
+			ShortURL:    h.Host + "/" + link.ShortURL,
+			OriginalURL: link.OriginalURL,
+			Clicks:      link.Clicks,
+			Tags:        link.Tags,
+			Folder:      link.Folder,
+			Title:       link.Title,
+			Metadata:    metadataData(link.Metadata),
+		})
+	}
+
+	return result
+}
+
+// userURLsProto возвращает grpc представление ссылок пользователя.
+func (h Handler) userURLsProto(links []storage.Link) []*pb.AllShorterURLsResponse {
+	result := make([]*pb.AllShorterURLsResponse, 0, len(links))
+
+	for _, link := range links {
+		result = append(result, &pb.AllShorterURLsResponse{
+			ShortURL:    h.Host + "/" + link.ShortURL,
+			OriginalURL: link.OriginalURL,
+			Clicks:      link.Clicks,
+			Tags:        link.Tags,
+			Folder:      link.Folder,
+			Title:       link.Title,
+			Metadata:    metadataProto(link.Metadata),
+		})
+	}
+
+	return result
+}
+
+// SearchURLsHandler ищет ссылки текущего пользователя по адресу перенаправления, короткому имени, названию и тегам
+// и отправляет их от новых к старым. Если ничего не найдено, отправляется пустой список.
+func (h Handler) SearchURLsHandler(w http.ResponseWriter, r *http.Request) {
+
+	ctx, cancel := context.WithTimeout(r.Context(), 60*time.Second)
+	defer cancel()
+
+	userID, err := h.getUserID(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	q, err := parseLinkSearch(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	links, err := h.Storage.SearchUserLinks(ctx, userID, q)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, h.userURLs(links))
+}
+
+// SearchLinks ищет ссылки пользователя для grpc.
+func (h Handler) SearchLinks(ctx context.Context, request *pb.SearchLinksRequest) (*pb.GetAllShorterURLsResponse, error) {
+	var from, to time.Time
+
+	if request.CreatedFrom != nil {
+		from = request.CreatedFrom.AsTime()
+	}
+	if request.CreatedTo != nil {
+		to = request.CreatedTo.AsTime()
+	}
+
+	q, err := linkSearch(request.Query, request.Prefix, from, to, int(request.Limit))
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+
+	links, err := h.Storage.SearchUserLinks(ctx, request.UserID, q)
+	if err != nil {
+		return nil, err
+	}
+
+	return &pb.GetAllShorterURLsResponse{ShortURLs: h.userURLsProto(links)}, nil
+}
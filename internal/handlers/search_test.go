@@ -0,0 +1,113 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/vladimirimekov/url-shortener/internal/storage"
+	pb "github.com/vladimirimekov/url-shortener/proto"
+)
+
+// TestParseLinkSearch проверяет разбор условий поиска из параметров запроса.
+func TestParseLinkSearch(t *testing.T) {
+	tests := []struct {
+		name    string
+		query   string
+		want    storage.LinkSearch
+		wantErr bool
+	}{
+		{name: "defaults", query: "q=+docs+", want: storage.LinkSearch{Query: "docs", Limit: defaultSearchLimit}},
+		{name: "prefix", query: "q=ex&match=prefix&limit=5", want: storage.LinkSearch{Query: "ex", Prefix: true, Limit: 5}},
+		{name: "bad match", query: "q=ex&match=regexp", wantErr: true},
+		{name: "bad limit", query: "limit=1000", wantErr: true},
+		{name: "zero limit", query: "limit=0", wantErr: true},
+		{name: "bad time", query: "created_from=yesterday", wantErr: true},
+		{name: "empty range", query: "created_from=2024-01-02T00:00:00Z&created_to=2024-01-01T00:00:00Z", wantErr: true},
+		{name: "control character", query: "q=a%0Ab", wantErr: true},
+		{name: "too long", query: "q=" + strings.Repeat("a", maxSearchQuery+1), wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			q, err := parseLinkSearch(httptest.NewRequest(http.MethodGet, "/api/user/urls/search?"+tt.query, nil))
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, q)
+		})
+	}
+}
+
+// TestHandler_SearchURLs проверяет поиск ссылок пользователя по адресу, названию и тегам.
+func TestHandler_SearchURLs(t *testing.T) {
+	d := Handler{Storage: storage.NewMemoryWork(map[string]map[string]string{}), Host: "http://localhost:8080", UserKey: userKey}
+
+	h := chi.NewRouter()
+	h.Get("/api/user/urls/search", d.SearchURLsHandler)
+	h.Patch("/api/user/urls/{short}", d.UpdateURLHandler)
+
+	request := func(method, target, user, body string) *httptest.ResponseRecorder {
+		r := httptest.NewRequest(method, target, strings.NewReader(body))
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), userKey, user)))
+
+		return w
+	}
+
+	search := func(query string) []AllUserURLs {
+		w := request(http.MethodGet, "/api/user/urls/search?"+query, "owner", "")
+		require.Equal(t, http.StatusOK, w.Code)
+
+		var result []AllUserURLs
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &result))
+
+		return result
+	}
+
+	docs := mustCreate(t, d, &pb.CreateShortLinkRequest{OriginalURL: "https://example.com/docs", UserID: "owner", Title: " Documentation ", Tags: []string{"manual"}})
+	blog := mustCreate(t, d, &pb.CreateShortLinkRequest{OriginalURL: "https://blog.example.org/post", UserID: "owner"})
+	mustCreate(t, d, &pb.CreateShortLinkRequest{OriginalURL: "https://example.com/docs/private", UserID: "other"})
+
+	result := search("q=documentation")
+	require.Len(t, result, 1)
+	assert.Equal(t, docs, result[0].ShortURL)
+	assert.Equal(t, "Documentation", result[0].Title)
+
+	assert.Len(t, search("q=example"), 2)
+	assert.Len(t, search("q=man&match=prefix"), 1)
+	assert.Len(t, search("q=blog.example&match=prefix"), 1)
+
+	w := request(http.MethodGet, "/api/user/urls/search?q=nothing", "owner", "")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.JSONEq(t, `[]`, w.Body.String())
+
+	assert.Equal(t, http.StatusBadRequest, request(http.MethodGet, "/api/user/urls/search?match=fuzzy", "owner", "").Code)
+
+	w = request(http.MethodPatch, "/api/user/urls/"+strings.TrimPrefix(blog, d.Host+"/"), "owner", `{"title":"Company blog"}`)
+	require.Equal(t, http.StatusOK, w.Code)
+	result = search("q=company")
+	require.Len(t, result, 1)
+	assert.Equal(t, blog, result[0].ShortURL)
+
+	assert.Equal(t, http.StatusBadRequest, request(http.MethodPatch, "/api/user/urls/"+strings.TrimPrefix(blog, d.Host+"/"), "owner", `{"title":"a\nb"}`).Code)
+
+	response, err := d.SearchLinks(context.Background(), &pb.SearchLinksRequest{UserID: "owner", Query: "docs"})
+	require.NoError(t, err)
+	require.Len(t, response.ShortURLs, 1)
+	assert.Equal(t, docs, response.ShortURLs[0].ShortURL)
+
+	_, err = d.SearchLinks(context.Background(), &pb.SearchLinksRequest{UserID: "owner", Limit: maxSearchLimit + 1})
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+}
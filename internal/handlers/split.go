@@ -0,0 +1,190 @@
+package handlers
+
+import (
+	"crypto/rand"
+	"errors"
+	"math/big"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/vladimirimekov/url-shortener/internal/storage"
+	pb "github.com/vladimirimekov/url-shortener/proto"
+)
+
+// Ограничения вариантов A/B теста.
+const (
+	maxVariants       = 10
+	maxVariantName    = 32
+	maxVariantWeight  = 1000000
+	variantCookieAge  = 30 * 24 * time.Hour
+	variantCookieName = "variant_"
+)
+
+// Ошибки проверки вариантов A/B теста.
+var (
+	errTooManyVariants    = errors.New("too many variants")
+	errInvalidVariant     = errors.New("variant must have a valid url and a weight from 0 to 1000000")
+	errInvalidVariantName = errors.New("variant names must be unique and consist of up to 32 letters, digits, '-' or '_'")
+	errNoVariantWeight    = errors.New("at least one variant must have a positive weight")
+)
+
+// VariantData содержит структуру для json данных с вариантом A/B теста.
+type VariantData struct {
+	Name   string `json:"name,omitempty"`
+	URL    string `json:"url"`
+	Weight int    `json:"weight"`
+}
+
+// checkVariants проверяет варианты A/B теста и возвращает их в виде для сохранения.
+// Вариантам без названия присваивается название по их номеру в списке.
+func checkVariants(variants []VariantData) ([]storage.Variant, error) {
+	if len(variants) == 0 {
+		return nil, nil
+	}
+	if len(variants) > maxVariants {
+		return nil, errTooManyVariants
+	}
+
+	result := make([]storage.Variant, 0, len(variants))
+	names := map[string]bool{}
+	total := 0
+
+	for i, v := range variants {
+		variant := storage.Variant{Name: strings.TrimSpace(v.Name), URL: strings.TrimSpace(v.URL), Weight: v.Weight}
+
+		if _, err := url.ParseRequestURI(variant.URL); err != nil || variant.Weight < 0 || variant.Weight > maxVariantWeight {
+			return nil, errInvalidVariant
+		}
+
+		if variant.Name == "" {
+			variant.Name = "variant-" + strconv.Itoa(i+1)
+		}
+		if !validVariantName(variant.Name) || names[variant.Name] {
+			return nil, errInvalidVariantName
+		}
+		names[variant.Name] = true
+
+		total += variant.Weight
+		result = append(result, variant)
+	}
+
+	if total == 0 {
+		return nil, errNoVariantWeight
+	}
+
+	return result, nil
+}
+
+// validVariantName проверяет, что название варианта можно сохранить в cookie без кодирования.
+func validVariantName(name string) bool {
+	if name == "" || len(name) > maxVariantName {
+		return false
+	}
+
+	for _, c := range name {
+		if !(c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' || c == '-' || c == '_') {
+			return false
+		}
+	}
+
+	return true
+}
+
+// pickVariant выбирает вариант пропорционально весам. Если посетителю уже назначен вариант sticky
+// и его вес не обнулён, возвращается он. Если вариантов нет, возвращается false.
+func pickVariant(variants []storage.Variant, sticky string) (storage.Variant, bool) {
+	total := 0
+
+	for _, v := range variants {
+		if v.Name == sticky && v.Weight > 0 {
+			return v, true
+		}
+		total += v.Weight
+	}
+
+	if total == 0 {
+		return storage.Variant{}, false
+	}
+
+	n, err := rand.Int(rand.Reader, big.NewInt(int64(total)))
+	if err != nil {
+		return variants[0], true
+	}
+
+	point := int(n.Int64())
+
+	for _, v := range variants {
+		if point < v.Weight {
+			return v, true
+		}
+		point -= v.Weight
+	}
+
+	return variants[len(variants)-1], true
+}
+
+// stickyVariant возвращает вариант, назначенный посетителю ранее, из cookie запроса.
+func stickyVariant(r *http.Request, shortname string) string {
+	cookie, err := r.Cookie(variantCookieName + shortname)
+	if err != nil {
+		return ""
+	}
+
+	return cookie.Value
+}
+
+// setStickyVariant сохраняет назначенный посетителю вариант в cookie, чтобы при повторных переходах он не менялся.
+func setStickyVariant(w http.ResponseWriter, shortname, variant string) {
+	http.SetCookie(w, &http.Cookie{
+		Name:     variantCookieName + shortname,
+		Value:    variant,
+		Path:     "/" + shortname,
+		MaxAge:   int(variantCookieAge.Seconds()),
+		HttpOnly: true,
+		SameSite: http.SameSiteLaxMode,
+	})
+}
+
+// variantsData возвращает json представление вариантов A/B теста.
+func variantsData(variants []storage.Variant) []VariantData {
+	if len(variants) == 0 {
+		return nil
+	}
+
+	result := make([]VariantData, 0, len(variants))
+
+	for _, v := range variants {
+		result = append(result, VariantData(v))
+	}
+
+	return result
+}
+
+// variantsFromProto возвращает варианты A/B теста из grpc запроса.
+func variantsFromProto(variants []*pb.Variant) []VariantData {
+	if len(variants) == 0 {
+		return nil
+	}
+
+	result := make([]VariantData, 0, len(variants))
+
+	for _, v := range variants {
+		result = append(result, VariantData{Name: v.Name, URL: v.Url, Weight: int(v.Weight)})
+	}
+
+	return result
+}
+
+// variantsProto возвращает grpc представление вариантов A/B теста.
+func variantsProto(variants []storage.Variant) []*pb.Variant {
+	result := make([]*pb.Variant, 0, len(variants))
+
+	for _, v := range variants {
+		result = append(result, &pb.Variant{Name: v.Name, Url: v.URL, Weight: int32(v.Weight)})
+	}
+
+	return result
+}
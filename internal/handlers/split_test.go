@@ -0,0 +1,159 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/vladimirimekov/url-shortener/internal/clicks"
+	"github.com/vladimirimekov/url-shortener/internal/storage"
+	pb "github.com/vladimirimekov/url-shortener/proto"
+)
+
+// TestCheckVariants проверяет валидацию вариантов A/B теста.
+func TestCheckVariants(t *testing.T) {
+	tests := []struct {
+		name     string
+		variants []VariantData
+		wantErr  error
+		want     []storage.Variant
+	}{
+		{name: "no variants"},
+		{
+			name:     "default names",
+			variants: []VariantData{{URL: "https://example.com/a", Weight: 3}, {URL: "https://example.com/b", Weight: 0}},
+			want:     []storage.Variant{{Name: "variant-1", URL: "https://example.com/a", Weight: 3}, {Name: "variant-2", URL: "https://example.com/b"}},
+		},
+		{name: "invalid url", variants: []VariantData{{URL: "example", Weight: 1}}, wantErr: errInvalidVariant},
+		{name: "negative weight", variants: []VariantData{{URL: "https://example.com", Weight: -1}}, wantErr: errInvalidVariant},
+		{name: "zero total weight", variants: []VariantData{{URL: "https://example.com"}}, wantErr: errNoVariantWeight},
+		{name: "unsafe name", variants: []VariantData{{Name: "a;b", URL: "https://example.com", Weight: 1}}, wantErr: errInvalidVariantName},
+		{
+			name:     "duplicate names",
+			variants: []VariantData{{Name: "a", URL: "https://example.com", Weight: 1}, {Name: "a", URL: "https://example.org", Weight: 1}},
+			wantErr:  errInvalidVariantName,
+		},
+		{name: "too many variants", variants: make([]VariantData, maxVariants+1), wantErr: errTooManyVariants},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			variants, err := checkVariants(tt.variants)
+			if tt.wantErr != nil {
+				assert.ErrorIs(t, err, tt.wantErr)
+				return
+			}
+
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, variants)
+		})
+	}
+}
+
+// TestPickVariant проверяет распределение переходов по весам вариантов и закрепление варианта за посетителем.
+func TestPickVariant(t *testing.T) {
+	variants := []storage.Variant{{Name: "a", Weight: 3}, {Name: "b", Weight: 1}, {Name: "off", Weight: 0}}
+
+	counts := map[string]int{}
+	for i := 0; i < 4000; i++ {
+		v, ok := pickVariant(variants, "")
+		require.True(t, ok)
+		counts[v.Name]++
+	}
+
+	assert.InDelta(t, 3000, counts["a"], 200)
+	assert.InDelta(t, 1000, counts["b"], 200)
+	assert.Zero(t, counts["off"])
+
+	v, _ := pickVariant(variants, "b")
+	assert.Equal(t, "b", v.Name)
+
+	//вариант с нулевым весом не сохраняется за посетителем
+	v, _ = pickVariant(variants, "off")
+	assert.NotEqual(t, "off", v.Name)
+
+	_, ok := pickVariant(nil, "a")
+	assert.False(t, ok)
+}
+
+// TestHandler_Variants проверяет закрепление варианта через cookie, изменение весов и статистику по вариантам.
+func TestHandler_Variants(t *testing.T) {
+	s := storage.NewMemoryWork(map[string]map[string]string{})
+	recorder := clicks.New(s, []byte("salt"), 10, 10, time.Second, nil)
+	go recorder.Run(context.Background())
+
+	d := Handler{Storage: s, Host: "http://localhost:8080", UserKey: userKey, Clicks: recorder}
+
+	response, err := d.CreateShortLink(context.Background(), &pb.CreateShortLinkRequest{
+		OriginalURL: "https://example.com",
+		UserID:      "owner",
+		Variants:    []*pb.Variant{{Name: "a", Url: "https://example.com/a", Weight: 1}, {Name: "b", Url: "https://example.com/b", Weight: 1}},
+	})
+	require.NoError(t, err)
+	shortname := strings.TrimPrefix(response.ShortURL, d.Host+"/")
+
+	h := chi.NewRouter()
+	h.Get("/{id}", d.MainHandler)
+	h.Patch("/api/user/urls/{short}", d.UpdateURLHandler)
+
+	redirect := func(cookies []*http.Cookie) *httptest.ResponseRecorder {
+		request := httptest.NewRequest(http.MethodGet, "/"+shortname, nil)
+		for _, cookie := range cookies {
+			request.AddCookie(cookie)
+		}
+
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, request)
+		require.Equal(t, http.StatusTemporaryRedirect, w.Code)
+
+		return w
+	}
+
+	first := redirect(nil)
+	cookies := first.Result().Cookies()
+	require.Len(t, cookies, 1)
+	assert.Equal(t, "variant_"+shortname, cookies[0].Name)
+	assigned := cookies[0].Value
+	location := first.Header().Get("Location")
+
+	for i := 0; i < 5; i++ {
+		w := redirect(cookies)
+		assert.Equal(t, location, w.Header().Get("Location"))
+		assert.Empty(t, w.Result().Cookies())
+	}
+
+	//после обнуления веса посетитель переназначается на другой вариант
+	weights := map[string]int{"a": 1, "b": 1}
+	weights[assigned] = 0
+	body := fmt.Sprintf(`{"variants":[{"name":"a","url":"https://example.com/a","weight":%d},{"name":"b","url":"https://example.com/b","weight":%d}]}`, weights["a"], weights["b"])
+
+	w := httptest.NewRecorder()
+	request := httptest.NewRequest(http.MethodPatch, "/api/user/urls/"+shortname, strings.NewReader(body))
+	h.ServeHTTP(w, request.WithContext(context.WithValue(request.Context(), userKey, "owner")))
+	require.Equal(t, http.StatusOK, w.Code)
+
+	moved := redirect(cookies)
+	assert.NotEqual(t, location, moved.Header().Get("Location"))
+	require.Len(t, moved.Result().Cookies(), 1)
+	assert.NotEqual(t, assigned, moved.Result().Cookies()[0].Value)
+
+	recorder.Close()
+
+	stats, err := d.GetLinkStats(context.Background(), &pb.GetLinkStatsRequest{ShortURL: shortname, UserID: "owner", IncludeBots: true})
+	require.NoError(t, err)
+	require.Len(t, stats.Variants, 2)
+	assert.Equal(t, assigned, stats.Variants[0].Name)
+	assert.Equal(t, int64(6), stats.Variants[0].Clicks)
+	assert.Equal(t, int64(1), stats.Variants[1].Clicks)
+
+	original, err := d.GetOriginalLink(context.Background(), &pb.GetOriginalLinkRequest{ShortURL: shortname})
+	require.NoError(t, err)
+	assert.NotEqual(t, assigned, original.Variant)
+}
@@ -0,0 +1,241 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	"github.com/vladimirimekov/url-shortener/internal/analytics"
+	"github.com/vladimirimekov/url-shortener/internal/storage"
+	pb "github.com/vladimirimekov/url-shortener/proto"
+)
+
+// BucketData содержит структуру для json данных с количеством переходов за интервал.
+type BucketData struct {
+	Start  time.Time `json:"start"`
+	Clicks int64     `json:"clicks"`
+}
+
+// CountData содержит структуру для json данных с количеством переходов для значения разбивки.
+type CountData struct {
+	Name   string `json:"name"`
+	Clicks int64  `json:"clicks"`
+}
+
+// LinkStatsData содержит структуру для json данных со статистикой переходов по ссылке.
+type LinkStatsData struct {
+	ShortURL       string       `json:"short_url"`
+	From           time.Time    `json:"from"`
+	To             time.Time    `json:"to"`
+	Interval       string       `json:"interval"`
+	Total          int64        `json:"total"`
+	UniqueVisitors uint64       `json:"unique_visitors"`
+	Series         []BucketData `json:"series"`
+	Referrers      []CountData  `json:"referrers"`
+	Browsers       []CountData  `json:"browsers"`
+	OS             []CountData  `json:"os"`
+	Rules          []CountData  `json:"rules"`
+	Variants       []CountData  `json:"variants"`
+	Classes        []CountData  `json:"classes"`
+}
+
+// linkStats возвращает статистику переходов по ссылке, если она принадлежит пользователю.
+func (h Handler) linkStats(ctx context.Context, userID, shortname string, q analytics.Query) (analytics.Report, error) {
+	link, err := h.Storage.GetLink(ctx, shortname)
+	if err != nil {
+		return analytics.Report{}, err
+	}
+
+	if link.UserID != userID {
+		return analytics.Report{}, storage.ErrNotOwner
+	}
+
+	return analytics.LinkReport(ctx, h.Storage, shortname, q)
+}
+
+// countData возвращает json представление разбивки.
+func countData(counts []analytics.Count) []CountData {
+	result := make([]CountData, 0, len(counts))
+
+	for _, v := range counts {
+		result = append(result, CountData{Name: v.Name, Clicks: v.Clicks})
+	}
+
+	return result
+}
+
+// parseTime разбирает время в формате RFC3339. Пустая строка соответствует нулевому времени.
+func parseTime(value string) (time.Time, error) {
+	if value == "" {
+		return time.Time{}, nil
+	}
+
+	return time.Parse(time.RFC3339, value)
+}
+
+// parseStatsQuery возвращает параметры отчёта из параметров запроса. Период задаётся параметрами from и to в формате RFC3339,
+// интервал группировки - параметром interval (hour или day), переходы ботов учитываются при include_bots=true.
+func parseStatsQuery(r *http.Request) (analytics.Query, error) {
+	from, err := parseTime(r.URL.Query().Get("from"))
+	if err != nil {
+		return analytics.Query{}, errors.New("Invalid from value")
+	}
+
+	to, err := parseTime(r.URL.Query().Get("to"))
+	if err != nil {
+		return analytics.Query{}, errors.New("Invalid to value")
+	}
+
+	q, err := analytics.NewQuery(from, to, r.URL.Query().Get("interval"))
+	if err != nil {
+		return analytics.Query{}, err
+	}
+
+	if value := r.URL.Query().Get("include_bots"); value != "" {
+		q.IncludeBots, err = strconv.ParseBool(value)
+		if err != nil {
+			return analytics.Query{}, errors.New("Invalid include_bots value")
+		}
+	}
+
+	return q, nil
+}
+
+// statsQuery возвращает параметры отчёта из grpc запроса.
+func statsQuery(from, to *timestamppb.Timestamp, interval string, includeBots bool) (analytics.Query, error) {
+	var fromTime, toTime time.Time
+
+	if from != nil {
+		fromTime = from.AsTime()
+	}
+	if to != nil {
+		toTime = to.AsTime()
+	}
+
+	q, err := analytics.NewQuery(fromTime, toTime, interval)
+	if err != nil {
+		return analytics.Query{}, status.Error(codes.InvalidArgument, err.Error())
+	}
+
+	q.IncludeBots = includeBots
+
+	return q, nil
+}
+
+// seriesData возвращает json представление количества переходов по интервалам.
+func seriesData(series []analytics.Bucket) []BucketData {
+	result := make([]BucketData, 0, len(series))
+
+	for _, v := range series {
+		result = append(result, BucketData{Start: v.Start, Clicks: v.Clicks})
+	}
+
+	return result
+}
+
+// statsSeries возвращает grpc представление количества переходов по интервалам.
+func statsSeries(series []analytics.Bucket) []*pb.StatsBucket {
+	result := make([]*pb.StatsBucket, 0, len(series))
+
+	for _, v := range series {
+		result = append(result, &pb.StatsBucket{Start: timestamppb.New(v.Start), Clicks: v.Clicks})
+	}
+
+	return result
+}
+
+// GetURLStatsHandler отправляет статистику переходов по ссылке текущего пользователя.
+// Период задаётся параметрами from и to в формате RFC3339, интервал группировки - параметром interval (hour или day).
+// Переходы ботов учитываются, только если передан параметр include_bots=true.
+func (h Handler) GetURLStatsHandler(w http.ResponseWriter, r *http.Request) {
+
+	ctx, cancel := context.WithTimeout(r.Context(), 60*time.Second)
+	defer cancel()
+
+	userID, err := h.getUserID(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	q, err := parseStatsQuery(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	shortname := chi.URLParam(r, "short")
+
+	report, err := h.linkStats(ctx, userID, shortname, q)
+	if err != nil {
+		http.Error(w, err.Error(), linkErrorStatus(err))
+		return
+	}
+
+	result := LinkStatsData{
+		ShortURL:       h.Host + "/" + shortname,
+		From:           report.Query.From,
+		To:             report.Query.To,
+		Interval:       string(report.Query.Interval),
+		Total:          report.Total,
+		UniqueVisitors: report.UniqueVisitors,
+		Series:         seriesData(report.Series),
+		Referrers:      countData(report.Referrers),
+		Browsers:       countData(report.Browsers),
+		OS:             countData(report.OS),
+		Rules:          countData(report.Rules),
+		Variants:       countData(report.Variants),
+		Classes:        countData(report.Classes),
+	}
+
+	writeJSON(w, http.StatusOK, result)
+}
+
+// statsCounts возвращает grpc представление разбивки.
+func statsCounts(counts []analytics.Count) []*pb.StatsCount {
+	result := make([]*pb.StatsCount, 0, len(counts))
+
+	for _, v := range counts {
+		result = append(result, &pb.StatsCount{Name: v.Name, Clicks: v.Clicks})
+	}
+
+	return result
+}
+
+// GetLinkStats возвращает статистику переходов по ссылке для grpc.
+func (h Handler) GetLinkStats(ctx context.Context, request *pb.GetLinkStatsRequest) (*pb.GetLinkStatsResponse, error) {
+	q, err := statsQuery(request.From, request.To, request.Interval, request.IncludeBots)
+	if err != nil {
+		return nil, err
+	}
+
+	report, err := h.linkStats(ctx, request.UserID, request.ShortURL, q)
+	if err != nil {
+		return nil, linkStatusError(err)
+	}
+
+	response := &pb.GetLinkStatsResponse{
+		ShortURL:       h.Host + "/" + request.ShortURL,
+		From:           timestamppb.New(report.Query.From),
+		To:             timestamppb.New(report.Query.To),
+		Interval:       string(report.Query.Interval),
+		Total:          report.Total,
+		UniqueVisitors: report.UniqueVisitors,
+		Series:         statsSeries(report.Series),
+		Referrers:      statsCounts(report.Referrers),
+		Browsers:       statsCounts(report.Browsers),
+		Os:             statsCounts(report.OS),
+		Rules:          statsCounts(report.Rules),
+		Variants:       statsCounts(report.Variants),
+		Classes:        statsCounts(report.Classes),
+	}
+
+	return response, nil
+}
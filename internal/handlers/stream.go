@@ -0,0 +1,210 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"time"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	"github.com/vladimirimekov/url-shortener/internal/analytics"
+	"github.com/vladimirimekov/url-shortener/internal/storage"
+	"github.com/vladimirimekov/url-shortener/internal/useragent"
+	pb "github.com/vladimirimekov/url-shortener/proto"
+)
+
+// Параметры потока переходов.
+const (
+	streamHeartbeat = 15 * time.Second
+	ownerCacheTTL   = time.Minute
+)
+
+// errStreamUnavailable возвращается, если поток переходов не настроен.
+var errStreamUnavailable = errors.New("click stream is not available")
+
+// ClickEventData содержит структуру для json данных о переходе по ссылке в потоке переходов.
+type ClickEventData struct {
+	ShortURL string    `json:"short_url"`
+	Time     time.Time `json:"time"`
+	Referrer string    `json:"referrer"`
+	Browser  string    `json:"browser"`
+	OS       string    `json:"os"`
+	Device   string    `json:"device"`
+	Class    string    `json:"class"`
+	Rule     string    `json:"rule"`
+	Variant  string    `json:"variant,omitempty"`
+}
+
+// ownerEntry хранит результат проверки владельца ссылки.
+type ownerEntry struct {
+	owned   bool
+	checked time.Time
+}
+
+// ownerFilter отбирает переходы по ссылкам пользователя, кэшируя проверку владельца ссылки.
+type ownerFilter struct {
+	storage Repositories
+	userID  string
+	cache   map[string]ownerEntry
+}
+
+// owns проверяет, принадлежит ли ссылка пользователю.
+func (f ownerFilter) owns(ctx context.Context, shortname string) bool {
+	now := time.Now()
+
+	if entry, ok := f.cache[shortname]; ok && now.Sub(entry.checked) < ownerCacheTTL {
+		return entry.owned
+	}
+
+	link, err := f.storage.GetLink(ctx, shortname)
+	owned := err == nil && link.UserID == f.userID
+
+	f.cache[shortname] = ownerEntry{owned: owned, checked: now}
+
+	return owned
+}
+
+// clickEventData возвращает данные о переходе для отправки в поток.
+func (h Handler) clickEventData(c storage.Click) ClickEventData {
+	agent := useragent.Parse(c.UserAgent)
+
+	return ClickEventData{
+		ShortURL: h.Host + "/" + c.ShortURL,
+		Time:     c.Time,
+		Referrer: analytics.ReferrerHost(c.Referrer),
+		Browser:  agent.Browser,
+		OS:       agent.OS,
+		Device:   agent.Device,
+		Class:    c.ClickClass(),
+		Rule:     analytics.RuleName(c.Rule),
+		Variant:  c.Variant,
+	}
+}
+
+// streamClicks передаёт в send переходы по ссылкам пользователя до отмены контекста или ошибки отправки.
+// Раз в streamHeartbeat send вызывается с nil, чтобы поддерживать соединение.
+func (h Handler) streamClicks(ctx context.Context, userID string, send func(*ClickEventData) error) error {
+	if h.Events == nil {
+		return errStreamUnavailable
+	}
+
+	sub := h.Events.Subscribe()
+	defer func() {
+		sub.Close()
+
+		if dropped := sub.TakeDropped(); dropped > 0 {
+			log.Printf("click stream of user %s dropped %d events", userID, dropped)
+		}
+	}()
+
+	filter := ownerFilter{storage: h.Storage, userID: userID, cache: map[string]ownerEntry{}}
+
+	ticker := time.NewTicker(streamHeartbeat)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+			if err := send(nil); err != nil {
+				return err
+			}
+		case c, ok := <-sub.Events():
+			if !ok {
+				return nil
+			}
+			if !filter.owns(ctx, c.ShortURL) {
+				continue
+			}
+
+			event := h.clickEventData(c)
+			if err := send(&event); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// StreamClicksHandler отправляет переходы по ссылкам текущего пользователя в реальном времени в формате Server-Sent Events.
+func (h Handler) StreamClicksHandler(w http.ResponseWriter, r *http.Request) {
+
+	userID, err := h.getUserID(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	flusher, ok := w.(http.Flusher)
+	if !ok || h.Events == nil {
+		http.Error(w, errStreamUnavailable.Error(), http.StatusNotImplemented)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+	flusher.Flush()
+
+	err = h.streamClicks(r.Context(), userID, func(event *ClickEventData) error {
+		if event == nil {
+			_, err := io.WriteString(w, ": ping\n\n")
+			flusher.Flush()
+			return err
+		}
+
+		data, err := json.Marshal(event)
+		if err != nil {
+			return err
+		}
+
+		if _, err = fmt.Fprintf(w, "event: click\ndata: %s\n\n", data); err != nil {
+			return err
+		}
+		flusher.Flush()
+
+		return nil
+	})
+	if err != nil && !errors.Is(err, context.Canceled) {
+		log.Print(err)
+	}
+}
+
+// StreamClicks отправляет переходы по ссылкам пользователя в реальном времени для grpc.
+func (h Handler) StreamClicks(request *pb.StreamClicksRequest, stream pb.UrlShortener_StreamClicksServer) error {
+	if h.Events == nil {
+		return status.Error(codes.Unimplemented, errStreamUnavailable.Error())
+	}
+
+	err := h.streamClicks(stream.Context(), request.UserID, func(event *ClickEventData) error {
+		if event == nil {
+			return nil
+		}
+
+		return stream.Send(&pb.ClickEvent{
+			ShortURL: event.ShortURL,
+			Time:     timestamppb.New(event.Time),
+			Referrer: event.Referrer,
+			Browser:  event.Browser,
+			Os:       event.OS,
+			Device:   event.Device,
+			Class:    event.Class,
+			Rule:     event.Rule,
+			Variant:  event.Variant,
+		})
+	})
+	if errors.Is(err, context.Canceled) {
+		return status.Error(codes.Canceled, err.Error())
+	}
+
+	return err
+}
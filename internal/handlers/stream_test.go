@@ -0,0 +1,89 @@
+package handlers
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/vladimirimekov/url-shortener/internal/broker"
+	"github.com/vladimirimekov/url-shortener/internal/clicks"
+	"github.com/vladimirimekov/url-shortener/internal/storage"
+)
+
+// TestHandler_StreamClicksHandler проверяет, что в поток попадают только переходы по ссылкам пользователя.
+func TestHandler_StreamClicksHandler(t *testing.T) {
+	s := storage.NewMemoryWork(map[string]map[string]string{})
+	require.NoError(t, s.SaveData(context.Background(), map[string]map[string]string{
+		"owner":    {"abc": "https://example.com"},
+		"stranger": {"xyz": "https://example.org"},
+	}))
+
+	d := Handler{
+		Storage: s,
+		Host:    "http://localhost:8080",
+		UserKey: userKey,
+		Clicks:  clicks.New(s, []byte("salt"), 10, 10, time.Second, nil),
+		Events:  broker.New(10),
+	}
+
+	h := chi.NewRouter()
+	h.Use(func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), userKey, "owner")))
+		})
+	})
+	h.Get("/{id}", d.MainHandler)
+	h.Get("/api/user/urls/stream", d.StreamClicksHandler)
+
+	server := httptest.NewServer(h)
+	defer server.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	request, err := http.NewRequestWithContext(ctx, http.MethodGet, server.URL+"/api/user/urls/stream", nil)
+	require.NoError(t, err)
+
+	response, err := http.DefaultClient.Do(request)
+	require.NoError(t, err)
+	defer response.Body.Close()
+
+	assert.Equal(t, http.StatusOK, response.StatusCode)
+	assert.Equal(t, "text/event-stream", response.Header.Get("Content-Type"))
+
+	assert.Eventually(t, func() bool { return d.Events.Subscribers() == 1 }, time.Second, 10*time.Millisecond)
+
+	client := &http.Client{CheckRedirect: func(*http.Request, []*http.Request) error { return http.ErrUseLastResponse }}
+	for _, shortname := range []string{"xyz", "abc"} {
+		redirect, err := client.Get(server.URL + "/" + shortname)
+		require.NoError(t, err)
+		redirect.Body.Close()
+		assert.Equal(t, http.StatusTemporaryRedirect, redirect.StatusCode)
+	}
+
+	reader := bufio.NewReader(response.Body)
+
+	line, err := reader.ReadString('\n')
+	require.NoError(t, err)
+	assert.Equal(t, "event: click\n", line)
+
+	line, err = reader.ReadString('\n')
+	require.NoError(t, err)
+
+	var event ClickEventData
+	require.NoError(t, json.Unmarshal([]byte(strings.TrimPrefix(strings.TrimSpace(line), "data: ")), &event))
+	assert.Equal(t, "http://localhost:8080/abc", event.ShortURL)
+	assert.Equal(t, storage.ClassHuman, event.Class)
+
+	cancel()
+	assert.Eventually(t, func() bool { return d.Events.Subscribers() == 0 }, time.Second, 10*time.Millisecond)
+}
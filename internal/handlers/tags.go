@@ -0,0 +1,354 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/go-chi/chi/v5"
+	"google.golang.org/protobuf/types/known/emptypb"
+
+	"github.com/vladimirimekov/url-shortener/internal/storage"
+	pb "github.com/vladimirimekov/url-shortener/proto"
+)
+
+// Ограничения тегов и папок ссылок.
+const (
+	maxTags         = 20
+	maxTagLength    = 32
+	maxFolderLength = 100
+)
+
+// Ошибки проверки тегов и папок ссылок.
+var (
+	errInvalidTag    = errors.New("tags must consist of up to 32 letters, digits, '-', '_' or '.'")
+	errTooManyTags   = errors.New("a link can have at most 20 tags")
+	errInvalidFolder = errors.New("folder must be up to 100 characters without control characters")
+	errTagNotFound   = errors.New("tag not found")
+)
+
+// LabelData содержит структуру для json данных с тегом или папкой и количеством ссылок с ними.
+type LabelData struct {
+	Name  string `json:"name"`
+	Links int64  `json:"links"`
+}
+
+// RenameTagData содержит структуру для получения json данных с новым названием тега.
+type RenameTagData struct {
+	Name string `json:"name"`
+}
+
+// normalizeTag проверяет тег и приводит его к нижнему регистру.
+func normalizeTag(tag string) (string, error) {
+	tag = strings.ToLower(strings.TrimSpace(tag))
+	if tag == "" || utf8.RuneCountInString(tag) > maxTagLength {
+		return "", errInvalidTag
+	}
+
+	for _, c := range tag {
+		if !unicode.IsLetter(c) && !unicode.IsDigit(c) && c != '-' && c != '_' && c != '.' {
+			return "", errInvalidTag
+		}
+	}
+
+	return tag, nil
+}
+
+// checkTags проверяет теги ссылки и возвращает их отсортированный список без повторов.
+func checkTags(tags []string) ([]string, error) {
+	if len(tags) == 0 {
+		return nil, nil
+	}
+
+	seen := map[string]bool{}
+	result := make([]string, 0, len(tags))
+
+	for _, tag := range tags {
+		tag, err := normalizeTag(tag)
+		if err != nil {
+			return nil, err
+		}
+		if seen[tag] {
+			continue
+		}
+		seen[tag] = true
+		result = append(result, tag)
+	}
+
+	if len(result) > maxTags {
+		return nil, errTooManyTags
+	}
+
+	sort.Strings(result)
+
+	return result, nil
+}
+
+// checkFolder проверяет папку ссылки и возвращает её без пробелов по краям. Пустая строка означает отсутствие папки.
+func checkFolder(folder string) (string, error) {
+	folder = strings.TrimSpace(folder)
+	if utf8.RuneCountInString(folder) > maxFolderLength {
+		return "", errInvalidFolder
+	}
+
+	for _, c := range folder {
+		if unicode.IsControl(c) {
+			return "", errInvalidFolder
+		}
+	}
+
+	return folder, nil
+}
+
+// linkFilter проверяет условия выборки ссылок пользователя по тегам и папке.
+func linkFilter(tags []string, folder string) (storage.LinkFilter, error) {
+	tags, err := checkTags(tags)
+	if err != nil {
+		return storage.LinkFilter{}, err
+	}
+
+	folder, err = checkFolder(folder)
+	if err != nil {
+		return storage.LinkFilter{}, err
+	}
+
+	return storage.LinkFilter{Tags: tags, Folder: folder}, nil
+}
+
+// labelCounts возвращает количество ссылок пользователя для каждого тега или, если folders истинно, для каждой папки.
+// Значения упорядочены по названию, ссылки без папки не учитываются.
+func (h Handler) labelCounts(ctx context.Context, userID string, folders bool) ([]LabelData, error) {
+	links, err := h.Storage.GetUserLinks(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	counts := map[string]int64{}
+
+	for _, link := range links {
+		if !folders {
+			for _, tag := range link.Tags {
+				counts[tag]++
+			}
+		} else if link.Folder != "" {
+			counts[link.Folder]++
+		}
+	}
+
+	result := make([]LabelData, 0, len(counts))
+
+	for name, links := range counts {
+		result = append(result, LabelData{Name: name, Links: links})
+	}
+
+	sort.Slice(result, func(i, j int) bool { return result[i].Name < result[j].Name })
+
+	return result, nil
+}
+
+// renameTag заменяет тег у всех ссылок пользователя и возвращает новое название тега с количеством изменённых ссылок.
+func (h Handler) renameTag(ctx context.Context, userID, from, to string) (LabelData, error) {
+	from, err := normalizeTag(from)
+	if err != nil {
+		return LabelData{}, errTagNotFound
+	}
+
+	to, err = normalizeTag(to)
+	if err != nil {
+		return LabelData{}, err
+	}
+
+	renamed, err := h.Storage.RenameTag(ctx, userID, from, to)
+	if err != nil {
+		return LabelData{}, err
+	}
+	if renamed == 0 {
+		return LabelData{}, errTagNotFound
+	}
+
+	return LabelData{Name: to, Links: renamed}, nil
+}
+
+// deleteTag убирает тег у всех ссылок пользователя.
+func (h Handler) deleteTag(ctx context.Context, userID, tag string) error {
+	tag, err := normalizeTag(tag)
+	if err != nil {
+		return errTagNotFound
+	}
+
+	deleted, err := h.Storage.DeleteTag(ctx, userID, tag)
+	if err != nil {
+		return err
+	}
+	if deleted == 0 {
+		return errTagNotFound
+	}
+
+	return nil
+}
+
+// tagParam возвращает тег из пути запроса.
+func tagParam(r *http.Request) string {
+	tag := chi.URLParam(r, "tag")
+	if value, err := url.PathUnescape(tag); err == nil {
+		return value
+	}
+
+	return tag
+}
+
+// labelsProto возвращает grpc представление тегов или папок.
+func labelsProto(labels []LabelData) []*pb.LabelCount {
+	result := make([]*pb.LabelCount, 0, len(labels))
+
+	for _, v := range labels {
+		result = append(result, &pb.LabelCount{Name: v.Name, Links: v.Links})
+	}
+
+	return result
+}
+
+// GetTagsHandler отправляет теги ссылок текущего пользователя с количеством ссылок с каждым тегом.
+func (h Handler) GetTagsHandler(w http.ResponseWriter, r *http.Request) {
+
+	ctx, cancel := context.WithTimeout(r.Context(), 60*time.Second)
+	defer cancel()
+
+	userID, err := h.getUserID(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	result, err := h.labelCounts(ctx, userID, false)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, result)
+}
+
+// RenameTagHandler переименовывает тег у всех ссылок текущего пользователя.
+// Если у ссылки уже есть тег с новым названием, теги объединяются.
+func (h Handler) RenameTagHandler(w http.ResponseWriter, r *http.Request) {
+
+	ctx, cancel := context.WithTimeout(r.Context(), 60*time.Second)
+	defer cancel()
+
+	userID, err := h.getUserID(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	b, err := io.ReadAll(r.Body)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	defer r.Body.Close()
+
+	var data RenameTagData
+
+	if err = json.Unmarshal(b, &data); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	result, err := h.renameTag(ctx, userID, tagParam(r), data.Name)
+	if err != nil {
+		http.Error(w, err.Error(), linkErrorStatus(err))
+		return
+	}
+
+	writeJSON(w, http.StatusOK, result)
+}
+
+// DeleteTagHandler убирает тег у всех ссылок текущего пользователя. Сами ссылки не удаляются.
+func (h Handler) DeleteTagHandler(w http.ResponseWriter, r *http.Request) {
+
+	ctx, cancel := context.WithTimeout(r.Context(), 60*time.Second)
+	defer cancel()
+
+	userID, err := h.getUserID(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	if err = h.deleteTag(ctx, userID, tagParam(r)); err != nil {
+		http.Error(w, err.Error(), linkErrorStatus(err))
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// GetFoldersHandler отправляет папки ссылок текущего пользователя с количеством ссылок в каждой папке.
+func (h Handler) GetFoldersHandler(w http.ResponseWriter, r *http.Request) {
+
+	ctx, cancel := context.WithTimeout(r.Context(), 60*time.Second)
+	defer cancel()
+
+	userID, err := h.getUserID(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	result, err := h.labelCounts(ctx, userID, true)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, result)
+}
+
+// GetTags возвращает теги ссылок пользователя для grpc.
+func (h Handler) GetTags(ctx context.Context, request *pb.GetTagsRequest) (*pb.GetTagsResponse, error) {
+	tags, err := h.labelCounts(ctx, request.UserID, false)
+	if err != nil {
+		return nil, err
+	}
+
+	return &pb.GetTagsResponse{Tags: labelsProto(tags)}, nil
+}
+
+// RenameTag переименовывает тег у всех ссылок пользователя для grpc.
+func (h Handler) RenameTag(ctx context.Context, request *pb.RenameTagRequest) (*pb.LabelCount, error) {
+	result, err := h.renameTag(ctx, request.UserID, request.Name, request.NewName)
+	if err != nil {
+		return nil, linkStatusError(err)
+	}
+
+	return &pb.LabelCount{Name: result.Name, Links: result.Links}, nil
+}
+
+// DeleteTag убирает тег у всех ссылок пользователя для grpc.
+func (h Handler) DeleteTag(ctx context.Context, request *pb.DeleteTagRequest) (*emptypb.Empty, error) {
+	if err := h.deleteTag(ctx, request.UserID, request.Name); err != nil {
+		return nil, linkStatusError(err)
+	}
+
+	return &emptypb.Empty{}, nil
+}
+
+// GetFolders возвращает папки ссылок пользователя для grpc.
+func (h Handler) GetFolders(ctx context.Context, request *pb.GetFoldersRequest) (*pb.GetFoldersResponse, error) {
+	folders, err := h.labelCounts(ctx, request.UserID, true)
+	if err != nil {
+		return nil, err
+	}
+
+	return &pb.GetFoldersResponse{Folders: labelsProto(folders)}, nil
+}
@@ -0,0 +1,138 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/vladimirimekov/url-shortener/internal/storage"
+	pb "github.com/vladimirimekov/url-shortener/proto"
+)
+
+// TestCheckTags проверяет нормализацию и валидацию тегов ссылки.
+func TestCheckTags(t *testing.T) {
+	tooMany := make([]string, maxTags+1)
+	for i := range tooMany {
+		tooMany[i] = strings.Repeat("t", i+1)
+	}
+
+	tests := []struct {
+		name    string
+		tags    []string
+		want    []string
+		wantErr error
+	}{
+		{name: "empty"},
+		{name: "normalized", tags: []string{" News ", "docs", "news", "v1.2_beta-3"}, want: []string{"docs", "news", "v1.2_beta-3"}},
+		{name: "unicode", tags: []string{"Новости"}, want: []string{"новости"}},
+		{name: "blank", tags: []string{" "}, wantErr: errInvalidTag},
+		{name: "space inside", tags: []string{"two words"}, wantErr: errInvalidTag},
+		{name: "too long", tags: []string{strings.Repeat("a", maxTagLength+1)}, wantErr: errInvalidTag},
+		{name: "too many", tags: tooMany, wantErr: errTooManyTags},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tags, err := checkTags(tt.tags)
+			if tt.wantErr != nil {
+				assert.ErrorIs(t, err, tt.wantErr)
+				return
+			}
+
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, tags)
+		})
+	}
+}
+
+// TestHandler_Tags проверяет назначение тегов и папок ссылкам, фильтрацию списка ссылок и управление тегами.
+func TestHandler_Tags(t *testing.T) {
+	d := Handler{Storage: storage.NewMemoryWork(map[string]map[string]string{}), Host: "http://localhost:8080", UserKey: userKey}
+
+	h := chi.NewRouter()
+	h.Post("/api/shorten", d.PostShortenHandler)
+	h.Get("/api/user/urls", d.GetAllShorterURLsHandler)
+	h.Patch("/api/user/urls/{short}", d.UpdateURLHandler)
+	h.Get("/api/user/tags", d.GetTagsHandler)
+	h.Patch("/api/user/tags/{tag}", d.RenameTagHandler)
+	h.Delete("/api/user/tags/{tag}", d.DeleteTagHandler)
+	h.Get("/api/user/folders", d.GetFoldersHandler)
+
+	request := func(method, target, body string) *httptest.ResponseRecorder {
+		r := httptest.NewRequest(method, target, strings.NewReader(body))
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), userKey, "owner")))
+
+		return w
+	}
+
+	list := func(query string) []AllUserURLs {
+		w := request(http.MethodGet, "/api/user/urls"+query, "")
+		require.Equal(t, http.StatusOK, w.Code)
+
+		var result []AllUserURLs
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &result))
+
+		return result
+	}
+
+	w := request(http.MethodPost, "/api/shorten", `{"url":"https://example.com/a","tags":["News","docs"],"folder":" Work "}`)
+	require.Equal(t, http.StatusCreated, w.Code)
+	var result map[string]string
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &result))
+	first := result["result"]
+
+	assert.Equal(t, http.StatusBadRequest, request(http.MethodPost, "/api/shorten", `{"url":"https://example.com/x","tags":["a b"]}`).Code)
+
+	second := mustCreate(t, d, &pb.CreateShortLinkRequest{OriginalURL: "https://example.com/b", UserID: "owner", Tags: []string{"news"}})
+	mustCreate(t, d, &pb.CreateShortLinkRequest{OriginalURL: "https://example.com/c", UserID: "owner"})
+
+	assert.Len(t, list(""), 3)
+
+	links := list("?tag=news&tag=docs")
+	require.Len(t, links, 1)
+	assert.Equal(t, first, links[0].ShortURL)
+	assert.Equal(t, []string{"docs", "news"}, links[0].Tags)
+	assert.Equal(t, "Work", links[0].Folder)
+
+	assert.Len(t, list("?tag=NEWS"), 2)
+	assert.Len(t, list("?folder=Work"), 1)
+	assert.Equal(t, http.StatusBadRequest, request(http.MethodGet, "/api/user/urls?tag=%21", "").Code)
+
+	w = request(http.MethodPatch, "/api/user/urls/"+strings.TrimPrefix(second, d.Host+"/"), `{"tags":[],"folder":"Work"}`)
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Len(t, list("?tag=news"), 1)
+	assert.Len(t, list("?folder=Work"), 2)
+
+	w = request(http.MethodGet, "/api/user/folders", "")
+	assert.JSONEq(t, `[{"name":"Work","links":2}]`, w.Body.String())
+
+	w = request(http.MethodPatch, "/api/user/tags/news", `{"name":"docs"}`)
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.JSONEq(t, `{"name":"docs","links":1}`, w.Body.String())
+
+	w = request(http.MethodGet, "/api/user/tags", "")
+	assert.JSONEq(t, `[{"name":"docs","links":1}]`, w.Body.String())
+
+	assert.Equal(t, http.StatusNotFound, request(http.MethodPatch, "/api/user/tags/news", `{"name":"other"}`).Code)
+	assert.Equal(t, http.StatusBadRequest, request(http.MethodPatch, "/api/user/tags/docs", `{"name":""}`).Code)
+
+	assert.Equal(t, http.StatusNoContent, request(http.MethodDelete, "/api/user/tags/docs", "").Code)
+	assert.Equal(t, http.StatusNotFound, request(http.MethodDelete, "/api/user/tags/docs", "").Code)
+	assert.Len(t, list(""), 3)
+
+	response, err := d.GetAllShorterURLs(context.Background(), &pb.GetAllShorterURLsRequest{UserID: "owner", Folder: "Work"})
+	require.NoError(t, err)
+	assert.Len(t, response.ShortURLs, 2)
+
+	_, err = d.GetAllShorterURLs(context.Background(), &pb.GetAllShorterURLsRequest{UserID: "owner", Tags: []string{"a b"}})
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+}
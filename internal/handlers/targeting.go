@@ -0,0 +1,204 @@
+package handlers
+
+import (
+	"errors"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/vladimirimekov/url-shortener/internal/analytics"
+	"github.com/vladimirimekov/url-shortener/internal/geoip"
+	"github.com/vladimirimekov/url-shortener/internal/realip"
+	"github.com/vladimirimekov/url-shortener/internal/storage"
+	"github.com/vladimirimekov/url-shortener/internal/useragent"
+	pb "github.com/vladimirimekov/url-shortener/proto"
+)
+
+// Ограничения правил таргетинга.
+const (
+	maxTargetRules    = 20
+	maxTargetRuleName = 64
+)
+
+// Ошибки проверки правил таргетинга.
+var (
+	errTooManyRules    = errors.New("too many targeting rules")
+	errInvalidRule     = errors.New("targeting rule must have a valid url and at least one of os, device, browser or countries")
+	errInvalidDevice   = errors.New("targeting rule device must be desktop, mobile or tablet")
+	errInvalidCountry  = errors.New("targeting rule countries must be ISO 3166-1 alpha-2 codes")
+	errInvalidRuleName = errors.New("targeting rule names must be unique, not longer than 64 characters and not equal to \"default\"")
+)
+
+// TargetRuleData содержит структуру для json данных с правилом таргетинга.
+type TargetRuleData struct {
+	Name      string   `json:"name,omitempty"`
+	OS        string   `json:"os,omitempty"`
+	Device    string   `json:"device,omitempty"`
+	Browser   string   `json:"browser,omitempty"`
+	Countries []string `json:"countries,omitempty"`
+	URL       string   `json:"url"`
+}
+
+// checkRules проверяет правила таргетинга и возвращает их в виде для сохранения.
+// Правилам без названия присваивается название по их номеру в списке.
+func checkRules(rules []TargetRuleData) ([]storage.TargetRule, error) {
+	if len(rules) > maxTargetRules {
+		return nil, errTooManyRules
+	}
+
+	result := make([]storage.TargetRule, 0, len(rules))
+	names := map[string]bool{}
+
+	for i, v := range rules {
+		rule := storage.TargetRule{
+			Name:    strings.TrimSpace(v.Name),
+			OS:      strings.TrimSpace(v.OS),
+			Device:  strings.ToLower(strings.TrimSpace(v.Device)),
+			Browser: strings.TrimSpace(v.Browser),
+			URL:     strings.TrimSpace(v.URL),
+		}
+
+		if _, err := url.ParseRequestURI(rule.URL); err != nil {
+			return nil, errInvalidRule
+		}
+		for _, code := range v.Countries {
+			country, ok := geoip.NormalizeCountry(code)
+			if !ok {
+				return nil, errInvalidCountry
+			}
+			rule.Countries = append(rule.Countries, country)
+		}
+
+		if rule.OS == "" && rule.Device == "" && rule.Browser == "" && len(rule.Countries) == 0 {
+			return nil, errInvalidRule
+		}
+
+		switch rule.Device {
+		case "", useragent.DeviceDesktop, useragent.DeviceMobile, useragent.DeviceTablet:
+		default:
+			return nil, errInvalidDevice
+		}
+
+		if rule.Name == "" {
+			rule.Name = "rule-" + strconv.Itoa(i+1)
+		}
+		if len(rule.Name) > maxTargetRuleName || rule.Name == analytics.DefaultRule || names[rule.Name] {
+			return nil, errInvalidRuleName
+		}
+		names[rule.Name] = true
+
+		result = append(result, rule)
+	}
+
+	return result, nil
+}
+
+// matches проверяет, подходит ли правило для устройства и страны посетителя. Значения сравниваются без учёта регистра.
+// Правило со списком стран не подходит посетителю, страну которого определить не удалось.
+func matches(rule storage.TargetRule, agent useragent.Agent, country string) bool {
+	return (rule.OS == "" || strings.EqualFold(rule.OS, agent.OS)) &&
+		(rule.Device == "" || strings.EqualFold(rule.Device, agent.Device)) &&
+		(rule.Browser == "" || strings.EqualFold(rule.Browser, agent.Browser)) &&
+		(len(rule.Countries) == 0 || containsCountry(rule.Countries, country))
+}
+
+// containsCountry проверяет, входит ли страна в список стран правила.
+func containsCountry(countries []string, country string) bool {
+	if country == "" {
+		return false
+	}
+
+	for _, v := range countries {
+		if v == country {
+			return true
+		}
+	}
+
+	return false
+}
+
+// redirectTarget описывает выбранный для посетителя адрес перенаправления.
+// Rule содержит название сработавшего правила таргетинга, Variant - название варианта A/B теста.
+type redirectTarget struct {
+	URL     string
+	Rule    string
+	Variant string
+}
+
+// destination выбирает адрес перенаправления для посетителя с IP-адресом ip и заголовком User-Agent userAgent.
+// Правила таргетинга проверяются по порядку, если ни одно не подошло, выбирается вариант A/B теста
+// с учётом ранее назначенного варианта sticky, а при отсутствии вариантов - оригинальный URL.
+func (h Handler) destination(link storage.Link, userAgent string, ip net.IP, sticky string) redirectTarget {
+	if len(link.Rules) > 0 {
+		agent := useragent.Parse(userAgent)
+		country := h.Geo.Country(ip)
+
+		for _, rule := range link.Rules {
+			if matches(rule, agent, country) {
+				return redirectTarget{URL: rule.URL, Rule: rule.Name}
+			}
+		}
+	}
+
+	if variant, ok := pickVariant(link.Variants, sticky); ok {
+		return redirectTarget{URL: variant.URL, Variant: variant.Name}
+	}
+
+	return redirectTarget{URL: link.OriginalURL}
+}
+
+// requestDestination выбирает адрес перенаправления для посетителя, отправившего запрос,
+// и запоминает в cookie назначенный ему вариант A/B теста.
+func (h Handler) requestDestination(w http.ResponseWriter, r *http.Request, link storage.Link) redirectTarget {
+	sticky := stickyVariant(r, link.ShortURL)
+
+	target := h.destination(link, r.UserAgent(), realip.FromRequest(r), sticky)
+	if target.Variant != "" && target.Variant != sticky {
+		setStickyVariant(w, link.ShortURL, target.Variant)
+	}
+
+	return target
+}
+
+// targetRulesData возвращает json представление правил таргетинга.
+func targetRulesData(rules []storage.TargetRule) []TargetRuleData {
+	if len(rules) == 0 {
+		return nil
+	}
+
+	result := make([]TargetRuleData, 0, len(rules))
+
+	for _, v := range rules {
+		result = append(result, TargetRuleData(v))
+	}
+
+	return result
+}
+
+// targetRulesFromProto возвращает правила таргетинга из grpc запроса.
+func targetRulesFromProto(rules []*pb.TargetRule) []TargetRuleData {
+	if len(rules) == 0 {
+		return nil
+	}
+
+	result := make([]TargetRuleData, 0, len(rules))
+
+	for _, v := range rules {
+		result = append(result, TargetRuleData{Name: v.Name, OS: v.Os, Device: v.Device, Browser: v.Browser, Countries: v.Countries, URL: v.Url})
+	}
+
+	return result
+}
+
+// targetRulesProto возвращает grpc представление правил таргетинга.
+func targetRulesProto(rules []storage.TargetRule) []*pb.TargetRule {
+	result := make([]*pb.TargetRule, 0, len(rules))
+
+	for _, v := range rules {
+		result = append(result, &pb.TargetRule{Name: v.Name, Os: v.OS, Device: v.Device, Browser: v.Browser, Countries: v.Countries, Url: v.URL})
+	}
+
+	return result
+}
@@ -0,0 +1,205 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/vladimirimekov/url-shortener/internal/clicks"
+	"github.com/vladimirimekov/url-shortener/internal/geoip"
+	"github.com/vladimirimekov/url-shortener/internal/storage"
+	pb "github.com/vladimirimekov/url-shortener/proto"
+)
+
+const (
+	uaIPhone  = "Mozilla/5.0 (iPhone; CPU iPhone OS 17_1 like Mac OS X) AppleWebKit/605.1.15 (KHTML, like Gecko) Version/17.1 Mobile/15E148 Safari/604.1"
+	uaAndroid = "Mozilla/5.0 (Linux; Android 14; Pixel 8) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/120.0.0.0 Mobile Safari/537.36"
+	uaWindows = "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/120.0.0.0 Safari/537.36"
+)
+
+// TestCheckRules проверяет валидацию правил таргетинга.
+func TestCheckRules(t *testing.T) {
+	tests := []struct {
+		name    string
+		rules   []TargetRuleData
+		wantErr error
+		want    []storage.TargetRule
+	}{
+		{
+			name:  "default names",
+			rules: []TargetRuleData{{OS: "iOS", URL: "https://apps.apple.com/app"}, {Device: " Mobile ", URL: "https://m.example.com"}},
+			want: []storage.TargetRule{
+				{Name: "rule-1", OS: "iOS", URL: "https://apps.apple.com/app"},
+				{Name: "rule-2", Device: "mobile", URL: "https://m.example.com"},
+			},
+		},
+		{name: "no conditions", rules: []TargetRuleData{{URL: "https://example.com"}}, wantErr: errInvalidRule},
+		{name: "invalid url", rules: []TargetRuleData{{OS: "iOS", URL: "example"}}, wantErr: errInvalidRule},
+		{name: "countries only", rules: []TargetRuleData{{Countries: []string{"de", " AT"}, URL: "https://example.de"}}, want: []storage.TargetRule{{Name: "rule-1", Countries: []string{"DE", "AT"}, URL: "https://example.de"}}},
+		{name: "invalid country", rules: []TargetRuleData{{Countries: []string{"DEU"}, URL: "https://example.de"}}, wantErr: errInvalidCountry},
+		{name: "unknown device", rules: []TargetRuleData{{Device: "watch", URL: "https://example.com"}}, wantErr: errInvalidDevice},
+		{name: "reserved name", rules: []TargetRuleData{{Name: "default", OS: "iOS", URL: "https://example.com"}}, wantErr: errInvalidRuleName},
+		{
+			name:    "duplicate names",
+			rules:   []TargetRuleData{{Name: "app", OS: "iOS", URL: "https://example.com"}, {Name: "app", OS: "Android", URL: "https://example.com"}},
+			wantErr: errInvalidRuleName,
+		},
+		{name: "too many rules", rules: make([]TargetRuleData, maxTargetRules+1), wantErr: errTooManyRules},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rules, err := checkRules(tt.rules)
+			if tt.wantErr != nil {
+				assert.ErrorIs(t, err, tt.wantErr)
+				return
+			}
+
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, rules)
+		})
+	}
+}
+
+// TestHandler_TargetRules проверяет выбор адреса перенаправления по правилам таргетинга и запись сработавшего правила.
+func TestHandler_TargetRules(t *testing.T) {
+	s := storage.NewMemoryWork(map[string]map[string]string{})
+	recorder := clicks.New(s, []byte("salt"), 10, 10, time.Second, nil)
+	go recorder.Run(context.Background())
+
+	d := Handler{Storage: s, Host: "http://localhost:8080", UserKey: userKey, LengthOfShortname: 8, Clicks: recorder}
+
+	h := chi.NewRouter()
+	h.Post("/api/shorten", d.PostShortenHandler)
+	h.Get("/{id}", d.MainHandler)
+
+	body := `{"url":"https://example.com","rules":[
+		{"name":"ios","os":"iOS","url":"https://apps.apple.com/app"},
+		{"device":"mobile","url":"https://m.example.com"}
+	]}`
+
+	w := httptest.NewRecorder()
+	request := httptest.NewRequest(http.MethodPost, "/api/shorten", strings.NewReader(body))
+	h.ServeHTTP(w, request.WithContext(context.WithValue(request.Context(), userKey, "owner")))
+	require.Equal(t, http.StatusCreated, w.Code)
+
+	var created map[string]string
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &created))
+	shortname := strings.TrimPrefix(created["result"], d.Host+"/")
+
+	tests := []struct {
+		name         string
+		userAgent    string
+		wantLocation string
+	}{
+		{name: "first matching rule", userAgent: uaIPhone, wantLocation: "https://apps.apple.com/app"},
+		{name: "second rule", userAgent: uaAndroid, wantLocation: "https://m.example.com"},
+		{name: "default destination", userAgent: uaWindows, wantLocation: "https://example.com"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			request := httptest.NewRequest(http.MethodGet, "/"+shortname, nil)
+			request.Header.Set("User-Agent", tt.userAgent)
+
+			w := httptest.NewRecorder()
+			h.ServeHTTP(w, request)
+
+			assert.Equal(t, http.StatusTemporaryRedirect, w.Code)
+			assert.Equal(t, tt.wantLocation, w.Header().Get("Location"))
+		})
+	}
+
+	recorder.Close()
+
+	saved, err := s.GetClicks(context.Background(), []string{shortname}, time.Time{}, time.Now().Add(time.Minute))
+	require.NoError(t, err)
+
+	rules := make([]string, 0, len(saved))
+	for _, c := range saved {
+		rules = append(rules, c.Rule)
+	}
+	assert.Equal(t, []string{"ios", "rule-2", ""}, rules)
+
+	response, err := d.GetOriginalLink(context.Background(), &pb.GetOriginalLinkRequest{ShortURL: shortname, UserAgent: uaIPhone})
+	require.NoError(t, err)
+	assert.Equal(t, "https://apps.apple.com/app", response.OriginalURL)
+
+	_, err = d.UpdateLink(context.Background(), &pb.UpdateLinkRequest{ShortURL: shortname, UserID: "owner", ClearRules: true})
+	require.NoError(t, err)
+
+	response, err = d.GetOriginalLink(context.Background(), &pb.GetOriginalLinkRequest{ShortURL: shortname, UserAgent: uaIPhone})
+	require.NoError(t, err)
+	assert.Equal(t, "https://example.com", response.OriginalURL)
+
+	_, err = d.CreateShortLink(context.Background(), &pb.CreateShortLinkRequest{
+		OriginalURL: "https://example.org",
+		UserID:      "owner",
+		Rules:       []*pb.TargetRule{{Url: "https://example.org/any"}},
+	})
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+}
+
+// TestHandler_GeoRules проверяет выбор адреса перенаправления по стране, определённой по IP-адресу клиента.
+func TestHandler_GeoRules(t *testing.T) {
+	ranges, err := geoip.ParseRanges(strings.NewReader("5.0.0.0,5.0.255.255,DE\n7.0.0.0,7.0.255.255,FR\n"))
+	require.NoError(t, err)
+
+	d := Handler{Storage: storage.NewMemoryWork(map[string]map[string]string{}), Host: "http://localhost:8080", Geo: geoip.New()}
+	d.Geo.SetRanges(ranges)
+
+	response, err := d.CreateShortLink(context.Background(), &pb.CreateShortLinkRequest{
+		OriginalURL: "https://example.com",
+		UserID:      "owner",
+		Rules: []*pb.TargetRule{
+			{Name: "dach", Countries: []string{"DE", "AT", "CH"}, Url: "https://example.com/de"},
+			{Name: "fr", Countries: []string{"fr"}, Url: "https://example.com/fr"},
+		},
+	})
+	require.NoError(t, err)
+	shortname := strings.TrimPrefix(response.ShortURL, d.Host+"/")
+
+	h := chi.NewRouter()
+	h.Get("/{id}", d.MainHandler)
+
+	tests := []struct {
+		name         string
+		header       http.Header
+		remoteAddr   string
+		wantLocation string
+	}{
+		{name: "real ip header", header: http.Header{"X-Real-Ip": {"5.0.1.1"}}, wantLocation: "https://example.com/de"},
+		{name: "forwarded for", header: http.Header{"X-Forwarded-For": {"7.0.0.1, 5.0.1.1"}}, wantLocation: "https://example.com/fr"},
+		{name: "remote address", remoteAddr: "7.0.3.4:1234", wantLocation: "https://example.com/fr"},
+		{name: "unknown country", remoteAddr: "192.0.2.1:1234", wantLocation: "https://example.com"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			request := httptest.NewRequest(http.MethodGet, "/"+shortname, nil)
+			for key, values := range tt.header {
+				request.Header[key] = values
+			}
+			if tt.remoteAddr != "" {
+				request.RemoteAddr = tt.remoteAddr
+			}
+
+			w := httptest.NewRecorder()
+			h.ServeHTTP(w, request)
+
+			assert.Equal(t, http.StatusTemporaryRedirect, w.Code)
+			assert.Equal(t, tt.wantLocation, w.Header().Get("Location"))
+		})
+	}
+
+	original, err := d.GetOriginalLink(context.Background(), &pb.GetOriginalLinkRequest{ShortURL: shortname, ClientIP: "5.0.0.1"})
+	require.NoError(t, err)
+	assert.Equal(t, "https://example.com/de", original.OriginalURL)
+}
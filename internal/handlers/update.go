@@ -0,0 +1,446 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	"github.com/vladimirimekov/url-shortener/internal/storage"
+	"github.com/vladimirimekov/url-shortener/internal/webhooks"
+	pb "github.com/vladimirimekov/url-shortener/proto"
+)
+
+// LinkData содержит структуру для json данных с информацией о ссылке.
+type LinkData struct {
+	ShortURL          string           `json:"short_url"`
+	OriginalURL       string           `json:"original_url"`
+	Version           int64            `json:"version"`
+	CreatedAt         time.Time        `json:"created_at"`
+	UpdatedAt         time.Time        `json:"updated_at"`
+	PasswordProtected bool             `json:"password_protected"`
+	MaxClicks         int64            `json:"max_clicks,omitempty"`
+	RemainingClicks   *int64           `json:"remaining_clicks,omitempty"`
+	NotBefore         *time.Time       `json:"not_before,omitempty"`
+	NotAfter          *time.Time       `json:"not_after,omitempty"`
+	Rules             []TargetRuleData `json:"rules,omitempty"`
+	Variants          []VariantData    `json:"variants,omitempty"`
+	RedirectStatus    int              `json:"redirect_status"`
+	CacheControl      string           `json:"cache_control,omitempty"`
+	MetaRefresh       bool             `json:"meta_refresh"`
+	Passthrough       bool             `json:"passthrough"`
+	Campaign          string           `json:"campaign,omitempty"`
+	Tags              []string         `json:"tags,omitempty"`
+	Folder            string           `json:"folder,omitempty"`
+	Title             string           `json:"title,omitempty"`
+	Metadata          *MetadataData    `json:"metadata,omitempty"`
+}
+
+// UpdateData содержит структуру для получения json данных с изменениями ссылки.
+// Незаполненные поля не изменяются.
+type UpdateData struct {
+	OriginalURL *string `json:"original_url,omitempty"`
+
+	//NotBefore и NotAfter задают окно действия ссылки в формате RFC3339, пустая строка снимает границу.
+	NotBefore *string `json:"not_before,omitempty"`
+	NotAfter  *string `json:"not_after,omitempty"`
+
+	//Rules и Variants заменяют правила таргетинга и варианты A/B теста, пустой список удаляет их.
+	Rules    *[]TargetRuleData `json:"rules,omitempty"`
+	Variants *[]VariantData    `json:"variants,omitempty"`
+
+	//Нулевой RedirectStatus и пустой CacheControl возвращают значения по умолчанию.
+	RedirectStatus *int    `json:"redirect_status,omitempty"`
+	CacheControl   *string `json:"cache_control,omitempty"`
+	MetaRefresh    *bool   `json:"meta_refresh,omitempty"`
+	Passthrough    *bool   `json:"passthrough,omitempty"`
+
+	//Пустой Campaign исключает ссылку из кампании.
+	Campaign *string `json:"campaign,omitempty"`
+
+	//Пустой список Tags удаляет теги ссылки, пустой Folder убирает ссылку из папки, пустой Title удаляет название.
+	Tags   *[]string `json:"tags,omitempty"`
+	Folder *string   `json:"folder,omitempty"`
+	Title  *string   `json:"title,omitempty"`
+
+	//Version используется для оптимистичной блокировки, если не передан заголовок If-Match.
+	Version int64 `json:"version,omitempty"`
+}
+
+// Ошибки проверки запроса на изменение ссылки.
+var (
+	errNothingToUpdate = errors.New("nothing to update")
+	errInvalidURL      = errors.New("invalid URL value")
+	errInvalidTime     = errors.New("not_before and not_after must be in RFC3339 format")
+)
+
+// linkData возвращает json представление ссылки.
+func (h Handler) linkData(link storage.Link) LinkData {
+	data := LinkData{
+		ShortURL:          h.Host + "/" + link.ShortURL,
+		OriginalURL:       link.OriginalURL,
+		Version:           link.Version,
+		CreatedAt:         link.CreatedAt,
+		UpdatedAt:         link.UpdatedAt,
+		PasswordProtected: link.PasswordHash != "",
+		MaxClicks:         link.MaxClicks,
+		Rules:             targetRulesData(link.Rules),
+		Variants:          variantsData(link.Variants),
+		RedirectStatus:    redirectStatus(link),
+		CacheControl:      link.CacheControl,
+		MetaRefresh:       link.MetaRefresh,
+		Passthrough:       link.Passthrough,
+		Campaign:          link.CampaignID,
+		Tags:              link.Tags,
+		Folder:            link.Folder,
+		Title:             link.Title,
+		Metadata:          metadataData(link.Metadata),
+	}
+
+	if link.MaxClicks > 0 {
+		remaining := link.RemainingClicks
+		data.RemainingClicks = &remaining
+	}
+	if !link.NotBefore.IsZero() {
+		data.NotBefore = &link.NotBefore
+	}
+	if !link.NotAfter.IsZero() {
+		data.NotAfter = &link.NotAfter
+	}
+
+	return data
+}
+
+// etag возвращает значение заголовка ETag для версии ссылки.
+func etag(version int64) string {
+	return `"` + strconv.FormatInt(version, 10) + `"`
+}
+
+// parseIfMatch возвращает версию ссылки из заголовка If-Match.
+func parseIfMatch(r *http.Request) (int64, error) {
+	value := strings.TrimSpace(r.Header.Get("If-Match"))
+	if value == "" || value == "*" {
+		return 0, nil
+	}
+
+	value = strings.Trim(strings.TrimPrefix(value, "W/"), `"`)
+
+	return strconv.ParseInt(value, 10, 64)
+}
+
+// GetUserURLHandler отправляет информацию о ссылке текущего пользователя вместе с её версией в заголовке ETag.
+func (h Handler) GetUserURLHandler(w http.ResponseWriter, r *http.Request) {
+
+	ctx, cancel := context.WithTimeout(r.Context(), 60*time.Second)
+	defer cancel()
+
+	userID, err := h.getUserID(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	link, err := h.Storage.GetLink(ctx, chi.URLParam(r, "short"))
+	if err == nil && link.UserID != userID {
+		err = storage.ErrNotOwner
+	}
+	if err != nil {
+		http.Error(w, err.Error(), linkErrorStatus(err))
+		return
+	}
+
+	w.Header().Set("ETag", etag(link.Version))
+	writeJSON(w, http.StatusOK, h.linkData(link))
+}
+
+// UpdateURLHandler изменяет ссылку текущего пользователя.
+// Для оптимистичной блокировки используется версия из заголовка If-Match либо из тела запроса.
+func (h Handler) UpdateURLHandler(w http.ResponseWriter, r *http.Request) {
+
+	ctx, cancel := context.WithTimeout(r.Context(), 60*time.Second)
+	defer cancel()
+
+	userID, err := h.getUserID(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	b, err := io.ReadAll(r.Body)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	defer r.Body.Close()
+
+	var u UpdateData
+
+	if err = json.Unmarshal(b, &u); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	version, err := parseIfMatch(r)
+	if err != nil {
+		http.Error(w, "Invalid If-Match value", http.StatusBadRequest)
+		return
+	}
+	if version == 0 {
+		version = u.Version
+	}
+
+	update, err := u.updateFunc()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if u.Campaign != nil {
+		if err = h.checkCampaign(ctx, userID, *u.Campaign); err != nil {
+			http.Error(w, err.Error(), linkErrorStatus(err))
+			return
+		}
+	}
+
+	link, err := h.Storage.UpdateLink(ctx, userID, chi.URLParam(r, "short"), version, update)
+	if err != nil {
+		http.Error(w, err.Error(), linkErrorStatus(err))
+		return
+	}
+
+	h.emit(ctx, userID, webhooks.EventLinkUpdated, link.ShortURL)
+	if u.OriginalURL != nil {
+		h.Metadata.Enqueue(link.ShortURL)
+	}
+
+	w.Header().Set("ETag", etag(link.Version))
+	writeJSON(w, http.StatusOK, h.linkData(link))
+}
+
+// updateFunc проверяет изменения и возвращает функцию для их применения к ссылке.
+func (u UpdateData) updateFunc() (storage.UpdateFunc, error) {
+	if u.OriginalURL == nil && u.NotBefore == nil && u.NotAfter == nil && u.Rules == nil && u.Variants == nil &&
+		u.RedirectStatus == nil && u.CacheControl == nil && u.MetaRefresh == nil && u.Passthrough == nil && u.Campaign == nil &&
+		u.Tags == nil && u.Folder == nil && u.Title == nil {
+		return nil, errNothingToUpdate
+	}
+
+	//проверка на валидность url
+	if u.OriginalURL != nil {
+		if _, err := url.ParseRequestURI(*u.OriginalURL); err != nil {
+			return nil, errInvalidURL
+		}
+	}
+
+	var (
+		notBefore, notAfter time.Time
+		rules               []storage.TargetRule
+		tags                []string
+		folder              string
+		title               string
+		variants            []storage.Variant
+		cacheControl        string
+		err                 error
+	)
+
+	if u.NotBefore != nil {
+		if notBefore, err = parseTime(*u.NotBefore); err != nil {
+			return nil, errInvalidTime
+		}
+	}
+	if u.NotAfter != nil {
+		if notAfter, err = parseTime(*u.NotAfter); err != nil {
+			return nil, errInvalidTime
+		}
+	}
+	if u.Rules != nil {
+		if rules, err = checkRules(*u.Rules); err != nil {
+			return nil, err
+		}
+	}
+	if u.Variants != nil {
+		if variants, err = checkVariants(*u.Variants); err != nil {
+			return nil, err
+		}
+	}
+	if u.RedirectStatus != nil {
+		if err = checkRedirectStatus(*u.RedirectStatus); err != nil {
+			return nil, err
+		}
+	}
+	if u.CacheControl != nil {
+		if cacheControl, err = normalizeCacheControl(*u.CacheControl); err != nil {
+			return nil, err
+		}
+	}
+	if u.Tags != nil {
+		if tags, err = checkTags(*u.Tags); err != nil {
+			return nil, err
+		}
+	}
+	if u.Folder != nil {
+		if folder, err = checkFolder(*u.Folder); err != nil {
+			return nil, err
+		}
+	}
+	if u.Title != nil {
+		if title, err = checkTitle(*u.Title); err != nil {
+			return nil, err
+		}
+	}
+
+	return func(link *storage.Link) error {
+		if link.IsDeleted {
+			return storage.ErrDeleted
+		}
+
+		if u.OriginalURL != nil {
+			if link.OriginalURL != *u.OriginalURL {
+				link.Metadata = storage.LinkMetadata{}
+			}
+			link.OriginalURL = *u.OriginalURL
+		}
+		if u.NotBefore != nil {
+			link.NotBefore = notBefore
+		}
+		if u.NotAfter != nil {
+			link.NotAfter = notAfter
+		}
+		if u.Rules != nil {
+			link.Rules = rules
+		}
+		if u.Variants != nil {
+			link.Variants = variants
+		}
+		if u.RedirectStatus != nil {
+			link.RedirectStatus = *u.RedirectStatus
+		}
+		if u.CacheControl != nil {
+			link.CacheControl = cacheControl
+		}
+		if u.MetaRefresh != nil {
+			link.MetaRefresh = *u.MetaRefresh
+		}
+		if u.Passthrough != nil {
+			link.Passthrough = *u.Passthrough
+		}
+		if u.Campaign != nil {
+			link.CampaignID = *u.Campaign
+		}
+		if u.Tags != nil {
+			link.Tags = tags
+		}
+		if u.Folder != nil {
+			link.Folder = folder
+		}
+		if u.Title != nil {
+			link.Title = title
+		}
+
+		//окно проверяется вместе с неизменёнными границами
+		return checkWindow(link.NotBefore, link.NotAfter)
+	}, nil
+}
+
+// windowBound возвращает изменение границы окна действия ссылки из grpc запроса.
+func windowBound(t *timestamppb.Timestamp, remove bool) *string {
+	if t == nil && !remove {
+		return nil
+	}
+
+	var value string
+	if !remove {
+		value = t.AsTime().Format(time.RFC3339Nano)
+	}
+
+	return &value
+}
+
+// timestamp возвращает grpc представление времени. Для нулевого времени возвращается nil.
+func timestamp(t time.Time) *timestamppb.Timestamp {
+	if t.IsZero() {
+		return nil
+	}
+
+	return timestamppb.New(t)
+}
+
+// UpdateLink изменяет ссылку текущего пользователя для grpc.
+// Поля запроса приводятся к UpdateData, пустой originalURL не изменяется.
+func (h Handler) UpdateLink(ctx context.Context, request *pb.UpdateLinkRequest) (*pb.UpdateLinkResponse, error) {
+	u := UpdateData{
+		NotBefore: windowBound(request.NotBefore, request.ClearNotBefore),
+		NotAfter:  windowBound(request.NotAfter, request.ClearNotAfter),
+	}
+	if request.OriginalURL != "" {
+		u.OriginalURL = &request.OriginalURL
+	}
+	if rules := targetRulesFromProto(request.Rules); rules != nil || request.ClearRules {
+		u.Rules = &rules
+	}
+	if variants := variantsFromProto(request.Variants); variants != nil || request.ClearVariants {
+		u.Variants = &variants
+	}
+	if request.RedirectStatus != nil {
+		code := int(*request.RedirectStatus)
+		u.RedirectStatus = &code
+	}
+	u.CacheControl = request.CacheControl
+	u.MetaRefresh = request.MetaRefresh
+	u.Passthrough = request.Passthrough
+	u.Campaign = request.Campaign
+	if len(request.Tags) > 0 || request.ClearTags {
+		u.Tags = &request.Tags
+	}
+	u.Folder = request.Folder
+	u.Title = request.Title
+
+	update, err := u.updateFunc()
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+
+	if u.Campaign != nil {
+		if err = h.checkCampaign(ctx, request.UserID, *u.Campaign); err != nil {
+			return nil, linkStatusError(err)
+		}
+	}
+
+	link, err := h.Storage.UpdateLink(ctx, request.UserID, request.ShortURL, request.Version, update)
+	if err != nil {
+		return nil, linkStatusError(err)
+	}
+
+	h.emit(ctx, request.UserID, webhooks.EventLinkUpdated, link.ShortURL)
+	if u.OriginalURL != nil {
+		h.Metadata.Enqueue(link.ShortURL)
+	}
+
+	return &pb.UpdateLinkResponse{
+		ShortURL:       h.Host + "/" + link.ShortURL,
+		OriginalURL:    link.OriginalURL,
+		Version:        link.Version,
+		NotBefore:      timestamp(link.NotBefore),
+		NotAfter:       timestamp(link.NotAfter),
+		Rules:          targetRulesProto(link.Rules),
+		Variants:       variantsProto(link.Variants),
+		RedirectStatus: int32(redirectStatus(link)),
+		CacheControl:   link.CacheControl,
+		MetaRefresh:    link.MetaRefresh,
+		Passthrough:    link.Passthrough,
+		Campaign:       link.CampaignID,
+		Tags:           link.Tags,
+		Folder:         link.Folder,
+		Title:          link.Title,
+	}, nil
+}
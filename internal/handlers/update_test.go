@@ -0,0 +1,66 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/vladimirimekov/url-shortener/internal/storage"
+)
+
+// TestHandler_UpdateURLHandler проверяет изменение оригинального URL ссылки.
+func TestHandler_UpdateURLHandler(t *testing.T) {
+	tests := []struct {
+		name       string
+		userID     string
+		shortname  string
+		body       string
+		ifMatch    string
+		wantStatus int
+		wantETag   string
+	}{
+		{name: "update with If-Match", userID: "owner", shortname: "abc", body: `{"original_url":"https://example.org"}`, ifMatch: `"1"`, wantStatus: http.StatusOK, wantETag: `"2"`},
+		{name: "update with version in body", userID: "owner", shortname: "abc", body: `{"original_url":"https://example.net","version":2}`, wantStatus: http.StatusOK, wantETag: `"3"`},
+		{name: "stale version", userID: "owner", shortname: "abc", body: `{"original_url":"https://example.com"}`, ifMatch: `"1"`, wantStatus: http.StatusPreconditionFailed},
+		{name: "another user", userID: "stranger", shortname: "abc", body: `{"original_url":"https://example.com"}`, wantStatus: http.StatusForbidden},
+		{name: "unknown link", userID: "owner", shortname: "nope", body: `{"original_url":"https://example.com"}`, wantStatus: http.StatusNotFound},
+		{name: "invalid url", userID: "owner", shortname: "abc", body: `{"original_url":"example"}`, wantStatus: http.StatusBadRequest},
+		{name: "nothing to update", userID: "owner", shortname: "abc", body: `{}`, wantStatus: http.StatusBadRequest},
+	}
+
+	s := storage.NewMemoryWork(map[string]map[string]string{})
+	require.NoError(t, s.SaveData(context.Background(), map[string]map[string]string{"owner": {"abc": "https://example.com"}}))
+
+	d := Handler{Storage: s, Host: "http://localhost:8080", UserKey: userKey}
+
+	h := chi.NewRouter()
+	h.Patch("/api/user/urls/{short}", d.UpdateURLHandler)
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+
+			request := httptest.NewRequest(http.MethodPatch, "/api/user/urls/"+tt.shortname, strings.NewReader(tt.body))
+			request = request.WithContext(context.WithValue(request.Context(), userKey, tt.userID))
+			if tt.ifMatch != "" {
+				request.Header.Set("If-Match", tt.ifMatch)
+			}
+
+			h.ServeHTTP(w, request)
+			result := w.Result()
+			defer result.Body.Close()
+
+			assert.Equal(t, tt.wantStatus, result.StatusCode)
+			assert.Equal(t, tt.wantETag, result.Header.Get("ETag"))
+		})
+	}
+
+	originalURL, _ := s.GetURLByShortname(context.Background(), "abc")
+	assert.Equal(t, "https://example.net", originalURL)
+}
@@ -0,0 +1,298 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+
+	"github.com/vladimirimekov/url-shortener/internal/storage"
+	"github.com/vladimirimekov/url-shortener/internal/webhooks"
+)
+
+// errWebhooksUnavailable возвращается, если отправка уведомлений не настроена.
+var errWebhooksUnavailable = errors.New("webhooks are not available")
+
+// WebhookData содержит структуру для json данных с информацией о вебхуке.
+// Секрет для проверки подписи возвращается только при регистрации вебхука.
+type WebhookData struct {
+	ID        string    `json:"id"`
+	URL       string    `json:"url"`
+	Events    []string  `json:"events"`
+	Secret    string    `json:"secret,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// CreateWebhookData содержит структуру для получения json данных с адресом вебхука и событиями, о которых нужно уведомлять.
+// Пустой список событий означает подписку на все события.
+type CreateWebhookData struct {
+	URL    string   `json:"url"`
+	Events []string `json:"events"`
+}
+
+// DeliveryData содержит структуру для json данных с информацией об уведомлении в очереди доставки.
+type DeliveryData struct {
+	ID          string          `json:"id"`
+	WebhookID   string          `json:"webhook_id"`
+	Event       string          `json:"event"`
+	Status      string          `json:"status"`
+	Attempts    int             `json:"attempts"`
+	NextAttempt time.Time       `json:"next_attempt"`
+	LastError   string          `json:"last_error,omitempty"`
+	CreatedAt   time.Time       `json:"created_at"`
+	Payload     json.RawMessage `json:"payload"`
+}
+
+// webhookData возвращает json представление вебхука.
+func webhookData(w storage.Webhook) WebhookData {
+	events := w.Events
+	if len(events) == 0 {
+		events = []string{webhooks.AllEvents}
+	}
+
+	return WebhookData{ID: w.ID, URL: w.URL, Events: events, CreatedAt: w.CreatedAt}
+}
+
+// deliveryData возвращает json представление уведомления.
+func deliveryData(d storage.Delivery) DeliveryData {
+	return DeliveryData{
+		ID:          d.ID,
+		WebhookID:   d.WebhookID,
+		Event:       d.Event,
+		Status:      d.Status,
+		Attempts:    d.Attempts,
+		NextAttempt: d.NextAttempt,
+		LastError:   d.LastError,
+		CreatedAt:   d.CreatedAt,
+		Payload:     d.Payload,
+	}
+}
+
+// emit ставит в очередь уведомления о событии со ссылкой. Ошибка постановки не прерывает обработку запроса.
+func (h Handler) emit(ctx context.Context, userID, event, shortname string) {
+	if h.Webhooks == nil {
+		return
+	}
+
+	link, err := h.Storage.GetLink(ctx, shortname)
+	if err != nil {
+		log.Print(err)
+		return
+	}
+
+	if err = h.Webhooks.Emit(ctx, userID, event, h.linkData(link)); err != nil {
+		log.Print(err)
+	}
+}
+
+// emitCreated ставит в очередь уведомления о созданных ссылках.
+func (h Handler) emitCreated(ctx context.Context, data map[string]map[string]string) {
+	for userID, links := range data {
+		for shortname := range links {
+			h.emit(ctx, userID, webhooks.EventLinkCreated, shortname)
+		}
+	}
+}
+
+// deleteLinks удаляет ссылки пользователя и ставит в очередь уведомления о тех, что действительно были удалены.
+func (h Handler) deleteLinks(ctx context.Context, shortnames []string, userID string) {
+	var active []string
+
+	if h.Webhooks != nil {
+		for _, shortname := range shortnames {
+			link, err := h.Storage.GetLink(ctx, shortname)
+			if err == nil && link.UserID == userID && !link.IsDeleted {
+				active = append(active, shortname)
+			}
+		}
+	}
+
+	h.Storage.DeleteData(shortnames, userID)
+
+	for _, shortname := range active {
+		h.emit(ctx, userID, webhooks.EventLinkDeleted, shortname)
+	}
+}
+
+// CreateWebhookHandler регистрирует вебхук текущего пользователя и возвращает секрет для проверки подписи уведомлений.
+func (h Handler) CreateWebhookHandler(w http.ResponseWriter, r *http.Request) {
+
+	ctx, cancel := context.WithTimeout(r.Context(), 60*time.Second)
+	defer cancel()
+
+	userID, err := h.getUserID(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	b, err := io.ReadAll(r.Body)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	defer r.Body.Close()
+
+	var c CreateWebhookData
+
+	if err = json.Unmarshal(b, &c); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if err = webhooks.Validate(c.URL, c.Events); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	secret, err := webhooks.NewSecret()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	webhook := storage.Webhook{
+		ID:        uuid.NewString(),
+		UserID:    userID,
+		URL:       c.URL,
+		Secret:    secret,
+		Events:    c.Events,
+		CreatedAt: time.Now().UTC(),
+	}
+
+	if err = h.Storage.SaveWebhook(ctx, webhook); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	result := webhookData(webhook)
+	result.Secret = secret
+
+	writeJSON(w, http.StatusCreated, result)
+}
+
+// GetWebhooksHandler отправляет список вебхуков текущего пользователя.
+func (h Handler) GetWebhooksHandler(w http.ResponseWriter, r *http.Request) {
+
+	ctx, cancel := context.WithTimeout(r.Context(), 60*time.Second)
+	defer cancel()
+
+	userID, err := h.getUserID(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	list, err := h.Storage.GetWebhooks(ctx, userID)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	result := make([]WebhookData, 0, len(list))
+
+	for _, v := range list {
+		result = append(result, webhookData(v))
+	}
+
+	writeJSON(w, http.StatusOK, result)
+}
+
+// DeleteWebhookHandler удаляет вебхук текущего пользователя вместе с его очередью доставки.
+func (h Handler) DeleteWebhookHandler(w http.ResponseWriter, r *http.Request) {
+
+	ctx, cancel := context.WithTimeout(r.Context(), 60*time.Second)
+	defer cancel()
+
+	userID, err := h.getUserID(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	if err = h.Storage.DeleteWebhook(ctx, userID, chi.URLParam(r, "id")); err != nil {
+		http.Error(w, err.Error(), linkErrorStatus(err))
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// GetDeliveriesHandler отправляет уведомления текущего пользователя с указанным в параметре status статусом.
+// По умолчанию отправляются недоставленные уведомления, исчерпавшие попытки доставки.
+func (h Handler) GetDeliveriesHandler(w http.ResponseWriter, r *http.Request) {
+
+	ctx, cancel := context.WithTimeout(r.Context(), 60*time.Second)
+	defer cancel()
+
+	userID, err := h.getUserID(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	status := r.URL.Query().Get("status")
+	switch status {
+	case "":
+		status = storage.DeliveryDead
+	case storage.DeliveryDead, storage.DeliveryPending:
+	default:
+		http.Error(w, "status must be dead or pending", http.StatusBadRequest)
+		return
+	}
+
+	list, err := h.Storage.GetDeliveries(ctx, userID, status)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	result := make([]DeliveryData, 0, len(list))
+
+	for _, v := range list {
+		result = append(result, deliveryData(v))
+	}
+
+	writeJSON(w, http.StatusOK, result)
+}
+
+// ReplayDeliveryHandler возвращает уведомление текущего пользователя в очередь для немедленной доставки.
+func (h Handler) ReplayDeliveryHandler(w http.ResponseWriter, r *http.Request) {
+
+	ctx, cancel := context.WithTimeout(r.Context(), 60*time.Second)
+	defer cancel()
+
+	userID, err := h.getUserID(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	if h.Webhooks == nil {
+		http.Error(w, errWebhooksUnavailable.Error(), http.StatusNotImplemented)
+		return
+	}
+
+	delivery, err := h.Storage.GetDelivery(ctx, chi.URLParam(r, "id"))
+	if err == nil && delivery.UserID != userID {
+		err = storage.ErrNotOwner
+	}
+	if err != nil {
+		http.Error(w, err.Error(), linkErrorStatus(err))
+		return
+	}
+
+	delivery, err = h.Webhooks.Replay(ctx, delivery)
+	if err != nil {
+		http.Error(w, err.Error(), linkErrorStatus(err))
+		return
+	}
+
+	writeJSON(w, http.StatusAccepted, deliveryData(delivery))
+}
@@ -0,0 +1,130 @@
+package keypool
+
+import (
+	"context"
+	"errors"
+	"log"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Store - интерфейс хранилища пула заранее сгенерированных имён.
+type Store interface {
+	AddPoolKeys(context.Context, []string) (int, error)
+	ClaimPoolKey(context.Context, string) (string, error)
+	ReclaimPoolKeys(context.Context, time.Time) (int, error)
+	CountPoolKeys(context.Context) (int, error)
+	HasPoolKey(context.Context, string) (bool, error)
+}
+
+// GenerateFunc генерирует n новых неиспользуемых имён.
+type GenerateFunc func(ctx context.Context, n int) ([]string, error)
+
+// Pool выдаёт заранее проверенные уникальные имена и поддерживает их запас в хранилище.
+type Pool struct {
+	store    Store
+	generate GenerateFunc
+	instance string
+
+	size     int
+	lowWater int
+	claimTTL time.Duration
+	interval time.Duration
+
+	wake chan struct{}
+}
+
+// New - конструктор Pool.
+func New(store Store, generate GenerateFunc, size, lowWater int, claimTTL, interval time.Duration) *Pool {
+	if lowWater > size {
+		lowWater = size
+	}
+
+	return &Pool{
+		store:    store,
+		generate: generate,
+		instance: uuid.NewString(),
+		size:     size,
+		lowWater: lowWater,
+		claimTTL: claimTTL,
+		interval: interval,
+		wake:     make(chan struct{}, 1),
+	}
+}
+
+// Take закрепляет за текущим экземпляром свободное имя из пула.
+// Имя удаляется из пула при сохранении ссылки, а незадействованное имя возвращается в пул после истечения claimTTL.
+func (p *Pool) Take(ctx context.Context) (string, error) {
+	key, err := p.store.ClaimPoolKey(ctx, p.instance)
+
+	//при каждой выдаче проверяем, не пора ли пополнить пул
+	select {
+	case p.wake <- struct{}{}:
+	default:
+	}
+
+	return key, err
+}
+
+// Contains проверяет, находится ли имя в пуле. Имена, сгенерированные в обход пула, не должны в нём находиться,
+// иначе пул позже выдаст уже занятое имя.
+func (p *Pool) Contains(ctx context.Context, key string) (bool, error) {
+	return p.store.HasPoolKey(ctx, key)
+}
+
+// Refill пополняет пул до size, если количество свободных имён опустилось ниже lowWater.
+func (p *Pool) Refill(ctx context.Context) (int, error) {
+	count, err := p.store.CountPoolKeys(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	if count >= p.lowWater {
+		return 0, nil
+	}
+
+	keys, err := p.generate(ctx, p.size-count)
+	if err != nil {
+		return 0, err
+	}
+
+	return p.store.AddPoolKeys(ctx, keys)
+}
+
+// Reclaim возвращает в пул имена, закреплённые дольше claimTTL назад, например упавшими экземплярами.
+func (p *Pool) Reclaim(ctx context.Context) (int, error) {
+	return p.store.ReclaimPoolKeys(ctx, time.Now().Add(-p.claimTTL))
+}
+
+// Run поддерживает запас имён в пуле до отмены контекста.
+func (p *Pool) Run(ctx context.Context) {
+	ticker := time.NewTicker(p.interval)
+	defer ticker.Stop()
+
+	p.maintain(ctx, true)
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			p.maintain(ctx, true)
+		case <-p.wake:
+			p.maintain(ctx, false)
+		}
+	}
+}
+
+// maintain пополняет пул и при необходимости возвращает в него зависшие имена.
+func (p *Pool) maintain(ctx context.Context, reclaim bool) {
+	if reclaim {
+		if _, err := p.Reclaim(ctx); err != nil {
+			log.Print(err)
+		}
+	}
+
+	if _, err := p.Refill(ctx); err != nil && !errors.Is(err, context.Canceled) {
+		log.Print(err)
+	}
+}
@@ -0,0 +1,90 @@
+package keypool
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/vladimirimekov/url-shortener/internal/storage"
+)
+
+// sequence возвращает GenerateFunc, выдающую последовательные имена.
+func sequence() GenerateFunc {
+	var counter int
+	return func(_ context.Context, n int) ([]string, error) {
+		result := make([]string, 0, n)
+		for i := 0; i < n; i++ {
+			counter++
+			result = append(result, fmt.Sprintf("key%d", counter))
+		}
+		return result, nil
+	}
+}
+
+// TestPool тестирует пополнение пула, выдачу имён и возврат зависших имён.
+func TestPool(t *testing.T) {
+	type store interface {
+		Store
+		SaveData(context.Context, map[string]map[string]string) error
+	}
+
+	tests := []struct {
+		name  string
+		store store
+		file  string
+	}{
+		{name: "memory", store: storage.NewMemoryWork(map[string]map[string]string{})},
+		{name: "file", store: storage.FileSystemConnect{Filename: "keypool_test.gob"}, file: "keypool_test.gob"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.file != "" {
+				defer os.Remove(tt.file)
+			}
+
+			ctx := context.Background()
+			p := New(tt.store, sequence(), 5, 2, time.Minute, time.Minute)
+
+			added, err := p.Refill(ctx)
+			require.NoError(t, err)
+			assert.Equal(t, 5, added)
+
+			//пул заполнен выше нижней границы
+			added, err = p.Refill(ctx)
+			require.NoError(t, err)
+			assert.Equal(t, 0, added)
+
+			taken := map[string]struct{}{}
+			for i := 0; i < 5; i++ {
+				key, err := p.Take(ctx)
+				require.NoError(t, err)
+				taken[key] = struct{}{}
+			}
+			assert.Len(t, taken, 5)
+
+			//сохранение ссылки расходует имя из пула
+			for key := range taken {
+				require.NoError(t, tt.store.SaveData(ctx, map[string]map[string]string{"user": {key: "https://example.com"}}))
+				break
+			}
+
+			_, err = p.Take(ctx)
+			assert.ErrorIs(t, err, storage.ErrPoolEmpty)
+
+			//имена, закреплённые упавшим экземпляром, возвращаются в пул
+			crashed := New(tt.store, sequence(), 5, 2, -time.Second, time.Minute)
+			reclaimed, err := crashed.Reclaim(ctx)
+			require.NoError(t, err)
+			assert.Equal(t, 4, reclaimed)
+
+			count, err := tt.store.CountPoolKeys(ctx)
+			require.NoError(t, err)
+			assert.Equal(t, 4, count)
+		})
+	}
+}
@@ -0,0 +1,302 @@
+package metadata
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"mime"
+	"net/http"
+	"net/url"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"golang.org/x/net/html/charset"
+
+	"github.com/vladimirimekov/url-shortener/internal/safehttp"
+	"github.com/vladimirimekov/url-shortener/internal/storage"
+)
+
+// userAgent - значение заголовка User-Agent запросов за страницами.
+const userAgent = "url-shortener-metadata/1.0"
+
+// maxErrorLength ограничивает длину сохраняемого текста ошибки загрузки.
+const maxErrorLength = 256
+
+// Ошибки загрузки страницы.
+var (
+	ErrNotHTTP = errors.New("destination is not an http or https url")
+	// errStale возвращается, если адрес перенаправления ссылки изменился во время загрузки.
+	errStale = errors.New("the link destination has changed")
+)
+
+// StatusError возвращается при ответе страницы с кодом, отличным от 2xx.
+type StatusError struct {
+	Code int
+}
+
+// Error возвращает текст ошибки.
+func (e StatusError) Error() string {
+	return fmt.Sprintf("unexpected status %d %s", e.Code, http.StatusText(e.Code))
+}
+
+// temporary сообщает, имеет ли смысл повторить запрос: при ошибках сети, таймаутах, кодах 429 и 5xx.
+func temporary(err error) bool {
+	var status StatusError
+	if errors.As(err, &status) {
+		return status.Code == http.StatusTooManyRequests || status.Code >= 500
+	}
+
+	return !errors.Is(err, ErrNotHTTP) && !errors.Is(err, safehttp.ErrForbiddenAddress)
+}
+
+// Store - интерфейс хранилища ссылок, в которые сохраняются сведения о страницах.
+type Store interface {
+	GetLink(context.Context, string) (storage.Link, error)
+	ConfigureLink(context.Context, string, storage.UpdateFunc) (storage.Link, error)
+}
+
+// job - задание на загрузку сведений о странице ссылки.
+type job struct {
+	shortname string
+	attempt   int
+}
+
+// Fetcher в фоне загружает страницы перенаправления созданных ссылок и сохраняет их сведения.
+// Каждая страница читается не дольше таймаута клиента и не больше maxBytes байт. При временных ошибках загрузка
+// повторяется до maxAttempts раз с задержкой, растущей от retryDelay. Одновременно к одному хосту выполняется
+// не больше perHost запросов. Адреса перенаправления задают пользователи, поэтому в работе используется клиент
+// из safehttp.NewClient, не соединяющийся с внутренними адресами.
+type Fetcher struct {
+	store  Store
+	client *http.Client
+
+	workers     int
+	maxBytes    int64
+	maxAttempts int
+	retryDelay  time.Duration
+	perHost     int
+
+	jobs    chan job
+	dropped uint64
+
+	mu    sync.Mutex
+	hosts map[string]*hostLimit
+}
+
+// hostLimit ограничивает количество одновременных запросов к хосту.
+type hostLimit struct {
+	slots chan struct{}
+	users int
+}
+
+// New - конструктор Fetcher.
+func New(store Store, client *http.Client, workers, queueSize int, maxBytes int64, maxAttempts int, retryDelay time.Duration, perHost int) *Fetcher {
+	if workers <= 0 {
+		workers = 1
+	}
+	if maxAttempts <= 0 {
+		maxAttempts = 1
+	}
+	if perHost <= 0 {
+		perHost = 1
+	}
+
+	return &Fetcher{
+		store:       store,
+		client:      client,
+		workers:     workers,
+		maxBytes:    maxBytes,
+		maxAttempts: maxAttempts,
+		retryDelay:  retryDelay,
+		perHost:     perHost,
+		jobs:        make(chan job, queueSize),
+		hosts:       map[string]*hostLimit{},
+	}
+}
+
+// Enqueue ставит ссылку в очередь на загрузку сведений о странице. Если очередь заполнена, ссылка отбрасывается,
+// чтобы не задерживать создание ссылки. Для nil Fetcher ничего не делает.
+func (f *Fetcher) Enqueue(shortname string) bool {
+	if f == nil {
+		return false
+	}
+
+	return f.enqueue(job{shortname: shortname, attempt: 1})
+}
+
+// enqueue ставит задание в очередь без блокировки.
+func (f *Fetcher) enqueue(j job) bool {
+	select {
+	case f.jobs <- j:
+		return true
+	default:
+		atomic.AddUint64(&f.dropped, 1)
+		return false
+	}
+}
+
+// Dropped возвращает количество отброшенных из-за переполнения очереди заданий.
+func (f *Fetcher) Dropped() uint64 {
+	return atomic.LoadUint64(&f.dropped)
+}
+
+// Run обрабатывает очередь в workers горутинах до отмены контекста.
+func (f *Fetcher) Run(ctx context.Context) {
+	var wg sync.WaitGroup
+
+	for i := 0; i < f.workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			for {
+				select {
+				case <-ctx.Done():
+					return
+				case j := <-f.jobs:
+					f.process(ctx, j)
+				}
+			}
+		}()
+	}
+
+	wg.Wait()
+}
+
+// Backoff возвращает задержку перед попыткой с номером attempt: retryDelay перед второй попыткой,
+// вдвое больше перед каждой следующей.
+func (f *Fetcher) Backoff(attempt int) time.Duration {
+	return f.retryDelay << (attempt - 2)
+}
+
+// process загружает сведения о странице ссылки и сохраняет их. При временной ошибке задание ставится в очередь
+// повторно после задержки, после последней попытки сохраняется текст ошибки.
+func (f *Fetcher) process(ctx context.Context, j job) {
+	link, err := f.store.GetLink(ctx, j.shortname)
+	if err != nil {
+		if !errors.Is(err, storage.ErrNotFound) && !errors.Is(err, context.Canceled) {
+			log.Print(err)
+		}
+		return
+	}
+
+	destination := link.OriginalURL
+
+	data, err := f.Fetch(ctx, destination)
+	if err != nil {
+		if ctx.Err() != nil {
+			return
+		}
+		if j.attempt < f.maxAttempts && temporary(err) {
+			next := job{shortname: j.shortname, attempt: j.attempt + 1}
+			time.AfterFunc(f.Backoff(next.attempt), func() {
+				if ctx.Err() == nil {
+					f.enqueue(next)
+				}
+			})
+			return
+		}
+
+		data.Error = err.Error()
+		if len(data.Error) > maxErrorLength {
+			data.Error = data.Error[:maxErrorLength]
+		}
+	}
+
+	data.FetchedAt = time.Now().UTC()
+
+	_, err = f.store.ConfigureLink(ctx, j.shortname, func(link *storage.Link) error {
+		if link.OriginalURL != destination {
+			return errStale
+		}
+		link.Metadata = data
+		return nil
+	})
+	if err != nil && !errors.Is(err, errStale) && !errors.Is(err, storage.ErrNotFound) && !errors.Is(err, context.Canceled) {
+		log.Print(err)
+	}
+}
+
+// Fetch загружает страницу по адресу rawURL и возвращает её сведения. Страницы, не являющиеся html,
+// возвращаются без сведений.
+func (f *Fetcher) Fetch(ctx context.Context, rawURL string) (storage.LinkMetadata, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return storage.LinkMetadata{}, ErrNotHTTP
+	}
+
+	release, err := f.acquire(ctx, u.Hostname())
+	if err != nil {
+		return storage.LinkMetadata{}, err
+	}
+	defer release()
+
+	request, err := http.NewRequestWithContext(ctx, http.MethodGet, u.String(), nil)
+	if err != nil {
+		return storage.LinkMetadata{}, err
+	}
+
+	request.Header.Set("User-Agent", userAgent)
+	request.Header.Set("Accept", "text/html,application/xhtml+xml;q=0.9,*/*;q=0.1")
+
+	response, err := f.client.Do(request)
+	if err != nil {
+		return storage.LinkMetadata{}, err
+	}
+	defer response.Body.Close()
+
+	if response.StatusCode < 200 || response.StatusCode >= 300 {
+		return storage.LinkMetadata{}, StatusError{Code: response.StatusCode}
+	}
+
+	contentType := response.Header.Get("Content-Type")
+	if mediaType, _, err := mime.ParseMediaType(contentType); err != nil || (mediaType != "text/html" && mediaType != "application/xhtml+xml") {
+		return storage.LinkMetadata{}, nil
+	}
+
+	body, err := charset.NewReader(io.LimitReader(response.Body, f.maxBytes), contentType)
+	if err != nil {
+		return storage.LinkMetadata{}, nil
+	}
+
+	//после перенаправлений относительные адреса разрешаются относительно адреса полученной страницы
+	return Parse(body, response.Request.URL), nil
+}
+
+// acquire занимает место в ограничении запросов к хосту и возвращает функцию его освобождения.
+func (f *Fetcher) acquire(ctx context.Context, host string) (func(), error) {
+	f.mu.Lock()
+	limit, ok := f.hosts[host]
+	if !ok {
+		limit = &hostLimit{slots: make(chan struct{}, f.perHost)}
+		f.hosts[host] = limit
+	}
+	limit.users++
+	f.mu.Unlock()
+
+	leave := func() {
+		f.mu.Lock()
+		defer f.mu.Unlock()
+
+		//ограничение удаляется, когда к хосту не осталось запросов, чтобы мапа не росла бесконечно
+		limit.users--
+		if limit.users == 0 {
+			delete(f.hosts, host)
+		}
+	}
+
+	select {
+	case limit.slots <- struct{}{}:
+	case <-ctx.Done():
+		leave()
+		return nil, ctx.Err()
+	}
+
+	return func() {
+		<-limit.slots
+		leave()
+	}, nil
+}
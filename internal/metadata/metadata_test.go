@@ -0,0 +1,295 @@
+package metadata
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/vladimirimekov/url-shortener/internal/safehttp"
+	"github.com/vladimirimekov/url-shortener/internal/storage"
+)
+
+// TestParse проверяет извлечение сведений из заголовка html страницы.
+func TestParse(t *testing.T) {
+	pageURL, err := url.Parse("https://example.com/blog/post?id=1")
+	require.NoError(t, err)
+
+	tests := []struct {
+		name string
+		html string
+		want storage.LinkMetadata
+	}{
+		{
+			name: "title and description",
+			html: `<html><head><title>
+				Hello   world </title><meta name="Description" content="About"></head></html>`,
+			want: storage.LinkMetadata{Title: "Hello world", Description: "About", Favicon: "https://example.com/favicon.ico"},
+		},
+		{
+			name: "open graph wins",
+			html: `<head><title>Plain</title><meta name="description" content="plain">
+				<meta property="og:title" content="Rich"><meta property="og:description" content="rich">
+				<meta property="og:image" content="/img/cover.png"><meta property="og:site_name" content="Example">
+				<link rel="shortcut icon" href="icons/fav.png"></head>`,
+			want: storage.LinkMetadata{
+				Title:       "Rich",
+				Description: "rich",
+				Image:       "https://example.com/img/cover.png",
+				SiteName:    "Example",
+				Favicon:     "https://example.com/blog/icons/fav.png",
+			},
+		},
+		{
+			name: "base href",
+			html: `<head><base href="https://cdn.example.net/assets/"><link rel="icon" href="fav.ico"></head>`,
+			want: storage.LinkMetadata{Favicon: "https://cdn.example.net/assets/fav.ico"},
+		},
+		{
+			name: "stops at body",
+			html: `<head></head><body><title>Inside body</title><meta property="og:title" content="late"></body>`,
+			want: storage.LinkMetadata{Favicon: "https://example.com/favicon.ico"},
+		},
+		{
+			name: "unsafe image",
+			html: `<meta property="og:image" content="javascript:alert(1)"><title>` + strings.Repeat("я", maxTitleLength+10) + `</title>`,
+			want: storage.LinkMetadata{Title: strings.Repeat("я", maxTitleLength), Favicon: "https://example.com/favicon.ico"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, Parse(strings.NewReader(tt.html), pageURL))
+		})
+	}
+}
+
+// TestFetcher_Fetch проверяет загрузку страниц с перенаправлением, ограничением размера, другой кодировкой и не html содержимым.
+func TestFetcher_Fetch(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/page", func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, userAgent, r.Header.Get("User-Agent"))
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		fmt.Fprint(w, `<head><title>Page</title><link rel="icon" href="/static/icon.png"></head>`)
+	})
+	mux.HandleFunc("/moved", func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, "/page", http.StatusFound)
+	})
+	mux.HandleFunc("/huge", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		fmt.Fprint(w, "<head><!--"+strings.Repeat("x", 4096)+"--><title>Too far</title></head>")
+	})
+	mux.HandleFunc("/latin1", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html; charset=iso-8859-1")
+		w.Write([]byte("<title>Caf\xe9</title>"))
+	})
+	mux.HandleFunc("/image", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "image/png")
+		w.Write([]byte{0x89, 'P', 'N', 'G'})
+	})
+	mux.HandleFunc("/missing", http.NotFound)
+
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	f := New(nil, server.Client(), 1, 1, 1024, 1, time.Millisecond, 1)
+
+	tests := []struct {
+		name    string
+		url     string
+		want    storage.LinkMetadata
+		wantErr error
+	}{
+		{name: "page", url: server.URL + "/page", want: storage.LinkMetadata{Title: "Page", Favicon: server.URL + "/static/icon.png"}},
+		{name: "redirect", url: server.URL + "/moved", want: storage.LinkMetadata{Title: "Page", Favicon: server.URL + "/static/icon.png"}},
+		{name: "size limit", url: server.URL + "/huge", want: storage.LinkMetadata{Favicon: server.URL + "/favicon.ico"}},
+		{name: "charset", url: server.URL + "/latin1", want: storage.LinkMetadata{Title: "Café", Favicon: server.URL + "/favicon.ico"}},
+		{name: "not html", url: server.URL + "/image"},
+		{name: "not found", url: server.URL + "/missing", wantErr: StatusError{Code: http.StatusNotFound}},
+		{name: "not http", url: "ftp://example.com/file", wantErr: ErrNotHTTP},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data, err := f.Fetch(context.Background(), tt.url)
+			if tt.wantErr != nil {
+				assert.ErrorIs(t, err, tt.wantErr)
+				return
+			}
+
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, data)
+		})
+	}
+}
+
+// waitMetadata ожидает, пока у ссылки появятся загруженные сведения о странице.
+func waitMetadata(t *testing.T, s storage.MemoryWork, shortname string) storage.LinkMetadata {
+	t.Helper()
+
+	var link storage.Link
+
+	require.Eventually(t, func() bool {
+		var err error
+		link, err = s.GetLink(context.Background(), shortname)
+		require.NoError(t, err)
+		return !link.Metadata.FetchedAt.IsZero()
+	}, 5*time.Second, 10*time.Millisecond)
+
+	return link.Metadata
+}
+
+// TestFetcher_Run проверяет сохранение сведений о странице с повторными попытками при временных ошибках.
+func TestFetcher_Run(t *testing.T) {
+	var requests int32
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/flaky", func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&requests, 1) < 3 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.Header().Set("Content-Type", "text/html")
+		fmt.Fprint(w, `<meta property="og:title" content="Finally">`)
+	})
+	mux.HandleFunc("/gone", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusGone)
+	})
+	mux.HandleFunc("/slow", func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-r.Context().Done():
+		case <-time.After(time.Second):
+		}
+	})
+
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	s := storage.NewMemoryWork(map[string]map[string]string{})
+	require.NoError(t, s.SaveData(context.Background(), map[string]map[string]string{
+		"owner": {"flaky": server.URL + "/flaky", "gone": server.URL + "/gone", "slow": server.URL + "/slow"},
+	}))
+
+	client := server.Client()
+	client.Timeout = 50 * time.Millisecond
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	f := New(s, client, 2, 10, 1024, 3, 10*time.Millisecond, 2)
+	go f.Run(ctx)
+
+	assert.False(t, (*Fetcher)(nil).Enqueue("flaky"))
+	for _, shortname := range []string{"flaky", "gone", "slow"} {
+		require.True(t, f.Enqueue(shortname))
+	}
+
+	data := waitMetadata(t, s, "flaky")
+	assert.Equal(t, "Finally", data.Title)
+	assert.Empty(t, data.Error)
+	assert.Equal(t, int32(3), atomic.LoadInt32(&requests))
+
+	data = waitMetadata(t, s, "gone")
+	assert.Equal(t, StatusError{Code: http.StatusGone}.Error(), data.Error)
+
+	data = waitMetadata(t, s, "slow")
+	assert.NotEmpty(t, data.Error)
+}
+
+// TestFetcher_InternalAddress проверяет, что клиент из safehttp не загружает страницы внутренних адресов
+// и такая ошибка сохраняется без повторных попыток.
+func TestFetcher_InternalAddress(t *testing.T) {
+	var requests int32
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&requests, 1)
+		w.Header().Set("Content-Type", "text/html")
+		fmt.Fprint(w, "<title>secret</title>")
+	}))
+	defer server.Close()
+
+	s := storage.NewMemoryWork(map[string]map[string]string{})
+	require.NoError(t, s.SaveData(context.Background(), map[string]map[string]string{"owner": {"internal": server.URL + "/admin"}}))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	f := New(s, safehttp.NewClient(time.Second), 1, 10, 1024, 3, time.Millisecond, 1)
+	go f.Run(ctx)
+
+	_, err := f.Fetch(ctx, server.URL)
+	assert.ErrorIs(t, err, safehttp.ErrForbiddenAddress)
+
+	require.True(t, f.Enqueue("internal"))
+
+	data := waitMetadata(t, s, "internal")
+	assert.Empty(t, data.Title)
+	assert.Contains(t, data.Error, safehttp.ErrForbiddenAddress.Error())
+	assert.Zero(t, atomic.LoadInt32(&requests))
+}
+
+// TestFetcher_HostLimit проверяет, что к одному хосту одновременно выполняется не больше заданного количества запросов.
+func TestFetcher_HostLimit(t *testing.T) {
+	var (
+		mu              sync.Mutex
+		active, highest int
+	)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		active++
+		if active > highest {
+			highest = active
+		}
+		mu.Unlock()
+
+		time.Sleep(20 * time.Millisecond)
+
+		mu.Lock()
+		active--
+		mu.Unlock()
+
+		w.Header().Set("Content-Type", "text/html")
+		fmt.Fprint(w, "<title>ok</title>")
+	}))
+	defer server.Close()
+
+	links := map[string]string{}
+	for i := 0; i < 10; i++ {
+		links[fmt.Sprintf("link%d", i)] = fmt.Sprintf("%s/%d", server.URL, i)
+	}
+
+	s := storage.NewMemoryWork(map[string]map[string]string{})
+	require.NoError(t, s.SaveData(context.Background(), map[string]map[string]string{"owner": links}))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	f := New(s, server.Client(), 8, len(links), 1024, 1, time.Millisecond, 2)
+	go f.Run(ctx)
+
+	for shortname := range links {
+		require.True(t, f.Enqueue(shortname))
+	}
+	for shortname := range links {
+		assert.Equal(t, "ok", waitMetadata(t, s, shortname).Title)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+
+	assert.LessOrEqual(t, highest, 2)
+	assert.Positive(t, highest)
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	assert.Empty(t, f.hosts)
+}
@@ -0,0 +1,187 @@
+package metadata
+
+import (
+	"io"
+	"net/url"
+	"strings"
+	"unicode/utf8"
+
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+
+	"github.com/vladimirimekov/url-shortener/internal/storage"
+)
+
+// Ограничения длины сохраняемых сведений о странице.
+const (
+	maxTitleLength       = 300
+	maxDescriptionLength = 1000
+	maxURLLength         = 2048
+)
+
+// page содержит сведения, найденные в заголовке html страницы.
+type page struct {
+	title, ogTitle             string
+	description, ogDescription string
+	image, siteName, icon      string
+	base                       string
+}
+
+// Parse читает заголовок html страницы и возвращает её сведения. Относительные адреса изображения и значка
+// разрешаются относительно адреса страницы pageURL или элемента <base>. Если значок не указан, используется /favicon.ico.
+// Чтение прекращается на теге <body> или в конце заголовка.
+func Parse(r io.Reader, pageURL *url.URL) storage.LinkMetadata {
+	var p page
+
+	z := html.NewTokenizer(r)
+
+	inTitle := false
+
+	for {
+		tt := z.Next()
+
+		switch tt {
+		case html.ErrorToken:
+			return p.metadata(pageURL)
+		case html.TextToken:
+			if inTitle {
+				p.title += string(z.Text())
+			}
+		case html.EndTagToken:
+			name, _ := z.TagName()
+			switch atom.Lookup(name) {
+			case atom.Title:
+				inTitle = false
+			case atom.Head:
+				return p.metadata(pageURL)
+			}
+		case html.StartTagToken, html.SelfClosingTagToken:
+			name, hasAttr := z.TagName()
+			tag := atom.Lookup(name)
+
+			if tag == atom.Body {
+				return p.metadata(pageURL)
+			}
+			if tag == atom.Title && tt == html.StartTagToken {
+				inTitle = p.title == ""
+				continue
+			}
+			if !hasAttr {
+				continue
+			}
+
+			p.attributes(tag, attributes(z))
+		}
+	}
+}
+
+// attributes возвращает атрибуты текущего тега. Имена атрибутов приводятся к нижнему регистру токенизатором.
+func attributes(z *html.Tokenizer) map[string]string {
+	result := map[string]string{}
+
+	for {
+		key, value, more := z.TagAttr()
+		if _, ok := result[string(key)]; !ok {
+			result[string(key)] = string(value)
+		}
+		if !more {
+			return result
+		}
+	}
+}
+
+// attributes запоминает сведения из тегов <meta>, <link> и <base>. Используется первое найденное значение каждого свойства.
+func (p *page) attributes(tag atom.Atom, attrs map[string]string) {
+	set := func(field *string, value string) {
+		if *field == "" {
+			*field = strings.TrimSpace(value)
+		}
+	}
+
+	switch tag {
+	case atom.Meta:
+		content := attrs["content"]
+
+		switch strings.ToLower(attrs["property"]) {
+		case "og:title":
+			set(&p.ogTitle, content)
+		case "og:description":
+			set(&p.ogDescription, content)
+		case "og:image", "og:image:url":
+			set(&p.image, content)
+		case "og:site_name":
+			set(&p.siteName, content)
+		}
+
+		if strings.EqualFold(attrs["name"], "description") {
+			set(&p.description, content)
+		}
+	case atom.Link:
+		for _, rel := range strings.Fields(strings.ToLower(attrs["rel"])) {
+			if rel == "icon" {
+				set(&p.icon, attrs["href"])
+			}
+		}
+	case atom.Base:
+		set(&p.base, attrs["href"])
+	}
+}
+
+// metadata возвращает найденные сведения. Свойства OpenGraph имеют приоритет перед <title> и <meta name="description">.
+func (p page) metadata(pageURL *url.URL) storage.LinkMetadata {
+	base := pageURL
+	if p.base != "" {
+		if u, err := pageURL.Parse(p.base); err == nil {
+			base = u
+		}
+	}
+
+	title, description := p.ogTitle, p.ogDescription
+	if title == "" {
+		title = p.title
+	}
+	if description == "" {
+		description = p.description
+	}
+
+	icon := resolve(base, p.icon)
+	if icon == "" {
+		icon = resolve(pageURL, "/favicon.ico")
+	}
+
+	return storage.LinkMetadata{
+		Title:       truncate(strings.Join(strings.Fields(title), " "), maxTitleLength),
+		Description: truncate(strings.Join(strings.Fields(description), " "), maxDescriptionLength),
+		Image:       resolve(base, p.image),
+		SiteName:    truncate(strings.TrimSpace(p.siteName), maxTitleLength),
+		Favicon:     icon,
+	}
+}
+
+// resolve возвращает абсолютный http(s) адрес ref относительно base. Адреса других схем и слишком длинные адреса отбрасываются.
+func resolve(base *url.URL, ref string) string {
+	if ref == "" {
+		return ""
+	}
+
+	u, err := base.Parse(ref)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return ""
+	}
+
+	result := u.String()
+	if len(result) > maxURLLength {
+		return ""
+	}
+
+	return result
+}
+
+// truncate обрезает строку до limit символов.
+func truncate(s string, limit int) string {
+	if utf8.RuneCountInString(s) <= limit {
+		return s
+	}
+
+	return string([]rune(s)[:limit])
+}
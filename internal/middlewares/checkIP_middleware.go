@@ -3,7 +3,8 @@ package middlewares
 import (
 	"net"
 	"net/http"
-	"strings"
+
+	"github.com/vladimirimekov/url-shortener/internal/realip"
 )
 
 // IPSubnet хранит данные для проверки подсети.
@@ -15,13 +16,7 @@ type IPSubnet struct {
 func (h IPSubnet) CheckIP(next http.Handler) http.Handler {
 
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		realIP := net.ParseIP(r.Header.Get("X-Real-IP"))
-		if realIP == nil {
-			ips := r.Header.Get("X-Forwarded-For")
-			ipStrs := strings.Split(ips, ",")
-			ipStr := ipStrs[0]
-			realIP = net.ParseIP(ipStr)
-		}
+		realIP := realip.FromHeaders(r.Header)
 
 		_, trustedSubnet, err := net.ParseCIDR(h.IP)
 		if h.IP == "" || err != nil || !trustedSubnet.Contains(realIP) {
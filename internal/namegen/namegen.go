@@ -0,0 +1,153 @@
+package namegen
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"math/rand"
+	"sync"
+)
+
+// ErrKeyspaceExhausted возвращается, если за допустимое число попыток не удалось подобрать свободное имя.
+var ErrKeyspaceExhausted = errors.New("unable to generate unique shortname: keyspace exhausted")
+
+const letters = "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789"
+
+// Параметры по умолчанию для генератора.
+const (
+	DefaultMaxAttempts        = 20
+	DefaultCollisionThreshold = 0.3
+	DefaultMaxLength          = 32
+	collisionWindow           = 100
+)
+
+// Generator генерирует короткие имена и следит за частотой коллизий.
+// При превышении порога частоты коллизий длина имени автоматически увеличивается.
+type Generator struct {
+	mu sync.Mutex
+
+	length             int
+	maxLength          int
+	maxAttempts        int
+	collisionThreshold float64
+
+	attempts   uint64
+	collisions uint64
+
+	windowAttempts   int
+	windowCollisions int
+}
+
+// Stats содержит текущее состояние генератора.
+type Stats struct {
+	Length        int
+	Attempts      uint64
+	Collisions    uint64
+	CollisionRate float64
+}
+
+// New - конструктор Generator.
+func New(length, maxLength, maxAttempts int, collisionThreshold float64) *Generator {
+	if length <= 0 {
+		length = 1
+	}
+	if maxLength < length {
+		maxLength = length
+	}
+	if maxAttempts <= 0 {
+		maxAttempts = DefaultMaxAttempts
+	}
+	if collisionThreshold <= 0 || collisionThreshold > 1 {
+		collisionThreshold = DefaultCollisionThreshold
+	}
+
+	return &Generator{
+		length:             length,
+		maxLength:          maxLength,
+		maxAttempts:        maxAttempts,
+		collisionThreshold: collisionThreshold,
+	}
+}
+
+// Next возвращает имя, для которого exists вернула false.
+// После maxAttempts неудачных попыток возвращается ErrKeyspaceExhausted.
+func (g *Generator) Next(exists func(string) bool) (string, error) {
+	for i := 0; i < g.maxAttempts; i++ {
+		candidate := g.candidate()
+
+		if !exists(candidate) {
+			g.record(false)
+			return candidate, nil
+		}
+
+		g.record(true)
+	}
+
+	return "", fmt.Errorf("%w after %d attempts (length %d)", ErrKeyspaceExhausted, g.maxAttempts, g.Length())
+}
+
+// Length возвращает текущую длину генерируемых имён.
+func (g *Generator) Length() int {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	return g.length
+}
+
+// Stats возвращает статистику генератора.
+func (g *Generator) Stats() Stats {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	var rate float64
+	if g.attempts > 0 {
+		rate = float64(g.collisions) / float64(g.attempts)
+	}
+
+	return Stats{Length: g.length, Attempts: g.attempts, Collisions: g.collisions, CollisionRate: rate}
+}
+
+// Capacity возвращает количество возможных имён заданной длины.
+func Capacity(length int) float64 {
+	return math.Pow(float64(len(letters)), float64(length))
+}
+
+// Utilisation возвращает долю занятого пространства имён текущей длины.
+func (g *Generator) Utilisation(used int) float64 {
+	return float64(used) / Capacity(g.Length())
+}
+
+// candidate генерирует случайное имя текущей длины.
+func (g *Generator) candidate() string {
+	s := make([]byte, g.Length())
+	for i := range s {
+		s[i] = letters[rand.Intn(len(letters))]
+	}
+
+	return string(s)
+}
+
+// record учитывает результат попытки и при необходимости увеличивает длину имени.
+func (g *Generator) record(collision bool) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	g.attempts++
+	g.windowAttempts++
+	if collision {
+		g.collisions++
+		g.windowCollisions++
+	}
+
+	//рост длины при превышении порога коллизий, хотя бы после нескольких попыток в окне
+	rate := float64(g.windowCollisions) / float64(g.windowAttempts)
+	if g.windowCollisions >= 3 && rate > g.collisionThreshold && g.length < g.maxLength {
+		g.length++
+		g.windowAttempts, g.windowCollisions = 0, 0
+		return
+	}
+
+	if g.windowAttempts >= collisionWindow {
+		g.windowAttempts, g.windowCollisions = 0, 0
+	}
+}
@@ -0,0 +1,76 @@
+package namegen
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestGenerator_Next проверяет генерацию имён, рост длины и ограничение количества попыток.
+func TestGenerator_Next(t *testing.T) {
+	tests := []struct {
+		name       string
+		length     int
+		maxLength  int
+		exists     func(string) bool
+		wantErr    error
+		wantLength int
+	}{
+		{
+			name:       "free keyspace",
+			length:     8,
+			maxLength:  8,
+			exists:     func(string) bool { return false },
+			wantErr:    nil,
+			wantLength: 8,
+		},
+		{
+			name:       "saturated short names grow",
+			length:     2,
+			maxLength:  8,
+			exists:     func(s string) bool { return len(s) < 4 },
+			wantErr:    nil,
+			wantLength: 4,
+		},
+		{
+			name:       "saturated keyspace without growth",
+			length:     2,
+			maxLength:  2,
+			exists:     func(string) bool { return true },
+			wantErr:    ErrKeyspaceExhausted,
+			wantLength: 2,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := New(tt.length, tt.maxLength, 20, 0.3)
+
+			got, err := g.Next(tt.exists)
+			if tt.wantErr != nil {
+				assert.True(t, errors.Is(err, tt.wantErr))
+				assert.Equal(t, tt.wantLength, g.Length())
+				return
+			}
+
+			require.NoError(t, err)
+			assert.Len(t, got, tt.wantLength)
+			assert.False(t, tt.exists(got))
+		})
+	}
+}
+
+// TestGenerator_Stats проверяет учёт коллизий и заполненности.
+func TestGenerator_Stats(t *testing.T) {
+	g := New(1, 1, 5, 0.3)
+
+	_, err := g.Next(func(string) bool { return true })
+	require.Error(t, err)
+
+	stats := g.Stats()
+	assert.Equal(t, uint64(5), stats.Attempts)
+	assert.Equal(t, uint64(5), stats.Collisions)
+	assert.Equal(t, 1.0, stats.CollisionRate)
+	assert.InDelta(t, 31.0/62.0, g.Utilisation(31), 1e-9)
+}
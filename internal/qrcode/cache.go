@@ -0,0 +1,79 @@
+package qrcode
+
+import (
+	"container/list"
+	"sync"
+)
+
+// Cache хранит последние отрисованные изображения и вытесняет давно не использованные.
+type Cache struct {
+	capacity int
+
+	mu      sync.Mutex
+	order   *list.List
+	entries map[string]*list.Element
+}
+
+// cacheEntry - элемент кэша.
+type cacheEntry struct {
+	key   string
+	value []byte
+}
+
+// NewCache возвращает кэш на capacity изображений. При нулевой ёмкости кэш ничего не хранит.
+func NewCache(capacity int) *Cache {
+	return &Cache{capacity: capacity, order: list.New(), entries: make(map[string]*list.Element)}
+}
+
+// Get возвращает изображение по ключу. Метод безопасен для nil кэша.
+func (c *Cache) Get(key string) ([]byte, bool) {
+	if c == nil {
+		return nil, false
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	e, ok := c.entries[key]
+	if !ok {
+		return nil, false
+	}
+	c.order.MoveToFront(e)
+
+	return e.Value.(*cacheEntry).value, true
+}
+
+// Put сохраняет изображение по ключу. Метод безопасен для nil кэша.
+func (c *Cache) Put(key string, value []byte) {
+	if c == nil || c.capacity <= 0 {
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if e, ok := c.entries[key]; ok {
+		e.Value.(*cacheEntry).value = value
+		c.order.MoveToFront(e)
+		return
+	}
+
+	c.entries[key] = c.order.PushFront(&cacheEntry{key: key, value: value})
+	for c.order.Len() > c.capacity {
+		e := c.order.Back()
+		c.order.Remove(e)
+		delete(c.entries, e.Value.(*cacheEntry).key)
+	}
+}
+
+// Len возвращает количество изображений в кэше.
+func (c *Cache) Len() int {
+	if c == nil {
+		return 0
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.order.Len()
+}
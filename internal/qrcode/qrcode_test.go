@@ -0,0 +1,304 @@
+package qrcode
+
+import (
+	"bytes"
+	"image/color"
+	"image/png"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestEncodeVersion проверяет выбор наименьшей версии, вмещающей данные.
+func TestEncodeVersion(t *testing.T) {
+	tests := []struct {
+		name        string
+		length      int
+		level       Level
+		wantVersion int
+		wantErr     error
+	}{
+		{name: "full version 1 low", length: 17, level: Low, wantVersion: 1},
+		{name: "overflow version 1 low", length: 18, level: Low, wantVersion: 2},
+		{name: "full version 1 high", length: 7, level: High, wantVersion: 1},
+		{name: "count field grows after version 9", length: 231, level: Low, wantVersion: 10},
+		{name: "full version 40 low", length: 2953, level: Low, wantVersion: 40},
+		{name: "too long", length: 2954, level: Low, wantErr: ErrTooLong},
+		{name: "unknown level", length: 1, level: Level(7), wantErr: ErrInvalidLevel},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, err := Encode(strings.Repeat("a", tt.length), tt.level)
+			if tt.wantErr != nil {
+				assert.ErrorIs(t, err, tt.wantErr)
+				return
+			}
+
+			require.NoError(t, err)
+			assert.Equal(t, tt.wantVersion, c.Version)
+			assert.Equal(t, tt.wantVersion*4+17, c.Size)
+		})
+	}
+}
+
+// TestEncodeStructure проверяет поисковые узоры, синхронизирующие полосы и обе копии информации о формате.
+func TestEncodeStructure(t *testing.T) {
+	for _, level := range []Level{Low, Medium, Quartile, High} {
+		c, err := Encode("https://example.com/abcdefgh", level)
+		require.NoError(t, err)
+
+		for _, corner := range [][2]int{{0, 0}, {c.Size - 7, 0}, {0, c.Size - 7}} {
+			for i := 0; i < 7; i++ {
+				assert.True(t, c.Black(corner[0]+i, corner[1]), "finder top edge")
+				assert.True(t, c.Black(corner[0], corner[1]+i), "finder left edge")
+			}
+			assert.False(t, c.Black(corner[0]+1, corner[1]+1), "finder ring")
+			assert.True(t, c.Black(corner[0]+3, corner[1]+3), "finder center")
+		}
+
+		for i := 8; i < c.Size-8; i++ {
+			assert.Equal(t, i%2 == 0, c.Black(i, 6), "horizontal timing")
+			assert.Equal(t, i%2 == 0, c.Black(6, i), "vertical timing")
+		}
+
+		//первая копия читается вокруг верхнего левого поискового узора, вторая - у двух других
+		var first, second int
+		for i, p := range [][2]int{{8, 0}, {8, 1}, {8, 2}, {8, 3}, {8, 4}, {8, 5}, {8, 7}, {8, 8}, {7, 8}, {5, 8}, {4, 8}, {3, 8}, {2, 8}, {1, 8}, {0, 8}} {
+			if c.Black(p[0], p[1]) {
+				first |= 1 << uint(i)
+			}
+		}
+		for i := 0; i < 15; i++ {
+			x, y := c.Size-1-i, 8
+			if i >= 8 {
+				x, y = 8, c.Size-15+i
+			}
+			if c.Black(x, y) {
+				second |= 1 << uint(i)
+			}
+		}
+		assert.Equal(t, first, second)
+
+		format := (first ^ 0x5412) >> 10
+		assert.Equal(t, formatBits[level], format>>3)
+		assert.Equal(t, c.Mask, format&7)
+		assert.True(t, c.Black(8, c.Size-8), "dark module")
+	}
+}
+
+// formatTable - информация о формате для каждого уровня коррекции и маски из ISO/IEC 18004, таблица C.1,
+// от старшего бита к младшему.
+var formatTable = [4][8]string{
+	Low:      {"111011111000100", "111001011110011", "111110110101010", "111100010011101", "110011000101111", "110001100011000", "110110001000001", "110100101110110"},
+	Medium:   {"101010000010010", "101000100100101", "101111001111100", "101101101001011", "100010111111001", "100000011001110", "100111110010111", "100101010100000"},
+	Quartile: {"011010101011111", "011000001101000", "011111100110001", "011101000000110", "010010010110100", "010000110000011", "010111011011010", "010101111101101"},
+	High:     {"001011010001001", "001001110111110", "001110011100111", "001100111010000", "000011101100010", "000001001010101", "000110100001100", "000100000111011"},
+}
+
+// readFormat читает информацию о формате вокруг верхнего левого поискового узора от старшего бита к младшему.
+func readFormat(c *Code) string {
+	var b strings.Builder
+
+	for _, p := range [][2]int{{0, 8}, {1, 8}, {2, 8}, {3, 8}, {4, 8}, {5, 8}, {7, 8}, {8, 8}, {8, 7}, {8, 5}, {8, 4}, {8, 3}, {8, 2}, {8, 1}, {8, 0}} {
+		if c.Black(p[0], p[1]) {
+			b.WriteByte('1')
+		} else {
+			b.WriteByte('0')
+		}
+	}
+
+	return b.String()
+}
+
+// readCodewords читает кодовые слова символа так, как это делает декодер: снимает маску по условиям
+// ISO/IEC 18004, таблица 10, и обходит модули данных зигзагом по парам столбцов от правого нижнего угла.
+func readCodewords(c *Code) []byte {
+	masked := [8]func(i, j int) bool{
+		func(i, j int) bool { return (i+j)%2 == 0 },
+		func(i, j int) bool { return i%2 == 0 },
+		func(i, j int) bool { return j%3 == 0 },
+		func(i, j int) bool { return (i+j)%3 == 0 },
+		func(i, j int) bool { return (i/2+j/3)%2 == 0 },
+		func(i, j int) bool { return (i*j)%2+(i*j)%3 == 0 },
+		func(i, j int) bool { return ((i*j)%2+(i*j)%3)%2 == 0 },
+		func(i, j int) bool { return ((i+j)%2+(i*j)%3)%2 == 0 },
+	}[c.Mask]
+
+	var (
+		result []byte
+		bits   int
+		value  byte
+	)
+
+	upward := true
+	for right := c.Size - 1; right > 0; right -= 2 {
+		//вертикальная синхронизирующая полоса не входит ни в одну пару столбцов
+		if right == 6 {
+			right--
+		}
+
+		for n := 0; n < c.Size; n++ {
+			row := n
+			if upward {
+				row = c.Size - 1 - n
+			}
+
+			for _, col := range []int{right, right - 1} {
+				if c.isFunction[row*c.Size+col] {
+					continue
+				}
+
+				value = value<<1 | boolByte(c.Black(col, row) != masked(row, col))
+				if bits++; bits%8 == 0 {
+					result = append(result, value)
+					value = 0
+				}
+			}
+		}
+
+		upward = !upward
+	}
+
+	return result
+}
+
+// boolByte возвращает 1 для true и 0 для false.
+func boolByte(b bool) byte {
+	if b {
+		return 1
+	}
+
+	return 0
+}
+
+// TestEncodeGolden сверяет коды Рида-Соломона, размещение данных, маску и информацию о формате с эталонами:
+// символом 1-M с маской 010 из ISO/IEC 18004, приложение I (данные "01234567"), и таблицей информации о формате.
+func TestEncodeGolden(t *testing.T) {
+	annexData := []byte{0x10, 0x20, 0x0C, 0x56, 0x61, 0x80, 0xEC, 0x11, 0xEC, 0x11, 0xEC, 0x11, 0xEC, 0x11, 0xEC, 0x11}
+	annexECC := []byte{0xA5, 0x24, 0xD4, 0xC1, 0xED, 0x36, 0xC7, 0x87, 0x2C, 0x55}
+
+	assert.Equal(t, annexECC, reedSolomonRemainder(annexData, reedSolomonDivisor(len(annexECC))))
+
+	//второй эталон - данные "HELLO WORLD" в символе 1-M
+	helloData := []byte{0x20, 0x5B, 0x0B, 0x78, 0xD1, 0x72, 0xDC, 0x4D, 0x43, 0x40, 0xEC, 0x11, 0xEC, 0x11, 0xEC, 0x11}
+	helloECC := []byte{0xC4, 0x23, 0x27, 0x77, 0xEB, 0xD7, 0xE7, 0xE2, 0x5D, 0x17}
+	assert.Equal(t, helloECC, reedSolomonRemainder(helloData, reedSolomonDivisor(len(helloECC))))
+
+	c := &Code{Size: 21, Version: 1, Level: Medium, Mask: 2}
+	c.modules = make([]bool, c.Size*c.Size)
+	c.isFunction = make([]bool, c.Size*c.Size)
+	c.drawFunctionPatterns()
+	c.drawCodewords(addECCAndInterleave(annexData, 1, Medium))
+	c.applyMask(2)
+	c.drawFormatBits(2)
+
+	assert.Equal(t, append(append([]byte{}, annexData...), annexECC...), readCodewords(c))
+	assert.Equal(t, formatTable[Medium][2], readFormat(c))
+
+	//байтовый режим: 0100, длина 00000001, байт "A", терминатор и байты заполнения
+	byteData := []byte{0x40, 0x14, 0x10, 0xEC, 0x11, 0xEC, 0x11, 0xEC, 0x11, 0xEC, 0x11, 0xEC, 0x11, 0xEC, 0x11, 0xEC}
+
+	for _, level := range []Level{Low, Medium, Quartile, High} {
+		for mask := 0; mask < 8; mask++ {
+			c, err := encode([]byte("A"), level, mask)
+			require.NoError(t, err)
+			assert.Equal(t, formatTable[level][mask], readFormat(c), "level %v mask %d", level, mask)
+
+			if level == Medium {
+				codewords := readCodewords(c)
+				require.Len(t, codewords, 26)
+				assert.Equal(t, byteData, codewords[:16], "mask %d", mask)
+				assert.Equal(t, reedSolomonRemainder(byteData, reedSolomonDivisor(10)), codewords[16:], "mask %d", mask)
+			}
+		}
+	}
+}
+
+// TestParse проверяет разбор уровня коррекции и цвета.
+func TestParse(t *testing.T) {
+	level, err := ParseLevel("q")
+	require.NoError(t, err)
+	assert.Equal(t, Quartile, level)
+
+	level, err = ParseLevel("")
+	require.NoError(t, err)
+	assert.Equal(t, Medium, level)
+
+	_, err = ParseLevel("X")
+	assert.ErrorIs(t, err, ErrInvalidLevel)
+
+	c, err := ParseColor("#1a2B3c")
+	require.NoError(t, err)
+	assert.Equal(t, color.RGBA{R: 0x1a, G: 0x2b, B: 0x3c, A: 0xff}, c)
+
+	for _, s := range []string{"", "fff", "12345g", "#1234567"} {
+		_, err = ParseColor(s)
+		assert.ErrorIs(t, err, ErrInvalidColor, s)
+	}
+}
+
+// TestRender проверяет размеры, рамку и цвета изображений.
+func TestRender(t *testing.T) {
+	c, err := Encode("https://example.com/abc", Medium)
+	require.NoError(t, err)
+
+	fg := color.RGBA{R: 0x11, G: 0x22, B: 0x33, A: 0xff}
+	bg := color.RGBA{R: 0xfe, G: 0xfd, B: 0xfc, A: 0xff}
+	modules := c.Size + 8
+
+	data, err := c.Render(Options{Format: FormatPNG, Size: modules*5 + 3, Margin: 4, Foreground: fg, Background: bg})
+	require.NoError(t, err)
+
+	img, err := png.Decode(bytes.NewReader(data))
+	require.NoError(t, err)
+	assert.Equal(t, modules*5, img.Bounds().Dx())
+	assert.Equal(t, modules*5, img.Bounds().Dy())
+	assert.Equal(t, bg, color.RGBAModel.Convert(img.At(2, 2)))
+	assert.Equal(t, fg, color.RGBAModel.Convert(img.At(4*5, 4*5)))
+
+	//слишком маленький размер даёт один пиксель на модуль
+	data, err = c.Render(Options{Format: FormatPNG, Size: 1, Foreground: fg, Background: bg})
+	require.NoError(t, err)
+	img, err = png.Decode(bytes.NewReader(data))
+	require.NoError(t, err)
+	assert.Equal(t, c.Size, img.Bounds().Dx())
+
+	data, err = c.Render(Options{Format: FormatSVG, Size: 300, Margin: 2, Foreground: fg, Background: bg})
+	require.NoError(t, err)
+	svg := string(data)
+	assert.True(t, strings.HasPrefix(svg, "<svg"))
+	assert.Contains(t, svg, `width="300"`)
+	assert.Contains(t, svg, `fill="#112233"`)
+	assert.Contains(t, svg, `fill="#fefdfc"`)
+	assert.Contains(t, svg, "M2 2h7v1h-7z")
+
+	_, err = c.Render(Options{Format: "gif"})
+	assert.ErrorIs(t, err, ErrInvalidFormat)
+	_, err = c.Render(Options{Format: FormatPNG, Margin: -1})
+	assert.ErrorIs(t, err, ErrInvalidMargin)
+}
+
+// TestCache проверяет вытеснение давно не использованных изображений.
+func TestCache(t *testing.T) {
+	c := NewCache(2)
+
+	c.Put("a", []byte("1"))
+	c.Put("b", []byte("2"))
+	_, ok := c.Get("a")
+	assert.True(t, ok)
+
+	c.Put("c", []byte("3"))
+	_, ok = c.Get("b")
+	assert.False(t, ok)
+	value, ok := c.Get("a")
+	assert.True(t, ok)
+	assert.Equal(t, []byte("1"), value)
+	assert.Equal(t, 2, c.Len())
+
+	var empty *Cache
+	empty.Put("a", nil)
+	_, ok = empty.Get("a")
+	assert.False(t, ok)
+}
@@ -0,0 +1,176 @@
+package qrcode
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	"image/png"
+	"strconv"
+	"strings"
+)
+
+// Форматы изображений QR кода.
+const (
+	FormatPNG = "png"
+	FormatSVG = "svg"
+)
+
+// Ограничения и значения по умолчанию параметров изображения.
+const (
+	MaxSize   = 4096
+	MaxMargin = 32
+
+	DefaultSize   = 256
+	DefaultMargin = 4
+)
+
+// Ошибки параметров изображения.
+var (
+	ErrInvalidFormat = errors.New("format must be png or svg")
+	ErrInvalidSize   = fmt.Errorf("size must be between 0 and %d", MaxSize)
+	ErrInvalidMargin = fmt.Errorf("margin must be between 0 and %d", MaxMargin)
+	ErrInvalidColor  = errors.New("color must be in RRGGBB hex format")
+)
+
+// Options содержит параметры изображения QR кода.
+// Size задаёт желаемую ширину изображения в пикселях: она округляется вниз до целого числа пикселей на модуль,
+// но модуль не бывает меньше одного пикселя. В SVG Size задаёт ширину и высоту элемента, а не число модулей.
+// Margin задаёт ширину светлой рамки в модулях.
+type Options struct {
+	Format     string
+	Size       int
+	Margin     int
+	Foreground color.RGBA
+	Background color.RGBA
+}
+
+// DefaultOptions возвращает параметры по умолчанию: PNG шириной 256 пикселей, рамка в 4 модуля, чёрный на белом.
+func DefaultOptions() Options {
+	return Options{
+		Format:     FormatPNG,
+		Size:       DefaultSize,
+		Margin:     DefaultMargin,
+		Foreground: color.RGBA{A: 0xff},
+		Background: color.RGBA{R: 0xff, G: 0xff, B: 0xff, A: 0xff},
+	}
+}
+
+// Validate проверяет параметры изображения.
+func (o Options) Validate() error {
+	switch {
+	case o.Format != FormatPNG && o.Format != FormatSVG:
+		return ErrInvalidFormat
+	case o.Size < 0 || o.Size > MaxSize:
+		return ErrInvalidSize
+	case o.Margin < 0 || o.Margin > MaxMargin:
+		return ErrInvalidMargin
+	default:
+		return nil
+	}
+}
+
+// ContentType возвращает MIME тип изображения.
+func (o Options) ContentType() string {
+	if o.Format == FormatSVG {
+		return "image/svg+xml"
+	}
+
+	return "image/png"
+}
+
+// ParseColor разбирает цвет в формате RRGGBB, допуская ведущий символ #.
+func ParseColor(s string) (color.RGBA, error) {
+	s = strings.TrimPrefix(s, "#")
+	if len(s) != 6 {
+		return color.RGBA{}, ErrInvalidColor
+	}
+
+	v, err := strconv.ParseUint(s, 16, 32)
+	if err != nil {
+		return color.RGBA{}, ErrInvalidColor
+	}
+
+	return color.RGBA{R: uint8(v >> 16), G: uint8(v >> 8), B: uint8(v), A: 0xff}, nil
+}
+
+// hexColor возвращает цвет в формате #rrggbb.
+func hexColor(c color.RGBA) string {
+	return fmt.Sprintf("#%02x%02x%02x", c.R, c.G, c.B)
+}
+
+// Render возвращает изображение QR кода в формате, указанном в параметрах.
+func (c *Code) Render(o Options) ([]byte, error) {
+	if err := o.Validate(); err != nil {
+		return nil, err
+	}
+
+	if o.Format == FormatSVG {
+		return c.SVG(o), nil
+	}
+
+	return c.PNG(o)
+}
+
+// PNG возвращает изображение QR кода в формате PNG с палитрой из двух цветов.
+func (c *Code) PNG(o Options) ([]byte, error) {
+	modules := c.Size + 2*o.Margin
+	scale := o.Size / modules
+	if scale < 1 {
+		scale = 1
+	}
+
+	img := image.NewPaletted(image.Rect(0, 0, modules*scale, modules*scale), color.Palette{o.Background, o.Foreground})
+	for y := 0; y < c.Size; y++ {
+		for x := 0; x < c.Size; x++ {
+			if !c.Black(x, y) {
+				continue
+			}
+			for py := (y + o.Margin) * scale; py < (y+o.Margin+1)*scale; py++ {
+				row := img.Pix[py*img.Stride:]
+				for px := (x + o.Margin) * scale; px < (x+o.Margin+1)*scale; px++ {
+					row[px] = 1
+				}
+			}
+		}
+	}
+
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		return nil, err
+	}
+
+	return buf.Bytes(), nil
+}
+
+// SVG возвращает изображение QR кода в формате SVG. Тёмные модули объединяются в горизонтальные отрезки.
+func (c *Code) SVG(o Options) []byte {
+	modules := c.Size + 2*o.Margin
+	size := o.Size
+	if size == 0 {
+		size = modules
+	}
+
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, `<svg xmlns="http://www.w3.org/2000/svg" width="%d" height="%d" viewBox="0 0 %d %d" shape-rendering="crispEdges">`, size, size, modules, modules)
+	fmt.Fprintf(&buf, `<rect width="100%%" height="100%%" fill="%s"/>`, hexColor(o.Background))
+	fmt.Fprintf(&buf, `<path fill="%s" d="`, hexColor(o.Foreground))
+
+	for y := 0; y < c.Size; y++ {
+		for x := 0; x < c.Size; x++ {
+			if !c.Black(x, y) {
+				continue
+			}
+			start := x
+			for x < c.Size && c.Black(x, y) {
+				x++
+			}
+			fmt.Fprintf(&buf, "M%d %dh%dv1h-%dz", start+o.Margin, y+o.Margin, x-start, x-start)
+		}
+	}
+
+	buf.WriteString(`"/></svg>`)
+
+	return buf.Bytes()
+}
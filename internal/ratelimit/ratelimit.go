@@ -0,0 +1,130 @@
+package ratelimit
+
+import (
+	"sync"
+	"time"
+)
+
+// Limiter ограничивает количество неудачных попыток по ключу в течение окна. Окно начинается с первой неудачной
+// попытки, после его окончания счётчик сбрасывается.
+type Limiter struct {
+	limit  int
+	window time.Duration
+
+	mu        sync.Mutex
+	failures  map[string]*failures
+	lastPurge time.Time
+}
+
+// failures содержит количество неудачных попыток и начало окна.
+type failures struct {
+	count int
+	start time.Time
+}
+
+// New - конструктор Limiter. Лимит меньше единицы отключает ограничение.
+func New(limit int, window time.Duration) *Limiter {
+	return &Limiter{limit: limit, window: window, failures: make(map[string]*failures)}
+}
+
+// Allow проверяет, разрешена ли очередная попытка, и возвращает время до её разрешения.
+// Метод безопасен для nil лимитера.
+func (l *Limiter) Allow(key string, now time.Time) (bool, time.Duration) {
+	if l == nil || l.limit < 1 {
+		return true, 0
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	f, ok := l.failures[key]
+	if !ok || now.Sub(f.start) >= l.window || f.count < l.limit {
+		return true, 0
+	}
+
+	return false, f.start.Add(l.window).Sub(now)
+}
+
+// Reservation - попытка, учтённая до её проверки.
+type Reservation struct {
+	l   *Limiter
+	key string
+	f   *failures
+}
+
+// Reserve атомарно проверяет, разрешена ли очередная попытка, и сразу учитывает её как неудачную,
+// поэтому одновременные попытки не могут превысить лимит, пока идёт их проверка. Если попытка не разрешена,
+// возвращается время до её разрешения. Удачную попытку нужно отменить методом Cancel.
+// Метод безопасен для nil лимитера.
+func (l *Limiter) Reserve(key string, now time.Time) (Reservation, bool, time.Duration) {
+	if l == nil || l.limit < 1 {
+		return Reservation{}, true, 0
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.purge(now)
+
+	f, ok := l.failures[key]
+	if !ok || now.Sub(f.start) >= l.window {
+		f = &failures{start: now}
+		l.failures[key] = f
+	}
+
+	if f.count >= l.limit {
+		return Reservation{}, false, f.start.Add(l.window).Sub(now)
+	}
+
+	f.count++
+
+	return Reservation{l: l, key: key, f: f}, true, 0
+}
+
+// Cancel отменяет учёт удачной попытки. Если окно, в котором попытка была учтена, уже закончилось, ничего не меняется.
+func (r Reservation) Cancel() {
+	if r.l == nil {
+		return
+	}
+
+	r.l.mu.Lock()
+	defer r.l.mu.Unlock()
+
+	if r.l.failures[r.key] == r.f && r.f.count > 0 {
+		r.f.count--
+	}
+}
+
+// Fail учитывает неудачную попытку. Метод безопасен для nil лимитера.
+func (l *Limiter) Fail(key string, now time.Time) {
+	if l == nil || l.limit < 1 {
+		return
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.purge(now)
+
+	f, ok := l.failures[key]
+	if !ok || now.Sub(f.start) >= l.window {
+		l.failures[key] = &failures{count: 1, start: now}
+		return
+	}
+
+	f.count++
+}
+
+// purge удаляет счётчики с истёкшим окном не чаще одного раза за окно.
+func (l *Limiter) purge(now time.Time) {
+	if now.Sub(l.lastPurge) < l.window {
+		return
+	}
+	l.lastPurge = now
+
+	for key, f := range l.failures {
+		if now.Sub(f.start) >= l.window {
+			delete(l.failures, key)
+		}
+	}
+}
@@ -0,0 +1,74 @@
+package ratelimit
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// TestLimiter проверяет блокировку после исчерпания попыток и сброс счётчика после окончания окна.
+func TestLimiter(t *testing.T) {
+	l := New(3, time.Minute)
+	now := time.Date(2023, 5, 10, 12, 0, 0, 0, time.UTC)
+
+	for i := 0; i < 3; i++ {
+		allowed, _ := l.Allow("abc", now)
+		assert.True(t, allowed)
+		l.Fail("abc", now.Add(time.Duration(i)*time.Second))
+	}
+
+	allowed, wait := l.Allow("abc", now.Add(10*time.Second))
+	assert.False(t, allowed)
+	assert.Equal(t, 50*time.Second, wait)
+
+	//другие ключи не затрагиваются
+	allowed, _ = l.Allow("other", now)
+	assert.True(t, allowed)
+
+	allowed, _ = l.Allow("abc", now.Add(time.Minute))
+	assert.True(t, allowed)
+
+	l.Fail("abc", now.Add(time.Minute))
+	allowed, _ = l.Allow("abc", now.Add(time.Minute))
+	assert.True(t, allowed)
+
+	var disabled *Limiter
+	disabled.Fail("abc", now)
+	allowed, _ = disabled.Allow("abc", now)
+	assert.True(t, allowed)
+}
+
+// TestLimiter_Reserve проверяет, что попытка учитывается до её проверки, а отменённая удачная попытка не расходует лимит.
+func TestLimiter_Reserve(t *testing.T) {
+	l := New(2, time.Minute)
+	now := time.Date(2023, 5, 10, 12, 0, 0, 0, time.UTC)
+
+	first, allowed, _ := l.Reserve("abc", now)
+	assert.True(t, allowed)
+	_, allowed, _ = l.Reserve("abc", now.Add(time.Second))
+	assert.True(t, allowed)
+
+	_, allowed, wait := l.Reserve("abc", now.Add(10*time.Second))
+	assert.False(t, allowed)
+	assert.Equal(t, 50*time.Second, wait)
+
+	first.Cancel()
+	_, allowed, _ = l.Reserve("abc", now.Add(10*time.Second))
+	assert.True(t, allowed)
+
+	//отмена попытки из закончившегося окна не затрагивает новое окно
+	stale, _, _ := l.Reserve("other", now)
+	_, allowed, _ = l.Reserve("other", now.Add(time.Minute))
+	assert.True(t, allowed)
+	stale.Cancel()
+	_, allowed, _ = l.Reserve("other", now.Add(time.Minute))
+	assert.True(t, allowed)
+	_, allowed, _ = l.Reserve("other", now.Add(time.Minute))
+	assert.False(t, allowed)
+
+	var disabled *Limiter
+	reservation, allowed, _ := disabled.Reserve("abc", now)
+	assert.True(t, allowed)
+	reservation.Cancel()
+}
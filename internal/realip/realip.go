@@ -0,0 +1,35 @@
+package realip
+
+import (
+	"net"
+	"net/http"
+	"strings"
+)
+
+// FromHeaders возвращает IP-адрес клиента из заголовка X-Real-IP, а если он не задан - первый адрес из X-Forwarded-For.
+// Если адрес определить не удалось, возвращается nil.
+func FromHeaders(header http.Header) net.IP {
+	realIP := net.ParseIP(header.Get("X-Real-IP"))
+	if realIP == nil {
+		ips := header.Get("X-Forwarded-For")
+		ipStrs := strings.Split(ips, ",")
+		ipStr := strings.TrimSpace(ipStrs[0])
+		realIP = net.ParseIP(ipStr)
+	}
+
+	return realIP
+}
+
+// FromRequest возвращает IP-адрес клиента из заголовков запроса, а если они не заданы - адрес соединения.
+func FromRequest(r *http.Request) net.IP {
+	if realIP := FromHeaders(r.Header); realIP != nil {
+		return realIP
+	}
+
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		host = r.RemoteAddr
+	}
+
+	return net.ParseIP(host)
+}
@@ -0,0 +1,33 @@
+package realip
+
+import (
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// TestFromRequest проверяет определение IP-адреса клиента.
+func TestFromRequest(t *testing.T) {
+	tests := []struct {
+		name   string
+		header http.Header
+		want   string
+	}{
+		{name: "real ip header", header: http.Header{"X-Real-Ip": {"10.0.0.1"}, "X-Forwarded-For": {"10.0.0.2"}}, want: "10.0.0.1"},
+		{name: "first forwarded address", header: http.Header{"X-Forwarded-For": {"10.0.0.2, 10.0.0.3"}}, want: "10.0.0.2"},
+		{name: "remote address", want: "192.0.2.1"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodGet, "/", nil)
+			for key, values := range tt.header {
+				r.Header[key] = values
+			}
+
+			assert.True(t, net.ParseIP(tt.want).Equal(FromRequest(r)))
+		})
+	}
+}
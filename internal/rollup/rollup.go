@@ -0,0 +1,112 @@
+package rollup
+
+import (
+	"context"
+	"errors"
+	"log"
+	"time"
+
+	"github.com/vladimirimekov/url-shortener/internal/analytics"
+	"github.com/vladimirimekov/url-shortener/internal/storage"
+)
+
+// step - наибольший период, переходы за который сводятся за один проход.
+const step = 24 * time.Hour
+
+// Store - интерфейс хранилища переходов и их сводок.
+type Store interface {
+	GetRollupWatermark(context.Context) (time.Time, error)
+	RollupClicks(context.Context, time.Time, storage.AggregateFunc) (time.Time, error)
+	DeleteClicksBefore(context.Context, time.Time) (int64, error)
+}
+
+// Roller периодически сводит переходы в часовые и дневные сводки и удаляет устаревшие переходы.
+type Roller struct {
+	store     Store
+	retention time.Duration
+	interval  time.Duration
+	delay     time.Duration
+}
+
+// New - конструктор Roller. Переходы хранятся не меньше retention и не удаляются, пока не попали в сводки.
+// Час сводится не раньше чем через delay после его окончания: переходы попадают в хранилище с задержкой буфера записи,
+// и переход, сохранённый после сведения его часа, уже не попал бы в сводки. Поэтому delay должен быть не меньше
+// интервала сброса буфера переходов с запасом.
+func New(store Store, retention, interval, delay time.Duration) *Roller {
+	return &Roller{store: store, retention: retention, interval: interval, delay: delay}
+}
+
+// RollUp сводит переходы за все часы, завершившиеся не позже чем за delay до момента now.
+func (r *Roller) RollUp(ctx context.Context, now time.Time) error {
+	cutoff := now.UTC().Add(-r.delay).Truncate(time.Hour)
+
+	for {
+		watermark, err := r.store.GetRollupWatermark(ctx)
+		if err != nil {
+			return err
+		}
+
+		if !watermark.Before(cutoff) {
+			return nil
+		}
+
+		//после первого сведения переходы обрабатываются порциями не больше step
+		to := cutoff
+		if !watermark.IsZero() && watermark.Add(step).Before(cutoff) {
+			to = watermark.Add(step)
+		}
+
+		if _, err = r.store.RollupClicks(ctx, to, analytics.Aggregate); err != nil {
+			return err
+		}
+	}
+}
+
+// Expire удаляет переходы старше retention, уже учтённые в сводках.
+func (r *Roller) Expire(ctx context.Context, now time.Time) (int64, error) {
+	watermark, err := r.store.GetRollupWatermark(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	before := now.UTC().Add(-r.retention)
+	if watermark.Before(before) {
+		before = watermark
+	}
+
+	if before.IsZero() {
+		return 0, nil
+	}
+
+	return r.store.DeleteClicksBefore(ctx, before)
+}
+
+// Run сводит переходы и удаляет устаревшие переходы каждые interval до отмены контекста.
+func (r *Roller) Run(ctx context.Context) {
+	ticker := time.NewTicker(r.interval)
+	defer ticker.Stop()
+
+	for {
+		r.maintain(ctx)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// maintain выполняет одно сведение переходов и удаление устаревших.
+func (r *Roller) maintain(ctx context.Context) {
+	now := time.Now()
+
+	if err := r.RollUp(ctx, now); err != nil && !errors.Is(err, context.Canceled) {
+		log.Print(err)
+		return
+	}
+
+	if _, err := r.Expire(ctx, now); err != nil && !errors.Is(err, context.Canceled) {
+		log.Print(err)
+	}
+}
@@ -0,0 +1,125 @@
+package rollup
+
+import (
+	"context"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/vladimirimekov/url-shortener/internal/analytics"
+	"github.com/vladimirimekov/url-shortener/internal/storage"
+)
+
+// TestRoller тестирует сведение переходов, отчёт по сводкам и удаление устаревших переходов.
+func TestRoller(t *testing.T) {
+	type store interface {
+		Store
+		analytics.Store
+		SaveClicks(context.Context, []storage.Click) error
+	}
+
+	tests := []struct {
+		name  string
+		store store
+		file  string
+	}{
+		{name: "memory", store: storage.NewMemoryWork(map[string]map[string]string{})},
+		{name: "file", store: storage.FileSystemConnect{Filename: "rollup_test.gob"}, file: "rollup_test.gob"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.file != "" {
+				defer os.Remove(tt.file)
+			}
+
+			ctx := context.Background()
+			day := time.Date(2023, 5, 1, 0, 0, 0, 0, time.UTC)
+
+			var clicks []storage.Click
+			for i := 0; i < 30; i++ {
+				clicks = append(clicks, storage.Click{
+					ShortURL:  "abc",
+					Time:      day.Add(time.Duration(i) * 3 * time.Hour),
+					Referrer:  "https://t.me/channel",
+					UserAgent: "curl/8.0",
+					IPHash:    string(rune('a' + i%5)),
+				})
+			}
+			require.NoError(t, tt.store.SaveClicks(ctx, clicks))
+
+			q, err := analytics.NewQuery(day, day.AddDate(0, 0, 5), "day")
+			require.NoError(t, err)
+			want := analytics.Build(q, clicks)
+
+			now := day.AddDate(0, 0, 3).Add(90 * time.Minute)
+			r := New(tt.store, 24*time.Hour, time.Minute, time.Minute)
+
+			require.NoError(t, r.RollUp(ctx, now))
+
+			watermark, err := tt.store.GetRollupWatermark(ctx)
+			require.NoError(t, err)
+			assert.Equal(t, now.Truncate(time.Hour), watermark)
+
+			//повторное сведение не учитывает переходы дважды
+			require.NoError(t, r.RollUp(ctx, now))
+
+			got, err := analytics.LinkReport(ctx, tt.store, "abc", q)
+			require.NoError(t, err)
+			assert.Equal(t, want.Total, got.Total)
+			assert.Equal(t, want.UniqueVisitors, got.UniqueVisitors)
+			assert.Equal(t, want.Series, got.Series)
+			assert.Equal(t, want.Referrers, got.Referrers)
+
+			hourly, err := tt.store.GetRollups(ctx, []string{"abc"}, storage.GranularityHour, day, day.AddDate(0, 0, 1))
+			require.NoError(t, err)
+			assert.Len(t, hourly, 8)
+
+			//удаляются только сведённые переходы старше срока хранения
+			deleted, err := r.Expire(ctx, now)
+			require.NoError(t, err)
+			assert.Equal(t, int64(17), deleted)
+
+			rest, err := tt.store.GetClicks(ctx, []string{"abc"}, day, day.AddDate(0, 0, 5))
+			require.NoError(t, err)
+			assert.Len(t, rest, 13)
+
+			got, err = analytics.LinkReport(ctx, tt.store, "abc", q)
+			require.NoError(t, err)
+			assert.Equal(t, want.Total, got.Total)
+			assert.Equal(t, want.Series, got.Series)
+		})
+	}
+}
+
+// TestRoller_Delay проверяет, что переход, сохранённый с задержкой буфера после окончания часа, попадает в сводки.
+func TestRoller_Delay(t *testing.T) {
+	ctx := context.Background()
+	s := storage.NewMemoryWork(map[string]map[string]string{})
+	hour := time.Date(2023, 5, 1, 10, 0, 0, 0, time.UTC)
+
+	require.NoError(t, s.SaveClicks(ctx, []storage.Click{{ShortURL: "abc", Time: hour.Add(30 * time.Minute)}}))
+
+	r := New(s, 24*time.Hour, time.Minute, 5*time.Second+time.Minute)
+
+	//через 30 секунд после окончания часа он ещё не сводится
+	require.NoError(t, r.RollUp(ctx, hour.Add(time.Hour+30*time.Second)))
+	watermark, err := s.GetRollupWatermark(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, hour, watermark)
+
+	//переход конца часа сохраняется после сброса буфера
+	require.NoError(t, s.SaveClicks(ctx, []storage.Click{{ShortURL: "abc", Time: hour.Add(time.Hour - time.Second)}}))
+
+	require.NoError(t, r.RollUp(ctx, hour.Add(time.Hour+2*time.Minute)))
+	watermark, err = s.GetRollupWatermark(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, hour.Add(time.Hour), watermark)
+
+	rollups, err := s.GetRollups(ctx, []string{"abc"}, storage.GranularityHour, hour, hour.Add(time.Hour))
+	require.NoError(t, err)
+	require.Len(t, rollups, 1)
+	assert.Equal(t, int64(2), rollups[0].Clicks)
+}
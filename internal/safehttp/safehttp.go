@@ -0,0 +1,115 @@
+package safehttp
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// ErrForbiddenAddress возвращается при попытке соединения с адресом, не являющимся публичным.
+var ErrForbiddenAddress = errors.New("destination address is not public")
+
+// reserved содержит диапазоны адресов, не покрытые методами net.IP, по которым нельзя выполнять исходящие запросы.
+var reserved = parseNets(
+	"0.0.0.0/8",
+	"100.64.0.0/10",
+	"192.0.0.0/24",
+	"192.0.2.0/24",
+	"198.18.0.0/15",
+	"198.51.100.0/24",
+	"203.0.113.0/24",
+	"240.0.0.0/4",
+	"64:ff9b::/96",
+	"2001:db8::/32",
+)
+
+// parseNets разбирает список подсетей в нотации CIDR.
+func parseNets(cidrs ...string) []*net.IPNet {
+	result := make([]*net.IPNet, 0, len(cidrs))
+
+	for _, cidr := range cidrs {
+		_, n, err := net.ParseCIDR(cidr)
+		if err != nil {
+			panic(err)
+		}
+		result = append(result, n)
+	}
+
+	return result
+}
+
+// Public сообщает, является ли адрес публичным: не loopback, не частным, не link-local (включая 169.254.169.254),
+// не multicast и не зарезервированным.
+func Public(ip net.IP) bool {
+	if ip == nil || ip.IsLoopback() || ip.IsPrivate() || ip.IsUnspecified() || ip.IsLinkLocalUnicast() ||
+		ip.IsLinkLocalMulticast() || ip.IsInterfaceLocalMulticast() || ip.IsMulticast() {
+		return false
+	}
+
+	for _, n := range reserved {
+		if n.Contains(ip) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// Control возвращает функцию для net.Dialer.Control, отклоняющую соединения с адресами, для которых allow возвращает false.
+// Проверяется уже разрешённый DNS адрес, поэтому проверку нельзя обойти доменом, указывающим на внутренний адрес,
+// и она выполняется для каждого перенаправления.
+func Control(allow func(net.IP) bool) func(network, address string, c syscall.RawConn) error {
+	return func(network, address string, _ syscall.RawConn) error {
+		host, _, err := net.SplitHostPort(address)
+		if err != nil {
+			return err
+		}
+
+		if ip := net.ParseIP(host); ip == nil || !allow(ip) {
+			return fmt.Errorf("%w: %s", ErrForbiddenAddress, host)
+		}
+
+		return nil
+	}
+}
+
+// NewClient возвращает http клиент с таймаутом timeout, соединяющийся только с публичными адресами.
+func NewClient(timeout time.Duration) *http.Client {
+	return newClient(timeout, Public)
+}
+
+// newClient возвращает http клиент, соединяющийся только с адресами, для которых allow возвращает true.
+func newClient(timeout time.Duration, allow func(net.IP) bool) *http.Client {
+	dialer := &net.Dialer{
+		Timeout:   30 * time.Second,
+		KeepAlive: 30 * time.Second,
+		Control:   Control(allow),
+	}
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	//прокси из окружения не используется: соединение с ним обошло бы проверку адреса назначения
+	transport.Proxy = nil
+	transport.DialContext = dialer.DialContext
+
+	return &http.Client{Timeout: timeout, Transport: transport}
+}
+
+// CheckURL отклоняет адреса, хост которых заведомо не является публичным: IP-адреса внутренних сетей и localhost.
+// Доменные имена здесь не разрешаются, их адреса проверяются при соединении.
+func CheckURL(u *url.URL) error {
+	host := strings.TrimSuffix(strings.ToLower(u.Hostname()), ".")
+	if host == "localhost" || strings.HasSuffix(host, ".localhost") {
+		return fmt.Errorf("%w: %s", ErrForbiddenAddress, host)
+	}
+
+	if ip := net.ParseIP(host); ip != nil && !Public(ip) {
+		return fmt.Errorf("%w: %s", ErrForbiddenAddress, host)
+	}
+
+	return nil
+}
@@ -1,24 +1,50 @@
 package server
 
 import (
+	"context"
 	"database/sql"
+	"html/template"
 	"log"
 	"time"
 
 	"github.com/go-chi/chi/v5"
 	"github.com/go-chi/chi/v5/middleware"
 	"github.com/vladimirimekov/url-shortener/internal"
+	"github.com/vladimirimekov/url-shortener/internal/botfilter"
+	"github.com/vladimirimekov/url-shortener/internal/broker"
+	"github.com/vladimirimekov/url-shortener/internal/clicks"
+	"github.com/vladimirimekov/url-shortener/internal/geoip"
 	"github.com/vladimirimekov/url-shortener/internal/handlers"
+	"github.com/vladimirimekov/url-shortener/internal/keypool"
+	"github.com/vladimirimekov/url-shortener/internal/metadata"
 	"github.com/vladimirimekov/url-shortener/internal/middlewares"
+	"github.com/vladimirimekov/url-shortener/internal/namegen"
+	"github.com/vladimirimekov/url-shortener/internal/qrcode"
+	"github.com/vladimirimekov/url-shortener/internal/ratelimit"
+	"github.com/vladimirimekov/url-shortener/internal/rollup"
+	"github.com/vladimirimekov/url-shortener/internal/safehttp"
 	"github.com/vladimirimekov/url-shortener/internal/storage"
+	"github.com/vladimirimekov/url-shortener/internal/webhooks"
 )
 
 type userIDtype string
 
+// repository объединяет интерфейсы хранилища, необходимые хэндлерам и фоновым подсистемам.
+type repository interface {
+	handlers.Repositories
+	keypool.Store
+	clicks.Store
+	clicks.SaltStore
+	rollup.Store
+	webhooks.Store
+	metadata.Store
+}
+
 const userKey userIDtype = "userid"
 
-// GetServer возвращает Chi сервер со всеми хэндлерами и мидлвэрами.
-func GetServer(dbConnection *sql.DB) (internal.Config, *chi.Mux) {
+// GetServer возвращает Chi сервер со всеми хэндлерами и мидлвэрами, а также хэндлер для grpc сервера.
+// Фоновые подсистемы останавливаются при отмене ctx.
+func GetServer(ctx context.Context, dbConnection *sql.DB) (internal.Config, *chi.Mux, handlers.Handler) {
 
 	cfg := internal.GetConfig()
 	memoryVar := make(map[string]map[string]string)
@@ -27,8 +53,22 @@ func GetServer(dbConnection *sql.DB) (internal.Config, *chi.Mux) {
 		LengthOfShortname: cfg.ShortnameLength,
 		Host:              cfg.BaseURL,
 		UserKey:           userKey,
+		Generator:         namegen.New(cfg.ShortnameLength, cfg.ShortnameMaxLength, cfg.ShortnameMaxAttempts, cfg.ShortnameCollisionThreshold),
+		QRCodes:           qrcode.NewCache(cfg.QRCacheSize),
+		PasswordAttempts:  ratelimit.New(cfg.PasswordMaxAttempts, cfg.PasswordAttemptWindow),
+		ComingSoon:        handlers.ComingSoon{URL: cfg.ComingSoonURL, Status: cfg.ComingSoonStatus},
 	}
 
+	if cfg.ComingSoonPage != "" {
+		page, err := template.ParseFiles(cfg.ComingSoonPage)
+		if err != nil {
+			log.Fatalf("unable to parse coming soon page %v: %v\n", cfg.ComingSoonPage, err)
+		}
+		h.ComingSoon.Page = page
+	}
+
+	var store repository
+
 	if cfg.DBAddress != "" {
 		var err error
 
@@ -44,11 +84,55 @@ func GetServer(dbConnection *sql.DB) (internal.Config, *chi.Mux) {
 			log.Fatalf("unable to connect to database %v\n", cfg.DBAddress)
 		}
 
-		h.Storage = storage.GetNewConnection(dbConnection, cfg.DBAddress, "file://migrations/postgres")
+		store = storage.GetNewConnection(dbConnection, cfg.DBAddress, "file://migrations/postgres")
 	} else if cfg.Filename != "" {
-		h.Storage = storage.FileSystemConnect{Filename: cfg.Filename}
+		store = storage.FileSystemConnect{Filename: cfg.Filename}
 	} else {
-		h.Storage = storage.MemoryWork{UserData: memoryVar}
+		store = storage.NewMemoryWork(memoryVar)
+	}
+
+	h.Storage = store
+
+	if cfg.KeyPoolSize > 0 {
+		h.Keys = keypool.New(store, h.GenerateShortnames, cfg.KeyPoolSize, cfg.KeyPoolLowWater, cfg.KeyPoolClaimTTL, cfg.KeyPoolRefillInterval)
+		go h.Keys.Run(ctx)
+	}
+
+	salt, err := clicks.Salt(ctx, store, cfg.ClickIPSalt)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	h.Bots = botfilter.New(cfg.BotBurstLimit, cfg.BotBurstWindow)
+	if cfg.BotPatternsFile != "" {
+		go h.Bots.Run(ctx, cfg.BotPatternsFile, cfg.BotPatternsCheckInterval)
+	}
+
+	h.Geo = geoip.New()
+	if cfg.GeoIPFile != "" {
+		go h.Geo.Run(ctx, cfg.GeoIPFile, cfg.GeoIPCheckInterval)
+	}
+
+	h.Events = broker.New(cfg.StreamBufferSize)
+
+	h.Webhooks = webhooks.New(store, safehttp.NewClient(cfg.WebhookTimeout), cfg.WebhookWorkers, cfg.WebhookMaxAttempts, cfg.WebhookBaseDelay, cfg.WebhookMaxDelay, cfg.WebhookPollInterval)
+	go h.Webhooks.Run(ctx)
+
+	//уведомления о переходах ставятся в очередь из сохранённых пачек, а не из потока событий, отбрасывающего переходы
+	webhookClicks := func(ctx context.Context, saved []storage.Click) error {
+		return h.Webhooks.EnqueueClicks(ctx, saved, cfg.BaseURL)
+	}
+
+	h.Clicks = clicks.New(store, salt, cfg.ClickBufferSize, cfg.ClickBatchSize, cfg.ClickFlushInterval, webhookClicks)
+	go h.Clicks.Run(ctx)
+
+	//час сводится после сброса буфера переходов, записанных в конце часа, с запасом RollupDelay
+	go rollup.New(store, cfg.ClickRetention, cfg.RollupInterval, cfg.ClickFlushInterval+cfg.RollupDelay).Run(ctx)
+
+	if cfg.MetadataWorkers > 0 {
+		h.Metadata = metadata.New(store, safehttp.NewClient(cfg.MetadataTimeout), cfg.MetadataWorkers, cfg.MetadataQueueSize,
+			cfg.MetadataMaxBytes, cfg.MetadataMaxAttempts, cfg.MetadataRetryDelay, cfg.MetadataHostConcurrency)
+		go h.Metadata.Run(ctx)
 	}
 
 	m := middlewares.UserCookies{Storage: h.Storage, Secret: cfg.Secret, UserKey: userKey}
@@ -67,6 +151,10 @@ func GetServer(dbConnection *sql.DB) (internal.Config, *chi.Mux) {
 
 	r.Route("/{id}", func(r chi.Router) {
 		r.Get("/", h.MainHandler)
+		r.Post("/", h.MainHandler)
+		r.Get("/qr", h.LinkQRCodeHandler)
+		r.Get("/*", h.MainHandler)
+		r.Post("/*", h.MainHandler)
 	})
 
 	r.Route("/", func(r chi.Router) {
@@ -75,6 +163,8 @@ func GetServer(dbConnection *sql.DB) (internal.Config, *chi.Mux) {
 
 	r.Route("/api", func(r chi.Router) {
 
+		r.Get("/qr/{id}", h.QRCodeHandler)
+
 		r.Route("/shorten", func(r chi.Router) {
 			r.Post("/", h.PostShortenHandler)
 
@@ -86,6 +176,44 @@ func GetServer(dbConnection *sql.DB) (internal.Config, *chi.Mux) {
 		r.Route("/user/urls", func(r chi.Router) {
 			r.Get("/", h.GetAllShorterURLsHandler)
 			r.Delete("/", h.DeleteBatchURLS)
+			r.Get("/stream", h.StreamClicksHandler)
+			r.Get("/search", h.SearchURLsHandler)
+
+			r.Route("/{short}", func(r chi.Router) {
+				r.Get("/", h.GetUserURLHandler)
+				r.Patch("/", h.UpdateURLHandler)
+				r.Get("/history", h.GetURLHistoryHandler)
+				r.Post("/rollback", h.RollbackURLHandler)
+				r.Get("/stats", h.GetURLStatsHandler)
+			})
+		})
+
+		r.Route("/user/webhooks", func(r chi.Router) {
+			r.Post("/", h.CreateWebhookHandler)
+			r.Get("/", h.GetWebhooksHandler)
+			r.Delete("/{id}", h.DeleteWebhookHandler)
+			r.Get("/deliveries", h.GetDeliveriesHandler)
+			r.Post("/deliveries/{id}/replay", h.ReplayDeliveryHandler)
+		})
+
+		r.Route("/user/tags", func(r chi.Router) {
+			r.Get("/", h.GetTagsHandler)
+			r.Patch("/{tag}", h.RenameTagHandler)
+			r.Delete("/{tag}", h.DeleteTagHandler)
+		})
+
+		r.Get("/user/folders", h.GetFoldersHandler)
+
+		r.Route("/user/campaigns", func(r chi.Router) {
+			r.Post("/", h.CreateCampaignHandler)
+			r.Get("/", h.GetCampaignsHandler)
+
+			r.Route("/{id}", func(r chi.Router) {
+				r.Get("/", h.GetCampaignHandler)
+				r.Patch("/", h.UpdateCampaignHandler)
+				r.Delete("/", h.DeleteCampaignHandler)
+				r.Get("/stats", h.GetCampaignStatsHandler)
+			})
 		})
 
 		r.Group(func(r chi.Router) {
@@ -99,5 +227,5 @@ func GetServer(dbConnection *sql.DB) (internal.Config, *chi.Mux) {
 		r.Get("/", h.PingConnection)
 	})
 
-	return cfg, r
+	return cfg, r, h
 }
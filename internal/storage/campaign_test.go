@@ -0,0 +1,79 @@
+package storage
+
+import (
+	"context"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestStorage_Campaigns проверяет сохранение кампаний и исключение ссылок из удалённой кампании.
+func TestStorage_Campaigns(t *testing.T) {
+	type campaignStorage interface {
+		SaveData(context.Context, map[string]map[string]string) error
+		GetLink(context.Context, string) (Link, error)
+		ConfigureLink(context.Context, string, UpdateFunc) (Link, error)
+		SaveCampaign(context.Context, Campaign) error
+		GetCampaign(context.Context, string) (Campaign, error)
+		GetCampaigns(context.Context, string) ([]Campaign, error)
+		DeleteCampaign(context.Context, string, string) error
+	}
+
+	tests := []struct {
+		name    string
+		storage campaignStorage
+		file    string
+	}{
+		{name: "memory", storage: NewMemoryWork(map[string]map[string]string{})},
+		{name: "file", storage: FileSystemConnect{Filename: "campaign_test.gob"}, file: "campaign_test.gob"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.file != "" {
+				defer os.Remove(tt.file)
+			}
+
+			ctx := context.Background()
+			s := tt.storage
+			now := time.Now().UTC()
+
+			spring := Campaign{ID: "spring", UserID: "owner", Name: "Spring", Parameters: map[string]string{"utm_source": "mail"}, CreatedAt: now, UpdatedAt: now}
+			autumn := Campaign{ID: "autumn", UserID: "owner", Name: "Autumn", Parameters: map[string]string{"utm_source": "ads"}, CreatedAt: now.Add(time.Second), UpdatedAt: now}
+			require.NoError(t, s.SaveCampaign(ctx, spring))
+			require.NoError(t, s.SaveCampaign(ctx, autumn))
+
+			spring.Name = "Spring sale"
+			require.NoError(t, s.SaveCampaign(ctx, spring))
+
+			got, err := s.GetCampaign(ctx, "spring")
+			require.NoError(t, err)
+			assert.Equal(t, "Spring sale", got.Name)
+
+			list, err := s.GetCampaigns(ctx, "owner")
+			require.NoError(t, err)
+			require.Len(t, list, 2)
+			assert.Equal(t, "spring", list[0].ID)
+
+			require.NoError(t, s.SaveData(ctx, map[string]map[string]string{"owner": {"abc": "https://example.com"}}))
+			_, err = s.ConfigureLink(ctx, "abc", func(link *Link) error {
+				link.CampaignID = "spring"
+				return nil
+			})
+			require.NoError(t, err)
+
+			assert.ErrorIs(t, s.DeleteCampaign(ctx, "intruder", "spring"), ErrNotOwner)
+			require.NoError(t, s.DeleteCampaign(ctx, "owner", "spring"))
+			assert.ErrorIs(t, s.DeleteCampaign(ctx, "owner", "spring"), ErrNotFound)
+
+			link, err := s.GetLink(ctx, "abc")
+			require.NoError(t, err)
+			assert.Empty(t, link.CampaignID)
+
+			_, err = s.GetCampaign(ctx, "spring")
+			assert.ErrorIs(t, err, ErrNotFound)
+		})
+	}
+}
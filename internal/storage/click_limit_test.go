@@ -0,0 +1,83 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"os"
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestStorage_ConsumeClick проверяет, что при одновременных переходах ссылка не перенаправляет больше заданного числа раз.
+func TestStorage_ConsumeClick(t *testing.T) {
+	type limitStorage interface {
+		SaveData(context.Context, map[string]map[string]string) error
+		GetLink(context.Context, string) (Link, error)
+		ConfigureLink(context.Context, string, UpdateFunc) (Link, error)
+		ConsumeClick(context.Context, string) error
+	}
+
+	tests := []struct {
+		name    string
+		storage limitStorage
+		file    string
+	}{
+		{name: "memory", storage: NewMemoryWork(map[string]map[string]string{})},
+		{name: "file", storage: FileSystemConnect{Filename: "click_limit_test.gob"}, file: "click_limit_test.gob"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.file != "" {
+				defer os.Remove(tt.file)
+			}
+
+			ctx := context.Background()
+			s := tt.storage
+
+			require.NoError(t, s.SaveData(ctx, map[string]map[string]string{"owner": {"abc": "https://example.com", "free": "https://example.org"}}))
+
+			_, err := s.ConfigureLink(ctx, "abc", func(link *Link) error {
+				link.MaxClicks = 5
+				link.RemainingClicks = 5
+				return nil
+			})
+			require.NoError(t, err)
+
+			var (
+				wg        sync.WaitGroup
+				consumed  int64
+				exhausted int64
+			)
+			for i := 0; i < 20; i++ {
+				wg.Add(1)
+				go func() {
+					defer wg.Done()
+					err := s.ConsumeClick(ctx, "abc")
+					switch {
+					case err == nil:
+						atomic.AddInt64(&consumed, 1)
+					case errors.Is(err, ErrClicksExhausted):
+						atomic.AddInt64(&exhausted, 1)
+					}
+				}()
+			}
+			wg.Wait()
+
+			assert.Equal(t, int64(5), consumed)
+			assert.Equal(t, int64(15), exhausted)
+
+			link, err := s.GetLink(ctx, "abc")
+			require.NoError(t, err)
+			assert.True(t, link.ClicksExhausted())
+			assert.Equal(t, int64(1), link.Version)
+
+			//ссылки без ограничения не исчерпываются
+			assert.NoError(t, s.ConsumeClick(ctx, "free"))
+			assert.ErrorIs(t, s.ConsumeClick(ctx, "nope"), ErrNotFound)
+		})
+	}
+}
@@ -0,0 +1,84 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestStorage_CreateLink проверяет, что ссылка сохраняется вместе с настройками, а при ошибке настроек не создаётся.
+func TestStorage_CreateLink(t *testing.T) {
+	type createStorage interface {
+		CreateLink(context.Context, string, string, string, UpdateFunc) (Link, error)
+		GetLink(context.Context, string) (Link, error)
+		GetLinkHistory(context.Context, string) ([]Revision, error)
+		GetURLByShortname(context.Context, string) (string, bool)
+		AddPoolKeys(context.Context, []string) (int, error)
+		CountPoolKeys(context.Context) (int, error)
+	}
+
+	tests := []struct {
+		name    string
+		storage createStorage
+		file    string
+	}{
+		{name: "memory", storage: NewMemoryWork(map[string]map[string]string{})},
+		{name: "file", storage: FileSystemConnect{Filename: "create_test.gob"}, file: "create_test.gob"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.file != "" {
+				defer os.Remove(tt.file)
+			}
+
+			ctx := context.Background()
+			s := tt.storage
+
+			_, err := s.AddPoolKeys(ctx, []string{"abc"})
+			require.NoError(t, err)
+
+			link, err := s.CreateLink(ctx, "owner", "abc", "https://example.com", func(link *Link) error {
+				link.PasswordHash = "hash"
+				link.MaxClicks = 1
+				link.RemainingClicks = 1
+				return nil
+			})
+			require.NoError(t, err)
+			assert.Equal(t, "owner", link.UserID)
+
+			link, err = s.GetLink(ctx, "abc")
+			require.NoError(t, err)
+			assert.Equal(t, "https://example.com", link.OriginalURL)
+			assert.Equal(t, "hash", link.PasswordHash)
+			assert.Equal(t, int64(1), link.RemainingClicks)
+			assert.Equal(t, int64(1), link.Version)
+			assert.False(t, link.CreatedAt.IsZero())
+
+			history, err := s.GetLinkHistory(ctx, "abc")
+			require.NoError(t, err)
+			assert.Len(t, history, 1)
+
+			count, err := s.CountPoolKeys(ctx)
+			require.NoError(t, err)
+			assert.Zero(t, count)
+
+			errConfigure := errors.New("configure failed")
+			_, err = s.CreateLink(ctx, "owner", "def", "https://example.org", func(link *Link) error { return errConfigure })
+			assert.ErrorIs(t, err, errConfigure)
+
+			_, err = s.GetLink(ctx, "def")
+			assert.ErrorIs(t, err, ErrNotFound)
+			_, deleted := s.GetURLByShortname(ctx, "def")
+			assert.False(t, deleted)
+
+			_, err = s.CreateLink(ctx, "owner", "ghi", "https://example.net", nil)
+			require.NoError(t, err)
+			url, _ := s.GetURLByShortname(ctx, "ghi")
+			assert.Equal(t, "https://example.net", url)
+		})
+	}
+}
@@ -0,0 +1,731 @@
+package storage
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// dataSet содержит данные хранилищ в памяти и в файле и реализует общие для них операции.
+type dataSet struct {
+	URLs      map[string]map[string]string
+	Pool      map[string]PoolKey
+	Links     map[string]Link
+	Revisions map[string][]Revision
+	Clicks    []Click
+
+	Rollups         map[string]Rollup
+	RollupWatermark time.Time
+
+	ClickSalt []byte
+
+	Webhooks   map[string]Webhook
+	Deliveries map[string]Delivery
+
+	Campaigns map[string]Campaign
+
+	//index не сохраняется в файл и строится заново при чтении данных.
+	index *searchIndex
+}
+
+// init создаёт незаполненные мапы.
+func (d *dataSet) init() {
+	if d.URLs == nil {
+		d.URLs = map[string]map[string]string{}
+	}
+	if d.Pool == nil {
+		d.Pool = map[string]PoolKey{}
+	}
+	if d.Links == nil {
+		d.Links = map[string]Link{}
+	}
+	if d.Revisions == nil {
+		d.Revisions = map[string][]Revision{}
+	}
+	if d.Rollups == nil {
+		d.Rollups = map[string]Rollup{}
+	}
+	if d.Webhooks == nil {
+		d.Webhooks = map[string]Webhook{}
+	}
+	if d.Deliveries == nil {
+		d.Deliveries = map[string]Delivery{}
+	}
+	if d.Campaigns == nil {
+		d.Campaigns = map[string]Campaign{}
+	}
+}
+
+// buildIndex строит индекс поиска по всем ссылкам.
+func (d *dataSet) buildIndex() {
+	d.index = newSearchIndex()
+
+	for _, values := range d.URLs {
+		for shortURL := range values {
+			if link, ok := d.findLink(shortURL); ok {
+				d.index.put(link)
+			}
+		}
+	}
+}
+
+// saveData сохраняет пользовательские ссылки.
+func (d dataSet) saveData(data map[string]map[string]string) {
+	now := time.Now()
+
+	for userID, values := range data {
+		if _, ok := d.URLs[userID]; !ok {
+			d.URLs[userID] = map[string]string{}
+		}
+
+		for shortURL, originalURL := range values {
+			d.URLs[userID][shortURL] = originalURL
+			delete(d.Pool, shortURL)
+
+			if _, ok := d.Links[shortURL]; !ok {
+				d.Links[shortURL] = Link{ShortURL: shortURL, Version: 1, CreatedAt: now, UpdatedAt: now}
+			}
+
+			link, ok := d.findLink(shortURL)
+			if !ok {
+				continue
+			}
+			if len(d.Revisions[shortURL]) == 0 {
+				d.Revisions[shortURL] = []Revision{newRevision(link)}
+			}
+			if d.index != nil {
+				d.index.put(link)
+			}
+		}
+	}
+}
+
+// copyURLs возвращает копию мапы пользовательских ссылок.
+func (d dataSet) copyURLs() map[string]map[string]string {
+	result := make(map[string]map[string]string, len(d.URLs))
+
+	for userID, values := range d.URLs {
+		links := make(map[string]string, len(values))
+		for shortURL, originalURL := range values {
+			links[shortURL] = originalURL
+		}
+		result[userID] = links
+	}
+
+	return result
+}
+
+// deleteData помечает на удаление ссылки пользователя.
+func (d dataSet) deleteData(arrayToDelete []string, user string) {
+	for _, shortURL := range arrayToDelete {
+		_, _ = d.updateLink(user, shortURL, 0, func(link *Link) error {
+			if link.IsDeleted {
+				return ErrDeleted
+			}
+			link.IsDeleted = true
+			return nil
+		})
+	}
+}
+
+// getURLByShortname возвращает оригинальный URL и признак удаления ссылки.
+func (d dataSet) getURLByShortname(shortname string) (string, bool) {
+	for _, value := range d.URLs {
+		if originalURL, ok := value[shortname]; ok {
+			if originalURL[0] == '-' {
+				return "", true
+			}
+			return originalURL, false
+		}
+	}
+
+	return "", false
+}
+
+// statistic возвращает количество ссылок и пользователей.
+func (d dataSet) statistic() (urls int, users int) {
+	for _, v := range d.URLs {
+		urls += len(v)
+	}
+
+	return urls, len(d.URLs)
+}
+
+// countShortnames возвращает количество коротких имён заданной длины.
+func (d dataSet) countShortnames(length int) (count int) {
+	for _, value := range d.URLs {
+		for shortname := range value {
+			if utf8.RuneCountInString(shortname) == length {
+				count++
+			}
+		}
+	}
+	return count
+}
+
+// shortnameExists проверяет, используется ли короткое имя.
+func (d dataSet) shortnameExists(shortname string) bool {
+	for _, value := range d.URLs {
+		if _, ok := value[shortname]; ok {
+			return true
+		}
+	}
+	return false
+}
+
+// addPoolKeys добавляет в пул имена, которые ещё не используются.
+func (d dataSet) addPoolKeys(keys []string) (added int) {
+	for _, key := range keys {
+		if _, ok := d.Pool[key]; ok || d.shortnameExists(key) {
+			continue
+		}
+		d.Pool[key] = PoolKey{}
+		added++
+	}
+
+	return added
+}
+
+// claimPoolKey закрепляет свободное имя из пула за экземпляром сервиса.
+func (d dataSet) claimPoolKey(instanceID string) (string, error) {
+	for key, value := range d.Pool {
+		if value.ClaimedBy == "" {
+			d.Pool[key] = PoolKey{ClaimedBy: instanceID, ClaimedAt: time.Now()}
+			return key, nil
+		}
+	}
+
+	return "", ErrPoolEmpty
+}
+
+// reclaimPoolKeys возвращает в пул имена, закреплённые раньше before и так и не использованные.
+// Второе значение сообщает, были ли изменены данные.
+func (d dataSet) reclaimPoolKeys(before time.Time) (reclaimed int, changed bool) {
+	for key, value := range d.Pool {
+		if value.ClaimedBy == "" || !value.ClaimedAt.Before(before) {
+			continue
+		}
+		changed = true
+		if d.shortnameExists(key) {
+			delete(d.Pool, key)
+			continue
+		}
+		d.Pool[key] = PoolKey{}
+		reclaimed++
+	}
+
+	return reclaimed, changed
+}
+
+// countPoolKeys возвращает количество свободных имён в пуле.
+func (d dataSet) countPoolKeys() (count int) {
+	for _, value := range d.Pool {
+		if value.ClaimedBy == "" {
+			count++
+		}
+	}
+
+	return count
+}
+
+// findLink собирает ссылку из мапы пользовательских ссылок и дополнительных данных ссылок.
+func (d dataSet) findLink(shortname string) (Link, bool) {
+	for userID, values := range d.URLs {
+		originalURL, ok := values[shortname]
+		if !ok {
+			continue
+		}
+
+		link, ok := d.Links[shortname]
+		if !ok {
+			link = Link{ShortURL: shortname, Version: 1}
+		}
+
+		link.UserID = userID
+		link.IsDeleted = originalURL != "" && originalURL[0] == '-'
+		if link.IsDeleted {
+			originalURL = originalURL[1:]
+		}
+		link.OriginalURL = originalURL
+
+		return link, true
+	}
+
+	return Link{}, false
+}
+
+// putLink сохраняет ссылку в мапу пользовательских ссылок и в дополнительные данные ссылок.
+func (d dataSet) putLink(link Link) {
+	for _, values := range d.URLs {
+		delete(values, link.ShortURL)
+	}
+
+	if _, ok := d.URLs[link.UserID]; !ok {
+		d.URLs[link.UserID] = map[string]string{}
+	}
+
+	originalURL := link.OriginalURL
+	if link.IsDeleted {
+		originalURL = "-" + originalURL
+	}
+
+	d.URLs[link.UserID][link.ShortURL] = originalURL
+	d.Links[link.ShortURL] = link
+
+	if d.index != nil {
+		d.index.put(link)
+	}
+}
+
+// updateLink проверяет владельца и версию ссылки, применяет к ней изменение и сохраняет запись в историю.
+func (d dataSet) updateLink(userID, shortname string, version int64, update UpdateFunc) (Link, error) {
+	link, ok := d.findLink(shortname)
+	if !ok {
+		return Link{}, ErrNotFound
+	}
+
+	before := link
+
+	if err := applyUpdate(&link, userID, version, update); err != nil {
+		return Link{}, err
+	}
+
+	d.putLink(link)
+
+	if revisionChanged(before, link) {
+		if len(d.Revisions[shortname]) == 0 {
+			d.Revisions[shortname] = []Revision{newRevision(before)}
+		}
+		d.Revisions[shortname] = append(d.Revisions[shortname], newRevision(link))
+	}
+
+	return link, nil
+}
+
+// configureLink применяет к ссылке изменение настроек без проверки владельца, увеличения версии и записи в историю.
+func (d dataSet) configureLink(shortname string, update UpdateFunc) (Link, error) {
+	link, ok := d.findLink(shortname)
+	if !ok {
+		return Link{}, ErrNotFound
+	}
+
+	if err := update(&link); err != nil {
+		return Link{}, err
+	}
+
+	d.putLink(link)
+
+	return link, nil
+}
+
+// createLink сохраняет новую ссылку пользователя вместе с настройками. Настройки применяются до сохранения,
+// поэтому при ошибке ссылка не создаётся.
+func (d dataSet) createLink(userID, shortname, originalURL string, configure UpdateFunc) (Link, error) {
+	now := time.Now()
+	link := Link{ShortURL: shortname, UserID: userID, OriginalURL: originalURL, Version: 1, CreatedAt: now, UpdatedAt: now}
+
+	if configure != nil {
+		if err := configure(&link); err != nil {
+			return Link{}, err
+		}
+	}
+
+	delete(d.Pool, shortname)
+	d.putLink(link)
+	d.Revisions[shortname] = []Revision{newRevision(link)}
+
+	return link, nil
+}
+
+// consumeClick уменьшает остаток перенаправлений ссылки с ограничением количества переходов.
+func (d dataSet) consumeClick(shortname string) error {
+	link, ok := d.findLink(shortname)
+	if !ok {
+		return ErrNotFound
+	}
+
+	if link.MaxClicks == 0 {
+		return nil
+	}
+
+	if link.ClicksExhausted() {
+		return ErrClicksExhausted
+	}
+
+	link.RemainingClicks--
+	d.putLink(link)
+
+	return nil
+}
+
+// linkHistory возвращает историю изменений ссылки в порядке возрастания версий.
+func (d dataSet) linkHistory(shortname string) ([]Revision, error) {
+	link, ok := d.findLink(shortname)
+	if !ok {
+		return nil, ErrNotFound
+	}
+
+	revisions := append([]Revision(nil), d.Revisions[shortname]...)
+	if len(revisions) == 0 {
+		revisions = []Revision{newRevision(link)}
+	}
+
+	sort.Slice(revisions, func(i, j int) bool { return revisions[i].Version < revisions[j].Version })
+
+	return revisions, nil
+}
+
+// userLinks возвращает все ссылки пользователя.
+func (d dataSet) userLinks(userID string) []Link {
+	result := make([]Link, 0, len(d.URLs[userID]))
+
+	for shortURL := range d.URLs[userID] {
+		if link, ok := d.findLink(shortURL); ok {
+			result = append(result, link)
+		}
+	}
+
+	return result
+}
+
+// findUserLinks возвращает страницу ссылок пользователя, подходящих под условия выборки.
+func (d dataSet) findUserLinks(userID string, filter LinkFilter) LinkPage {
+	var result []Link
+
+	for _, link := range d.userLinks(userID) {
+		if filter.Matches(link) {
+			result = append(result, link)
+		}
+	}
+
+	return filter.page(result)
+}
+
+// searchUserLinks возвращает ссылки пользователя, подходящие под условия поиска, от новых к старым.
+// Если запрос не короче триграммы, проверяются только ссылки, найденные по индексу.
+func (d dataSet) searchUserLinks(userID string, q LinkSearch) []Link {
+	if d.index == nil {
+		d.buildIndex()
+	}
+
+	var result []Link
+
+	shortnames, ok := d.index.candidates(userID, strings.ToLower(q.Query))
+	if !ok {
+		for shortname := range d.URLs[userID] {
+			shortnames = append(shortnames, shortname)
+		}
+	}
+
+	for _, shortname := range shortnames {
+		if link, ok := d.findLink(shortname); ok && link.UserID == userID && q.Matches(link) {
+			result = append(result, link)
+		}
+	}
+
+	return sortSearchResult(result, q.Limit)
+}
+
+// renameTag заменяет тег from на тег to у всех ссылок пользователя и возвращает количество изменённых ссылок.
+func (d dataSet) renameTag(userID, from, to string) (renamed int64) {
+	for _, link := range d.userLinks(userID) {
+		if !hasTag(link.Tags, from) {
+			continue
+		}
+
+		tags := make([]string, 0, len(link.Tags))
+		for _, tag := range link.Tags {
+			if tag != from && tag != to {
+				tags = append(tags, tag)
+			}
+		}
+		link.Tags = append(tags, to)
+		sort.Strings(link.Tags)
+
+		d.putLink(link)
+		renamed++
+	}
+
+	return renamed
+}
+
+// deleteTag убирает тег у всех ссылок пользователя и возвращает количество изменённых ссылок.
+func (d dataSet) deleteTag(userID, tag string) (deleted int64) {
+	for _, link := range d.userLinks(userID) {
+		if !hasTag(link.Tags, tag) {
+			continue
+		}
+
+		tags := make([]string, 0, len(link.Tags)-1)
+		for _, t := range link.Tags {
+			if t != tag {
+				tags = append(tags, t)
+			}
+		}
+		link.Tags = tags
+
+		d.putLink(link)
+		deleted++
+	}
+
+	return deleted
+}
+
+// saveClicks сохраняет переходы и увеличивает счётчики переходов ссылок.
+func (d *dataSet) saveClicks(clicks []Click) {
+	d.Clicks = append(d.Clicks, clicks...)
+
+	for _, c := range clicks {
+		//переходы ботов не учитываются в счётчике переходов ссылки
+		if c.ClickClass() == ClassBot {
+			continue
+		}
+
+		link, ok := d.findLink(c.ShortURL)
+		if !ok {
+			continue
+		}
+		link.Clicks++
+		d.Links[c.ShortURL] = link
+	}
+}
+
+// shortnameSet возвращает множество коротких имён.
+func shortnameSet(shortnames []string) map[string]bool {
+	set := make(map[string]bool, len(shortnames))
+
+	for _, shortname := range shortnames {
+		set[shortname] = true
+	}
+
+	return set
+}
+
+// linksClicks возвращает переходы по ссылкам shortnames за период [from, to).
+func (d dataSet) linksClicks(shortnames []string, from, to time.Time) []Click {
+	var result []Click
+
+	set := shortnameSet(shortnames)
+
+	for _, c := range d.Clicks {
+		if set[c.ShortURL] && !c.Time.Before(from) && c.Time.Before(to) {
+			result = append(result, c)
+		}
+	}
+
+	return result
+}
+
+// rollupKey возвращает ключ сводки в мапе сводок.
+func rollupKey(granularity, shortname, class string, start time.Time) string {
+	return granularity + "|" + shortname + "|" + class + "|" + strconv.FormatInt(start.Unix(), 10)
+}
+
+// rollupClicks добавляет в сводки переходы с момента предыдущего сведения до to.
+func (d *dataSet) rollupClicks(to time.Time, aggregate AggregateFunc) time.Time {
+	if !d.RollupWatermark.Before(to) {
+		return d.RollupWatermark
+	}
+
+	var clicks []Click
+
+	for _, c := range d.Clicks {
+		if !c.Time.Before(d.RollupWatermark) && c.Time.Before(to) {
+			clicks = append(clicks, c)
+		}
+	}
+
+	for _, r := range aggregate(clicks) {
+		key := rollupKey(r.Granularity, r.ShortURL, r.Class, r.Start)
+
+		existing, ok := d.Rollups[key]
+		if !ok {
+			existing = Rollup{ShortURL: r.ShortURL, Granularity: r.Granularity, Start: r.Start, Class: r.Class}
+		}
+		existing.Merge(r)
+
+		d.Rollups[key] = existing
+	}
+
+	d.RollupWatermark = to
+
+	return to
+}
+
+// linksRollups возвращает сводки ссылок shortnames, начинающиеся в период [from, to).
+func (d dataSet) linksRollups(shortnames []string, granularity string, from, to time.Time) []Rollup {
+	var result []Rollup
+
+	set := shortnameSet(shortnames)
+
+	for _, r := range d.Rollups {
+		if set[r.ShortURL] && r.Granularity == granularity && !r.Start.Before(from) && r.Start.Before(to) {
+			result = append(result, r)
+		}
+	}
+
+	sort.Slice(result, func(i, j int) bool {
+		if !result[i].Start.Equal(result[j].Start) {
+			return result[i].Start.Before(result[j].Start)
+		}
+		return result[i].Class < result[j].Class
+	})
+
+	return result
+}
+
+// clickSalt возвращает сохранённую соль хэширования адресов, сохраняя candidate, если соли ещё нет.
+// Второе значение сообщает, что соль была сохранена.
+func (d *dataSet) clickSalt(candidate []byte) ([]byte, bool) {
+	if len(d.ClickSalt) != 0 {
+		return append([]byte(nil), d.ClickSalt...), false
+	}
+
+	d.ClickSalt = append([]byte(nil), candidate...)
+
+	return append([]byte(nil), candidate...), true
+}
+
+// deleteClicksBefore удаляет переходы, совершённые раньше before.
+func (d *dataSet) deleteClicksBefore(before time.Time) int64 {
+	clicks := d.Clicks[:0]
+
+	for _, c := range d.Clicks {
+		if !c.Time.Before(before) {
+			clicks = append(clicks, c)
+		}
+	}
+
+	deleted := int64(len(d.Clicks) - len(clicks))
+	d.Clicks = clicks
+
+	return deleted
+}
+
+// userWebhooks возвращает вебхуки пользователя в порядке регистрации.
+func (d dataSet) userWebhooks(userID string) []Webhook {
+	var result []Webhook
+
+	for _, w := range d.Webhooks {
+		if w.UserID == userID {
+			result = append(result, w)
+		}
+	}
+
+	sort.Slice(result, func(i, j int) bool { return result[i].CreatedAt.Before(result[j].CreatedAt) })
+
+	return result
+}
+
+// deleteWebhook удаляет вебхук пользователя вместе с его очередью доставки.
+func (d *dataSet) deleteWebhook(userID, id string) error {
+	w, ok := d.Webhooks[id]
+	if !ok {
+		return ErrNotFound
+	}
+	if w.UserID != userID {
+		return ErrNotOwner
+	}
+
+	delete(d.Webhooks, id)
+
+	for key, delivery := range d.Deliveries {
+		if delivery.WebhookID == id {
+			delete(d.Deliveries, key)
+		}
+	}
+
+	return nil
+}
+
+// claimDeliveries выбирает до limit ожидающих уведомлений, время доставки которых наступило,
+// и откладывает их следующую попытку на lease, чтобы их не взял в работу другой обработчик.
+func (d *dataSet) claimDeliveries(now time.Time, limit int, lease time.Duration) []Delivery {
+	var due []Delivery
+
+	for _, delivery := range d.Deliveries {
+		if delivery.Status == DeliveryPending && !delivery.NextAttempt.After(now) {
+			due = append(due, delivery)
+		}
+	}
+
+	sort.Slice(due, func(i, j int) bool { return due[i].NextAttempt.Before(due[j].NextAttempt) })
+
+	if len(due) > limit {
+		due = due[:limit]
+	}
+
+	for i := range due {
+		due[i].NextAttempt = now.Add(lease)
+		d.Deliveries[due[i].ID] = due[i]
+	}
+
+	return due
+}
+
+// renewDelivery продлевает аренду уведомления до until, если оно всё ещё выдано с арендой до claimed.
+func (d *dataSet) renewDelivery(id string, claimed, until time.Time) error {
+	delivery, ok := d.Deliveries[id]
+	if !ok || delivery.Status != DeliveryPending || !delivery.NextAttempt.Equal(claimed) {
+		return ErrLeaseLost
+	}
+
+	delivery.NextAttempt = until
+	d.Deliveries[id] = delivery
+
+	return nil
+}
+
+// userDeliveries возвращает уведомления пользователя с указанным статусом в порядке создания.
+func (d dataSet) userDeliveries(userID, status string) []Delivery {
+	var result []Delivery
+
+	for _, delivery := range d.Deliveries {
+		if delivery.UserID == userID && delivery.Status == status {
+			result = append(result, delivery)
+		}
+	}
+
+	sort.Slice(result, func(i, j int) bool { return result[i].CreatedAt.Before(result[j].CreatedAt) })
+
+	return result
+}
+
+// userCampaigns возвращает кампании пользователя в порядке создания.
+func (d dataSet) userCampaigns(userID string) []Campaign {
+	var result []Campaign
+
+	for _, c := range d.Campaigns {
+		if c.UserID == userID {
+			result = append(result, c)
+		}
+	}
+
+	sort.Slice(result, func(i, j int) bool { return result[i].CreatedAt.Before(result[j].CreatedAt) })
+
+	return result
+}
+
+// deleteCampaign удаляет кампанию пользователя и исключает из неё ссылки.
+func (d dataSet) deleteCampaign(userID, id string) error {
+	c, ok := d.Campaigns[id]
+	if !ok {
+		return ErrNotFound
+	}
+	if c.UserID != userID {
+		return ErrNotOwner
+	}
+
+	delete(d.Campaigns, id)
+
+	for shortname, link := range d.Links {
+		if link.CampaignID == id {
+			link.CampaignID = ""
+			d.Links[shortname] = link
+		}
+	}
+
+	return nil
+}
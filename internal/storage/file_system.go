@@ -7,6 +7,8 @@ import (
 	"errors"
 	"fmt"
 	"os"
+	"sync"
+	"time"
 )
 
 // FileSystemConnect содержит имя файла.
@@ -14,6 +16,18 @@ type FileSystemConnect struct {
 	Filename string
 }
 
+// fileLocks хранит блокировки для каждого файла хранилища.
+var fileLocks sync.Map
+
+// fileIndexes хранит построенные индексы поиска для каждого файла хранилища до следующей записи в файл.
+var fileIndexes sync.Map
+
+// lock возвращает блокировку файла хранилища.
+func (s FileSystemConnect) lock() *sync.RWMutex {
+	mu, _ := fileLocks.LoadOrStore(s.Filename, &sync.RWMutex{})
+	return mu.(*sync.RWMutex)
+}
+
 // openFile возвращает ссылку на открытый файл.
 func (s FileSystemConnect) openFile(flag int) *os.File {
 
@@ -26,9 +40,9 @@ func (s FileSystemConnect) openFile(flag int) *os.File {
 	return dataFile
 }
 
-// ReadData читает данные из файла.
-func (s FileSystemConnect) ReadData(context.Context) map[string]map[string]string {
-	var data map[string]map[string]string
+// load читает всё содержимое файла. Поддерживается и прежний формат файла, содержащий только ссылки.
+func (s FileSystemConnect) load() dataSet {
+	var data dataSet
 
 	dataFile := s.openFile(os.O_RDONLY)
 
@@ -40,31 +54,29 @@ func (s FileSystemConnect) ReadData(context.Context) map[string]map[string]strin
 		}
 	}(dataFile)
 
-	err := gob.NewDecoder(dataFile).Decode(&data)
+	if err := gob.NewDecoder(dataFile).Decode(&data); err != nil {
+		var urls map[string]map[string]string
 
-	if err != nil {
-		data = map[string]map[string]string{}
+		if _, err = dataFile.Seek(0, 0); err == nil {
+			err = gob.NewDecoder(dataFile).Decode(&urls)
+		}
+		if err != nil {
+			urls = nil
+		}
+
+		data = dataSet{URLs: urls}
 	}
 
+	data.init()
+
 	return data
 }
 
-// SaveData сохраняет данные в файл.
-func (s FileSystemConnect) SaveData(ctx context.Context, d map[string]map[string]string) error {
-	data := s.ReadData(ctx)
-
-	for userID, values := range d {
-		if _, ok := data[userID]; !ok {
-			data[userID] = map[string]string{}
-		}
+// store перезаписывает содержимое файла.
+func (s FileSystemConnect) store(data dataSet) error {
+	fileIndexes.Delete(s.Filename)
 
-		for shortURL, originalURL := range values {
-			data[userID][shortURL] = originalURL
-		}
-
-	}
-
-	dataFile := s.openFile(os.O_WRONLY)
+	dataFile := s.openFile(os.O_WRONLY | os.O_TRUNC)
 	defer dataFile.Close()
 
 	writer := bufio.NewWriter(dataFile)
@@ -82,20 +94,51 @@ func (s FileSystemConnect) SaveData(ctx context.Context, d map[string]map[string
 	}
 
 	return nil
+}
+
+// searchIndex возвращает индекс поиска по данным файла, построенный при предыдущем поиске, или строит его заново.
+func (s FileSystemConnect) searchIndex(data dataSet) *searchIndex {
+	if index, ok := fileIndexes.Load(s.Filename); ok {
+		return index.(*searchIndex)
+	}
+
+	data.buildIndex()
+	fileIndexes.Store(s.Filename, data.index)
+
+	return data.index
+}
+
+// ReadData читает данные из файла.
+func (s FileSystemConnect) ReadData(context.Context) map[string]map[string]string {
+	mu := s.lock()
+	mu.RLock()
+	defer mu.RUnlock()
+
+	return s.load().URLs
+}
+
+// SaveData сохраняет данные в файл.
+func (s FileSystemConnect) SaveData(_ context.Context, d map[string]map[string]string) error {
+	mu := s.lock()
+	mu.Lock()
+	defer mu.Unlock()
+
+	data := s.load()
+	data.saveData(d)
 
+	return s.store(data)
 }
 
 // DeleteData удаляет данные из файла.
 func (s FileSystemConnect) DeleteData(arrayToDelete []string, user string) {
-	data := s.ReadData(context.Background())
+	mu := s.lock()
+	mu.Lock()
+	defer mu.Unlock()
 
-	for _, shortURL := range arrayToDelete {
-		if _, isDelete := s.GetURLByShortname(context.Background(), shortURL); !isDelete {
-			data[user][shortURL] = "-" + data[user][shortURL]
-		}
-	}
+	data := s.load()
+	data.deleteData(arrayToDelete, user)
 
-	err := s.SaveData(context.Background(), data)
+	err := s.store(data)
 	if err != nil {
 		fmt.Println(err)
 		os.Exit(1)
@@ -103,20 +146,12 @@ func (s FileSystemConnect) DeleteData(arrayToDelete []string, user string) {
 }
 
 // GetURLByShortname возвращает исходный URL на основе исходной ссылки.
-func (s FileSystemConnect) GetURLByShortname(ctx context.Context, shortname string) (string, bool) {
+func (s FileSystemConnect) GetURLByShortname(_ context.Context, shortname string) (string, bool) {
+	mu := s.lock()
+	mu.RLock()
+	defer mu.RUnlock()
 
-	data := s.ReadData(ctx)
-
-	for _, value := range data {
-		if originalURL, ok := value[shortname]; ok {
-			if originalURL[0] == '-' {
-				return "", true
-			}
-			return originalURL, false
-		}
-	}
-
-	return "", false
+	return s.load().getURLByShortname(shortname)
 }
 
 // PingDBConnection - заглушка для интерфейса.
@@ -127,10 +162,520 @@ func (s FileSystemConnect) PingDBConnection(ctx context.Context) error {
 
 // GetStatistic - возвращает количество ссылок и пользователей
 func (s FileSystemConnect) GetStatistic() (urls int, users int) {
-	data := s.ReadData(context.Background())
-	for _, v := range data {
-		urls += len(v)
+	mu := s.lock()
+	mu.RLock()
+	defer mu.RUnlock()
+
+	return s.load().statistic()
+}
+
+// CountShortnames возвращает количество коротких имён заданной длины.
+func (s FileSystemConnect) CountShortnames(length int) int {
+	mu := s.lock()
+	mu.RLock()
+	defer mu.RUnlock()
+
+	return s.load().countShortnames(length)
+}
+
+// AddPoolKeys добавляет в пул имена, которые ещё не используются.
+func (s FileSystemConnect) AddPoolKeys(_ context.Context, keys []string) (int, error) {
+	mu := s.lock()
+	mu.Lock()
+	defer mu.Unlock()
+
+	data := s.load()
+
+	added := data.addPoolKeys(keys)
+	if added == 0 {
+		return 0, nil
+	}
+
+	return added, s.store(data)
+}
+
+// ClaimPoolKey атомарно закрепляет свободное имя из пула за экземпляром сервиса.
+func (s FileSystemConnect) ClaimPoolKey(_ context.Context, instanceID string) (string, error) {
+	mu := s.lock()
+	mu.Lock()
+	defer mu.Unlock()
+
+	data := s.load()
+
+	key, err := data.claimPoolKey(instanceID)
+	if err != nil {
+		return "", err
+	}
+
+	return key, s.store(data)
+}
+
+// ReclaimPoolKeys возвращает в пул имена, закреплённые раньше before и так и не использованные.
+func (s FileSystemConnect) ReclaimPoolKeys(_ context.Context, before time.Time) (int, error) {
+	mu := s.lock()
+	mu.Lock()
+	defer mu.Unlock()
+
+	data := s.load()
+
+	reclaimed, changed := data.reclaimPoolKeys(before)
+	if !changed {
+		return 0, nil
+	}
+
+	return reclaimed, s.store(data)
+}
+
+// HasPoolKey проверяет, находится ли имя в пуле.
+func (s FileSystemConnect) HasPoolKey(_ context.Context, key string) (bool, error) {
+	mu := s.lock()
+	mu.RLock()
+	defer mu.RUnlock()
+
+	_, ok := s.load().Pool[key]
+
+	return ok, nil
+}
+
+// CountPoolKeys возвращает количество свободных имён в пуле.
+func (s FileSystemConnect) CountPoolKeys(context.Context) (int, error) {
+	mu := s.lock()
+	mu.RLock()
+	defer mu.RUnlock()
+
+	return s.load().countPoolKeys(), nil
+}
+
+// GetLink возвращает ссылку по короткому имени.
+func (s FileSystemConnect) GetLink(_ context.Context, shortname string) (Link, error) {
+	mu := s.lock()
+	mu.RLock()
+	defer mu.RUnlock()
+
+	link, ok := s.load().findLink(shortname)
+	if !ok {
+		return Link{}, ErrNotFound
+	}
+
+	return link, nil
+}
+
+// UpdateLink изменяет ссылку пользователя. Если version не равна нулю, она должна совпадать с текущей версией ссылки.
+func (s FileSystemConnect) UpdateLink(_ context.Context, userID, shortname string, version int64, update UpdateFunc) (Link, error) {
+	mu := s.lock()
+	mu.Lock()
+	defer mu.Unlock()
+
+	data := s.load()
+
+	link, err := data.updateLink(userID, shortname, version, update)
+	if err != nil {
+		return Link{}, err
+	}
+
+	return link, s.store(data)
+}
+
+// ConfigureLink задаёт настройки только что созданной ссылки без увеличения версии и записи в историю.
+func (s FileSystemConnect) ConfigureLink(_ context.Context, shortname string, update UpdateFunc) (Link, error) {
+	mu := s.lock()
+	mu.Lock()
+	defer mu.Unlock()
+
+	data := s.load()
+
+	link, err := data.configureLink(shortname, update)
+	if err != nil {
+		return Link{}, err
+	}
+
+	return link, s.store(data)
+}
+
+// CreateLink сохраняет новую ссылку пользователя вместе с настройками configure одной записью.
+func (s FileSystemConnect) CreateLink(_ context.Context, userID, shortname, originalURL string, configure UpdateFunc) (Link, error) {
+	mu := s.lock()
+	mu.Lock()
+	defer mu.Unlock()
+
+	data := s.load()
+
+	link, err := data.createLink(userID, shortname, originalURL, configure)
+	if err != nil {
+		return Link{}, err
+	}
+
+	return link, s.store(data)
+}
+
+// ConsumeClick атомарно уменьшает остаток перенаправлений ссылки. Если остаток исчерпан, возвращается ErrClicksExhausted.
+func (s FileSystemConnect) ConsumeClick(_ context.Context, shortname string) error {
+	mu := s.lock()
+	mu.Lock()
+	defer mu.Unlock()
+
+	data := s.load()
+
+	if err := data.consumeClick(shortname); err != nil {
+		return err
+	}
+
+	return s.store(data)
+}
+
+// GetLinkHistory возвращает историю изменений ссылки.
+func (s FileSystemConnect) GetLinkHistory(_ context.Context, shortname string) ([]Revision, error) {
+	mu := s.lock()
+	mu.RLock()
+	defer mu.RUnlock()
+
+	return s.load().linkHistory(shortname)
+}
+
+// GetUserLinks возвращает все ссылки пользователя.
+func (s FileSystemConnect) GetUserLinks(_ context.Context, userID string) ([]Link, error) {
+	mu := s.lock()
+	mu.RLock()
+	defer mu.RUnlock()
+
+	return s.load().userLinks(userID), nil
+}
+
+// FindUserLinks возвращает страницу ссылок пользователя, подходящих под условия выборки.
+func (s FileSystemConnect) FindUserLinks(_ context.Context, userID string, filter LinkFilter) (LinkPage, error) {
+	mu := s.lock()
+	mu.RLock()
+	defer mu.RUnlock()
+
+	return s.load().findUserLinks(userID, filter), nil
+}
+
+// SearchUserLinks ищет ссылки пользователя в файле по индексу, который строится заново после каждой записи в файл.
+func (s FileSystemConnect) SearchUserLinks(_ context.Context, userID string, q LinkSearch) ([]Link, error) {
+	mu := s.lock()
+	mu.RLock()
+	defer mu.RUnlock()
+
+	data := s.load()
+	data.index = s.searchIndex(data)
+
+	return data.searchUserLinks(userID, q), nil
+}
+
+// RenameTag заменяет тег у всех ссылок пользователя и возвращает количество изменённых ссылок.
+func (s FileSystemConnect) RenameTag(_ context.Context, userID, from, to string) (int64, error) {
+	mu := s.lock()
+	mu.Lock()
+	defer mu.Unlock()
+
+	data := s.load()
+
+	renamed := data.renameTag(userID, from, to)
+	if renamed == 0 {
+		return 0, nil
+	}
+
+	return renamed, s.store(data)
+}
+
+// DeleteTag убирает тег у всех ссылок пользователя и возвращает количество изменённых ссылок.
+func (s FileSystemConnect) DeleteTag(_ context.Context, userID, tag string) (int64, error) {
+	mu := s.lock()
+	mu.Lock()
+	defer mu.Unlock()
+
+	data := s.load()
+
+	deleted := data.deleteTag(userID, tag)
+	if deleted == 0 {
+		return 0, nil
+	}
+
+	return deleted, s.store(data)
+}
+
+// SaveClicks сохраняет пачку переходов по ссылкам.
+func (s FileSystemConnect) SaveClicks(_ context.Context, clicks []Click) error {
+	mu := s.lock()
+	mu.Lock()
+	defer mu.Unlock()
+
+	data := s.load()
+	data.saveClicks(clicks)
+
+	return s.store(data)
+}
+
+// GetClicks возвращает переходы по ссылкам shortnames за период [from, to).
+func (s FileSystemConnect) GetClicks(_ context.Context, shortnames []string, from, to time.Time) ([]Click, error) {
+	mu := s.lock()
+	mu.RLock()
+	defer mu.RUnlock()
+
+	return s.load().linksClicks(shortnames, from, to), nil
+}
+
+// GetRollupWatermark возвращает момент, до которого переходы уже добавлены в сводки.
+func (s FileSystemConnect) GetRollupWatermark(context.Context) (time.Time, error) {
+	mu := s.lock()
+	mu.RLock()
+	defer mu.RUnlock()
+
+	return s.load().RollupWatermark, nil
+}
+
+// RollupClicks добавляет в сводки переходы с момента предыдущего сведения до to и возвращает новую границу.
+func (s FileSystemConnect) RollupClicks(_ context.Context, to time.Time, aggregate AggregateFunc) (time.Time, error) {
+	mu := s.lock()
+	mu.Lock()
+	defer mu.Unlock()
+
+	data := s.load()
+
+	watermark := data.RollupWatermark
+	if !watermark.Before(to) {
+		return watermark, nil
+	}
+
+	return data.rollupClicks(to, aggregate), s.store(data)
+}
+
+// GetRollups возвращает сводки ссылок shortnames, начинающиеся в период [from, to).
+func (s FileSystemConnect) GetRollups(_ context.Context, shortnames []string, granularity string, from, to time.Time) ([]Rollup, error) {
+	mu := s.lock()
+	mu.RLock()
+	defer mu.RUnlock()
+
+	return s.load().linksRollups(shortnames, granularity, from, to), nil
+}
+
+// DeleteClicksBefore удаляет переходы, совершённые раньше before.
+func (s FileSystemConnect) DeleteClicksBefore(_ context.Context, before time.Time) (int64, error) {
+	mu := s.lock()
+	mu.Lock()
+	defer mu.Unlock()
+
+	data := s.load()
+
+	deleted := data.deleteClicksBefore(before)
+	if deleted == 0 {
+		return 0, nil
+	}
+
+	return deleted, s.store(data)
+}
+
+// ClickSalt возвращает соль хэширования адресов переходов, сохраняя candidate в файл, если соли ещё нет.
+func (s FileSystemConnect) ClickSalt(_ context.Context, candidate []byte) ([]byte, error) {
+	mu := s.lock()
+	mu.Lock()
+	defer mu.Unlock()
+
+	data := s.load()
+
+	salt, saved := data.clickSalt(candidate)
+	if !saved {
+		return salt, nil
+	}
+
+	return salt, s.store(data)
+}
+
+// SaveWebhook сохраняет вебхук.
+func (s FileSystemConnect) SaveWebhook(_ context.Context, w Webhook) error {
+	mu := s.lock()
+	mu.Lock()
+	defer mu.Unlock()
+
+	data := s.load()
+	data.Webhooks[w.ID] = w
+
+	return s.store(data)
+}
+
+// GetWebhook возвращает вебхук по идентификатору.
+func (s FileSystemConnect) GetWebhook(_ context.Context, id string) (Webhook, error) {
+	mu := s.lock()
+	mu.RLock()
+	defer mu.RUnlock()
+
+	w, ok := s.load().Webhooks[id]
+	if !ok {
+		return Webhook{}, ErrNotFound
+	}
+
+	return w, nil
+}
+
+// GetWebhooks возвращает вебхуки пользователя.
+func (s FileSystemConnect) GetWebhooks(_ context.Context, userID string) ([]Webhook, error) {
+	mu := s.lock()
+	mu.RLock()
+	defer mu.RUnlock()
+
+	return s.load().userWebhooks(userID), nil
+}
+
+// DeleteWebhook удаляет вебхук пользователя вместе с его очередью доставки.
+func (s FileSystemConnect) DeleteWebhook(_ context.Context, userID, id string) error {
+	mu := s.lock()
+	mu.Lock()
+	defer mu.Unlock()
+
+	data := s.load()
+
+	if err := data.deleteWebhook(userID, id); err != nil {
+		return err
+	}
+
+	return s.store(data)
+}
+
+// EnqueueDeliveries добавляет уведомления в очередь доставки.
+func (s FileSystemConnect) EnqueueDeliveries(_ context.Context, deliveries []Delivery) error {
+	mu := s.lock()
+	mu.Lock()
+	defer mu.Unlock()
+
+	data := s.load()
+
+	for _, delivery := range deliveries {
+		data.Deliveries[delivery.ID] = delivery
+	}
+
+	return s.store(data)
+}
+
+// ClaimDeliveries выбирает до limit уведомлений, время доставки которых наступило, и откладывает их повторную выдачу на lease.
+func (s FileSystemConnect) ClaimDeliveries(_ context.Context, now time.Time, limit int, lease time.Duration) ([]Delivery, error) {
+	mu := s.lock()
+	mu.Lock()
+	defer mu.Unlock()
+
+	data := s.load()
+
+	deliveries := data.claimDeliveries(now, limit, lease)
+	if len(deliveries) == 0 {
+		return nil, nil
+	}
+
+	return deliveries, s.store(data)
+}
+
+// RenewDelivery продлевает аренду уведомления до until. Если аренда до claimed истекла и уведомление выдано
+// другому обработчику, возвращается ErrLeaseLost.
+func (s FileSystemConnect) RenewDelivery(_ context.Context, id string, claimed, until time.Time) error {
+	mu := s.lock()
+	mu.Lock()
+	defer mu.Unlock()
+
+	data := s.load()
+
+	if err := data.renewDelivery(id, claimed, until); err != nil {
+		return err
+	}
+
+	return s.store(data)
+}
+
+// UpdateDelivery сохраняет состояние уведомления.
+func (s FileSystemConnect) UpdateDelivery(_ context.Context, delivery Delivery) error {
+	mu := s.lock()
+	mu.Lock()
+	defer mu.Unlock()
+
+	data := s.load()
+
+	if _, ok := data.Deliveries[delivery.ID]; !ok {
+		return ErrNotFound
+	}
+
+	data.Deliveries[delivery.ID] = delivery
+
+	return s.store(data)
+}
+
+// DeleteDelivery удаляет доставленное уведомление из очереди.
+func (s FileSystemConnect) DeleteDelivery(_ context.Context, id string) error {
+	mu := s.lock()
+	mu.Lock()
+	defer mu.Unlock()
+
+	data := s.load()
+	delete(data.Deliveries, id)
+
+	return s.store(data)
+}
+
+// GetDelivery возвращает уведомление по идентификатору.
+func (s FileSystemConnect) GetDelivery(_ context.Context, id string) (Delivery, error) {
+	mu := s.lock()
+	mu.RLock()
+	defer mu.RUnlock()
+
+	delivery, ok := s.load().Deliveries[id]
+	if !ok {
+		return Delivery{}, ErrNotFound
+	}
+
+	return delivery, nil
+}
+
+// GetDeliveries возвращает уведомления пользователя с указанным статусом.
+func (s FileSystemConnect) GetDeliveries(_ context.Context, userID, status string) ([]Delivery, error) {
+	mu := s.lock()
+	mu.RLock()
+	defer mu.RUnlock()
+
+	return s.load().userDeliveries(userID, status), nil
+}
+
+// SaveCampaign сохраняет новую или изменённую кампанию.
+func (s FileSystemConnect) SaveCampaign(_ context.Context, c Campaign) error {
+	mu := s.lock()
+	mu.Lock()
+	defer mu.Unlock()
+
+	data := s.load()
+	data.Campaigns[c.ID] = c
+
+	return s.store(data)
+}
+
+// GetCampaign возвращает кампанию по идентификатору.
+func (s FileSystemConnect) GetCampaign(_ context.Context, id string) (Campaign, error) {
+	mu := s.lock()
+	mu.RLock()
+	defer mu.RUnlock()
+
+	c, ok := s.load().Campaigns[id]
+	if !ok {
+		return Campaign{}, ErrNotFound
+	}
+
+	return c, nil
+}
+
+// GetCampaigns возвращает кампании пользователя.
+func (s FileSystemConnect) GetCampaigns(_ context.Context, userID string) ([]Campaign, error) {
+	mu := s.lock()
+	mu.RLock()
+	defer mu.RUnlock()
+
+	return s.load().userCampaigns(userID), nil
+}
+
+// DeleteCampaign удаляет кампанию пользователя и исключает из неё ссылки.
+func (s FileSystemConnect) DeleteCampaign(_ context.Context, userID, id string) error {
+	mu := s.lock()
+	mu.Lock()
+	defer mu.Unlock()
+
+	data := s.load()
+
+	if err := data.deleteCampaign(userID, id); err != nil {
+		return err
 	}
 
-	return urls, len(data)
+	return s.store(data)
 }
@@ -0,0 +1,70 @@
+package storage
+
+import (
+	"context"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestStorage_LinkHistory проверяет запись истории изменений ссылки в памяти и в файле.
+func TestStorage_LinkHistory(t *testing.T) {
+	type historyStorage interface {
+		SaveData(context.Context, map[string]map[string]string) error
+		DeleteData([]string, string)
+		UpdateLink(context.Context, string, string, int64, UpdateFunc) (Link, error)
+		GetLinkHistory(context.Context, string) ([]Revision, error)
+	}
+
+	tests := []struct {
+		name    string
+		storage historyStorage
+		file    string
+	}{
+		{name: "memory", storage: NewMemoryWork(map[string]map[string]string{})},
+		{name: "file", storage: FileSystemConnect{Filename: "history_test.gob"}, file: "history_test.gob"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.file != "" {
+				defer os.Remove(tt.file)
+			}
+
+			ctx := context.Background()
+			s := tt.storage
+
+			require.NoError(t, s.SaveData(ctx, map[string]map[string]string{"owner": {"abc": "https://example.com"}}))
+
+			_, err := s.UpdateLink(ctx, "owner", "abc", 1, func(link *Link) error {
+				link.OriginalURL = "https://example.org"
+				return nil
+			})
+			require.NoError(t, err)
+
+			//устаревшая версия не изменяет ссылку и не попадает в историю
+			_, err = s.UpdateLink(ctx, "owner", "abc", 1, func(link *Link) error {
+				link.OriginalURL = "https://example.net"
+				return nil
+			})
+			assert.ErrorIs(t, err, ErrVersionConflict)
+
+			s.DeleteData([]string{"abc"}, "owner")
+
+			revisions, err := s.GetLinkHistory(ctx, "abc")
+			require.NoError(t, err)
+			require.Len(t, revisions, 3)
+
+			assert.Equal(t, int64(1), revisions[0].Version)
+			assert.Equal(t, "https://example.com", revisions[0].OriginalURL)
+			assert.Equal(t, int64(2), revisions[1].Version)
+			assert.Equal(t, "https://example.org", revisions[1].OriginalURL)
+			assert.Equal(t, int64(3), revisions[2].Version)
+			assert.True(t, revisions[2].IsDeleted)
+
+			_, err = s.GetLinkHistory(ctx, "unknown")
+			assert.ErrorIs(t, err, ErrNotFound)
+		})
+	}
+}
@@ -0,0 +1,94 @@
+package storage
+
+import (
+	"context"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestStorage_FindUserLinksPages проверяет сортировку ссылок пользователя и постраничную выборку по позиции.
+func TestStorage_FindUserLinksPages(t *testing.T) {
+	type pageStorage interface {
+		SaveData(context.Context, map[string]map[string]string) error
+		ConfigureLink(context.Context, string, UpdateFunc) (Link, error)
+		FindUserLinks(context.Context, string, LinkFilter) (LinkPage, error)
+	}
+
+	tests := []struct {
+		name    string
+		storage pageStorage
+		file    string
+	}{
+		{name: "memory", storage: NewMemoryWork(map[string]map[string]string{})},
+		{name: "file", storage: FileSystemConnect{Filename: "listing_test.gob"}, file: "listing_test.gob"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.file != "" {
+				defer os.Remove(tt.file)
+			}
+
+			ctx := context.Background()
+			s := tt.storage
+
+			require.NoError(t, s.SaveData(ctx, map[string]map[string]string{
+				"owner": {"a": "https://example.com/a", "b": "https://example.com/b", "c": "https://example.com/c", "d": "https://example.com/d"},
+			}))
+
+			now := time.Now().UTC()
+			settings := map[string]Link{
+				"a": {CreatedAt: now.Add(-3 * time.Hour), Clicks: 5},
+				"b": {CreatedAt: now.Add(-time.Hour), Clicks: 1, CampaignID: "spring"},
+				"c": {CreatedAt: now.Add(-time.Hour), Clicks: 5},
+				"d": {CreatedAt: now, Clicks: 0, CampaignID: "spring"},
+			}
+			for short, v := range settings {
+				v := v
+				_, err := s.ConfigureLink(ctx, short, func(link *Link) error {
+					link.CreatedAt = v.CreatedAt
+					link.Clicks = v.Clicks
+					link.CampaignID = v.CampaignID
+					return nil
+				})
+				require.NoError(t, err)
+			}
+
+			//pages собирает короткие имена всех страниц выборки
+			pages := func(filter LinkFilter) (result [][]string) {
+				for {
+					page, err := s.FindUserLinks(ctx, "owner", filter)
+					require.NoError(t, err)
+					assert.Equal(t, int64(4), page.Total)
+
+					var names []string
+					for _, link := range page.Links {
+						names = append(names, link.ShortURL)
+					}
+					result = append(result, names)
+
+					if !page.HasMore {
+						return result
+					}
+					cursor := page.Links[len(page.Links)-1].Cursor()
+					filter.After = &cursor
+				}
+			}
+
+			assert.Equal(t, [][]string{{"a", "b", "c", "d"}}, pages(LinkFilter{}))
+			assert.Equal(t, [][]string{{"d", "c", "b"}, {"a"}}, pages(LinkFilter{Desc: true, Limit: 3}))
+			assert.Equal(t, [][]string{{"c", "a"}, {"b", "d"}}, pages(LinkFilter{Sort: LinkSortClicks, Desc: true, Limit: 2}))
+			assert.Equal(t, [][]string{{"a"}, {"b"}, {"c"}, {"d"}}, pages(LinkFilter{Sort: LinkSortAlias, Limit: 1}))
+
+			page, err := s.FindUserLinks(ctx, "owner", LinkFilter{Campaign: "spring"})
+			require.NoError(t, err)
+			assert.Equal(t, int64(2), page.Total)
+			require.Len(t, page.Links, 2)
+			assert.Equal(t, "b", page.Links[0].ShortURL)
+			assert.Equal(t, "d", page.Links[1].ShortURL)
+		})
+	}
+}
@@ -3,31 +3,47 @@ package storage
 import (
 	"context"
 	"errors"
+	"sync"
+	"time"
 )
 
-// MemoryWork хранит данные в мапе.
+// MemoryWork хранит данные в мапе. Создаётся только конструктором NewMemoryWork: все копии значения работают
+// с общими данными под общей блокировкой.
 type MemoryWork struct {
-	UserData map[string]map[string]string
+	state *memoryState
 }
 
-// ReadData возвращает мапу с данными.
+// memoryState хранит данные хранилища в памяти вместе с блокировкой.
+type memoryState struct {
+	mu   sync.Mutex
+	data dataSet
+}
+
+// NewMemoryWork - конструктор MemoryWork. Ссылки из userData копируются, поэтому переданную мапу можно
+// изменять после вызова без блокировки хранилища.
+func NewMemoryWork(userData map[string]map[string]string) MemoryWork {
+	data := dataSet{URLs: dataSet{URLs: userData}.copyURLs()}
+	data.init()
+	data.buildIndex()
+
+	return MemoryWork{state: &memoryState{data: data}}
+}
+
+// ReadData возвращает копию мапы с данными. Копия делается под блокировкой, чтобы вызывающий код мог обходить её
+// одновременно с записью новых ссылок.
 func (s MemoryWork) ReadData(context.Context) map[string]map[string]string {
-	return s.UserData
+	s.state.mu.Lock()
+	defer s.state.mu.Unlock()
+
+	return s.state.data.copyURLs()
 }
 
 // SaveData сохраняет пользовательские ссылки в память.
 func (s MemoryWork) SaveData(_ context.Context, d map[string]map[string]string) error {
+	s.state.mu.Lock()
+	defer s.state.mu.Unlock()
 
-	for userID, values := range d {
-		if _, ok := s.UserData[userID]; !ok {
-			s.UserData[userID] = map[string]string{}
-		}
-
-		for shortURL, originalURL := range values {
-			s.UserData[userID][shortURL] = originalURL
-		}
-
-	}
+	s.state.data.saveData(d)
 
 	return nil
 
@@ -35,27 +51,18 @@ func (s MemoryWork) SaveData(_ context.Context, d map[string]map[string]string)
 
 // DeleteData помечает на удаление сохранённые ссылки.
 func (s MemoryWork) DeleteData(arrayToDelete []string, user string) {
+	s.state.mu.Lock()
+	defer s.state.mu.Unlock()
 
-	for _, shortURL := range arrayToDelete {
-		if _, isDelete := s.GetURLByShortname(context.Background(), shortURL); !isDelete {
-			s.UserData[user][shortURL] = "-" + s.UserData[user][shortURL]
-		}
-	}
+	s.state.data.deleteData(arrayToDelete, user)
 }
 
 // GetURLByShortname возвращает оригинальный URL из памяти на основе сокращённок ссылки.
 func (s MemoryWork) GetURLByShortname(_ context.Context, shortname string) (string, bool) {
+	s.state.mu.Lock()
+	defer s.state.mu.Unlock()
 
-	for _, value := range s.UserData {
-		if originalURL, ok := value[shortname]; ok {
-			if originalURL[0] == '-' {
-				return "", true
-			}
-			return originalURL, false
-		}
-	}
-
-	return "", false
+	return s.state.data.getURLByShortname(shortname)
 }
 
 // PingDBConnection - заглушка для работы интерфейса.
@@ -66,9 +73,365 @@ func (s MemoryWork) PingDBConnection(context.Context) error {
 
 // GetStatistic возвращает данные статистики
 func (s MemoryWork) GetStatistic() (urls int, users int) {
-	for _, v := range s.UserData {
-		urls += len(v)
+	s.state.mu.Lock()
+	defer s.state.mu.Unlock()
+
+	return s.state.data.statistic()
+}
+
+// CountShortnames возвращает количество коротких имён заданной длины.
+func (s MemoryWork) CountShortnames(length int) int {
+	s.state.mu.Lock()
+	defer s.state.mu.Unlock()
+
+	return s.state.data.countShortnames(length)
+}
+
+// AddPoolKeys добавляет в пул имена, которые ещё не используются.
+func (s MemoryWork) AddPoolKeys(_ context.Context, keys []string) (int, error) {
+	s.state.mu.Lock()
+	defer s.state.mu.Unlock()
+
+	return s.state.data.addPoolKeys(keys), nil
+}
+
+// ClaimPoolKey атомарно закрепляет свободное имя из пула за экземпляром сервиса.
+func (s MemoryWork) ClaimPoolKey(_ context.Context, instanceID string) (string, error) {
+	s.state.mu.Lock()
+	defer s.state.mu.Unlock()
+
+	return s.state.data.claimPoolKey(instanceID)
+}
+
+// ReclaimPoolKeys возвращает в пул имена, закреплённые раньше before и так и не использованные.
+func (s MemoryWork) ReclaimPoolKeys(_ context.Context, before time.Time) (int, error) {
+	s.state.mu.Lock()
+	defer s.state.mu.Unlock()
+
+	reclaimed, _ := s.state.data.reclaimPoolKeys(before)
+
+	return reclaimed, nil
+}
+
+// HasPoolKey проверяет, находится ли имя в пуле.
+func (s MemoryWork) HasPoolKey(_ context.Context, key string) (bool, error) {
+	s.state.mu.Lock()
+	defer s.state.mu.Unlock()
+
+	_, ok := s.state.data.Pool[key]
+
+	return ok, nil
+}
+
+// CountPoolKeys возвращает количество свободных имён в пуле.
+func (s MemoryWork) CountPoolKeys(context.Context) (int, error) {
+	s.state.mu.Lock()
+	defer s.state.mu.Unlock()
+
+	return s.state.data.countPoolKeys(), nil
+}
+
+// GetLink возвращает ссылку по короткому имени.
+func (s MemoryWork) GetLink(_ context.Context, shortname string) (Link, error) {
+	s.state.mu.Lock()
+	defer s.state.mu.Unlock()
+
+	link, ok := s.state.data.findLink(shortname)
+	if !ok {
+		return Link{}, ErrNotFound
+	}
+
+	return link, nil
+}
+
+// UpdateLink изменяет ссылку пользователя. Если version не равна нулю, она должна совпадать с текущей версией ссылки.
+func (s MemoryWork) UpdateLink(_ context.Context, userID, shortname string, version int64, update UpdateFunc) (Link, error) {
+	s.state.mu.Lock()
+	defer s.state.mu.Unlock()
+
+	return s.state.data.updateLink(userID, shortname, version, update)
+}
+
+// ConfigureLink задаёт настройки только что созданной ссылки без увеличения версии и записи в историю.
+func (s MemoryWork) ConfigureLink(_ context.Context, shortname string, update UpdateFunc) (Link, error) {
+	s.state.mu.Lock()
+	defer s.state.mu.Unlock()
+
+	return s.state.data.configureLink(shortname, update)
+}
+
+// CreateLink сохраняет новую ссылку пользователя вместе с настройками configure одной записью.
+func (s MemoryWork) CreateLink(_ context.Context, userID, shortname, originalURL string, configure UpdateFunc) (Link, error) {
+	s.state.mu.Lock()
+	defer s.state.mu.Unlock()
+
+	return s.state.data.createLink(userID, shortname, originalURL, configure)
+}
+
+// ConsumeClick атомарно уменьшает остаток перенаправлений ссылки. Если остаток исчерпан, возвращается ErrClicksExhausted.
+func (s MemoryWork) ConsumeClick(_ context.Context, shortname string) error {
+	s.state.mu.Lock()
+	defer s.state.mu.Unlock()
+
+	return s.state.data.consumeClick(shortname)
+}
+
+// GetLinkHistory возвращает историю изменений ссылки.
+func (s MemoryWork) GetLinkHistory(_ context.Context, shortname string) ([]Revision, error) {
+	s.state.mu.Lock()
+	defer s.state.mu.Unlock()
+
+	return s.state.data.linkHistory(shortname)
+}
+
+// GetUserLinks возвращает все ссылки пользователя.
+func (s MemoryWork) GetUserLinks(_ context.Context, userID string) ([]Link, error) {
+	s.state.mu.Lock()
+	defer s.state.mu.Unlock()
+
+	return s.state.data.userLinks(userID), nil
+}
+
+// FindUserLinks возвращает страницу ссылок пользователя, подходящих под условия выборки.
+func (s MemoryWork) FindUserLinks(_ context.Context, userID string, filter LinkFilter) (LinkPage, error) {
+	s.state.mu.Lock()
+	defer s.state.mu.Unlock()
+
+	return s.state.data.findUserLinks(userID, filter), nil
+}
+
+// SearchUserLinks ищет ссылки пользователя по индексу в памяти.
+func (s MemoryWork) SearchUserLinks(_ context.Context, userID string, q LinkSearch) ([]Link, error) {
+	s.state.mu.Lock()
+	defer s.state.mu.Unlock()
+
+	return s.state.data.searchUserLinks(userID, q), nil
+}
+
+// RenameTag заменяет тег у всех ссылок пользователя и возвращает количество изменённых ссылок.
+func (s MemoryWork) RenameTag(_ context.Context, userID, from, to string) (int64, error) {
+	s.state.mu.Lock()
+	defer s.state.mu.Unlock()
+
+	return s.state.data.renameTag(userID, from, to), nil
+}
+
+// DeleteTag убирает тег у всех ссылок пользователя и возвращает количество изменённых ссылок.
+func (s MemoryWork) DeleteTag(_ context.Context, userID, tag string) (int64, error) {
+	s.state.mu.Lock()
+	defer s.state.mu.Unlock()
+
+	return s.state.data.deleteTag(userID, tag), nil
+}
+
+// SaveClicks сохраняет пачку переходов по ссылкам.
+func (s MemoryWork) SaveClicks(_ context.Context, clicks []Click) error {
+	s.state.mu.Lock()
+	defer s.state.mu.Unlock()
+
+	s.state.data.saveClicks(clicks)
+
+	return nil
+}
+
+// GetClicks возвращает переходы по ссылкам shortnames за период [from, to).
+func (s MemoryWork) GetClicks(_ context.Context, shortnames []string, from, to time.Time) ([]Click, error) {
+	s.state.mu.Lock()
+	defer s.state.mu.Unlock()
+
+	return s.state.data.linksClicks(shortnames, from, to), nil
+}
+
+// GetRollupWatermark возвращает момент, до которого переходы уже добавлены в сводки.
+func (s MemoryWork) GetRollupWatermark(context.Context) (time.Time, error) {
+	s.state.mu.Lock()
+	defer s.state.mu.Unlock()
+
+	return s.state.data.RollupWatermark, nil
+}
+
+// RollupClicks добавляет в сводки переходы с момента предыдущего сведения до to и возвращает новую границу.
+func (s MemoryWork) RollupClicks(_ context.Context, to time.Time, aggregate AggregateFunc) (time.Time, error) {
+	s.state.mu.Lock()
+	defer s.state.mu.Unlock()
+
+	return s.state.data.rollupClicks(to, aggregate), nil
+}
+
+// GetRollups возвращает сводки ссылок shortnames, начинающиеся в период [from, to).
+func (s MemoryWork) GetRollups(_ context.Context, shortnames []string, granularity string, from, to time.Time) ([]Rollup, error) {
+	s.state.mu.Lock()
+	defer s.state.mu.Unlock()
+
+	return s.state.data.linksRollups(shortnames, granularity, from, to), nil
+}
+
+// DeleteClicksBefore удаляет переходы, совершённые раньше before.
+func (s MemoryWork) DeleteClicksBefore(_ context.Context, before time.Time) (int64, error) {
+	s.state.mu.Lock()
+	defer s.state.mu.Unlock()
+
+	return s.state.data.deleteClicksBefore(before), nil
+}
+
+// ClickSalt возвращает соль хэширования адресов переходов, сохраняя candidate, если соли ещё нет.
+func (s MemoryWork) ClickSalt(_ context.Context, candidate []byte) ([]byte, error) {
+	s.state.mu.Lock()
+	defer s.state.mu.Unlock()
+
+	salt, _ := s.state.data.clickSalt(candidate)
+
+	return salt, nil
+}
+
+// SaveWebhook сохраняет вебхук.
+func (s MemoryWork) SaveWebhook(_ context.Context, w Webhook) error {
+	s.state.mu.Lock()
+	defer s.state.mu.Unlock()
+
+	s.state.data.Webhooks[w.ID] = w
+
+	return nil
+}
+
+// GetWebhook возвращает вебхук по идентификатору.
+func (s MemoryWork) GetWebhook(_ context.Context, id string) (Webhook, error) {
+	s.state.mu.Lock()
+	defer s.state.mu.Unlock()
+
+	w, ok := s.state.data.Webhooks[id]
+	if !ok {
+		return Webhook{}, ErrNotFound
 	}
 
-	return urls, len(s.UserData)
+	return w, nil
+}
+
+// GetWebhooks возвращает вебхуки пользователя.
+func (s MemoryWork) GetWebhooks(_ context.Context, userID string) ([]Webhook, error) {
+	s.state.mu.Lock()
+	defer s.state.mu.Unlock()
+
+	return s.state.data.userWebhooks(userID), nil
+}
+
+// DeleteWebhook удаляет вебхук пользователя вместе с его очередью доставки.
+func (s MemoryWork) DeleteWebhook(_ context.Context, userID, id string) error {
+	s.state.mu.Lock()
+	defer s.state.mu.Unlock()
+
+	return s.state.data.deleteWebhook(userID, id)
+}
+
+// EnqueueDeliveries добавляет уведомления в очередь доставки.
+func (s MemoryWork) EnqueueDeliveries(_ context.Context, deliveries []Delivery) error {
+	s.state.mu.Lock()
+	defer s.state.mu.Unlock()
+
+	for _, delivery := range deliveries {
+		s.state.data.Deliveries[delivery.ID] = delivery
+	}
+
+	return nil
+}
+
+// ClaimDeliveries выбирает до limit уведомлений, время доставки которых наступило, и откладывает их повторную выдачу на lease.
+func (s MemoryWork) ClaimDeliveries(_ context.Context, now time.Time, limit int, lease time.Duration) ([]Delivery, error) {
+	s.state.mu.Lock()
+	defer s.state.mu.Unlock()
+
+	return s.state.data.claimDeliveries(now, limit, lease), nil
+}
+
+// RenewDelivery продлевает аренду уведомления до until. Если аренда до claimed истекла и уведомление выдано
+// другому обработчику, возвращается ErrLeaseLost.
+func (s MemoryWork) RenewDelivery(_ context.Context, id string, claimed, until time.Time) error {
+	s.state.mu.Lock()
+	defer s.state.mu.Unlock()
+
+	return s.state.data.renewDelivery(id, claimed, until)
+}
+
+// UpdateDelivery сохраняет состояние уведомления.
+func (s MemoryWork) UpdateDelivery(_ context.Context, delivery Delivery) error {
+	s.state.mu.Lock()
+	defer s.state.mu.Unlock()
+
+	if _, ok := s.state.data.Deliveries[delivery.ID]; !ok {
+		return ErrNotFound
+	}
+
+	s.state.data.Deliveries[delivery.ID] = delivery
+
+	return nil
+}
+
+// DeleteDelivery удаляет доставленное уведомление из очереди.
+func (s MemoryWork) DeleteDelivery(_ context.Context, id string) error {
+	s.state.mu.Lock()
+	defer s.state.mu.Unlock()
+
+	delete(s.state.data.Deliveries, id)
+
+	return nil
+}
+
+// GetDelivery возвращает уведомление по идентификатору.
+func (s MemoryWork) GetDelivery(_ context.Context, id string) (Delivery, error) {
+	s.state.mu.Lock()
+	defer s.state.mu.Unlock()
+
+	delivery, ok := s.state.data.Deliveries[id]
+	if !ok {
+		return Delivery{}, ErrNotFound
+	}
+
+	return delivery, nil
+}
+
+// GetDeliveries возвращает уведомления пользователя с указанным статусом.
+func (s MemoryWork) GetDeliveries(_ context.Context, userID, status string) ([]Delivery, error) {
+	s.state.mu.Lock()
+	defer s.state.mu.Unlock()
+
+	return s.state.data.userDeliveries(userID, status), nil
+}
+
+// SaveCampaign сохраняет новую или изменённую кампанию.
+func (s MemoryWork) SaveCampaign(_ context.Context, c Campaign) error {
+	s.state.mu.Lock()
+	defer s.state.mu.Unlock()
+
+	s.state.data.Campaigns[c.ID] = c
+
+	return nil
+}
+
+// GetCampaign возвращает кампанию по идентификатору.
+func (s MemoryWork) GetCampaign(_ context.Context, id string) (Campaign, error) {
+	s.state.mu.Lock()
+	defer s.state.mu.Unlock()
+
+	c, ok := s.state.data.Campaigns[id]
+	if !ok {
+		return Campaign{}, ErrNotFound
+	}
+
+	return c, nil
+}
+
+// GetCampaigns возвращает кампании пользователя.
+func (s MemoryWork) GetCampaigns(_ context.Context, userID string) ([]Campaign, error) {
+	s.state.mu.Lock()
+	defer s.state.mu.Unlock()
+
+	return s.state.data.userCampaigns(userID), nil
+}
+
+// DeleteCampaign удаляет кампанию пользователя и исключает из неё ссылки.
+func (s MemoryWork) DeleteCampaign(_ context.Context, userID, id string) error {
+	s.state.mu.Lock()
+	defer s.state.mu.Unlock()
+
+	return s.state.data.deleteCampaign(userID, id)
 }
@@ -2,8 +2,12 @@ package storage
 
 import (
 	"context"
+	"fmt"
 	"reflect"
+	"sync"
 	"testing"
+
+	"github.com/stretchr/testify/assert"
 )
 
 func TestMemoryStorage_WriteReadData(t *testing.T) {
@@ -19,9 +23,7 @@ func TestMemoryStorage_WriteReadData(t *testing.T) {
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			s := MemoryWork{
-				UserData: make(map[string]map[string]string),
-			}
+			s := NewMemoryWork(make(map[string]map[string]string))
 			ctx := context.Background()
 			s.SaveData(ctx, tt.want)
 			if got := s.ReadData(ctx); !reflect.DeepEqual(got, tt.want) {
@@ -41,3 +43,60 @@ func TestMemoryStorage_WriteReadData(t *testing.T) {
 
 	}
 }
+
+// TestMemoryStorage_ReadDataConcurrent проверяет, что ReadData возвращает копию данных, которую можно обходить
+// одновременно с сохранением новых ссылок.
+func TestMemoryStorage_ReadDataConcurrent(t *testing.T) {
+	s := NewMemoryWork(make(map[string]map[string]string))
+	ctx := context.Background()
+
+	var wg sync.WaitGroup
+
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		for i := 0; i < 200; i++ {
+			s.SaveData(ctx, map[string]map[string]string{"user": {fmt.Sprint(i): "https://example.com"}})
+		}
+	}()
+	go func() {
+		defer wg.Done()
+		for i := 0; i < 200; i++ {
+			for _, value := range s.ReadData(ctx) {
+				for range value {
+				}
+			}
+		}
+	}()
+	wg.Wait()
+
+	data := s.ReadData(ctx)
+	assert.Len(t, data["user"], 200)
+
+	delete(data["user"], "0")
+	assert.Len(t, s.ReadData(ctx)["user"], 200)
+}
+
+// TestMemoryStorage_CountShortnames проверяет, что учитываются только короткие имена заданной длины.
+func TestMemoryStorage_CountShortnames(t *testing.T) {
+	s := NewMemoryWork(map[string]map[string]string{
+		"first":  {"a": "https://a.example.com", "bc": "https://bc.example.com"},
+		"second": {"de": "https://de.example.com", "fgh": "https://fgh.example.com"},
+	})
+
+	tests := []struct {
+		name   string
+		length int
+		want   int
+	}{
+		{"one", 1, 1},
+		{"two", 2, 2},
+		{"three", 3, 1},
+		{"missing", 4, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, s.CountShortnames(tt.length))
+		})
+	}
+}
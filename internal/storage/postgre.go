@@ -3,14 +3,18 @@ package storage
 import (
 	"context"
 	"database/sql"
+	"encoding/json"
 	"errors"
 	"log"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/golang-migrate/migrate/v4"
 	_ "github.com/golang-migrate/migrate/v4/database/postgres"
 	_ "github.com/golang-migrate/migrate/v4/source/file"
 
+	"github.com/jackc/pgerrcode"
 	"github.com/lib/pq"
 )
 
@@ -26,6 +30,122 @@ type URLRow struct {
 	OriginalURL string
 }
 
+// linkColumns - список столбцов для чтения ссылки функцией scanLink.
+const linkColumns = `urls.shortURL, urls.originalURL, users.user_Cookie, urls.isDelete, urls.version, urls.created_at, urls.updated_at, urls.clicks,
+	urls.password_hash, urls.max_clicks, urls.remaining_clicks, urls.not_before, urls.not_after, urls.target_rules, urls.variants,
+	urls.redirect_status, urls.cache_control, urls.meta_refresh, urls.passthrough, COALESCE(urls.campaign_ID, ''), urls.tags, urls.folder,
+	urls.title, urls.metadata`
+
+// scanLink читает ссылку из строки результата запроса со столбцами linkColumns.
+func scanLink(row interface{ Scan(...any) error }) (link Link, err error) {
+	var (
+		notBefore, notAfter sql.NullTime
+		rules, variants     []byte
+		metadata            []byte
+	)
+
+	err = row.Scan(&link.ShortURL, &link.OriginalURL, &link.UserID, &link.IsDeleted, &link.Version, &link.CreatedAt, &link.UpdatedAt, &link.Clicks,
+		&link.PasswordHash, &link.MaxClicks, &link.RemainingClicks, &notBefore, &notAfter, &rules, &variants,
+		&link.RedirectStatus, &link.CacheControl, &link.MetaRefresh, &link.Passthrough, &link.CampaignID, pq.Array(&link.Tags), &link.Folder,
+		&link.Title, &metadata)
+	if err != nil {
+		return Link{}, err
+	}
+
+	link.NotBefore = notBefore.Time
+	link.NotAfter = notAfter.Time
+
+	if err = json.Unmarshal(rules, &link.Rules); err != nil {
+		return Link{}, err
+	}
+	if err = json.Unmarshal(variants, &link.Variants); err != nil {
+		return Link{}, err
+	}
+	if err = json.Unmarshal(metadata, &link.Metadata); err != nil {
+		return Link{}, err
+	}
+
+	return link, nil
+}
+
+// marshalList возвращает json представление списка. Пустой список сохраняется как пустой массив.
+func marshalList[T any](list []T) ([]byte, error) {
+	if list == nil {
+		list = []T{}
+	}
+
+	return json.Marshal(list)
+}
+
+// nullTime возвращает NULL для нулевого времени.
+func nullTime(t time.Time) sql.NullTime {
+	return sql.NullTime{Time: t, Valid: !t.IsZero()}
+}
+
+// saveLink сохраняет изменённую ссылку в рамках транзакции.
+func saveLink(ctx context.Context, tx *sql.Tx, link Link) error {
+	rules, err := marshalList(link.Rules)
+	if err != nil {
+		return err
+	}
+	variants, err := marshalList(link.Variants)
+	if err != nil {
+		return err
+	}
+	metadata, err := json.Marshal(link.Metadata)
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.ExecContext(ctx, `
+	UPDATE urls SET
+		originalURL = $2,
+		isDelete = $3,
+		version = $4,
+		updated_at = $5,
+		user_ID = (SELECT user_ID FROM users WHERE user_Cookie = $6),
+		password_hash = $7,
+		max_clicks = $8,
+		remaining_clicks = $9,
+		not_before = $10,
+		not_after = $11,
+		target_rules = $12,
+		variants = $13,
+		redirect_status = $14,
+		cache_control = $15,
+		meta_refresh = $16,
+		passthrough = $17,
+		campaign_ID = NULLIF($18, ''),
+		tags = $19,
+		folder = $20,
+		title = $21,
+		metadata = $22
+	WHERE shortURL = $1;`,
+		link.ShortURL, link.OriginalURL, link.IsDeleted, link.Version, link.UpdatedAt, link.UserID,
+		link.PasswordHash, link.MaxClicks, link.RemainingClicks, nullTime(link.NotBefore), nullTime(link.NotAfter), rules, variants,
+		link.RedirectStatus, link.CacheControl, link.MetaRefresh, link.Passthrough, link.CampaignID, pq.Array(nonNilTags(link.Tags)), link.Folder,
+		link.Title, metadata)
+
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) && pgerrcode.IsIntegrityConstraintViolation(string(pqErr.Code)) {
+		return ErrURLExists
+	}
+
+	return err
+}
+
+// lockLink читает ссылку и блокирует её строку до конца транзакции.
+func lockLink(ctx context.Context, tx *sql.Tx, shortname string) (Link, error) {
+	row := tx.QueryRowContext(ctx, "SELECT "+linkColumns+" FROM urls INNER JOIN users ON users.user_ID = urls.user_ID WHERE urls.shortURL = $1 FOR UPDATE OF urls;", shortname)
+
+	link, err := scanLink(row)
+	if errors.Is(err, sql.ErrNoRows) {
+		return Link{}, ErrNotFound
+	}
+
+	return link, err
+}
+
 // GetNewConnection - конструктор PostgreConnect.
 func GetNewConnection(db *sql.DB, dbConf string, migrationAddress string) PostgreConnect {
 
@@ -140,6 +260,22 @@ func (s PostgreConnect) SaveData(ctx context.Context, d map[string]map[string]st
 
 	defer sqlInsertData.Close()
 
+	sqlDeletePoolKey, err := tx.Prepare("DELETE FROM key_pool WHERE key = $1;")
+	if err != nil {
+		log.Print(err)
+		return err
+	}
+
+	defer sqlDeletePoolKey.Close()
+
+	sqlInsertRevision, err := tx.Prepare("INSERT INTO url_revisions (shortURL, version, originalURL, user_ID) VALUES ($2, 1, $3, (SELECT user_ID from users WHERE user_Cookie=$1));")
+	if err != nil {
+		log.Print(err)
+		return err
+	}
+
+	defer sqlInsertRevision.Close()
+
 	for userID, values := range d {
 
 		_, err := sqlInsertUser.Exec(userID)
@@ -155,6 +291,18 @@ func (s PostgreConnect) SaveData(ctx context.Context, d map[string]map[string]st
 				log.Print(err)
 				return err
 			}
+
+			_, err = sqlDeletePoolKey.Exec(shortURL)
+			if err != nil {
+				log.Print(err)
+				return err
+			}
+
+			_, err = sqlInsertRevision.Exec(userID, shortURL, originalURL)
+			if err != nil {
+				log.Print(err)
+				return err
+			}
 		}
 	}
 
@@ -172,7 +320,16 @@ func (s PostgreConnect) DeleteData(data []string, user string) {
 	}
 	defer tx.Rollback()
 
-	sqlDeleteURLS, err := tx.Prepare("update urls set isDelete = true from (select unnest($1::text[]) as shortURL) as data_table where urls.shortURL = data_table.shortURL and urls.user_ID = (SELECT user_ID from users WHERE user_Cookie=$2);")
+	sqlDeleteURLS, err := tx.Prepare(`
+	WITH deleted AS (
+		UPDATE urls SET isDelete = true, version = urls.version + 1, updated_at = now()
+		FROM (SELECT unnest($1::text[]) AS shortURL) AS data_table
+		WHERE urls.shortURL = data_table.shortURL AND urls.isDelete = false
+			AND urls.user_ID = (SELECT user_ID from users WHERE user_Cookie=$2)
+		RETURNING urls.shortURL, urls.version, urls.originalURL, urls.user_ID, urls.updated_at
+	)
+	INSERT INTO url_revisions (shortURL, version, originalURL, user_ID, isDelete, changed_at)
+	SELECT shortURL, version, originalURL, user_ID, true, updated_at FROM deleted;`)
 
 	if err != nil {
 		log.Print(err)
@@ -245,3 +402,1283 @@ func (s PostgreConnect) GetStatistic() (urls int, users int) {
 
 	return
 }
+
+// CountShortnames возвращает количество коротких имён заданной длины.
+func (s PostgreConnect) CountShortnames(length int) (count int) {
+	err := s.DBConnect.QueryRow("select count(*) from urls where char_length(shortURL) = $1;", length).Scan(&count)
+	if err != nil {
+		log.Print(err)
+		return 0
+	}
+
+	return count
+}
+
+// AddPoolKeys добавляет в пул имена, которые ещё не используются.
+func (s PostgreConnect) AddPoolKeys(ctx context.Context, keys []string) (int, error) {
+	tx, err := s.DBConnect.BeginTx(ctx, nil)
+	if err != nil {
+		log.Print(err)
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	sqlInsertKey, err := tx.PrepareContext(ctx, "INSERT INTO key_pool (key) SELECT $1 WHERE NOT EXISTS (SELECT 1 FROM urls WHERE shortURL = $1) ON CONFLICT (key) DO NOTHING;")
+	if err != nil {
+		log.Print(err)
+		return 0, err
+	}
+
+	defer sqlInsertKey.Close()
+
+	var added int64
+
+	for _, key := range keys {
+		result, err := sqlInsertKey.ExecContext(ctx, key)
+		if err != nil {
+			log.Print(err)
+			return 0, err
+		}
+
+		n, err := result.RowsAffected()
+		if err != nil {
+			log.Print(err)
+			return 0, err
+		}
+		added += n
+	}
+
+	return int(added), tx.Commit()
+}
+
+// ClaimPoolKey атомарно закрепляет свободное имя из пула за экземпляром сервиса.
+func (s PostgreConnect) ClaimPoolKey(ctx context.Context, instanceID string) (key string, err error) {
+	err = s.DBConnect.QueryRowContext(ctx, `
+	UPDATE key_pool SET claimed_by = $1, claimed_at = now()
+	WHERE key = (SELECT key FROM key_pool WHERE claimed_by IS NULL LIMIT 1 FOR UPDATE SKIP LOCKED)
+	RETURNING key;`, instanceID).Scan(&key)
+
+	if errors.Is(err, sql.ErrNoRows) {
+		return "", ErrPoolEmpty
+	}
+	if err != nil {
+		log.Print(err)
+		return "", err
+	}
+
+	return key, nil
+}
+
+// ReclaimPoolKeys возвращает в пул имена, закреплённые раньше before и так и не использованные.
+func (s PostgreConnect) ReclaimPoolKeys(ctx context.Context, before time.Time) (int, error) {
+	tx, err := s.DBConnect.BeginTx(ctx, nil)
+	if err != nil {
+		log.Print(err)
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	_, err = tx.ExecContext(ctx, "DELETE FROM key_pool USING urls WHERE key_pool.key = urls.shortURL AND key_pool.claimed_at < $1;", before)
+	if err != nil {
+		log.Print(err)
+		return 0, err
+	}
+
+	result, err := tx.ExecContext(ctx, "UPDATE key_pool SET claimed_by = NULL, claimed_at = NULL WHERE claimed_by IS NOT NULL AND claimed_at < $1;", before)
+	if err != nil {
+		log.Print(err)
+		return 0, err
+	}
+
+	reclaimed, err := result.RowsAffected()
+	if err != nil {
+		log.Print(err)
+		return 0, err
+	}
+
+	return int(reclaimed), tx.Commit()
+}
+
+// HasPoolKey проверяет, находится ли имя в пуле.
+func (s PostgreConnect) HasPoolKey(ctx context.Context, key string) (ok bool, err error) {
+	err = s.DBConnect.QueryRowContext(ctx, "SELECT EXISTS (SELECT 1 FROM key_pool WHERE key = $1);", key).Scan(&ok)
+	if err != nil {
+		log.Print(err)
+		return false, err
+	}
+
+	return ok, nil
+}
+
+// CountPoolKeys возвращает количество свободных имён в пуле.
+func (s PostgreConnect) CountPoolKeys(ctx context.Context) (count int, err error) {
+	err = s.DBConnect.QueryRowContext(ctx, "SELECT count(*) FROM key_pool WHERE claimed_by IS NULL;").Scan(&count)
+	if err != nil {
+		log.Print(err)
+		return 0, err
+	}
+
+	return count, nil
+}
+
+// GetLink возвращает ссылку по короткому имени.
+func (s PostgreConnect) GetLink(ctx context.Context, shortname string) (Link, error) {
+	row := s.DBConnect.QueryRowContext(ctx, "SELECT "+linkColumns+" FROM urls INNER JOIN users ON users.user_ID = urls.user_ID WHERE urls.shortURL = $1;", shortname)
+
+	link, err := scanLink(row)
+	if errors.Is(err, sql.ErrNoRows) {
+		return Link{}, ErrNotFound
+	}
+	if err != nil {
+		log.Print(err)
+		return Link{}, err
+	}
+
+	return link, nil
+}
+
+// UpdateLink изменяет ссылку пользователя. Если version не равна нулю, она должна совпадать с текущей версией ссылки.
+func (s PostgreConnect) UpdateLink(ctx context.Context, userID, shortname string, version int64, update UpdateFunc) (Link, error) {
+	tx, err := s.DBConnect.BeginTx(ctx, nil)
+	if err != nil {
+		log.Print(err)
+		return Link{}, err
+	}
+	defer tx.Rollback()
+
+	link, err := lockLink(ctx, tx, shortname)
+	if errors.Is(err, ErrNotFound) {
+		return Link{}, err
+	}
+	if err != nil {
+		log.Print(err)
+		return Link{}, err
+	}
+
+	before := link
+
+	if err = applyUpdate(&link, userID, version, update); err != nil {
+		return Link{}, err
+	}
+
+	err = saveLink(ctx, tx, link)
+	if errors.Is(err, ErrURLExists) {
+		return Link{}, err
+	}
+	if err != nil {
+		log.Print(err)
+		return Link{}, err
+	}
+
+	if revisionChanged(before, link) {
+		_, err = tx.ExecContext(ctx, `
+		INSERT INTO url_revisions (shortURL, version, originalURL, user_ID, isDelete, changed_at)
+		VALUES ($1, $2, $3, (SELECT user_ID FROM users WHERE user_Cookie = $4), $5, $6);`,
+			link.ShortURL, link.Version, link.OriginalURL, link.UserID, link.IsDeleted, link.UpdatedAt)
+		if err != nil {
+			log.Print(err)
+			return Link{}, err
+		}
+	}
+
+	return link, tx.Commit()
+}
+
+// ConfigureLink задаёт настройки только что созданной ссылки без увеличения версии и записи в историю.
+func (s PostgreConnect) ConfigureLink(ctx context.Context, shortname string, update UpdateFunc) (Link, error) {
+	tx, err := s.DBConnect.BeginTx(ctx, nil)
+	if err != nil {
+		log.Print(err)
+		return Link{}, err
+	}
+	defer tx.Rollback()
+
+	link, err := lockLink(ctx, tx, shortname)
+	if errors.Is(err, ErrNotFound) {
+		return Link{}, err
+	}
+	if err != nil {
+		log.Print(err)
+		return Link{}, err
+	}
+
+	if err = update(&link); err != nil {
+		return Link{}, err
+	}
+
+	if err = saveLink(ctx, tx, link); err != nil {
+		log.Print(err)
+		return Link{}, err
+	}
+
+	return link, tx.Commit()
+}
+
+// CreateLink сохраняет новую ссылку пользователя вместе с настройками configure в одной транзакции,
+// поэтому ссылка не становится доступной без настроек. Если исходный URL уже сокращён, возвращается ErrURLExists.
+func (s PostgreConnect) CreateLink(ctx context.Context, userID, shortname, originalURL string, configure UpdateFunc) (Link, error) {
+	tx, err := s.DBConnect.BeginTx(ctx, nil)
+	if err != nil {
+		log.Print(err)
+		return Link{}, err
+	}
+	defer tx.Rollback()
+
+	_, err = tx.ExecContext(ctx, "INSERT INTO users (user_Cookie) VALUES ($1) ON CONFLICT (user_Cookie) DO NOTHING;", userID)
+	if err != nil {
+		log.Print(err)
+		return Link{}, err
+	}
+
+	_, err = tx.ExecContext(ctx, "INSERT INTO urls (user_ID, shortURL, originalURL) VALUES ((SELECT user_ID from users WHERE user_Cookie=$1), $2, $3);",
+		userID, shortname, originalURL)
+
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) && pgerrcode.IsIntegrityConstraintViolation(string(pqErr.Code)) {
+		return Link{}, ErrURLExists
+	}
+	if err != nil {
+		log.Print(err)
+		return Link{}, err
+	}
+
+	_, err = tx.ExecContext(ctx, "DELETE FROM key_pool WHERE key = $1;", shortname)
+	if err != nil {
+		log.Print(err)
+		return Link{}, err
+	}
+
+	link, err := lockLink(ctx, tx, shortname)
+	if err != nil {
+		log.Print(err)
+		return Link{}, err
+	}
+
+	if configure != nil {
+		if err = configure(&link); err != nil {
+			return Link{}, err
+		}
+
+		if err = saveLink(ctx, tx, link); err != nil {
+			log.Print(err)
+			return Link{}, err
+		}
+	}
+
+	_, err = tx.ExecContext(ctx, "INSERT INTO url_revisions (shortURL, version, originalURL, user_ID) VALUES ($2, 1, $3, (SELECT user_ID from users WHERE user_Cookie=$1));",
+		userID, shortname, originalURL)
+	if err != nil {
+		log.Print(err)
+		return Link{}, err
+	}
+
+	return link, tx.Commit()
+}
+
+// ConsumeClick атомарно уменьшает остаток перенаправлений ссылки. Если остаток исчерпан, возвращается ErrClicksExhausted.
+func (s PostgreConnect) ConsumeClick(ctx context.Context, shortname string) error {
+	result, err := s.DBConnect.ExecContext(ctx, `
+	UPDATE urls SET remaining_clicks = remaining_clicks - 1
+	WHERE shortURL = $1 AND max_clicks > 0 AND remaining_clicks > 0;`, shortname)
+	if err != nil {
+		log.Print(err)
+		return err
+	}
+
+	updated, err := result.RowsAffected()
+	if err != nil {
+		log.Print(err)
+		return err
+	}
+
+	if updated > 0 {
+		return nil
+	}
+
+	var maxClicks int64
+
+	err = s.DBConnect.QueryRowContext(ctx, "SELECT max_clicks FROM urls WHERE shortURL = $1;", shortname).Scan(&maxClicks)
+	if errors.Is(err, sql.ErrNoRows) {
+		return ErrNotFound
+	}
+	if err != nil {
+		log.Print(err)
+		return err
+	}
+
+	if maxClicks > 0 {
+		return ErrClicksExhausted
+	}
+
+	return nil
+}
+
+// GetLinkHistory возвращает историю изменений ссылки.
+func (s PostgreConnect) GetLinkHistory(ctx context.Context, shortname string) ([]Revision, error) {
+	link, err := s.GetLink(ctx, shortname)
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := s.DBConnect.QueryContext(ctx, `
+	SELECT r.shortURL, r.version, r.originalURL, users.user_Cookie, r.isDelete, r.changed_at
+	FROM url_revisions r
+	INNER JOIN users ON users.user_ID = r.user_ID
+	WHERE r.shortURL = $1
+	ORDER BY r.version;`, shortname)
+	if err != nil {
+		log.Print(err)
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	var revisions []Revision
+
+	for rows.Next() {
+		var v Revision
+
+		err = rows.Scan(&v.ShortURL, &v.Version, &v.OriginalURL, &v.UserID, &v.IsDeleted, &v.ChangedAt)
+		if err != nil {
+			log.Print(err)
+			return nil, err
+		}
+
+		revisions = append(revisions, v)
+	}
+
+	if err = rows.Err(); err != nil {
+		log.Print(err)
+		return nil, err
+	}
+
+	if len(revisions) == 0 {
+		revisions = []Revision{newRevision(link)}
+	}
+
+	return revisions, nil
+}
+
+// GetUserLinks возвращает все ссылки пользователя.
+func (s PostgreConnect) GetUserLinks(ctx context.Context, userID string) ([]Link, error) {
+	return s.queryLinks(ctx, "SELECT "+linkColumns+" FROM urls INNER JOIN users ON users.user_ID = urls.user_ID WHERE users.user_Cookie = $1;", userID)
+}
+
+// FindUserLinks возвращает страницу ссылок пользователя, подходящих под условия выборки.
+// Страница выбирается по позиции последней полученной ссылки, короткие имена сравниваются побайтово.
+func (s PostgreConnect) FindUserLinks(ctx context.Context, userID string, filter LinkFilter) (LinkPage, error) {
+	const where = `users.user_Cookie = $1 AND urls.tags @> $2 AND ($3 = '' OR urls.folder = $3) AND ($4 = '' OR urls.campaign_ID = $4)`
+
+	args := []any{userID, pq.Array(nonNilTags(filter.Tags)), filter.Folder, filter.Campaign}
+
+	var page LinkPage
+
+	err := s.DBConnect.QueryRowContext(ctx, "SELECT count(*) FROM urls INNER JOIN users ON users.user_ID = urls.user_ID WHERE "+where+";", args...).Scan(&page.Total)
+	if err != nil {
+		log.Print(err)
+		return LinkPage{}, err
+	}
+
+	direction, compare := "ASC", ">"
+	if filter.Desc {
+		direction, compare = "DESC", "<"
+	}
+
+	var column string
+	var value any
+
+	switch filter.Sort {
+	case LinkSortClicks:
+		column = "urls.clicks"
+		if filter.After != nil {
+			value = filter.After.Clicks
+		}
+	case LinkSortAlias:
+	default:
+		column = "urls.created_at"
+		if filter.After != nil {
+			value = filter.After.CreatedAt
+		}
+	}
+
+	order := `urls.shortURL COLLATE "C" ` + direction
+	if column != "" {
+		order = column + " " + direction + ", " + order
+	}
+
+	query := "SELECT " + linkColumns + " FROM urls INNER JOIN users ON users.user_ID = urls.user_ID WHERE " + where
+
+	if filter.After != nil {
+		if column == "" {
+			args = append(args, filter.After.ShortURL)
+			query += ` AND urls.shortURL COLLATE "C" ` + compare + " $5"
+		} else {
+			args = append(args, value, filter.After.ShortURL)
+			query += " AND (" + column + `, urls.shortURL COLLATE "C") ` + compare + " ($5, $6)"
+		}
+	}
+
+	query += " ORDER BY " + order
+
+	if filter.Limit > 0 {
+		args = append(args, filter.Limit+1)
+		query += " LIMIT $" + strconv.Itoa(len(args))
+	}
+
+	page.Links, err = s.queryLinks(ctx, query+";", args...)
+	if err != nil {
+		return LinkPage{}, err
+	}
+
+	if filter.Limit > 0 && len(page.Links) > filter.Limit {
+		page.Links = page.Links[:filter.Limit]
+		page.HasMore = true
+	}
+
+	return page, nil
+}
+
+// SearchUserLinks ищет ссылки пользователя, используя триграммные индексы pg_trgm, и возвращает их от новых к старым.
+func (s PostgreConnect) SearchUserLinks(ctx context.Context, userID string, q LinkSearch) ([]Link, error) {
+	query := escapeLike(strings.ToLower(q.Query))
+
+	pattern, tagsPattern := "%"+query+"%", "%"+query+"%"
+	if q.Prefix {
+		pattern, tagsPattern = query+"%", "%\n"+query+"%"
+	}
+
+	limit := sql.NullInt64{Int64: int64(q.Limit), Valid: q.Limit > 0}
+
+	return s.queryLinks(ctx, `
+	SELECT `+linkColumns+`
+	FROM urls
+	INNER JOIN users ON users.user_ID = urls.user_ID
+	WHERE users.user_Cookie = $1
+		AND ($2 = '' OR lower(urls.originalURL) LIKE $3 OR ($4 AND urls_url_host(urls.originalURL) LIKE $3)
+			OR lower(urls.shortURL) LIKE $3 OR lower(urls.title) LIKE $3 OR urls_tags_text(urls.tags) LIKE $5)
+		AND ($6::TIMESTAMP WITH TIME ZONE IS NULL OR urls.created_at >= $6)
+		AND ($7::TIMESTAMP WITH TIME ZONE IS NULL OR urls.created_at < $7)
+	ORDER BY urls.created_at DESC, urls.shortURL
+	LIMIT $8;`,
+		userID, query, pattern, q.Prefix, tagsPattern, nullTime(q.CreatedFrom), nullTime(q.CreatedTo), limit)
+}
+
+// escapeLike экранирует специальные символы шаблона LIKE.
+func escapeLike(s string) string {
+	return strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`).Replace(s)
+}
+
+// queryLinks выполняет запрос со столбцами linkColumns и возвращает прочитанные ссылки.
+func (s PostgreConnect) queryLinks(ctx context.Context, query string, args ...any) ([]Link, error) {
+	rows, err := s.DBConnect.QueryContext(ctx, query, args...)
+	if err != nil {
+		log.Print(err)
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	var links []Link
+
+	for rows.Next() {
+		link, err := scanLink(rows)
+		if err != nil {
+			log.Print(err)
+			return nil, err
+		}
+
+		links = append(links, link)
+	}
+
+	if err = rows.Err(); err != nil {
+		log.Print(err)
+		return nil, err
+	}
+
+	return links, nil
+}
+
+// nonNilTags возвращает пустой список вместо nil, чтобы в базу данных записывался пустой массив, а не NULL.
+func nonNilTags(tags []string) []string {
+	if tags == nil {
+		return []string{}
+	}
+
+	return tags
+}
+
+// RenameTag заменяет тег у всех ссылок пользователя и возвращает количество изменённых ссылок.
+// Список тегов остаётся отсортированным и не содержит повторов.
+func (s PostgreConnect) RenameTag(ctx context.Context, userID, from, to string) (int64, error) {
+	result, err := s.DBConnect.ExecContext(ctx, `
+	UPDATE urls SET tags = ARRAY(SELECT DISTINCT t FROM unnest(array_replace(urls.tags, $2, $3)) AS t ORDER BY t COLLATE "C")
+	WHERE urls.user_ID = (SELECT user_ID FROM users WHERE user_Cookie = $1) AND urls.tags @> ARRAY[$2]::TEXT[];`,
+		userID, from, to)
+	if err != nil {
+		log.Print(err)
+		return 0, err
+	}
+
+	return result.RowsAffected()
+}
+
+// DeleteTag убирает тег у всех ссылок пользователя и возвращает количество изменённых ссылок.
+func (s PostgreConnect) DeleteTag(ctx context.Context, userID, tag string) (int64, error) {
+	result, err := s.DBConnect.ExecContext(ctx, `
+	UPDATE urls SET tags = array_remove(urls.tags, $2)
+	WHERE urls.user_ID = (SELECT user_ID FROM users WHERE user_Cookie = $1) AND urls.tags @> ARRAY[$2]::TEXT[];`,
+		userID, tag)
+	if err != nil {
+		log.Print(err)
+		return 0, err
+	}
+
+	return result.RowsAffected()
+}
+
+// SaveClicks сохраняет пачку переходов по ссылкам и увеличивает счётчики переходов.
+func (s PostgreConnect) SaveClicks(ctx context.Context, clicks []Click) error {
+	tx, err := s.DBConnect.BeginTx(ctx, nil)
+	if err != nil {
+		log.Print(err)
+		return err
+	}
+	defer tx.Rollback()
+
+	sqlCopyClicks, err := tx.PrepareContext(ctx, pq.CopyIn("clicks", "shorturl", "clicked_at", "referrer", "user_agent", "ip_hash", "class", "target_rule", "variant"))
+	if err != nil {
+		log.Print(err)
+		return err
+	}
+
+	counts := map[string]int64{}
+
+	for _, c := range clicks {
+		if _, err = sqlCopyClicks.ExecContext(ctx, c.ShortURL, c.Time, c.Referrer, c.UserAgent, c.IPHash, c.ClickClass(), c.Rule, c.Variant); err != nil {
+			log.Print(err)
+			return err
+		}
+
+		//переходы ботов не учитываются в счётчике переходов ссылки
+		if c.ClickClass() != ClassBot {
+			counts[c.ShortURL]++
+		}
+	}
+
+	if _, err = sqlCopyClicks.ExecContext(ctx); err != nil {
+		log.Print(err)
+		return err
+	}
+
+	if err = sqlCopyClicks.Close(); err != nil {
+		log.Print(err)
+		return err
+	}
+
+	shortnames := make([]string, 0, len(counts))
+	increments := make([]int64, 0, len(counts))
+
+	for shortname, n := range counts {
+		shortnames = append(shortnames, shortname)
+		increments = append(increments, n)
+	}
+
+	_, err = tx.ExecContext(ctx, `
+	UPDATE urls SET clicks = urls.clicks + data_table.n
+	FROM (SELECT unnest($1::text[]) AS shortURL, unnest($2::bigint[]) AS n) AS data_table
+	WHERE urls.shortURL = data_table.shortURL;`, pq.Array(shortnames), pq.Array(increments))
+	if err != nil {
+		log.Print(err)
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// GetClicks возвращает переходы по ссылкам shortnames за период [from, to).
+func (s PostgreConnect) GetClicks(ctx context.Context, shortnames []string, from, to time.Time) ([]Click, error) {
+	rows, err := s.DBConnect.QueryContext(ctx, `
+	SELECT shortURL, clicked_at, referrer, user_agent, ip_hash, class, target_rule, variant
+	FROM clicks
+	WHERE shortURL = ANY($1) AND clicked_at >= $2 AND clicked_at < $3
+	ORDER BY clicked_at;`, pq.Array(shortnames), from, to)
+	if err != nil {
+		log.Print(err)
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	var clicks []Click
+
+	for rows.Next() {
+		var c Click
+
+		err = rows.Scan(&c.ShortURL, &c.Time, &c.Referrer, &c.UserAgent, &c.IPHash, &c.Class, &c.Rule, &c.Variant)
+		if err != nil {
+			log.Print(err)
+			return nil, err
+		}
+
+		clicks = append(clicks, c)
+	}
+
+	if err = rows.Err(); err != nil {
+		log.Print(err)
+		return nil, err
+	}
+
+	return clicks, nil
+}
+
+// rollupLockID - ключ блокировки, под которой выполняется сведение переходов.
+const rollupLockID = 7241001
+
+// rollupTables сопоставляет размер интервала сводки с таблицей, в которой она хранится.
+var rollupTables = map[string]string{
+	GranularityHour: "click_rollups_hourly",
+	GranularityDay:  "click_rollups_daily",
+}
+
+// GetRollupWatermark возвращает момент, до которого переходы уже добавлены в сводки.
+func (s PostgreConnect) GetRollupWatermark(ctx context.Context) (watermark time.Time, err error) {
+	err = s.DBConnect.QueryRowContext(ctx, "SELECT watermark FROM click_rollup_state WHERE id = 1;").Scan(&watermark)
+	if errors.Is(err, sql.ErrNoRows) {
+		return time.Time{}, nil
+	}
+	if err != nil {
+		log.Print(err)
+		return time.Time{}, err
+	}
+
+	return watermark.UTC(), nil
+}
+
+// RollupClicks добавляет в сводки переходы с момента предыдущего сведения до to и возвращает новую границу.
+// Сведение выполняется под блокировкой, поэтому несколько экземпляров сервиса не учитывают переходы дважды.
+func (s PostgreConnect) RollupClicks(ctx context.Context, to time.Time, aggregate AggregateFunc) (time.Time, error) {
+	tx, err := s.DBConnect.BeginTx(ctx, nil)
+	if err != nil {
+		log.Print(err)
+		return time.Time{}, err
+	}
+	defer tx.Rollback()
+
+	if _, err = tx.ExecContext(ctx, "SELECT pg_advisory_xact_lock($1);", rollupLockID); err != nil {
+		log.Print(err)
+		return time.Time{}, err
+	}
+
+	var watermark time.Time
+
+	err = tx.QueryRowContext(ctx, "SELECT watermark FROM click_rollup_state WHERE id = 1;").Scan(&watermark)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		log.Print(err)
+		return time.Time{}, err
+	}
+
+	watermark = watermark.UTC()
+	if !watermark.Before(to) {
+		return watermark, nil
+	}
+
+	rows, err := tx.QueryContext(ctx, `
+	SELECT shortURL, clicked_at, referrer, user_agent, ip_hash, class, target_rule, variant
+	FROM clicks
+	WHERE clicked_at >= $1 AND clicked_at < $2;`, watermark, to)
+	if err != nil {
+		log.Print(err)
+		return time.Time{}, err
+	}
+
+	var clicks []Click
+
+	for rows.Next() {
+		var c Click
+
+		if err = rows.Scan(&c.ShortURL, &c.Time, &c.Referrer, &c.UserAgent, &c.IPHash, &c.Class, &c.Rule, &c.Variant); err != nil {
+			rows.Close()
+			log.Print(err)
+			return time.Time{}, err
+		}
+
+		clicks = append(clicks, c)
+	}
+
+	rows.Close()
+
+	if err = rows.Err(); err != nil {
+		log.Print(err)
+		return time.Time{}, err
+	}
+
+	for _, r := range aggregate(clicks) {
+		if err = saveRollup(ctx, tx, r); err != nil {
+			return time.Time{}, err
+		}
+	}
+
+	_, err = tx.ExecContext(ctx, `
+	INSERT INTO click_rollup_state (id, watermark) VALUES (1, $1)
+	ON CONFLICT (id) DO UPDATE SET watermark = EXCLUDED.watermark;`, to)
+	if err != nil {
+		log.Print(err)
+		return time.Time{}, err
+	}
+
+	return to, tx.Commit()
+}
+
+// saveRollup прибавляет сводку к уже сохранённой сводке за тот же интервал.
+func saveRollup(ctx context.Context, tx *sql.Tx, r Rollup) error {
+	table, ok := rollupTables[r.Granularity]
+	if !ok {
+		return errors.New("unknown rollup granularity " + r.Granularity)
+	}
+
+	row := tx.QueryRowContext(ctx, "SELECT shortURL, bucket_start, class, clicks, referrers, browsers, os, target_rules, variants, visitors FROM "+table+" WHERE shortURL = $1 AND bucket_start = $2 AND class = $3 FOR UPDATE;", r.ShortURL, r.Start, r.Class)
+
+	existing, err := scanRollup(row)
+	if errors.Is(err, sql.ErrNoRows) {
+		existing = Rollup{ShortURL: r.ShortURL, Start: r.Start, Class: r.Class}
+		err = nil
+	}
+	if err != nil {
+		log.Print(err)
+		return err
+	}
+
+	existing.Merge(r)
+
+	referrers, err := json.Marshal(existing.Referrers)
+	if err != nil {
+		return err
+	}
+	browsers, err := json.Marshal(existing.Browsers)
+	if err != nil {
+		return err
+	}
+	os, err := json.Marshal(existing.OS)
+	if err != nil {
+		return err
+	}
+	rules, err := json.Marshal(existing.Rules)
+	if err != nil {
+		return err
+	}
+	variants, err := json.Marshal(existing.Variants)
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.ExecContext(ctx, `
+	INSERT INTO `+table+` (shortURL, bucket_start, class, clicks, referrers, browsers, os, target_rules, variants, visitors)
+	VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
+	ON CONFLICT (shortURL, bucket_start, class) DO UPDATE SET
+		clicks = EXCLUDED.clicks,
+		referrers = EXCLUDED.referrers,
+		browsers = EXCLUDED.browsers,
+		os = EXCLUDED.os,
+		target_rules = EXCLUDED.target_rules,
+		variants = EXCLUDED.variants,
+		visitors = EXCLUDED.visitors;`,
+		existing.ShortURL, existing.Start, existing.Class, existing.Clicks, referrers, browsers, os, rules, variants, existing.Visitors)
+	if err != nil {
+		log.Print(err)
+		return err
+	}
+
+	return nil
+}
+
+// scanRollup читает сводку из строки результата запроса.
+func scanRollup(row interface{ Scan(...any) error }) (r Rollup, err error) {
+	var referrers, browsers, os, rules, variants []byte
+
+	err = row.Scan(&r.ShortURL, &r.Start, &r.Class, &r.Clicks, &referrers, &browsers, &os, &rules, &variants, &r.Visitors)
+	if err != nil {
+		return Rollup{}, err
+	}
+
+	r.Start = r.Start.UTC()
+
+	if err = json.Unmarshal(referrers, &r.Referrers); err != nil {
+		return Rollup{}, err
+	}
+	if err = json.Unmarshal(browsers, &r.Browsers); err != nil {
+		return Rollup{}, err
+	}
+	if err = json.Unmarshal(os, &r.OS); err != nil {
+		return Rollup{}, err
+	}
+	if err = json.Unmarshal(rules, &r.Rules); err != nil {
+		return Rollup{}, err
+	}
+	if err = json.Unmarshal(variants, &r.Variants); err != nil {
+		return Rollup{}, err
+	}
+
+	return r, nil
+}
+
+// GetRollups возвращает сводки ссылок shortnames, начинающиеся в период [from, to).
+func (s PostgreConnect) GetRollups(ctx context.Context, shortnames []string, granularity string, from, to time.Time) ([]Rollup, error) {
+	table, ok := rollupTables[granularity]
+	if !ok {
+		return nil, errors.New("unknown rollup granularity " + granularity)
+	}
+
+	rows, err := s.DBConnect.QueryContext(ctx, `
+	SELECT shortURL, bucket_start, class, clicks, referrers, browsers, os, target_rules, variants, visitors
+	FROM `+table+`
+	WHERE shortURL = ANY($1) AND bucket_start >= $2 AND bucket_start < $3
+	ORDER BY bucket_start, class;`, pq.Array(shortnames), from, to)
+	if err != nil {
+		log.Print(err)
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	var rollups []Rollup
+
+	for rows.Next() {
+		r, err := scanRollup(rows)
+		if err != nil {
+			log.Print(err)
+			return nil, err
+		}
+
+		r.Granularity = granularity
+		rollups = append(rollups, r)
+	}
+
+	if err = rows.Err(); err != nil {
+		log.Print(err)
+		return nil, err
+	}
+
+	return rollups, nil
+}
+
+// DeleteClicksBefore удаляет переходы, совершённые раньше before.
+func (s PostgreConnect) DeleteClicksBefore(ctx context.Context, before time.Time) (int64, error) {
+	result, err := s.DBConnect.ExecContext(ctx, "DELETE FROM clicks WHERE clicked_at < $1;", before)
+	if err != nil {
+		log.Print(err)
+		return 0, err
+	}
+
+	return result.RowsAffected()
+}
+
+// ClickSalt возвращает соль хэширования адресов переходов, сохраняя candidate, если соли ещё нет.
+// Соль хранится в базе, поэтому все экземпляры сервиса и перезапуски используют одну и ту же соль.
+func (s PostgreConnect) ClickSalt(ctx context.Context, candidate []byte) (salt []byte, err error) {
+	_, err = s.DBConnect.ExecContext(ctx, "INSERT INTO click_salt (salt) VALUES ($1) ON CONFLICT (id) DO NOTHING;", candidate)
+	if err != nil {
+		log.Print(err)
+		return nil, err
+	}
+
+	err = s.DBConnect.QueryRowContext(ctx, "SELECT salt FROM click_salt WHERE id = 1;").Scan(&salt)
+	if err != nil {
+		log.Print(err)
+		return nil, err
+	}
+
+	return salt, nil
+}
+
+// webhookColumns - список столбцов для чтения вебхука функцией scanWebhook.
+const webhookColumns = `w.webhook_ID, users.user_Cookie, w.url, w.secret, w.events, w.created_at`
+
+// scanWebhook читает вебхук из строки результата запроса.
+func scanWebhook(row interface{ Scan(...any) error }) (w Webhook, err error) {
+	err = row.Scan(&w.ID, &w.UserID, &w.URL, &w.Secret, pq.Array(&w.Events), &w.CreatedAt)
+	return w, err
+}
+
+// deliveryColumns - список столбцов для чтения уведомления функцией scanDelivery.
+const deliveryColumns = `d.delivery_ID, d.webhook_ID, users.user_Cookie, d.event, d.payload, d.status, d.attempts, d.next_attempt, d.last_error, d.created_at, d.updated_at`
+
+// scanDelivery читает уведомление из строки результата запроса.
+func scanDelivery(row interface{ Scan(...any) error }) (d Delivery, err error) {
+	err = row.Scan(&d.ID, &d.WebhookID, &d.UserID, &d.Event, &d.Payload, &d.Status, &d.Attempts, &d.NextAttempt, &d.LastError, &d.CreatedAt, &d.UpdatedAt)
+	return d, err
+}
+
+// SaveWebhook сохраняет вебхук.
+func (s PostgreConnect) SaveWebhook(ctx context.Context, w Webhook) error {
+	tx, err := s.DBConnect.BeginTx(ctx, nil)
+	if err != nil {
+		log.Print(err)
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err = tx.ExecContext(ctx, "INSERT INTO users (user_Cookie) VALUES ($1) ON CONFLICT (user_Cookie) DO NOTHING;", w.UserID); err != nil {
+		log.Print(err)
+		return err
+	}
+
+	_, err = tx.ExecContext(ctx, `
+	INSERT INTO webhooks (webhook_ID, user_ID, url, secret, events, created_at)
+	VALUES ($1, (SELECT user_ID FROM users WHERE user_Cookie = $2), $3, $4, $5, $6);`,
+		w.ID, w.UserID, w.URL, w.Secret, pq.Array(w.Events), w.CreatedAt)
+	if err != nil {
+		log.Print(err)
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// GetWebhook возвращает вебхук по идентификатору.
+func (s PostgreConnect) GetWebhook(ctx context.Context, id string) (Webhook, error) {
+	row := s.DBConnect.QueryRowContext(ctx, "SELECT "+webhookColumns+" FROM webhooks w INNER JOIN users ON users.user_ID = w.user_ID WHERE w.webhook_ID = $1;", id)
+
+	w, err := scanWebhook(row)
+	if errors.Is(err, sql.ErrNoRows) {
+		return Webhook{}, ErrNotFound
+	}
+	if err != nil {
+		log.Print(err)
+		return Webhook{}, err
+	}
+
+	return w, nil
+}
+
+// GetWebhooks возвращает вебхуки пользователя.
+func (s PostgreConnect) GetWebhooks(ctx context.Context, userID string) ([]Webhook, error) {
+	rows, err := s.DBConnect.QueryContext(ctx, "SELECT "+webhookColumns+" FROM webhooks w INNER JOIN users ON users.user_ID = w.user_ID WHERE users.user_Cookie = $1 ORDER BY w.created_at;", userID)
+	if err != nil {
+		log.Print(err)
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	var webhooks []Webhook
+
+	for rows.Next() {
+		w, err := scanWebhook(rows)
+		if err != nil {
+			log.Print(err)
+			return nil, err
+		}
+
+		webhooks = append(webhooks, w)
+	}
+
+	if err = rows.Err(); err != nil {
+		log.Print(err)
+		return nil, err
+	}
+
+	return webhooks, nil
+}
+
+// DeleteWebhook удаляет вебхук пользователя вместе с его очередью доставки.
+func (s PostgreConnect) DeleteWebhook(ctx context.Context, userID, id string) error {
+	w, err := s.GetWebhook(ctx, id)
+	if err != nil {
+		return err
+	}
+
+	if w.UserID != userID {
+		return ErrNotOwner
+	}
+
+	if _, err = s.DBConnect.ExecContext(ctx, "DELETE FROM webhooks WHERE webhook_ID = $1;", id); err != nil {
+		log.Print(err)
+		return err
+	}
+
+	return nil
+}
+
+// EnqueueDeliveries добавляет уведомления в очередь доставки.
+func (s PostgreConnect) EnqueueDeliveries(ctx context.Context, deliveries []Delivery) error {
+	tx, err := s.DBConnect.BeginTx(ctx, nil)
+	if err != nil {
+		log.Print(err)
+		return err
+	}
+	defer tx.Rollback()
+
+	sqlInsertDelivery, err := tx.PrepareContext(ctx, `
+	INSERT INTO webhook_deliveries (delivery_ID, webhook_ID, user_ID, event, payload, status, attempts, next_attempt, last_error, created_at, updated_at)
+	VALUES ($1, $2, (SELECT user_ID FROM users WHERE user_Cookie = $3), $4, $5, $6, $7, $8, $9, $10, $11);`)
+	if err != nil {
+		log.Print(err)
+		return err
+	}
+
+	defer sqlInsertDelivery.Close()
+
+	for _, d := range deliveries {
+		_, err = sqlInsertDelivery.ExecContext(ctx, d.ID, d.WebhookID, d.UserID, d.Event, d.Payload, d.Status, d.Attempts, d.NextAttempt, d.LastError, d.CreatedAt, d.UpdatedAt)
+		if err != nil {
+			log.Print(err)
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+// ClaimDeliveries выбирает до limit уведомлений, время доставки которых наступило, и откладывает их повторную выдачу на lease.
+func (s PostgreConnect) ClaimDeliveries(ctx context.Context, now time.Time, limit int, lease time.Duration) ([]Delivery, error) {
+	rows, err := s.DBConnect.QueryContext(ctx, `
+	WITH due AS (
+		SELECT delivery_ID FROM webhook_deliveries
+		WHERE status = $1 AND next_attempt <= $2
+		ORDER BY next_attempt
+		LIMIT $3
+		FOR UPDATE SKIP LOCKED
+	), d AS (
+		UPDATE webhook_deliveries SET next_attempt = $4
+		FROM due WHERE webhook_deliveries.delivery_ID = due.delivery_ID
+		RETURNING webhook_deliveries.*
+	)
+	SELECT `+deliveryColumns+` FROM d INNER JOIN users ON users.user_ID = d.user_ID;`,
+		DeliveryPending, now, limit, now.Add(lease))
+	if err != nil {
+		log.Print(err)
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	var deliveries []Delivery
+
+	for rows.Next() {
+		d, err := scanDelivery(rows)
+		if err != nil {
+			log.Print(err)
+			return nil, err
+		}
+
+		deliveries = append(deliveries, d)
+	}
+
+	if err = rows.Err(); err != nil {
+		log.Print(err)
+		return nil, err
+	}
+
+	return deliveries, nil
+}
+
+// RenewDelivery продлевает аренду уведомления до until. Если аренда до claimed истекла и уведомление выдано
+// другому обработчику, возвращается ErrLeaseLost.
+func (s PostgreConnect) RenewDelivery(ctx context.Context, id string, claimed, until time.Time) error {
+	result, err := s.DBConnect.ExecContext(ctx, `
+	UPDATE webhook_deliveries SET next_attempt = $3
+	WHERE delivery_ID = $1 AND status = $4 AND next_attempt = $2;`, id, claimed, until, DeliveryPending)
+	if err != nil {
+		log.Print(err)
+		return err
+	}
+
+	if n, err := result.RowsAffected(); err == nil && n == 0 {
+		return ErrLeaseLost
+	}
+
+	return nil
+}
+
+// UpdateDelivery сохраняет состояние уведомления.
+func (s PostgreConnect) UpdateDelivery(ctx context.Context, d Delivery) error {
+	result, err := s.DBConnect.ExecContext(ctx, `
+	UPDATE webhook_deliveries SET status = $2, attempts = $3, next_attempt = $4, last_error = $5, updated_at = $6
+	WHERE delivery_ID = $1;`, d.ID, d.Status, d.Attempts, d.NextAttempt, d.LastError, d.UpdatedAt)
+	if err != nil {
+		log.Print(err)
+		return err
+	}
+
+	if n, err := result.RowsAffected(); err == nil && n == 0 {
+		return ErrNotFound
+	}
+
+	return nil
+}
+
+// DeleteDelivery удаляет доставленное уведомление из очереди.
+func (s PostgreConnect) DeleteDelivery(ctx context.Context, id string) error {
+	if _, err := s.DBConnect.ExecContext(ctx, "DELETE FROM webhook_deliveries WHERE delivery_ID = $1;", id); err != nil {
+		log.Print(err)
+		return err
+	}
+
+	return nil
+}
+
+// GetDelivery возвращает уведомление по идентификатору.
+func (s PostgreConnect) GetDelivery(ctx context.Context, id string) (Delivery, error) {
+	row := s.DBConnect.QueryRowContext(ctx, "SELECT "+deliveryColumns+" FROM webhook_deliveries d INNER JOIN users ON users.user_ID = d.user_ID WHERE d.delivery_ID = $1;", id)
+
+	d, err := scanDelivery(row)
+	if errors.Is(err, sql.ErrNoRows) {
+		return Delivery{}, ErrNotFound
+	}
+	if err != nil {
+		log.Print(err)
+		return Delivery{}, err
+	}
+
+	return d, nil
+}
+
+// GetDeliveries возвращает уведомления пользователя с указанным статусом.
+func (s PostgreConnect) GetDeliveries(ctx context.Context, userID, status string) ([]Delivery, error) {
+	rows, err := s.DBConnect.QueryContext(ctx, `
+	SELECT `+deliveryColumns+`
+	FROM webhook_deliveries d
+	INNER JOIN users ON users.user_ID = d.user_ID
+	WHERE users.user_Cookie = $1 AND d.status = $2
+	ORDER BY d.created_at;`, userID, status)
+	if err != nil {
+		log.Print(err)
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	var deliveries []Delivery
+
+	for rows.Next() {
+		d, err := scanDelivery(rows)
+		if err != nil {
+			log.Print(err)
+			return nil, err
+		}
+
+		deliveries = append(deliveries, d)
+	}
+
+	if err = rows.Err(); err != nil {
+		log.Print(err)
+		return nil, err
+	}
+
+	return deliveries, nil
+}
+
+// campaignColumns - список столбцов для чтения кампании функцией scanCampaign.
+const campaignColumns = `c.campaign_ID, users.user_Cookie, c.name, c.parameters, c.created_at, c.updated_at`
+
+// scanCampaign читает кампанию из строки результата запроса.
+func scanCampaign(row interface{ Scan(...any) error }) (c Campaign, err error) {
+	var parameters []byte
+
+	if err = row.Scan(&c.ID, &c.UserID, &c.Name, &parameters, &c.CreatedAt, &c.UpdatedAt); err != nil {
+		return Campaign{}, err
+	}
+
+	if err = json.Unmarshal(parameters, &c.Parameters); err != nil {
+		return Campaign{}, err
+	}
+
+	return c, nil
+}
+
+// SaveCampaign сохраняет новую или изменённую кампанию.
+func (s PostgreConnect) SaveCampaign(ctx context.Context, c Campaign) error {
+	parameters, err := json.Marshal(c.Parameters)
+	if err != nil {
+		return err
+	}
+
+	tx, err := s.DBConnect.BeginTx(ctx, nil)
+	if err != nil {
+		log.Print(err)
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err = tx.ExecContext(ctx, "INSERT INTO users (user_Cookie) VALUES ($1) ON CONFLICT (user_Cookie) DO NOTHING;", c.UserID); err != nil {
+		log.Print(err)
+		return err
+	}
+
+	_, err = tx.ExecContext(ctx, `
+	INSERT INTO campaigns (campaign_ID, user_ID, name, parameters, created_at, updated_at)
+	VALUES ($1, (SELECT user_ID FROM users WHERE user_Cookie = $2), $3, $4, $5, $6)
+	ON CONFLICT (campaign_ID) DO UPDATE SET name = EXCLUDED.name, parameters = EXCLUDED.parameters, updated_at = EXCLUDED.updated_at;`,
+		c.ID, c.UserID, c.Name, parameters, c.CreatedAt, c.UpdatedAt)
+	if err != nil {
+		log.Print(err)
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// GetCampaign возвращает кампанию по идентификатору.
+func (s PostgreConnect) GetCampaign(ctx context.Context, id string) (Campaign, error) {
+	row := s.DBConnect.QueryRowContext(ctx, "SELECT "+campaignColumns+" FROM campaigns c INNER JOIN users ON users.user_ID = c.user_ID WHERE c.campaign_ID = $1;", id)
+
+	c, err := scanCampaign(row)
+	if errors.Is(err, sql.ErrNoRows) {
+		return Campaign{}, ErrNotFound
+	}
+	if err != nil {
+		log.Print(err)
+		return Campaign{}, err
+	}
+
+	return c, nil
+}
+
+// GetCampaigns возвращает кампании пользователя.
+func (s PostgreConnect) GetCampaigns(ctx context.Context, userID string) ([]Campaign, error) {
+	rows, err := s.DBConnect.QueryContext(ctx, "SELECT "+campaignColumns+" FROM campaigns c INNER JOIN users ON users.user_ID = c.user_ID WHERE users.user_Cookie = $1 ORDER BY c.created_at;", userID)
+	if err != nil {
+		log.Print(err)
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	var campaigns []Campaign
+
+	for rows.Next() {
+		c, err := scanCampaign(rows)
+		if err != nil {
+			log.Print(err)
+			return nil, err
+		}
+
+		campaigns = append(campaigns, c)
+	}
+
+	if err = rows.Err(); err != nil {
+		log.Print(err)
+		return nil, err
+	}
+
+	return campaigns, nil
+}
+
+// DeleteCampaign удаляет кампанию пользователя. Ссылки исключаются из кампании внешним ключом таблицы urls.
+func (s PostgreConnect) DeleteCampaign(ctx context.Context, userID, id string) error {
+	c, err := s.GetCampaign(ctx, id)
+	if err != nil {
+		return err
+	}
+
+	if c.UserID != userID {
+		return ErrNotOwner
+	}
+
+	if _, err = s.DBConnect.ExecContext(ctx, "DELETE FROM campaigns WHERE campaign_ID = $1;", id); err != nil {
+		log.Print(err)
+		return err
+	}
+
+	return nil
+}
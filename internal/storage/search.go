@@ -0,0 +1,202 @@
+package storage
+
+import (
+	"sort"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// gramSize - длина подстрок, по которым строится индекс поиска ссылок.
+const gramSize = 3
+
+// LinkSearch задаёт условия поиска ссылок пользователя.
+type LinkSearch struct {
+	//Query ищется в адресе перенаправления, коротком имени, названии и тегах ссылки без учёта регистра.
+	//Если Prefix истинно, значение должно начинаться с Query, иначе - содержать его. При поиске по началу адреса
+	//перенаправления схема и префикс "www." не учитываются.
+	Query  string
+	Prefix bool
+
+	//CreatedFrom и CreatedTo ограничивают время создания ссылки. Нулевое время означает отсутствие границы.
+	CreatedFrom time.Time
+	CreatedTo   time.Time
+
+	//Limit ограничивает количество найденных ссылок, нулевой Limit означает отсутствие ограничения.
+	Limit int
+}
+
+// Matches проверяет, подходит ли ссылка под условия поиска.
+func (q LinkSearch) Matches(l Link) bool {
+	if !q.CreatedFrom.IsZero() && l.CreatedAt.Before(q.CreatedFrom) {
+		return false
+	}
+	if !q.CreatedTo.IsZero() && !l.CreatedAt.Before(q.CreatedTo) {
+		return false
+	}
+
+	query := strings.ToLower(q.Query)
+	if query == "" {
+		return true
+	}
+
+	match := strings.Contains
+	if q.Prefix {
+		match = strings.HasPrefix
+		if match(urlHost(strings.ToLower(l.OriginalURL)), query) {
+			return true
+		}
+	}
+
+	for _, value := range searchValues(l) {
+		if match(value, query) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// urlHost возвращает адрес без схемы и префикса "www.".
+func urlHost(u string) string {
+	if scheme, rest, ok := strings.Cut(u, "://"); ok && !strings.ContainsAny(scheme, ":/") {
+		u = rest
+	}
+
+	return strings.TrimPrefix(u, "www.")
+}
+
+// searchValues возвращает значения ссылки, по которым выполняется поиск, в нижнем регистре.
+func searchValues(l Link) []string {
+	values := make([]string, 0, len(l.Tags)+3)
+	values = append(values, strings.ToLower(l.OriginalURL), strings.ToLower(l.ShortURL), strings.ToLower(l.Title))
+
+	return append(values, l.Tags...)
+}
+
+// sortSearchResult упорядочивает найденные ссылки от новых к старым и обрезает список до limit.
+func sortSearchResult(links []Link, limit int) []Link {
+	sort.Slice(links, func(i, j int) bool {
+		if !links[i].CreatedAt.Equal(links[j].CreatedAt) {
+			return links[i].CreatedAt.After(links[j].CreatedAt)
+		}
+		return links[i].ShortURL < links[j].ShortURL
+	})
+
+	if limit > 0 && len(links) > limit {
+		links = links[:limit]
+	}
+
+	return links
+}
+
+// searchIndex - инвертированный индекс по триграммам значений ссылок для хранилищ в памяти и в файле.
+// Для каждой триграммы пользователя хранятся короткие имена ссылок, в значениях которых она встречается.
+type searchIndex struct {
+	grams map[string]map[string]struct{}
+	keys  map[string][]string
+}
+
+// newSearchIndex создаёт пустой индекс.
+func newSearchIndex() *searchIndex {
+	return &searchIndex{grams: map[string]map[string]struct{}{}, keys: map[string][]string{}}
+}
+
+// gramKey возвращает ключ триграммы пользователя.
+func gramKey(userID, gram string) string {
+	return userID + "\x00" + gram
+}
+
+// grams возвращает все различные триграммы строки.
+func grams(s string) []string {
+	runes := []rune(s)
+	if len(runes) < gramSize {
+		return nil
+	}
+
+	seen := map[string]bool{}
+	result := make([]string, 0, len(runes)-gramSize+1)
+
+	for i := 0; i+gramSize <= len(runes); i++ {
+		gram := string(runes[i : i+gramSize])
+		if !seen[gram] {
+			seen[gram] = true
+			result = append(result, gram)
+		}
+	}
+
+	return result
+}
+
+// put добавляет ссылку в индекс, заменяя её прежние значения.
+func (x *searchIndex) put(link Link) {
+	x.remove(link.ShortURL)
+
+	seen := map[string]bool{}
+
+	for _, value := range searchValues(link) {
+		for _, gram := range grams(value) {
+			key := gramKey(link.UserID, gram)
+			if seen[key] {
+				continue
+			}
+			seen[key] = true
+
+			if _, ok := x.grams[key]; !ok {
+				x.grams[key] = map[string]struct{}{}
+			}
+			x.grams[key][link.ShortURL] = struct{}{}
+			x.keys[link.ShortURL] = append(x.keys[link.ShortURL], key)
+		}
+	}
+}
+
+// remove удаляет ссылку из индекса.
+func (x *searchIndex) remove(shortname string) {
+	for _, key := range x.keys[shortname] {
+		delete(x.grams[key], shortname)
+		if len(x.grams[key]) == 0 {
+			delete(x.grams, key)
+		}
+	}
+
+	delete(x.keys, shortname)
+}
+
+// candidates возвращает короткие имена ссылок пользователя, значения которых содержат все триграммы запроса.
+// Если запрос короче триграммы, индекс не может сузить поиск и второе значение ложно.
+func (x *searchIndex) candidates(userID, query string) ([]string, bool) {
+	if utf8.RuneCountInString(query) < gramSize {
+		return nil, false
+	}
+
+	var smallest map[string]struct{}
+
+	queryGrams := grams(query)
+	for _, gram := range queryGrams {
+		postings := x.grams[gramKey(userID, gram)]
+		if len(postings) == 0 {
+			return nil, true
+		}
+		if smallest == nil || len(postings) < len(smallest) {
+			smallest = postings
+		}
+	}
+
+	var result []string
+
+	for shortname := range smallest {
+		found := true
+		for _, gram := range queryGrams {
+			if _, ok := x.grams[gramKey(userID, gram)][shortname]; !ok {
+				found = false
+				break
+			}
+		}
+		if found {
+			result = append(result, shortname)
+		}
+	}
+
+	return result, true
+}
@@ -0,0 +1,114 @@
+package storage
+
+import (
+	"context"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestLinkSearch_Matches проверяет сравнение ссылки с условиями поиска.
+func TestLinkSearch_Matches(t *testing.T) {
+	now := time.Now()
+	link := Link{ShortURL: "Promo", OriginalURL: "https://www.Example.com/spring/sale", Title: "Весенняя распродажа", Tags: []string{"news"}, CreatedAt: now}
+
+	tests := []struct {
+		name string
+		q    LinkSearch
+		want bool
+	}{
+		{name: "empty", q: LinkSearch{}, want: true},
+		{name: "url substring", q: LinkSearch{Query: "spring/SA"}, want: true},
+		{name: "alias", q: LinkSearch{Query: "prom"}, want: true},
+		{name: "title", q: LinkSearch{Query: "распродажа"}, want: true},
+		{name: "tag", q: LinkSearch{Query: "ew"}, want: true},
+		{name: "no match", q: LinkSearch{Query: "autumn"}, want: false},
+		{name: "host prefix", q: LinkSearch{Query: "example.com/sp", Prefix: true}, want: true},
+		{name: "url prefix", q: LinkSearch{Query: "https://www.ex", Prefix: true}, want: true},
+		{name: "not prefix", q: LinkSearch{Query: "sale", Prefix: true}, want: false},
+		{name: "created after", q: LinkSearch{CreatedFrom: now.Add(time.Second)}, want: false},
+		{name: "created before", q: LinkSearch{CreatedTo: now}, want: false},
+		{name: "created in range", q: LinkSearch{CreatedFrom: now, CreatedTo: now.Add(time.Second)}, want: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, tt.q.Matches(link))
+		})
+	}
+}
+
+// TestStorage_SearchUserLinks проверяет поиск ссылок пользователя и обновление индекса при изменении ссылок.
+func TestStorage_SearchUserLinks(t *testing.T) {
+	type searchStorage interface {
+		SaveData(context.Context, map[string]map[string]string) error
+		ConfigureLink(context.Context, string, UpdateFunc) (Link, error)
+		SearchUserLinks(context.Context, string, LinkSearch) ([]Link, error)
+	}
+
+	tests := []struct {
+		name    string
+		storage searchStorage
+		file    string
+	}{
+		{name: "memory", storage: NewMemoryWork(map[string]map[string]string{})},
+		{name: "file", storage: FileSystemConnect{Filename: "search_test.gob"}, file: "search_test.gob"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.file != "" {
+				defer os.Remove(tt.file)
+			}
+
+			ctx := context.Background()
+			s := tt.storage
+
+			require.NoError(t, s.SaveData(ctx, map[string]map[string]string{
+				"owner": {"docs": "https://example.com/docs", "blog": "https://blog.example.org/post"},
+				"other": {"mine": "https://example.com/docs/other"},
+			}))
+
+			now := time.Now()
+			for short, created := range map[string]time.Time{"docs": now.Add(-time.Hour), "blog": now} {
+				created := created
+				_, err := s.ConfigureLink(ctx, short, func(link *Link) error {
+					link.CreatedAt = created
+					return nil
+				})
+				require.NoError(t, err)
+			}
+
+			shortnames := func(q LinkSearch) []string {
+				links, err := s.SearchUserLinks(ctx, "owner", q)
+				require.NoError(t, err)
+
+				var result []string
+				for _, link := range links {
+					result = append(result, link.ShortURL)
+				}
+
+				return result
+			}
+
+			assert.Equal(t, []string{"blog", "docs"}, shortnames(LinkSearch{Query: "example"}))
+			assert.Equal(t, []string{"blog"}, shortnames(LinkSearch{Query: "example", Limit: 1}))
+			assert.Equal(t, []string{"docs"}, shortnames(LinkSearch{Query: "docs"}))
+			assert.Equal(t, []string{"blog"}, shortnames(LinkSearch{Query: "blog.ex", Prefix: true}))
+			assert.Equal(t, []string{"docs"}, shortnames(LinkSearch{Query: "o", CreatedTo: now}))
+			assert.Empty(t, shortnames(LinkSearch{Query: "other"}))
+
+			_, err := s.ConfigureLink(ctx, "blog", func(link *Link) error {
+				link.Title = "Release notes"
+				link.Tags = []string{"changelog"}
+				return nil
+			})
+			require.NoError(t, err)
+
+			assert.Equal(t, []string{"blog"}, shortnames(LinkSearch{Query: "RELEASE"}))
+			assert.Equal(t, []string{"blog"}, shortnames(LinkSearch{Query: "change", Prefix: true}))
+			assert.Empty(t, shortnames(LinkSearch{Query: "post", Prefix: true}))
+		})
+	}
+}
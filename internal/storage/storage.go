@@ -0,0 +1,424 @@
+package storage
+
+import (
+	"errors"
+	"sort"
+	"time"
+)
+
+// ErrPoolEmpty возвращается, если в пуле нет свободных коротких имён.
+var ErrPoolEmpty = errors.New("key pool is empty")
+
+// ErrLeaseLost возвращается при продлении аренды уведомления, которое уже выдано другому обработчику очереди.
+var ErrLeaseLost = errors.New("the delivery has been claimed by another worker")
+
+// Ошибки работы с отдельной ссылкой.
+var (
+	ErrNotFound        = errors.New("URL not found")
+	ErrNotOwner        = errors.New("the link belongs to another user")
+	ErrVersionConflict = errors.New("the link has been modified by another request")
+	ErrURLExists       = errors.New("the original URL has already been shortened")
+	ErrDeleted         = errors.New("this link has been removed")
+	ErrClicksExhausted = errors.New("the link has reached its click limit")
+	ErrNotActive       = errors.New("the link is not active yet")
+	ErrExpired         = errors.New("the link has expired")
+)
+
+// PoolKey описывает состояние имени в пуле заранее сгенерированных имён.
+type PoolKey struct {
+	ClaimedBy string
+	ClaimedAt time.Time
+}
+
+// Link описывает сокращённую ссылку.
+type Link struct {
+	ShortURL     string
+	OriginalURL  string
+	UserID       string
+	IsDeleted    bool
+	Version      int64
+	CreatedAt    time.Time
+	UpdatedAt    time.Time
+	Clicks       int64
+	PasswordHash string
+
+	//MaxClicks ограничивает количество перенаправлений, RemainingClicks содержит их остаток.
+	//Нулевой MaxClicks означает отсутствие ограничения.
+	MaxClicks       int64
+	RemainingClicks int64
+
+	//NotBefore и NotAfter задают окно действия ссылки. Нулевое время означает отсутствие границы.
+	NotBefore time.Time
+	NotAfter  time.Time
+
+	//Rules содержит упорядоченный список правил таргетинга. Если ни одно правило не подошло, используется OriginalURL
+	//либо, если заданы варианты, один из вариантов.
+	Rules    []TargetRule
+	Variants []Variant
+
+	//RedirectStatus задаёт код ответа перенаправления, нулевой код означает код по умолчанию.
+	//CacheControl содержит значение заголовка Cache-Control ответа, MetaRefresh включает перенаправление html страницей.
+	RedirectStatus int
+	CacheControl   string
+	MetaRefresh    bool
+
+	//Passthrough включает перенос дополнительного пути и параметров запроса короткой ссылки в адрес перенаправления.
+	Passthrough bool
+
+	//CampaignID содержит идентификатор кампании, параметры которой добавляются к адресу перенаправления.
+	CampaignID string
+
+	//Tags содержит отсортированный список тегов ссылки, Folder - папку, в которой пользователь хранит ссылку.
+	Tags   []string
+	Folder string
+
+	//Title содержит название ссылки, по которому пользователь может её найти.
+	Title string
+
+	//Metadata содержит сведения о странице перенаправления, загруженные после создания ссылки.
+	Metadata LinkMetadata
+}
+
+// LinkMetadata описывает страницу перенаправления: заголовок, описание, изображение и значок сайта.
+// Нулевое FetchedAt означает, что сведения ещё не загружались. В базе данных хранится в формате json.
+type LinkMetadata struct {
+	Title       string    `json:"title,omitempty"`
+	Description string    `json:"description,omitempty"`
+	Image       string    `json:"image,omitempty"`
+	SiteName    string    `json:"site_name,omitempty"`
+	Favicon     string    `json:"favicon,omitempty"`
+	FetchedAt   time.Time `json:"fetched_at"`
+	Error       string    `json:"error,omitempty"`
+}
+
+// Поля сортировки ссылок пользователя.
+const (
+	LinkSortCreated = "created"
+	LinkSortClicks  = "clicks"
+	LinkSortAlias   = "alias"
+)
+
+// LinkFilter задаёт условия выборки ссылок пользователя. Незаполненные условия не ограничивают выборку.
+type LinkFilter struct {
+	//Tags содержит теги, каждый из которых должен быть у ссылки.
+	Tags   []string
+	Folder string
+	//Campaign задаёт кампанию, к которой привязана ссылка.
+	Campaign string
+
+	//Sort задаёт поле сортировки (по умолчанию LinkSortCreated), Desc - обратный порядок.
+	//Ссылки с одинаковым значением поля упорядочиваются по короткому имени.
+	Sort string
+	Desc bool
+
+	//After задаёт позицию, после которой начинается страница, Limit - размер страницы.
+	//Нулевой Limit означает отсутствие ограничения.
+	After *LinkCursor
+	Limit int
+}
+
+// LinkCursor описывает позицию в упорядоченном списке ссылок: значения полей сортировки последней полученной ссылки.
+type LinkCursor struct {
+	CreatedAt time.Time
+	Clicks    int64
+	ShortURL  string
+}
+
+// LinkPage содержит страницу ссылок пользователя.
+type LinkPage struct {
+	Links []Link
+	//Total содержит количество всех ссылок, подходящих под условия выборки, HasMore сообщает о наличии следующей страницы.
+	Total   int64
+	HasMore bool
+}
+
+// Cursor возвращает позицию ссылки в упорядоченном списке.
+func (l Link) Cursor() LinkCursor {
+	return LinkCursor{CreatedAt: l.CreatedAt, Clicks: l.Clicks, ShortURL: l.ShortURL}
+}
+
+// Matches проверяет, подходит ли ссылка под условия выборки.
+func (f LinkFilter) Matches(l Link) bool {
+	if f.Folder != "" && l.Folder != f.Folder {
+		return false
+	}
+
+	if f.Campaign != "" && l.CampaignID != f.Campaign {
+		return false
+	}
+
+	for _, tag := range f.Tags {
+		if !hasTag(l.Tags, tag) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// less сообщает, должна ли ссылка с позицией a идти раньше ссылки с позицией b.
+func (f LinkFilter) less(a, b LinkCursor) bool {
+	if f.Desc {
+		a, b = b, a
+	}
+
+	switch f.Sort {
+	case LinkSortClicks:
+		if a.Clicks != b.Clicks {
+			return a.Clicks < b.Clicks
+		}
+	case LinkSortAlias:
+	default:
+		if !a.CreatedAt.Equal(b.CreatedAt) {
+			return a.CreatedAt.Before(b.CreatedAt)
+		}
+	}
+
+	return a.ShortURL < b.ShortURL
+}
+
+// page упорядочивает подходящие под условия ссылки и возвращает страницу, начинающуюся после позиции After.
+func (f LinkFilter) page(links []Link) LinkPage {
+	sort.Slice(links, func(i, j int) bool { return f.less(links[i].Cursor(), links[j].Cursor()) })
+
+	result := LinkPage{Total: int64(len(links))}
+
+	if f.After != nil {
+		after := *f.After
+		links = links[sort.Search(len(links), func(i int) bool { return f.less(after, links[i].Cursor()) }):]
+	}
+
+	if f.Limit > 0 && len(links) > f.Limit {
+		links = links[:f.Limit]
+		result.HasMore = true
+	}
+
+	result.Links = links
+
+	return result
+}
+
+// hasTag проверяет, есть ли тег в списке тегов ссылки.
+func hasTag(tags []string, tag string) bool {
+	for _, t := range tags {
+		if t == tag {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Variant описывает вариант адреса для A/B теста. Доля переходов на вариант пропорциональна его весу.
+// В базе данных варианты ссылки хранятся в формате json.
+type Variant struct {
+	Name   string `json:"name"`
+	URL    string `json:"url"`
+	Weight int    `json:"weight"`
+}
+
+// TargetRule описывает правило таргетинга по устройству и стране посетителя. Пустое условие подходит для любого значения,
+// правило со списком стран подходит для посетителей из любой из них. В базе данных правила ссылки хранятся в формате json.
+type TargetRule struct {
+	Name      string   `json:"name"`
+	OS        string   `json:"os,omitempty"`
+	Device    string   `json:"device,omitempty"`
+	Browser   string   `json:"browser,omitempty"`
+	Countries []string `json:"countries,omitempty"`
+	URL       string   `json:"url"`
+}
+
+// CheckWindow проверяет, действует ли ссылка в момент now.
+func (l Link) CheckWindow(now time.Time) error {
+	switch {
+	case !l.NotBefore.IsZero() && now.Before(l.NotBefore):
+		return ErrNotActive
+	case !l.NotAfter.IsZero() && !now.Before(l.NotAfter):
+		return ErrExpired
+	default:
+		return nil
+	}
+}
+
+// ClicksExhausted проверяет, исчерпано ли ограничение количества перенаправлений.
+func (l Link) ClicksExhausted() bool {
+	return l.MaxClicks > 0 && l.RemainingClicks <= 0
+}
+
+// Click описывает переход по сокращённой ссылке.
+type Click struct {
+	ShortURL  string
+	Time      time.Time
+	Referrer  string
+	UserAgent string
+	IPHash    string
+	Class     string
+	Rule      string
+	Variant   string
+}
+
+// Классы переходов.
+const (
+	ClassHuman    = "human"
+	ClassBot      = "bot"
+	ClassPrefetch = "prefetch"
+)
+
+// ClickClass возвращает класс перехода. Переходы без класса, сохранённые до появления классификации, считаются переходами людей.
+func (c Click) ClickClass() string {
+	if c.Class == "" {
+		return ClassHuman
+	}
+
+	return c.Class
+}
+
+// Размеры интервалов сводок переходов.
+const (
+	GranularityHour = "hour"
+	GranularityDay  = "day"
+)
+
+// Rollup содержит сводку переходов по ссылке за час или за день.
+type Rollup struct {
+	ShortURL    string
+	Granularity string
+	Start       time.Time
+	Class       string
+	Clicks      int64
+	Referrers   map[string]int64
+	Browsers    map[string]int64
+	OS          map[string]int64
+	Rules       map[string]int64
+	Variants    map[string]int64
+	Visitors    []byte
+}
+
+// AggregateFunc собирает сводки из переходов.
+type AggregateFunc func([]Click) []Rollup
+
+// Merge прибавляет к сводке другую сводку за тот же интервал.
+func (r *Rollup) Merge(other Rollup) {
+	r.Clicks += other.Clicks
+	r.Referrers = mergeCounts(r.Referrers, other.Referrers)
+	r.Browsers = mergeCounts(r.Browsers, other.Browsers)
+	r.OS = mergeCounts(r.OS, other.OS)
+	r.Rules = mergeCounts(r.Rules, other.Rules)
+	r.Variants = mergeCounts(r.Variants, other.Variants)
+
+	//регистры оценщика уникальных посетителей объединяются по максимуму
+	if len(r.Visitors) < len(other.Visitors) {
+		visitors := make([]byte, len(other.Visitors))
+		copy(visitors, r.Visitors)
+		r.Visitors = visitors
+	}
+	for i, v := range other.Visitors {
+		if v > r.Visitors[i] {
+			r.Visitors[i] = v
+		}
+	}
+}
+
+// mergeCounts прибавляет к счётчикам dst значения счётчиков src.
+func mergeCounts(dst, src map[string]int64) map[string]int64 {
+	if dst == nil {
+		dst = map[string]int64{}
+	}
+	for key, value := range src {
+		dst[key] += value
+	}
+
+	return dst
+}
+
+// Webhook описывает зарегистрированный пользователем адрес для уведомлений о событиях.
+type Webhook struct {
+	ID        string
+	UserID    string
+	URL       string
+	Secret    string
+	Events    []string
+	CreatedAt time.Time
+}
+
+// Campaign описывает маркетинговую кампанию пользователя. Parameters содержит шаблоны utm_* параметров,
+// которые добавляются к адресам перенаправления ссылок кампании. В базе данных параметры хранятся в формате json.
+type Campaign struct {
+	ID         string
+	UserID     string
+	Name       string
+	Parameters map[string]string
+	CreatedAt  time.Time
+	UpdatedAt  time.Time
+}
+
+// Статусы доставки уведомлений.
+const (
+	DeliveryPending = "pending"
+	DeliveryDead    = "dead"
+)
+
+// Delivery описывает уведомление в очереди доставки. Доставленные уведомления удаляются из очереди.
+type Delivery struct {
+	ID          string
+	WebhookID   string
+	UserID      string
+	Event       string
+	Payload     []byte
+	Status      string
+	Attempts    int
+	NextAttempt time.Time
+	LastError   string
+	CreatedAt   time.Time
+	UpdatedAt   time.Time
+}
+
+// Revision описывает неизменяемую запись истории изменений адреса, владельца или состояния ссылки.
+type Revision struct {
+	ShortURL    string
+	Version     int64
+	OriginalURL string
+	UserID      string
+	IsDeleted   bool
+	ChangedAt   time.Time
+}
+
+// UpdateFunc изменяет ссылку. Возвращённая ошибка отменяет изменение.
+type UpdateFunc func(*Link) error
+
+// applyUpdate проверяет владельца и версию ссылки, применяет изменение и увеличивает версию.
+func applyUpdate(link *Link, userID string, version int64, update UpdateFunc) error {
+	if link.UserID != userID {
+		return ErrNotOwner
+	}
+
+	if version != 0 && link.Version != version {
+		return ErrVersionConflict
+	}
+
+	if err := update(link); err != nil {
+		return err
+	}
+
+	link.Version++
+	link.UpdatedAt = time.Now()
+
+	return nil
+}
+
+// newRevision возвращает запись истории для текущего состояния ссылки.
+func newRevision(link Link) Revision {
+	return Revision{
+		ShortURL:    link.ShortURL,
+		Version:     link.Version,
+		OriginalURL: link.OriginalURL,
+		UserID:      link.UserID,
+		IsDeleted:   link.IsDeleted,
+		ChangedAt:   link.UpdatedAt,
+	}
+}
+
+// revisionChanged проверяет, нужно ли сохранять изменение ссылки в историю.
+func revisionChanged(before, after Link) bool {
+	return before.OriginalURL != after.OriginalURL || before.UserID != after.UserID || before.IsDeleted != after.IsDeleted
+}
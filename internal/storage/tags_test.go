@@ -0,0 +1,102 @@
+package storage
+
+import (
+	"context"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestStorage_Tags проверяет выборку ссылок по тегам и папке, переименование и удаление тегов.
+func TestStorage_Tags(t *testing.T) {
+	type tagStorage interface {
+		SaveData(context.Context, map[string]map[string]string) error
+		ConfigureLink(context.Context, string, UpdateFunc) (Link, error)
+		FindUserLinks(context.Context, string, LinkFilter) (LinkPage, error)
+		RenameTag(context.Context, string, string, string) (int64, error)
+		DeleteTag(context.Context, string, string) (int64, error)
+	}
+
+	tests := []struct {
+		name    string
+		storage tagStorage
+		file    string
+	}{
+		{name: "memory", storage: NewMemoryWork(map[string]map[string]string{})},
+		{name: "file", storage: FileSystemConnect{Filename: "tags_test.gob"}, file: "tags_test.gob"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.file != "" {
+				defer os.Remove(tt.file)
+			}
+
+			ctx := context.Background()
+			s := tt.storage
+
+			require.NoError(t, s.SaveData(ctx, map[string]map[string]string{
+				"owner": {"a": "https://example.com/a", "b": "https://example.com/b", "c": "https://example.com/c"},
+				"other": {"d": "https://example.com/d"},
+			}))
+
+			labels := map[string]Link{
+				"a": {Tags: []string{"docs", "news"}, Folder: "work"},
+				"b": {Tags: []string{"news"}},
+				"d": {Tags: []string{"news"}, Folder: "work"},
+			}
+			for short, v := range labels {
+				_, err := s.ConfigureLink(ctx, short, func(link *Link) error {
+					link.Tags = v.Tags
+					link.Folder = v.Folder
+					return nil
+				})
+				require.NoError(t, err)
+			}
+
+			shortnames := func(filter LinkFilter) []string {
+				page, err := s.FindUserLinks(ctx, "owner", filter)
+				require.NoError(t, err)
+
+				var result []string
+				for _, link := range page.Links {
+					result = append(result, link.ShortURL)
+				}
+
+				return result
+			}
+
+			assert.ElementsMatch(t, []string{"a", "b", "c"}, shortnames(LinkFilter{}))
+			assert.ElementsMatch(t, []string{"a", "b"}, shortnames(LinkFilter{Tags: []string{"news"}}))
+			assert.ElementsMatch(t, []string{"a"}, shortnames(LinkFilter{Tags: []string{"news", "docs"}}))
+			assert.ElementsMatch(t, []string{"a"}, shortnames(LinkFilter{Folder: "work"}))
+			assert.Empty(t, shortnames(LinkFilter{Tags: []string{"docs"}, Folder: "home"}))
+
+			renamed, err := s.RenameTag(ctx, "owner", "news", "docs")
+			require.NoError(t, err)
+			assert.Equal(t, int64(2), renamed)
+
+			page, err := s.FindUserLinks(ctx, "owner", LinkFilter{Tags: []string{"docs"}})
+			require.NoError(t, err)
+			require.Len(t, page.Links, 2)
+			for _, link := range page.Links {
+				assert.Equal(t, []string{"docs"}, link.Tags)
+			}
+
+			//теги других пользователей не изменяются
+			page, err = s.FindUserLinks(ctx, "other", LinkFilter{Tags: []string{"news"}})
+			require.NoError(t, err)
+			assert.Len(t, page.Links, 1)
+
+			deleted, err := s.DeleteTag(ctx, "owner", "docs")
+			require.NoError(t, err)
+			assert.Equal(t, int64(2), deleted)
+			assert.Empty(t, shortnames(LinkFilter{Tags: []string{"docs"}}))
+
+			deleted, err = s.DeleteTag(ctx, "owner", "docs")
+			require.NoError(t, err)
+			assert.Zero(t, deleted)
+		})
+	}
+}
@@ -0,0 +1,83 @@
+package useragent
+
+import "strings"
+
+// Типы устройств.
+const (
+	DeviceDesktop = "desktop"
+	DeviceMobile  = "mobile"
+	DeviceTablet  = "tablet"
+)
+
+// Other используется, если семейство браузера или ОС не распознано.
+const Other = "Other"
+
+// Agent содержит распознанные из заголовка User-Agent семейства браузера, ОС и тип устройства.
+type Agent struct {
+	Browser string
+	OS      string
+	Device  string
+}
+
+// rule сопоставляет подстроки заголовка User-Agent с названием семейства.
+type rule struct {
+	name    string
+	markers []string
+}
+
+// Правила проверяются по порядку, поэтому более специфичные семейства идут раньше.
+var (
+	browserRules = []rule{
+		{name: "Edge", markers: []string{"Edg/", "EdgA/", "EdgiOS/", "Edge/"}},
+		{name: "Opera", markers: []string{"OPR/", "Opera"}},
+		{name: "Samsung Internet", markers: []string{"SamsungBrowser/"}},
+		{name: "Yandex Browser", markers: []string{"YaBrowser/"}},
+		{name: "Firefox", markers: []string{"Firefox/", "FxiOS/"}},
+		{name: "Chrome", markers: []string{"Chrome/", "CriOS/", "Chromium/"}},
+		{name: "Safari", markers: []string{"Safari/"}},
+		{name: "Internet Explorer", markers: []string{"MSIE ", "Trident/"}},
+		{name: "curl", markers: []string{"curl/"}},
+		{name: "Wget", markers: []string{"Wget/"}},
+	}
+
+	osRules = []rule{
+		{name: "iOS", markers: []string{"iPhone", "iPad", "iPod"}},
+		{name: "Android", markers: []string{"Android"}},
+		{name: "ChromeOS", markers: []string{"CrOS"}},
+		{name: "Windows", markers: []string{"Windows"}},
+		{name: "macOS", markers: []string{"Macintosh", "Mac OS X"}},
+		{name: "Linux", markers: []string{"Linux", "X11"}},
+	}
+)
+
+// match возвращает название первого правила, маркер которого содержится в ua.
+func match(rules []rule, ua string) string {
+	for _, r := range rules {
+		for _, marker := range r.markers {
+			if strings.Contains(ua, marker) {
+				return r.name
+			}
+		}
+	}
+
+	return Other
+}
+
+// Parse распознаёт заголовок User-Agent.
+func Parse(ua string) Agent {
+	agent := Agent{
+		Browser: match(browserRules, ua),
+		OS:      match(osRules, ua),
+		Device:  DeviceDesktop,
+	}
+
+	switch {
+	case strings.Contains(ua, "iPad") || strings.Contains(ua, "Tablet") ||
+		(agent.OS == "Android" && !strings.Contains(ua, "Mobile")):
+		agent.Device = DeviceTablet
+	case strings.Contains(ua, "Mobi") || strings.Contains(ua, "iPhone") || strings.Contains(ua, "iPod"):
+		agent.Device = DeviceMobile
+	}
+
+	return agent
+}
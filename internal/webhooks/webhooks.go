@@ -0,0 +1,563 @@
+package webhooks
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/vladimirimekov/url-shortener/internal/safehttp"
+	"github.com/vladimirimekov/url-shortener/internal/storage"
+)
+
+// События, о которых отправляются уведомления.
+const (
+	EventLinkCreated = "link.created"
+	EventLinkClicked = "link.clicked"
+	EventLinkUpdated = "link.updated"
+	EventLinkDeleted = "link.deleted"
+
+	// AllEvents подписывает вебхук на все события.
+	AllEvents = "*"
+)
+
+// Заголовки запроса с уведомлением.
+const (
+	HeaderEvent     = "X-Webhook-Event"
+	HeaderDelivery  = "X-Webhook-Delivery"
+	HeaderTimestamp = "X-Webhook-Timestamp"
+	HeaderSignature = "X-Webhook-Signature"
+)
+
+// Параметры обработки очереди.
+const (
+	batchSize      = 100
+	maxErrorLength = 512
+	cacheTTL       = 30 * time.Second
+)
+
+// Ошибки регистрации вебхука.
+var (
+	ErrInvalidEvent = errors.New("unknown webhook event")
+	ErrInvalidURL   = errors.New("webhook url must be an absolute http or https url")
+)
+
+// events - события, на которые можно подписаться.
+var events = map[string]bool{
+	EventLinkCreated: true,
+	EventLinkClicked: true,
+	EventLinkUpdated: true,
+	EventLinkDeleted: true,
+	AllEvents:        true,
+}
+
+// Store - интерфейс хранилища вебхуков и очереди доставки.
+type Store interface {
+	GetLink(context.Context, string) (storage.Link, error)
+	GetWebhook(context.Context, string) (storage.Webhook, error)
+	GetWebhooks(context.Context, string) ([]storage.Webhook, error)
+	EnqueueDeliveries(context.Context, []storage.Delivery) error
+	ClaimDeliveries(context.Context, time.Time, int, time.Duration) ([]storage.Delivery, error)
+	RenewDelivery(context.Context, string, time.Time, time.Time) error
+	UpdateDelivery(context.Context, storage.Delivery) error
+	DeleteDelivery(context.Context, string) error
+}
+
+// Payload - тело запроса с уведомлением.
+type Payload struct {
+	ID        string      `json:"id"`
+	Event     string      `json:"event"`
+	CreatedAt time.Time   `json:"created_at"`
+	Data      interface{} `json:"data"`
+}
+
+// Dispatcher ставит уведомления о событиях в очередь и доставляет их с повторными попытками.
+// Задержка между попытками растёт экспоненциально от baseDelay до maxDelay; после maxAttempts неудачных попыток
+// уведомление помечается как недоставленное и может быть отправлено повторно вручную.
+// Уведомления разных вебхуков отправляются параллельно в workers горутинах, уведомления одного вебхука - по очереди.
+// Адреса вебхуков задают пользователи, поэтому в работе используется клиент из safehttp.NewClient.
+type Dispatcher struct {
+	store  Store
+	client *http.Client
+
+	workers     int
+	maxAttempts int
+	baseDelay   time.Duration
+	maxDelay    time.Duration
+	interval    time.Duration
+
+	wake chan struct{}
+
+	mu    sync.Mutex
+	cache map[string]cachedWebhooks
+}
+
+// cachedWebhooks хранит вебхуки пользователя, закэшированные для уведомлений о переходах.
+type cachedWebhooks struct {
+	webhooks []storage.Webhook
+	loaded   time.Time
+}
+
+// New - конструктор Dispatcher.
+func New(store Store, client *http.Client, workers, maxAttempts int, baseDelay, maxDelay, interval time.Duration) *Dispatcher {
+	if workers <= 0 {
+		workers = 1
+	}
+
+	return &Dispatcher{
+		store:       store,
+		client:      client,
+		workers:     workers,
+		maxAttempts: maxAttempts,
+		baseDelay:   baseDelay,
+		maxDelay:    maxDelay,
+		interval:    interval,
+		wake:        make(chan struct{}, 1),
+		cache:       map[string]cachedWebhooks{},
+	}
+}
+
+// NewSecret генерирует секрет для подписи уведомлений.
+func NewSecret() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+
+	return hex.EncodeToString(b), nil
+}
+
+// Sign возвращает подпись уведомления: HMAC-SHA256 от строки "<timestamp>.<body>" в шестнадцатеричном виде с префиксом sha256=.
+func Sign(secret string, timestamp int64, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(strconv.FormatInt(timestamp, 10)))
+	mac.Write([]byte("."))
+	mac.Write(body)
+
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// Verify проверяет подпись уведомления.
+func Verify(secret string, timestamp int64, body []byte, signature string) bool {
+	return hmac.Equal([]byte(Sign(secret, timestamp, body)), []byte(signature))
+}
+
+// Validate проверяет адрес и список событий вебхука. Пустой список событий означает подписку на все события.
+func Validate(rawURL string, eventNames []string) error {
+	u, err := url.ParseRequestURI(rawURL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return ErrInvalidURL
+	}
+	if err = safehttp.CheckURL(u); err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidURL, err)
+	}
+
+	for _, event := range eventNames {
+		if !events[event] {
+			return fmt.Errorf("%w: %s", ErrInvalidEvent, event)
+		}
+	}
+
+	return nil
+}
+
+// Matches проверяет, подписан ли вебхук на событие.
+func Matches(w storage.Webhook, event string) bool {
+	if len(w.Events) == 0 {
+		return true
+	}
+
+	for _, e := range w.Events {
+		if e == event || e == AllEvents {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Emit ставит в очередь уведомления о событии для всех подписанных на него вебхуков пользователя.
+// Для nil Dispatcher ничего не делает.
+func (d *Dispatcher) Emit(ctx context.Context, userID, event string, data interface{}) error {
+	if d == nil {
+		return nil
+	}
+
+	webhooks, err := d.store.GetWebhooks(ctx, userID)
+	if err != nil {
+		return err
+	}
+
+	return d.enqueue(ctx, webhooks, event, data)
+}
+
+// enqueue ставит в очередь уведомления о событии для подписанных на него вебхуков.
+func (d *Dispatcher) enqueue(ctx context.Context, webhooks []storage.Webhook, event string, data interface{}) error {
+	deliveries, err := newDeliveries(nil, webhooks, event, data, time.Now().UTC())
+	if err != nil {
+		return err
+	}
+
+	return d.save(ctx, deliveries)
+}
+
+// newDeliveries добавляет к deliveries уведомления о событии для подписанных на него вебхуков.
+func newDeliveries(deliveries []storage.Delivery, webhooks []storage.Webhook, event string, data interface{}, now time.Time) ([]storage.Delivery, error) {
+	for _, w := range webhooks {
+		if !Matches(w, event) {
+			continue
+		}
+
+		id := uuid.NewString()
+
+		payload, err := json.Marshal(Payload{ID: id, Event: event, CreatedAt: now, Data: data})
+		if err != nil {
+			return nil, err
+		}
+
+		deliveries = append(deliveries, storage.Delivery{
+			ID:          id,
+			WebhookID:   w.ID,
+			UserID:      w.UserID,
+			Event:       event,
+			Payload:     payload,
+			Status:      storage.DeliveryPending,
+			NextAttempt: now,
+			CreatedAt:   now,
+			UpdatedAt:   now,
+		})
+	}
+
+	return deliveries, nil
+}
+
+// save сохраняет уведомления в очередь и будит обработчик очереди.
+func (d *Dispatcher) save(ctx context.Context, deliveries []storage.Delivery) error {
+	if len(deliveries) == 0 {
+		return nil
+	}
+
+	if err := d.store.EnqueueDeliveries(ctx, deliveries); err != nil {
+		return err
+	}
+
+	d.notify()
+
+	return nil
+}
+
+// notify будит обработчик очереди.
+func (d *Dispatcher) notify() {
+	select {
+	case d.wake <- struct{}{}:
+	default:
+	}
+}
+
+// Replay возвращает уведомление в очередь для немедленной доставки со сбросом счётчика попыток.
+func (d *Dispatcher) Replay(ctx context.Context, delivery storage.Delivery) (storage.Delivery, error) {
+	now := time.Now().UTC()
+
+	delivery.Status = storage.DeliveryPending
+	delivery.Attempts = 0
+	delivery.NextAttempt = now
+	delivery.LastError = ""
+	delivery.UpdatedAt = now
+
+	if err := d.store.UpdateDelivery(ctx, delivery); err != nil {
+		return storage.Delivery{}, err
+	}
+
+	d.notify()
+
+	return delivery, nil
+}
+
+// Deliver отправляет уведомления, время доставки которых наступило, и возвращает количество выбранных уведомлений.
+// Ошибки сохранения результата отдельных уведомлений записываются в лог и не прерывают обработку остальных.
+func (d *Dispatcher) Deliver(ctx context.Context) (int, error) {
+	lease := d.lease()
+
+	deliveries, err := d.store.ClaimDeliveries(ctx, time.Now().UTC(), batchSize, lease)
+	if err != nil {
+		return 0, err
+	}
+
+	//уведомления группируются по вебхукам, чтобы медленный получатель занимал не больше одной горутины
+	var order []string
+	groups := map[string][]storage.Delivery{}
+
+	for _, delivery := range deliveries {
+		if _, ok := groups[delivery.WebhookID]; !ok {
+			order = append(order, delivery.WebhookID)
+		}
+		groups[delivery.WebhookID] = append(groups[delivery.WebhookID], delivery)
+	}
+
+	queue := make(chan []storage.Delivery)
+
+	var wg sync.WaitGroup
+
+	for i := 0; i < d.workers && i < len(order); i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			for group := range queue {
+				d.deliverGroup(ctx, group, lease)
+			}
+		}()
+	}
+
+	for _, id := range order {
+		queue <- groups[id]
+	}
+	close(queue)
+
+	wg.Wait()
+
+	return len(deliveries), nil
+}
+
+// lease возвращает срок аренды уведомления: его должно хватать на одну попытку доставки.
+func (d *Dispatcher) lease() time.Duration {
+	lease := d.client.Timeout + d.baseDelay
+	if d.client.Timeout <= 0 {
+		lease = time.Minute + d.baseDelay
+	}
+
+	return lease
+}
+
+// deliverGroup по очереди отправляет уведомления одного вебхука. Перед каждой отправкой аренда уведомления продлевается,
+// и если оно уже выдано другому обработчику, оно пропускается. После неудачной попытки остальные уведомления вебхука
+// откладываются до окончания их аренды, чтобы недоступный получатель не задерживал обработку очереди.
+func (d *Dispatcher) deliverGroup(ctx context.Context, group []storage.Delivery, lease time.Duration) {
+	for _, delivery := range group {
+		if ctx.Err() != nil {
+			return
+		}
+
+		until := time.Now().UTC().Add(lease)
+
+		if err := d.store.RenewDelivery(ctx, delivery.ID, delivery.NextAttempt, until); err != nil {
+			if !errors.Is(err, storage.ErrLeaseLost) && !errors.Is(err, context.Canceled) {
+				log.Print(err)
+			}
+			continue
+		}
+		delivery.NextAttempt = until
+
+		delivered, err := d.attempt(ctx, delivery)
+		if err != nil && !errors.Is(err, context.Canceled) {
+			log.Print(err)
+		}
+		if !delivered {
+			return
+		}
+	}
+}
+
+// attempt выполняет одну попытку доставки уведомления, сохраняет её результат и сообщает, было ли уведомление доставлено.
+func (d *Dispatcher) attempt(ctx context.Context, delivery storage.Delivery) (bool, error) {
+	sendErr := d.send(ctx, delivery)
+	if sendErr == nil {
+		return true, d.store.DeleteDelivery(ctx, delivery.ID)
+	}
+
+	now := time.Now().UTC()
+
+	delivery.Attempts++
+	delivery.LastError = sendErr.Error()
+	if len(delivery.LastError) > maxErrorLength {
+		delivery.LastError = delivery.LastError[:maxErrorLength]
+	}
+	delivery.UpdatedAt = now
+
+	if delivery.Attempts >= d.maxAttempts || errors.Is(sendErr, storage.ErrNotFound) {
+		delivery.Status = storage.DeliveryDead
+	} else {
+		delivery.NextAttempt = now.Add(d.Backoff(delivery.Attempts))
+	}
+
+	return false, d.store.UpdateDelivery(ctx, delivery)
+}
+
+// Backoff возвращает задержку перед следующей попыткой после attempts неудачных попыток.
+func (d *Dispatcher) Backoff(attempts int) time.Duration {
+	delay := d.baseDelay
+
+	for i := 1; i < attempts; i++ {
+		delay *= 2
+		if delay >= d.maxDelay {
+			return d.maxDelay
+		}
+	}
+
+	return delay
+}
+
+// send отправляет уведомление на адрес вебхука.
+func (d *Dispatcher) send(ctx context.Context, delivery storage.Delivery) error {
+	w, err := d.store.GetWebhook(ctx, delivery.WebhookID)
+	if err != nil {
+		return err
+	}
+
+	request, err := http.NewRequestWithContext(ctx, http.MethodPost, w.URL, bytes.NewReader(delivery.Payload))
+	if err != nil {
+		return err
+	}
+
+	timestamp := time.Now().Unix()
+
+	request.Header.Set("Content-Type", "application/json")
+	request.Header.Set(HeaderEvent, delivery.Event)
+	request.Header.Set(HeaderDelivery, delivery.ID)
+	request.Header.Set(HeaderTimestamp, strconv.FormatInt(timestamp, 10))
+	request.Header.Set(HeaderSignature, Sign(w.Secret, timestamp, delivery.Payload))
+
+	response, err := d.client.Do(request)
+	if err != nil {
+		return err
+	}
+	defer response.Body.Close()
+
+	io.Copy(io.Discard, io.LimitReader(response.Body, 1<<16))
+
+	if response.StatusCode < 200 || response.StatusCode >= 300 {
+		return fmt.Errorf("unexpected status %s", response.Status)
+	}
+
+	return nil
+}
+
+// Run обрабатывает очередь каждые interval и после постановки новых уведомлений до отмены контекста.
+func (d *Dispatcher) Run(ctx context.Context) {
+	ticker := time.NewTicker(d.interval)
+	defer ticker.Stop()
+
+	for {
+		for {
+			n, err := d.Deliver(ctx)
+			if err != nil && !errors.Is(err, context.Canceled) {
+				log.Print(err)
+			}
+			if err != nil || n < batchSize {
+				break
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		case <-d.wake:
+		}
+	}
+}
+
+// ClickData - данные уведомления о переходе по ссылке.
+type ClickData struct {
+	ShortURL string    `json:"short_url"`
+	Time     time.Time `json:"time"`
+	Referrer string    `json:"referrer"`
+	Class    string    `json:"class"`
+}
+
+// EnqueueClicks ставит в очередь уведомления о сохранённых переходах для вебхуков владельцев ссылок.
+// Вызывается после сохранения пачки переходов, поэтому уведомления не теряются при высокой нагрузке.
+// Переходы ботов не отправляются. Для nil Dispatcher ничего не делает.
+func (d *Dispatcher) EnqueueClicks(ctx context.Context, clicks []storage.Click, host string) error {
+	if d == nil {
+		return nil
+	}
+
+	now := time.Now().UTC()
+	owners := map[string]string{}
+
+	var deliveries []storage.Delivery
+
+	for _, c := range clicks {
+		if c.ClickClass() == storage.ClassBot {
+			continue
+		}
+
+		userID, ok := owners[c.ShortURL]
+		if !ok {
+			link, err := d.store.GetLink(ctx, c.ShortURL)
+			if errors.Is(err, storage.ErrNotFound) {
+				owners[c.ShortURL] = ""
+				continue
+			}
+			if err != nil {
+				return err
+			}
+
+			userID = link.UserID
+			owners[c.ShortURL] = userID
+		}
+		if userID == "" {
+			continue
+		}
+
+		webhooks, err := d.cachedWebhooks(ctx, userID)
+		if err != nil {
+			return err
+		}
+
+		deliveries, err = newDeliveries(deliveries, webhooks, EventLinkClicked, ClickData{
+			ShortURL: host + "/" + c.ShortURL,
+			Time:     c.Time,
+			Referrer: c.Referrer,
+			Class:    c.ClickClass(),
+		}, now)
+		if err != nil {
+			return err
+		}
+	}
+
+	return d.save(ctx, deliveries)
+}
+
+// cachedWebhooks возвращает вебхуки пользователя, перечитывая их из хранилища не чаще раза в cacheTTL.
+func (d *Dispatcher) cachedWebhooks(ctx context.Context, userID string) ([]storage.Webhook, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	now := time.Now()
+
+	if entry, ok := d.cache[userID]; ok && now.Sub(entry.loaded) < cacheTTL {
+		return entry.webhooks, nil
+	}
+
+	webhooks, err := d.store.GetWebhooks(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	//устаревшие записи удаляются, чтобы кэш не рос бесконечно
+	for key, entry := range d.cache {
+		if now.Sub(entry.loaded) >= cacheTTL {
+			delete(d.cache, key)
+		}
+	}
+
+	d.cache[userID] = cachedWebhooks{webhooks: webhooks, loaded: now}
+
+	return webhooks, nil
+}
@@ -0,0 +1,314 @@
+package webhooks
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/vladimirimekov/url-shortener/internal/storage"
+)
+
+// receiver - тестовый получатель уведомлений, проверяющий подпись.
+type receiver struct {
+	t      *testing.T
+	secret string
+
+	mu       sync.Mutex
+	status   int
+	payloads []Payload
+}
+
+// ServeHTTP принимает уведомление и отвечает заданным статусом.
+func (rc *receiver) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	body, err := io.ReadAll(r.Body)
+	require.NoError(rc.t, err)
+
+	timestamp, err := strconv.ParseInt(r.Header.Get(HeaderTimestamp), 10, 64)
+	require.NoError(rc.t, err)
+	assert.True(rc.t, Verify(rc.secret, timestamp, body, r.Header.Get(HeaderSignature)))
+
+	var p Payload
+	require.NoError(rc.t, json.Unmarshal(body, &p))
+	assert.Equal(rc.t, p.Event, r.Header.Get(HeaderEvent))
+	assert.Equal(rc.t, p.ID, r.Header.Get(HeaderDelivery))
+
+	rc.mu.Lock()
+	defer rc.mu.Unlock()
+
+	rc.payloads = append(rc.payloads, p)
+	w.WriteHeader(rc.status)
+}
+
+// received возвращает принятые уведомления.
+func (rc *receiver) received() []Payload {
+	rc.mu.Lock()
+	defer rc.mu.Unlock()
+
+	return append([]Payload(nil), rc.payloads...)
+}
+
+// setStatus задаёт статус ответа получателя.
+func (rc *receiver) setStatus(status int) {
+	rc.mu.Lock()
+	defer rc.mu.Unlock()
+
+	rc.status = status
+}
+
+// newWebhook регистрирует вебхук пользователя с адресом тестового получателя.
+func newWebhook(t *testing.T, s storage.MemoryWork, id, userID string, events []string) (*receiver, func()) {
+	secret, err := NewSecret()
+	require.NoError(t, err)
+
+	rc := &receiver{t: t, secret: secret, status: http.StatusOK}
+	server := httptest.NewServer(rc)
+
+	require.NoError(t, s.SaveWebhook(context.Background(), storage.Webhook{
+		ID:        id,
+		UserID:    userID,
+		URL:       server.URL,
+		Secret:    secret,
+		Events:    events,
+		CreatedAt: time.Now(),
+	}))
+
+	return rc, server.Close
+}
+
+// TestDispatcher проверяет доставку уведомлений, фильтр событий, повторные попытки и повторную отправку.
+func TestDispatcher(t *testing.T) {
+	ctx := context.Background()
+	s := storage.NewMemoryWork(map[string]map[string]string{})
+
+	all, closeAll := newWebhook(t, s, "all", "owner", nil)
+	defer closeAll()
+
+	deleted, closeDeleted := newWebhook(t, s, "deleted", "owner", []string{EventLinkDeleted})
+	defer closeDeleted()
+
+	d := New(s, &http.Client{Timeout: time.Second}, 2, 3, 0, 0, time.Minute)
+
+	require.NoError(t, d.Emit(ctx, "owner", EventLinkCreated, map[string]string{"short_url": "abc"}))
+	require.NoError(t, d.Emit(ctx, "stranger", EventLinkCreated, map[string]string{"short_url": "xyz"}))
+
+	n, err := d.Deliver(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 1, n)
+
+	require.Len(t, all.received(), 1)
+	assert.Equal(t, EventLinkCreated, all.received()[0].Event)
+	assert.Empty(t, deleted.received())
+
+	//получатель недоступен: после исчерпания попыток уведомление попадает в список недоставленных
+	deleted.setStatus(http.StatusInternalServerError)
+	require.NoError(t, d.Emit(ctx, "owner", EventLinkDeleted, map[string]string{"short_url": "abc"}))
+
+	for i := 0; i < 5; i++ {
+		_, err = d.Deliver(ctx)
+		require.NoError(t, err)
+	}
+
+	assert.Len(t, all.received(), 2)
+	assert.Len(t, deleted.received(), 3)
+
+	dead, err := s.GetDeliveries(ctx, "owner", storage.DeliveryDead)
+	require.NoError(t, err)
+	require.Len(t, dead, 1)
+	assert.Equal(t, 3, dead[0].Attempts)
+	assert.Equal(t, "deleted", dead[0].WebhookID)
+	assert.Contains(t, dead[0].LastError, "500")
+
+	//после восстановления получателя уведомление отправляется повторно вручную
+	deleted.setStatus(http.StatusNoContent)
+
+	_, err = d.Replay(ctx, dead[0])
+	require.NoError(t, err)
+
+	n, err = d.Deliver(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 1, n)
+	assert.Len(t, deleted.received(), 4)
+	assert.Equal(t, dead[0].ID, deleted.received()[3].ID)
+
+	dead, err = s.GetDeliveries(ctx, "owner", storage.DeliveryDead)
+	require.NoError(t, err)
+	assert.Empty(t, dead)
+}
+
+// TestDispatcher_SlowReceiver проверяет, что медленный получатель не задерживает уведомления других вебхуков
+// и занимает не больше одного таймаута за проход очереди.
+func TestDispatcher_SlowReceiver(t *testing.T) {
+	ctx := context.Background()
+	s := storage.NewMemoryWork(map[string]map[string]string{})
+
+	fast, closeFast := newWebhook(t, s, "fast", "owner", nil)
+	defer closeFast()
+
+	slow := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		//после чтения тела сервер замечает разрыв соединения клиентом по таймауту
+		io.Copy(io.Discard, r.Body)
+		select {
+		case <-r.Context().Done():
+		case <-time.After(5 * time.Second):
+		}
+	}))
+	defer slow.Close()
+
+	require.NoError(t, s.SaveWebhook(ctx, storage.Webhook{ID: "slow", UserID: "stranger", URL: slow.URL, Secret: "secret", CreatedAt: time.Now()}))
+
+	timeout := 200 * time.Millisecond
+	d := New(s, &http.Client{Timeout: timeout}, 4, 3, time.Minute, time.Hour, time.Minute)
+
+	for i := 0; i < 3; i++ {
+		require.NoError(t, d.Emit(ctx, "owner", EventLinkCreated, map[string]int{"i": i}))
+		require.NoError(t, d.Emit(ctx, "stranger", EventLinkCreated, map[string]int{"i": i}))
+	}
+
+	start := time.Now()
+	n, err := d.Deliver(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 6, n)
+	assert.Less(t, time.Since(start), 2*timeout)
+
+	assert.Len(t, fast.received(), 3)
+
+	//после неудачной попытки остальные уведомления медленного получателя ждут окончания аренды
+	pending, err := s.GetDeliveries(ctx, "stranger", storage.DeliveryPending)
+	require.NoError(t, err)
+	require.Len(t, pending, 3)
+
+	attempts := 0
+	for _, delivery := range pending {
+		attempts += delivery.Attempts
+		assert.True(t, delivery.NextAttempt.After(time.Now()))
+	}
+	assert.Equal(t, 1, attempts)
+}
+
+// lostLeaseStore - хранилище, в котором уведомления сразу после выдачи забирает другой экземпляр сервиса.
+type lostLeaseStore struct {
+	storage.MemoryWork
+}
+
+// ClaimDeliveries выдаёт уведомления и повторно выдаёт их другому обработчику, как после истечения аренды.
+func (s lostLeaseStore) ClaimDeliveries(ctx context.Context, now time.Time, limit int, lease time.Duration) ([]storage.Delivery, error) {
+	deliveries, err := s.MemoryWork.ClaimDeliveries(ctx, now, limit, lease)
+	if err != nil {
+		return nil, err
+	}
+
+	_, err = s.MemoryWork.ClaimDeliveries(ctx, now.Add(2*lease), limit, lease)
+
+	return deliveries, err
+}
+
+// failingDeleteStore - хранилище, не удаляющее доставленные уведомления.
+type failingDeleteStore struct {
+	storage.MemoryWork
+}
+
+// DeleteDelivery всегда возвращает ошибку.
+func (s failingDeleteStore) DeleteDelivery(context.Context, string) error {
+	return errors.New("storage is unavailable")
+}
+
+// TestDispatcher_Lease проверяет, что уведомление, выданное другому обработчику, не отправляется повторно,
+// а ошибка сохранения результата одного уведомления не прерывает отправку остальных.
+func TestDispatcher_Lease(t *testing.T) {
+	ctx := context.Background()
+	s := storage.NewMemoryWork(map[string]map[string]string{})
+
+	rc, closeServer := newWebhook(t, s, "all", "owner", nil)
+	defer closeServer()
+
+	d := New(lostLeaseStore{s}, &http.Client{Timeout: time.Second}, 2, 3, time.Second, time.Minute, time.Minute)
+	require.NoError(t, d.Emit(ctx, "owner", EventLinkCreated, nil))
+
+	n, err := d.Deliver(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 1, n)
+	assert.Empty(t, rc.received())
+
+	s = storage.NewMemoryWork(map[string]map[string]string{})
+
+	rc, closeServer = newWebhook(t, s, "all", "owner", nil)
+	defer closeServer()
+
+	d = New(failingDeleteStore{s}, &http.Client{Timeout: time.Second}, 2, 3, time.Second, time.Minute, time.Minute)
+	for i := 0; i < 3; i++ {
+		require.NoError(t, d.Emit(ctx, "owner", EventLinkCreated, map[string]int{"i": i}))
+	}
+
+	n, err = d.Deliver(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 3, n)
+	assert.Len(t, rc.received(), 3)
+}
+
+// TestDispatcher_EnqueueClicks проверяет, что уведомления ставятся в очередь для каждого сохранённого перехода,
+// кроме переходов ботов и переходов по неизвестным ссылкам.
+func TestDispatcher_EnqueueClicks(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	s := storage.NewMemoryWork(map[string]map[string]string{})
+	require.NoError(t, s.SaveData(ctx, map[string]map[string]string{"owner": {"abc": "https://example.com"}}))
+
+	rc, closeServer := newWebhook(t, s, "clicks", "owner", []string{EventLinkClicked})
+	defer closeServer()
+
+	d := New(s, &http.Client{Timeout: time.Second}, 2, 3, time.Millisecond, time.Second, 10*time.Millisecond)
+	assert.NoError(t, (*Dispatcher)(nil).EnqueueClicks(ctx, []storage.Click{{ShortURL: "abc"}}, ""))
+
+	clicks := []storage.Click{
+		{ShortURL: "abc", Time: time.Now(), UserAgent: "TelegramBot", Class: storage.ClassBot},
+		{ShortURL: "missing", Time: time.Now()},
+	}
+	for i := 0; i < 50; i++ {
+		clicks = append(clicks, storage.Click{ShortURL: "abc", Time: time.Now(), Referrer: "https://t.me/channel"})
+	}
+	require.NoError(t, d.EnqueueClicks(ctx, clicks, "http://localhost:8080"))
+
+	pending, err := s.GetDeliveries(ctx, "owner", storage.DeliveryPending)
+	require.NoError(t, err)
+	assert.Len(t, pending, 50)
+
+	go d.Run(ctx)
+
+	assert.Eventually(t, func() bool { return len(rc.received()) == 50 }, 5*time.Second, 10*time.Millisecond)
+
+	data := rc.received()[0].Data.(map[string]interface{})
+	assert.Equal(t, "http://localhost:8080/abc", data["short_url"])
+	assert.Equal(t, storage.ClassHuman, data["class"])
+}
+
+// TestBackoff проверяет экспоненциальный рост задержки между попытками.
+func TestBackoff(t *testing.T) {
+	d := New(nil, http.DefaultClient, 1, 10, time.Second, 10*time.Second, time.Minute)
+
+	assert.Equal(t, time.Second, d.Backoff(1))
+	assert.Equal(t, 2*time.Second, d.Backoff(2))
+	assert.Equal(t, 8*time.Second, d.Backoff(4))
+	assert.Equal(t, 10*time.Second, d.Backoff(5))
+}
+
+// TestValidate проверяет проверку адреса и событий вебхука.
+func TestValidate(t *testing.T) {
+	assert.NoError(t, Validate("https://example.com/hook", []string{EventLinkCreated, EventLinkClicked}))
+	assert.ErrorIs(t, Validate("http://localhost:9000", nil), ErrInvalidURL)
+	assert.ErrorIs(t, Validate("http://169.254.169.254/latest/meta-data", nil), ErrInvalidURL)
+	assert.ErrorIs(t, Validate("http://10.0.0.5/hook", nil), ErrInvalidURL)
+	assert.ErrorIs(t, Validate("ftp://example.com", nil), ErrInvalidURL)
+	assert.ErrorIs(t, Validate("example.com", nil), ErrInvalidURL)
+	assert.ErrorIs(t, Validate("https://example.com", []string{"link.renamed"}), ErrInvalidEvent)
+}
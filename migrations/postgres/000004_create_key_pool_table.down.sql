@@ -0,0 +1 @@
+DROP TABLE IF EXISTS key_pool;
\ No newline at end of file
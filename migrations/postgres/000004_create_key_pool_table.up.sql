@@ -0,0 +1,7 @@
+CREATE TABLE IF NOT EXISTS key_pool
+(
+    key VARCHAR(100) NOT NULL,
+    claimed_by VARCHAR(255),
+    claimed_at TIMESTAMP WITH TIME ZONE,
+    PRIMARY KEY(key)
+);
@@ -0,0 +1,6 @@
+DROP INDEX IF EXISTS urls_shorturl_idx;
+
+ALTER TABLE urls
+    DROP COLUMN IF EXISTS version,
+    DROP COLUMN IF EXISTS created_at,
+    DROP COLUMN IF EXISTS updated_at;
@@ -0,0 +1 @@
+DROP TABLE IF EXISTS url_revisions;
\ No newline at end of file
@@ -0,0 +1,15 @@
+CREATE TABLE IF NOT EXISTS url_revisions
+(
+    shortURL VARCHAR(100) NOT NULL,
+    version BIGINT NOT NULL,
+    originalURL TEXT NOT NULL,
+    user_ID INT,
+    isDelete BOOLEAN NOT NULL DEFAULT FALSE,
+    changed_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT now(),
+    PRIMARY KEY (shortURL, version),
+    FOREIGN KEY (user_ID) REFERENCES users (user_ID)
+);
+
+INSERT INTO url_revisions (shortURL, version, originalURL, user_ID, isDelete, changed_at)
+SELECT shortURL, version, originalURL, user_ID, isDelete, updated_at FROM urls
+ON CONFLICT DO NOTHING;
@@ -0,0 +1,14 @@
+ALTER TABLE urls ADD COLUMN IF NOT EXISTS clicks BIGINT NOT NULL DEFAULT 0;
+
+CREATE TABLE IF NOT EXISTS clicks
+(
+    click_ID BIGINT GENERATED ALWAYS AS IDENTITY,
+    shortURL VARCHAR(100) NOT NULL,
+    clicked_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    referrer TEXT NOT NULL DEFAULT '',
+    user_agent TEXT NOT NULL DEFAULT '',
+    ip_hash VARCHAR(64) NOT NULL DEFAULT '',
+    PRIMARY KEY(click_ID)
+);
+
+CREATE INDEX IF NOT EXISTS clicks_shorturl_clicked_at_idx ON clicks (shortURL, clicked_at);
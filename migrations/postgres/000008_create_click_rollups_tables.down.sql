@@ -0,0 +1,5 @@
+DROP INDEX IF EXISTS clicks_clicked_at_idx;
+
+DROP TABLE IF EXISTS click_rollup_state;
+DROP TABLE IF EXISTS click_rollups_daily;
+DROP TABLE IF EXISTS click_rollups_hourly;
@@ -0,0 +1,32 @@
+CREATE TABLE IF NOT EXISTS click_rollups_hourly
+(
+    shortURL VARCHAR(100) NOT NULL,
+    bucket_start TIMESTAMP WITH TIME ZONE NOT NULL,
+    clicks BIGINT NOT NULL DEFAULT 0,
+    referrers JSONB NOT NULL DEFAULT '{}',
+    browsers JSONB NOT NULL DEFAULT '{}',
+    os JSONB NOT NULL DEFAULT '{}',
+    visitors BYTEA,
+    PRIMARY KEY(shortURL, bucket_start)
+);
+
+CREATE TABLE IF NOT EXISTS click_rollups_daily
+(
+    shortURL VARCHAR(100) NOT NULL,
+    bucket_start TIMESTAMP WITH TIME ZONE NOT NULL,
+    clicks BIGINT NOT NULL DEFAULT 0,
+    referrers JSONB NOT NULL DEFAULT '{}',
+    browsers JSONB NOT NULL DEFAULT '{}',
+    os JSONB NOT NULL DEFAULT '{}',
+    visitors BYTEA,
+    PRIMARY KEY(shortURL, bucket_start)
+);
+
+CREATE TABLE IF NOT EXISTS click_rollup_state
+(
+    id INT NOT NULL DEFAULT 1 CHECK (id = 1),
+    watermark TIMESTAMP WITH TIME ZONE NOT NULL,
+    PRIMARY KEY(id)
+);
+
+CREATE INDEX IF NOT EXISTS clicks_clicked_at_idx ON clicks (clicked_at);
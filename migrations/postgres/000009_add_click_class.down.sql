@@ -0,0 +1,11 @@
+DELETE FROM click_rollups_daily WHERE class <> 'human';
+ALTER TABLE click_rollups_daily DROP CONSTRAINT IF EXISTS click_rollups_daily_pkey;
+ALTER TABLE click_rollups_daily DROP COLUMN IF EXISTS class;
+ALTER TABLE click_rollups_daily ADD PRIMARY KEY (shortURL, bucket_start);
+
+DELETE FROM click_rollups_hourly WHERE class <> 'human';
+ALTER TABLE click_rollups_hourly DROP CONSTRAINT IF EXISTS click_rollups_hourly_pkey;
+ALTER TABLE click_rollups_hourly DROP COLUMN IF EXISTS class;
+ALTER TABLE click_rollups_hourly ADD PRIMARY KEY (shortURL, bucket_start);
+
+ALTER TABLE clicks DROP COLUMN IF EXISTS class;
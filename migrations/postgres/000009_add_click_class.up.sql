@@ -0,0 +1,9 @@
+ALTER TABLE clicks ADD COLUMN IF NOT EXISTS class VARCHAR(16) NOT NULL DEFAULT 'human';
+
+ALTER TABLE click_rollups_hourly ADD COLUMN IF NOT EXISTS class VARCHAR(16) NOT NULL DEFAULT 'human';
+ALTER TABLE click_rollups_hourly DROP CONSTRAINT IF EXISTS click_rollups_hourly_pkey;
+ALTER TABLE click_rollups_hourly ADD PRIMARY KEY (shortURL, bucket_start, class);
+
+ALTER TABLE click_rollups_daily ADD COLUMN IF NOT EXISTS class VARCHAR(16) NOT NULL DEFAULT 'human';
+ALTER TABLE click_rollups_daily DROP CONSTRAINT IF EXISTS click_rollups_daily_pkey;
+ALTER TABLE click_rollups_daily ADD PRIMARY KEY (shortURL, bucket_start, class);
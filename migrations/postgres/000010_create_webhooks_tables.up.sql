@@ -0,0 +1,34 @@
+CREATE TABLE IF NOT EXISTS webhooks
+(
+    webhook_ID VARCHAR(36) NOT NULL,
+    user_ID INT NOT NULL,
+    url TEXT NOT NULL,
+    secret VARCHAR(64) NOT NULL,
+    events TEXT[] NOT NULL DEFAULT '{}',
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT now(),
+    PRIMARY KEY(webhook_ID),
+    FOREIGN KEY (user_ID) REFERENCES users (user_ID)
+);
+
+CREATE INDEX IF NOT EXISTS webhooks_user_id_idx ON webhooks (user_ID);
+
+CREATE TABLE IF NOT EXISTS webhook_deliveries
+(
+    delivery_ID VARCHAR(36) NOT NULL,
+    webhook_ID VARCHAR(36) NOT NULL,
+    user_ID INT NOT NULL,
+    event VARCHAR(32) NOT NULL,
+    payload BYTEA NOT NULL,
+    status VARCHAR(16) NOT NULL DEFAULT 'pending',
+    attempts INT NOT NULL DEFAULT 0,
+    next_attempt TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT now(),
+    last_error TEXT NOT NULL DEFAULT '',
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT now(),
+    updated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT now(),
+    PRIMARY KEY(delivery_ID),
+    FOREIGN KEY (webhook_ID) REFERENCES webhooks (webhook_ID) ON DELETE CASCADE,
+    FOREIGN KEY (user_ID) REFERENCES users (user_ID)
+);
+
+CREATE INDEX IF NOT EXISTS webhook_deliveries_due_idx ON webhook_deliveries (status, next_attempt);
+CREATE INDEX IF NOT EXISTS webhook_deliveries_user_idx ON webhook_deliveries (user_ID, status);
@@ -0,0 +1,3 @@
+ALTER TABLE urls
+    ADD COLUMN IF NOT EXISTS max_clicks BIGINT NOT NULL DEFAULT 0,
+    ADD COLUMN IF NOT EXISTS remaining_clicks BIGINT NOT NULL DEFAULT 0;
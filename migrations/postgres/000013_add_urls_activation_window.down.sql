@@ -0,0 +1,3 @@
+ALTER TABLE urls
+    DROP COLUMN IF EXISTS not_before,
+    DROP COLUMN IF EXISTS not_after;
@@ -0,0 +1,3 @@
+ALTER TABLE urls
+    ADD COLUMN IF NOT EXISTS not_before TIMESTAMP WITH TIME ZONE,
+    ADD COLUMN IF NOT EXISTS not_after TIMESTAMP WITH TIME ZONE;
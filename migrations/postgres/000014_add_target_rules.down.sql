@@ -0,0 +1,7 @@
+ALTER TABLE click_rollups_daily DROP COLUMN IF EXISTS target_rules;
+
+ALTER TABLE click_rollups_hourly DROP COLUMN IF EXISTS target_rules;
+
+ALTER TABLE clicks DROP COLUMN IF EXISTS target_rule;
+
+ALTER TABLE urls DROP COLUMN IF EXISTS target_rules;
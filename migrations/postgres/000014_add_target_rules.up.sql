@@ -0,0 +1,7 @@
+ALTER TABLE urls ADD COLUMN IF NOT EXISTS target_rules JSONB NOT NULL DEFAULT '[]';
+
+ALTER TABLE clicks ADD COLUMN IF NOT EXISTS target_rule VARCHAR(64) NOT NULL DEFAULT '';
+
+ALTER TABLE click_rollups_hourly ADD COLUMN IF NOT EXISTS target_rules JSONB NOT NULL DEFAULT '{}';
+
+ALTER TABLE click_rollups_daily ADD COLUMN IF NOT EXISTS target_rules JSONB NOT NULL DEFAULT '{}';
@@ -0,0 +1,7 @@
+ALTER TABLE click_rollups_daily DROP COLUMN IF EXISTS variants;
+
+ALTER TABLE click_rollups_hourly DROP COLUMN IF EXISTS variants;
+
+ALTER TABLE clicks DROP COLUMN IF EXISTS variant;
+
+ALTER TABLE urls DROP COLUMN IF EXISTS variants;
@@ -0,0 +1,7 @@
+ALTER TABLE urls ADD COLUMN IF NOT EXISTS variants JSONB NOT NULL DEFAULT '[]';
+
+ALTER TABLE clicks ADD COLUMN IF NOT EXISTS variant VARCHAR(64) NOT NULL DEFAULT '';
+
+ALTER TABLE click_rollups_hourly ADD COLUMN IF NOT EXISTS variants JSONB NOT NULL DEFAULT '{}';
+
+ALTER TABLE click_rollups_daily ADD COLUMN IF NOT EXISTS variants JSONB NOT NULL DEFAULT '{}';
@@ -0,0 +1,4 @@
+ALTER TABLE urls
+    DROP COLUMN IF EXISTS redirect_status,
+    DROP COLUMN IF EXISTS cache_control,
+    DROP COLUMN IF EXISTS meta_refresh;
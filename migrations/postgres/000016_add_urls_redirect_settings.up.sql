@@ -0,0 +1,4 @@
+ALTER TABLE urls
+    ADD COLUMN IF NOT EXISTS redirect_status INTEGER NOT NULL DEFAULT 0,
+    ADD COLUMN IF NOT EXISTS cache_control VARCHAR(256) NOT NULL DEFAULT '',
+    ADD COLUMN IF NOT EXISTS meta_refresh BOOLEAN NOT NULL DEFAULT FALSE;
@@ -0,0 +1,4 @@
+ALTER TABLE urls
+    DROP COLUMN IF EXISTS campaign_ID;
+
+DROP TABLE IF EXISTS campaigns;
@@ -0,0 +1,18 @@
+CREATE TABLE IF NOT EXISTS campaigns
+(
+    campaign_ID VARCHAR(36) NOT NULL,
+    user_ID INT NOT NULL,
+    name VARCHAR(100) NOT NULL,
+    parameters JSONB NOT NULL DEFAULT '{}',
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT now(),
+    updated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT now(),
+    PRIMARY KEY(campaign_ID),
+    FOREIGN KEY (user_ID) REFERENCES users (user_ID)
+);
+
+CREATE INDEX IF NOT EXISTS campaigns_user_id_idx ON campaigns (user_ID);
+
+ALTER TABLE urls
+    ADD COLUMN IF NOT EXISTS campaign_ID VARCHAR(36) REFERENCES campaigns (campaign_ID) ON DELETE SET NULL;
+
+CREATE INDEX IF NOT EXISTS urls_campaign_id_idx ON urls (campaign_ID);
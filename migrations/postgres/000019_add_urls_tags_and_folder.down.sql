@@ -0,0 +1,6 @@
+DROP INDEX IF EXISTS urls_user_id_folder_idx;
+DROP INDEX IF EXISTS urls_tags_idx;
+
+ALTER TABLE urls
+    DROP COLUMN IF EXISTS tags,
+    DROP COLUMN IF EXISTS folder;
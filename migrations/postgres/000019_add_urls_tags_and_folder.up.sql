@@ -0,0 +1,6 @@
+ALTER TABLE urls
+    ADD COLUMN IF NOT EXISTS tags TEXT[] NOT NULL DEFAULT '{}',
+    ADD COLUMN IF NOT EXISTS folder VARCHAR(100) NOT NULL DEFAULT '';
+
+CREATE INDEX IF NOT EXISTS urls_tags_idx ON urls USING GIN (tags);
+CREATE INDEX IF NOT EXISTS urls_user_id_folder_idx ON urls (user_ID, folder);
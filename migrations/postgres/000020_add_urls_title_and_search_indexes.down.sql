@@ -0,0 +1,12 @@
+DROP INDEX IF EXISTS urls_user_id_created_at_idx;
+DROP INDEX IF EXISTS urls_tags_trgm_idx;
+DROP INDEX IF EXISTS urls_title_trgm_idx;
+DROP INDEX IF EXISTS urls_shorturl_trgm_idx;
+DROP INDEX IF EXISTS urls_url_host_trgm_idx;
+DROP INDEX IF EXISTS urls_originalurl_trgm_idx;
+
+DROP FUNCTION IF EXISTS urls_tags_text(TEXT[]);
+DROP FUNCTION IF EXISTS urls_url_host(TEXT);
+
+ALTER TABLE urls
+    DROP COLUMN IF EXISTS title;
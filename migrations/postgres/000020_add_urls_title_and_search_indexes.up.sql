@@ -0,0 +1,19 @@
+CREATE EXTENSION IF NOT EXISTS pg_trgm;
+
+ALTER TABLE urls
+    ADD COLUMN IF NOT EXISTS title VARCHAR(200) NOT NULL DEFAULT '';
+
+CREATE OR REPLACE FUNCTION urls_url_host(url TEXT) RETURNS TEXT
+    LANGUAGE sql IMMUTABLE PARALLEL SAFE
+    AS $$ SELECT regexp_replace(lower(url), '^([^:/]*://)?(www\.)?', '') $$;
+
+CREATE OR REPLACE FUNCTION urls_tags_text(tags TEXT[]) RETURNS TEXT
+    LANGUAGE sql IMMUTABLE PARALLEL SAFE
+    AS $$ SELECT E'\n' || array_to_string(tags, E'\n') $$;
+
+CREATE INDEX IF NOT EXISTS urls_originalurl_trgm_idx ON urls USING GIN (lower(originalURL) gin_trgm_ops);
+CREATE INDEX IF NOT EXISTS urls_url_host_trgm_idx ON urls USING GIN (urls_url_host(originalURL) gin_trgm_ops);
+CREATE INDEX IF NOT EXISTS urls_shorturl_trgm_idx ON urls USING GIN (lower(shortURL) gin_trgm_ops);
+CREATE INDEX IF NOT EXISTS urls_title_trgm_idx ON urls USING GIN (lower(title) gin_trgm_ops);
+CREATE INDEX IF NOT EXISTS urls_tags_trgm_idx ON urls USING GIN (urls_tags_text(tags) gin_trgm_ops);
+CREATE INDEX IF NOT EXISTS urls_user_id_created_at_idx ON urls (user_ID, created_at);
@@ -0,0 +1,2 @@
+ALTER TABLE urls
+    ADD COLUMN IF NOT EXISTS metadata JSONB NOT NULL DEFAULT '{}';
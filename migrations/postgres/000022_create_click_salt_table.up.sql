@@ -0,0 +1,6 @@
+CREATE TABLE IF NOT EXISTS click_salt
+(
+    id INT NOT NULL DEFAULT 1 CHECK (id = 1),
+    salt BYTEA NOT NULL,
+    PRIMARY KEY(id)
+);